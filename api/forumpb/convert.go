@@ -0,0 +1,223 @@
+package forumpb
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+// Sort is the sort name the usecases take.
+func (s PostSort) Sort() string {
+	switch s {
+	case PostSort_POST_SORT_TREE:
+		return "tree"
+	case PostSort_POST_SORT_PARENT_TREE:
+		return "parent_tree"
+	}
+
+	return "flat"
+}
+
+func FromUser(user *models.User) *User {
+	if user == nil {
+		return nil
+	}
+
+	return &User{
+		Nickname: user.Nickname,
+		Email:    user.Email,
+		Fullname: user.FullName,
+		About:    user.About,
+		Version:  user.Version,
+	}
+}
+
+func FromUsers(users []*models.User) *Users {
+	res := &Users{Users: make([]*User, 0, len(users))}
+	for _, user := range users {
+		res.Users = append(res.Users, FromUser(user))
+	}
+
+	return res
+}
+
+func (u *User) Model() *models.User {
+	return &models.User{
+		Nickname: u.GetNickname(),
+		Email:    u.GetEmail(),
+		FullName: u.GetFullname(),
+		About:    u.GetAbout(),
+	}
+}
+
+func FromForum(forum *models.Forum) *Forum {
+	if forum == nil {
+		return nil
+	}
+
+	return &Forum{
+		Slug:  forum.Slug,
+		Title: forum.Title,
+		User:  forum.User,
+	}
+}
+
+func FromForumResponse(forum *models.ForumResponse) *Forum {
+	if forum == nil {
+		return nil
+	}
+
+	return &Forum{
+		Slug:    forum.Slug,
+		Title:   forum.Title,
+		User:    forum.User,
+		Threads: forum.Threads,
+		Posts:   forum.Posts,
+		Version: forum.Version,
+	}
+}
+
+func (f *Forum) Model() *models.Forum {
+	return &models.Forum{
+		Slug:  f.GetSlug(),
+		Title: f.GetTitle(),
+		User:  f.GetUser(),
+	}
+}
+
+func FromThread(thread *models.ThreadResponse) *Thread {
+	if thread == nil {
+		return nil
+	}
+
+	return &Thread{
+		Id:      thread.ID,
+		Slug:    thread.Slug,
+		Forum:   thread.Forum,
+		Author:  thread.Author,
+		Title:   thread.Title,
+		Message: thread.Message,
+		Created: timestamppb.New(thread.Created),
+		Votes:   thread.Votes,
+		Closed:  thread.Closed,
+		Pinned:  thread.Pinned,
+		Version: thread.Version,
+	}
+}
+
+func FromThreads(threads []*models.ThreadResponse) *Threads {
+	res := &Threads{Threads: make([]*Thread, 0, len(threads))}
+	for _, thread := range threads {
+		res.Threads = append(res.Threads, FromThread(thread))
+	}
+
+	return res
+}
+
+func (t *Thread) Model() *models.Thread {
+	thread := &models.Thread{
+		Author:  t.GetAuthor(),
+		Forum:   t.GetForum(),
+		Message: t.GetMessage(),
+		Slug:    t.GetSlug(),
+		Title:   t.GetTitle(),
+	}
+	if t.GetCreated() != nil {
+		thread.Created = t.GetCreated().AsTime()
+	}
+
+	return thread
+}
+
+// FromPost leaves created unset if the post has no valid creation time.
+func FromPost(post *models.Post) *Post {
+	if post == nil {
+		return nil
+	}
+
+	res := &Post{
+		Id:       post.ID,
+		Parent:   post.Parent,
+		Author:   post.Author,
+		Message:  post.Message,
+		Forum:    post.Forum,
+		Thread:   post.Thread,
+		IsEdited: post.IsEdited,
+		IsHidden: post.IsHidden,
+		Version:  post.Version,
+	}
+	if created, err := time.Parse(time.RFC3339Nano, post.Created); err == nil {
+		res.Created = timestamppb.New(created)
+	}
+	for _, attachment := range post.Attachments {
+		res.Attachments = append(res.Attachments, &Attachment{
+			Id:       attachment.ID,
+			Filename: attachment.Filename,
+			MimeType: attachment.MimeType,
+			Size:     attachment.Size,
+			Url:      attachment.URL,
+		})
+	}
+
+	return res
+}
+
+func FromPostEvent(post *models.PostEvent) *PostEvent {
+	if post == nil {
+		return nil
+	}
+
+	return &PostEvent{Position: post.Position, Post: FromPost(&post.Post)}
+}
+
+func FromPosts(posts []*models.Post) *Posts {
+	res := &Posts{Posts: make([]*Post, 0, len(posts))}
+	for _, post := range posts {
+		res.Posts = append(res.Posts, FromPost(post))
+	}
+
+	return res
+}
+
+func (p *Post) Model() *models.Post {
+	return &models.Post{
+		Parent:  p.GetParent(),
+		Author:  p.GetAuthor(),
+		Message: p.GetMessage(),
+	}
+}
+
+// Versions is the version list the usecases take for an update; zero
+// accepts any version.
+func Versions(version uint64) []int64 {
+	if version == 0 {
+		return nil
+	}
+
+	return []int64{int64(version)}
+}
+
+func optional(value *string) models.OptionalString {
+	if value == nil {
+		return models.OptionalString{}
+	}
+
+	return models.OptionalString{Value: *value, Present: true}
+}
+
+func (r *UpdateUserRequest) Update() *models.UserUpdate {
+	return &models.UserUpdate{
+		Email:    optional(r.Email),
+		FullName: optional(r.Fullname),
+		About:    optional(r.About),
+	}
+}
+
+func (r *UpdateThreadRequest) Update() *models.ThreadUpdate {
+	return &models.ThreadUpdate{
+		Title:   optional(r.Title),
+		Message: optional(r.Message),
+	}
+}
@@ -0,0 +1,909 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v3.21.12
+// source: forum.proto
+
+// The gRPC API serves the same usecases as the REST API of swagger.yml. Errors
+// carry the messages of the REST error bodies with the closest status code;
+// calls that need a user take it from "authorization: Bearer <token>"
+// metadata, or from the request in open auth mode.
+
+package forumpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	UserService_CreateUser_FullMethodName = "/forum.UserService/CreateUser"
+	UserService_GetUser_FullMethodName    = "/forum.UserService/GetUser"
+	UserService_UpdateUser_FullMethodName = "/forum.UserService/UpdateUser"
+)
+
+// UserServiceClient is the client API for UserService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type UserServiceClient interface {
+	// CreateUser fails with ALREADY_EXISTS when the nickname or the email is
+	// taken; the status details hold the users that took them as Users.
+	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*User, error)
+	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error)
+	UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*User, error)
+}
+
+type userServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUserServiceClient(cc grpc.ClientConnInterface) UserServiceClient {
+	return &userServiceClient{cc}
+}
+
+func (c *userServiceClient) CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, UserService_CreateUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, UserService_GetUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, UserService_UpdateUser_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UserServiceServer is the server API for UserService service.
+// All implementations must embed UnimplementedUserServiceServer
+// for forward compatibility
+type UserServiceServer interface {
+	// CreateUser fails with ALREADY_EXISTS when the nickname or the email is
+	// taken; the status details hold the users that took them as Users.
+	CreateUser(context.Context, *CreateUserRequest) (*User, error)
+	GetUser(context.Context, *GetUserRequest) (*User, error)
+	UpdateUser(context.Context, *UpdateUserRequest) (*User, error)
+	mustEmbedUnimplementedUserServiceServer()
+}
+
+// UnimplementedUserServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedUserServiceServer struct {
+}
+
+func (UnimplementedUserServiceServer) CreateUser(context.Context, *CreateUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
+}
+func (UnimplementedUserServiceServer) GetUser(context.Context, *GetUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+}
+func (UnimplementedUserServiceServer) UpdateUser(context.Context, *UpdateUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateUser not implemented")
+}
+func (UnimplementedUserServiceServer) mustEmbedUnimplementedUserServiceServer() {}
+
+// UnsafeUserServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UserServiceServer will
+// result in compilation errors.
+type UnsafeUserServiceServer interface {
+	mustEmbedUnimplementedUserServiceServer()
+}
+
+func RegisterUserServiceServer(s grpc.ServiceRegistrar, srv UserServiceServer) {
+	s.RegisterService(&UserService_ServiceDesc, srv)
+}
+
+func _UserService_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).CreateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_CreateUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).CreateUser(ctx, req.(*CreateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_GetUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetUser(ctx, req.(*GetUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_UpdateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).UpdateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_UpdateUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).UpdateUser(ctx, req.(*UpdateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// UserService_ServiceDesc is the grpc.ServiceDesc for UserService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var UserService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "forum.UserService",
+	HandlerType: (*UserServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateUser",
+			Handler:    _UserService_CreateUser_Handler,
+		},
+		{
+			MethodName: "GetUser",
+			Handler:    _UserService_GetUser_Handler,
+		},
+		{
+			MethodName: "UpdateUser",
+			Handler:    _UserService_UpdateUser_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "forum.proto",
+}
+
+const (
+	ForumService_CreateForum_FullMethodName = "/forum.ForumService/CreateForum"
+	ForumService_GetForum_FullMethodName    = "/forum.ForumService/GetForum"
+	ForumService_ListThreads_FullMethodName = "/forum.ForumService/ListThreads"
+	ForumService_ListUsers_FullMethodName   = "/forum.ForumService/ListUsers"
+)
+
+// ForumServiceClient is the client API for ForumService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ForumServiceClient interface {
+	// CreateForum fails with ALREADY_EXISTS when the slug is taken; the status
+	// details hold the existing forum.
+	CreateForum(ctx context.Context, in *CreateForumRequest, opts ...grpc.CallOption) (*Forum, error)
+	GetForum(ctx context.Context, in *GetForumRequest, opts ...grpc.CallOption) (*Forum, error)
+	ListThreads(ctx context.Context, in *ListThreadsRequest, opts ...grpc.CallOption) (*Threads, error)
+	ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*Users, error)
+}
+
+type forumServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewForumServiceClient(cc grpc.ClientConnInterface) ForumServiceClient {
+	return &forumServiceClient{cc}
+}
+
+func (c *forumServiceClient) CreateForum(ctx context.Context, in *CreateForumRequest, opts ...grpc.CallOption) (*Forum, error) {
+	out := new(Forum)
+	err := c.cc.Invoke(ctx, ForumService_CreateForum_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *forumServiceClient) GetForum(ctx context.Context, in *GetForumRequest, opts ...grpc.CallOption) (*Forum, error) {
+	out := new(Forum)
+	err := c.cc.Invoke(ctx, ForumService_GetForum_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *forumServiceClient) ListThreads(ctx context.Context, in *ListThreadsRequest, opts ...grpc.CallOption) (*Threads, error) {
+	out := new(Threads)
+	err := c.cc.Invoke(ctx, ForumService_ListThreads_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *forumServiceClient) ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*Users, error) {
+	out := new(Users)
+	err := c.cc.Invoke(ctx, ForumService_ListUsers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ForumServiceServer is the server API for ForumService service.
+// All implementations must embed UnimplementedForumServiceServer
+// for forward compatibility
+type ForumServiceServer interface {
+	// CreateForum fails with ALREADY_EXISTS when the slug is taken; the status
+	// details hold the existing forum.
+	CreateForum(context.Context, *CreateForumRequest) (*Forum, error)
+	GetForum(context.Context, *GetForumRequest) (*Forum, error)
+	ListThreads(context.Context, *ListThreadsRequest) (*Threads, error)
+	ListUsers(context.Context, *ListUsersRequest) (*Users, error)
+	mustEmbedUnimplementedForumServiceServer()
+}
+
+// UnimplementedForumServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedForumServiceServer struct {
+}
+
+func (UnimplementedForumServiceServer) CreateForum(context.Context, *CreateForumRequest) (*Forum, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateForum not implemented")
+}
+func (UnimplementedForumServiceServer) GetForum(context.Context, *GetForumRequest) (*Forum, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetForum not implemented")
+}
+func (UnimplementedForumServiceServer) ListThreads(context.Context, *ListThreadsRequest) (*Threads, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListThreads not implemented")
+}
+func (UnimplementedForumServiceServer) ListUsers(context.Context, *ListUsersRequest) (*Users, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUsers not implemented")
+}
+func (UnimplementedForumServiceServer) mustEmbedUnimplementedForumServiceServer() {}
+
+// UnsafeForumServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ForumServiceServer will
+// result in compilation errors.
+type UnsafeForumServiceServer interface {
+	mustEmbedUnimplementedForumServiceServer()
+}
+
+func RegisterForumServiceServer(s grpc.ServiceRegistrar, srv ForumServiceServer) {
+	s.RegisterService(&ForumService_ServiceDesc, srv)
+}
+
+func _ForumService_CreateForum_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateForumRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ForumServiceServer).CreateForum(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ForumService_CreateForum_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ForumServiceServer).CreateForum(ctx, req.(*CreateForumRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ForumService_GetForum_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetForumRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ForumServiceServer).GetForum(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ForumService_GetForum_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ForumServiceServer).GetForum(ctx, req.(*GetForumRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ForumService_ListThreads_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListThreadsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ForumServiceServer).ListThreads(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ForumService_ListThreads_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ForumServiceServer).ListThreads(ctx, req.(*ListThreadsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ForumService_ListUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ForumServiceServer).ListUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ForumService_ListUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ForumServiceServer).ListUsers(ctx, req.(*ListUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ForumService_ServiceDesc is the grpc.ServiceDesc for ForumService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ForumService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "forum.ForumService",
+	HandlerType: (*ForumServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateForum",
+			Handler:    _ForumService_CreateForum_Handler,
+		},
+		{
+			MethodName: "GetForum",
+			Handler:    _ForumService_GetForum_Handler,
+		},
+		{
+			MethodName: "ListThreads",
+			Handler:    _ForumService_ListThreads_Handler,
+		},
+		{
+			MethodName: "ListUsers",
+			Handler:    _ForumService_ListUsers_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "forum.proto",
+}
+
+const (
+	ThreadService_CreateThread_FullMethodName   = "/forum.ThreadService/CreateThread"
+	ThreadService_GetThread_FullMethodName      = "/forum.ThreadService/GetThread"
+	ThreadService_UpdateThread_FullMethodName   = "/forum.ThreadService/UpdateThread"
+	ThreadService_Vote_FullMethodName           = "/forum.ThreadService/Vote"
+	ThreadService_ListPosts_FullMethodName      = "/forum.ThreadService/ListPosts"
+	ThreadService_SubscribePosts_FullMethodName = "/forum.ThreadService/SubscribePosts"
+)
+
+// ThreadServiceClient is the client API for ThreadService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ThreadServiceClient interface {
+	// CreateThread fails with ALREADY_EXISTS when the slug is taken; the status
+	// details hold the existing thread.
+	CreateThread(ctx context.Context, in *CreateThreadRequest, opts ...grpc.CallOption) (*Thread, error)
+	GetThread(ctx context.Context, in *GetThreadRequest, opts ...grpc.CallOption) (*Thread, error)
+	UpdateThread(ctx context.Context, in *UpdateThreadRequest, opts ...grpc.CallOption) (*Thread, error)
+	// Vote replaces the user's earlier vote in the thread, if any.
+	Vote(ctx context.Context, in *VoteRequest, opts ...grpc.CallOption) (*Thread, error)
+	// ListPosts streams the posts of the thread in the requested order, page
+	// by page, until the thread runs out or the limit is reached.
+	ListPosts(ctx context.Context, in *ListPostsRequest, opts ...grpc.CallOption) (ThreadService_ListPostsClient, error)
+	// SubscribePosts streams the posts created in the thread from now on, in
+	// commit order. With a last_position it first sends the posts created
+	// after that one, so a dropped subscription resumes without gaps.
+	SubscribePosts(ctx context.Context, in *SubscribePostsRequest, opts ...grpc.CallOption) (ThreadService_SubscribePostsClient, error)
+}
+
+type threadServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewThreadServiceClient(cc grpc.ClientConnInterface) ThreadServiceClient {
+	return &threadServiceClient{cc}
+}
+
+func (c *threadServiceClient) CreateThread(ctx context.Context, in *CreateThreadRequest, opts ...grpc.CallOption) (*Thread, error) {
+	out := new(Thread)
+	err := c.cc.Invoke(ctx, ThreadService_CreateThread_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *threadServiceClient) GetThread(ctx context.Context, in *GetThreadRequest, opts ...grpc.CallOption) (*Thread, error) {
+	out := new(Thread)
+	err := c.cc.Invoke(ctx, ThreadService_GetThread_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *threadServiceClient) UpdateThread(ctx context.Context, in *UpdateThreadRequest, opts ...grpc.CallOption) (*Thread, error) {
+	out := new(Thread)
+	err := c.cc.Invoke(ctx, ThreadService_UpdateThread_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *threadServiceClient) Vote(ctx context.Context, in *VoteRequest, opts ...grpc.CallOption) (*Thread, error) {
+	out := new(Thread)
+	err := c.cc.Invoke(ctx, ThreadService_Vote_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *threadServiceClient) ListPosts(ctx context.Context, in *ListPostsRequest, opts ...grpc.CallOption) (ThreadService_ListPostsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ThreadService_ServiceDesc.Streams[0], ThreadService_ListPosts_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &threadServiceListPostsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ThreadService_ListPostsClient interface {
+	Recv() (*Post, error)
+	grpc.ClientStream
+}
+
+type threadServiceListPostsClient struct {
+	grpc.ClientStream
+}
+
+func (x *threadServiceListPostsClient) Recv() (*Post, error) {
+	m := new(Post)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *threadServiceClient) SubscribePosts(ctx context.Context, in *SubscribePostsRequest, opts ...grpc.CallOption) (ThreadService_SubscribePostsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ThreadService_ServiceDesc.Streams[1], ThreadService_SubscribePosts_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &threadServiceSubscribePostsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ThreadService_SubscribePostsClient interface {
+	Recv() (*PostEvent, error)
+	grpc.ClientStream
+}
+
+type threadServiceSubscribePostsClient struct {
+	grpc.ClientStream
+}
+
+func (x *threadServiceSubscribePostsClient) Recv() (*PostEvent, error) {
+	m := new(PostEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ThreadServiceServer is the server API for ThreadService service.
+// All implementations must embed UnimplementedThreadServiceServer
+// for forward compatibility
+type ThreadServiceServer interface {
+	// CreateThread fails with ALREADY_EXISTS when the slug is taken; the status
+	// details hold the existing thread.
+	CreateThread(context.Context, *CreateThreadRequest) (*Thread, error)
+	GetThread(context.Context, *GetThreadRequest) (*Thread, error)
+	UpdateThread(context.Context, *UpdateThreadRequest) (*Thread, error)
+	// Vote replaces the user's earlier vote in the thread, if any.
+	Vote(context.Context, *VoteRequest) (*Thread, error)
+	// ListPosts streams the posts of the thread in the requested order, page
+	// by page, until the thread runs out or the limit is reached.
+	ListPosts(*ListPostsRequest, ThreadService_ListPostsServer) error
+	// SubscribePosts streams the posts created in the thread from now on, in
+	// commit order. With a last_position it first sends the posts created
+	// after that one, so a dropped subscription resumes without gaps.
+	SubscribePosts(*SubscribePostsRequest, ThreadService_SubscribePostsServer) error
+	mustEmbedUnimplementedThreadServiceServer()
+}
+
+// UnimplementedThreadServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedThreadServiceServer struct {
+}
+
+func (UnimplementedThreadServiceServer) CreateThread(context.Context, *CreateThreadRequest) (*Thread, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateThread not implemented")
+}
+func (UnimplementedThreadServiceServer) GetThread(context.Context, *GetThreadRequest) (*Thread, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetThread not implemented")
+}
+func (UnimplementedThreadServiceServer) UpdateThread(context.Context, *UpdateThreadRequest) (*Thread, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateThread not implemented")
+}
+func (UnimplementedThreadServiceServer) Vote(context.Context, *VoteRequest) (*Thread, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Vote not implemented")
+}
+func (UnimplementedThreadServiceServer) ListPosts(*ListPostsRequest, ThreadService_ListPostsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListPosts not implemented")
+}
+func (UnimplementedThreadServiceServer) SubscribePosts(*SubscribePostsRequest, ThreadService_SubscribePostsServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribePosts not implemented")
+}
+func (UnimplementedThreadServiceServer) mustEmbedUnimplementedThreadServiceServer() {}
+
+// UnsafeThreadServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ThreadServiceServer will
+// result in compilation errors.
+type UnsafeThreadServiceServer interface {
+	mustEmbedUnimplementedThreadServiceServer()
+}
+
+func RegisterThreadServiceServer(s grpc.ServiceRegistrar, srv ThreadServiceServer) {
+	s.RegisterService(&ThreadService_ServiceDesc, srv)
+}
+
+func _ThreadService_CreateThread_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateThreadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ThreadServiceServer).CreateThread(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ThreadService_CreateThread_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ThreadServiceServer).CreateThread(ctx, req.(*CreateThreadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ThreadService_GetThread_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetThreadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ThreadServiceServer).GetThread(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ThreadService_GetThread_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ThreadServiceServer).GetThread(ctx, req.(*GetThreadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ThreadService_UpdateThread_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateThreadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ThreadServiceServer).UpdateThread(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ThreadService_UpdateThread_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ThreadServiceServer).UpdateThread(ctx, req.(*UpdateThreadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ThreadService_Vote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ThreadServiceServer).Vote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ThreadService_Vote_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ThreadServiceServer).Vote(ctx, req.(*VoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ThreadService_ListPosts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListPostsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ThreadServiceServer).ListPosts(m, &threadServiceListPostsServer{stream})
+}
+
+type ThreadService_ListPostsServer interface {
+	Send(*Post) error
+	grpc.ServerStream
+}
+
+type threadServiceListPostsServer struct {
+	grpc.ServerStream
+}
+
+func (x *threadServiceListPostsServer) Send(m *Post) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ThreadService_SubscribePosts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribePostsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ThreadServiceServer).SubscribePosts(m, &threadServiceSubscribePostsServer{stream})
+}
+
+type ThreadService_SubscribePostsServer interface {
+	Send(*PostEvent) error
+	grpc.ServerStream
+}
+
+type threadServiceSubscribePostsServer struct {
+	grpc.ServerStream
+}
+
+func (x *threadServiceSubscribePostsServer) Send(m *PostEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// ThreadService_ServiceDesc is the grpc.ServiceDesc for ThreadService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ThreadService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "forum.ThreadService",
+	HandlerType: (*ThreadServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateThread",
+			Handler:    _ThreadService_CreateThread_Handler,
+		},
+		{
+			MethodName: "GetThread",
+			Handler:    _ThreadService_GetThread_Handler,
+		},
+		{
+			MethodName: "UpdateThread",
+			Handler:    _ThreadService_UpdateThread_Handler,
+		},
+		{
+			MethodName: "Vote",
+			Handler:    _ThreadService_Vote_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListPosts",
+			Handler:       _ThreadService_ListPosts_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribePosts",
+			Handler:       _ThreadService_SubscribePosts_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "forum.proto",
+}
+
+const (
+	PostService_CreatePosts_FullMethodName = "/forum.PostService/CreatePosts"
+	PostService_GetPost_FullMethodName     = "/forum.PostService/GetPost"
+	PostService_UpdatePost_FullMethodName  = "/forum.PostService/UpdatePost"
+)
+
+// PostServiceClient is the client API for PostService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PostServiceClient interface {
+	// CreatePosts creates all of the posts or none of them.
+	CreatePosts(ctx context.Context, in *CreatePostsRequest, opts ...grpc.CallOption) (*Posts, error)
+	GetPost(ctx context.Context, in *GetPostRequest, opts ...grpc.CallOption) (*PostDetails, error)
+	UpdatePost(ctx context.Context, in *UpdatePostRequest, opts ...grpc.CallOption) (*Post, error)
+}
+
+type postServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPostServiceClient(cc grpc.ClientConnInterface) PostServiceClient {
+	return &postServiceClient{cc}
+}
+
+func (c *postServiceClient) CreatePosts(ctx context.Context, in *CreatePostsRequest, opts ...grpc.CallOption) (*Posts, error) {
+	out := new(Posts)
+	err := c.cc.Invoke(ctx, PostService_CreatePosts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postServiceClient) GetPost(ctx context.Context, in *GetPostRequest, opts ...grpc.CallOption) (*PostDetails, error) {
+	out := new(PostDetails)
+	err := c.cc.Invoke(ctx, PostService_GetPost_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *postServiceClient) UpdatePost(ctx context.Context, in *UpdatePostRequest, opts ...grpc.CallOption) (*Post, error) {
+	out := new(Post)
+	err := c.cc.Invoke(ctx, PostService_UpdatePost_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PostServiceServer is the server API for PostService service.
+// All implementations must embed UnimplementedPostServiceServer
+// for forward compatibility
+type PostServiceServer interface {
+	// CreatePosts creates all of the posts or none of them.
+	CreatePosts(context.Context, *CreatePostsRequest) (*Posts, error)
+	GetPost(context.Context, *GetPostRequest) (*PostDetails, error)
+	UpdatePost(context.Context, *UpdatePostRequest) (*Post, error)
+	mustEmbedUnimplementedPostServiceServer()
+}
+
+// UnimplementedPostServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedPostServiceServer struct {
+}
+
+func (UnimplementedPostServiceServer) CreatePosts(context.Context, *CreatePostsRequest) (*Posts, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePosts not implemented")
+}
+func (UnimplementedPostServiceServer) GetPost(context.Context, *GetPostRequest) (*PostDetails, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPost not implemented")
+}
+func (UnimplementedPostServiceServer) UpdatePost(context.Context, *UpdatePostRequest) (*Post, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePost not implemented")
+}
+func (UnimplementedPostServiceServer) mustEmbedUnimplementedPostServiceServer() {}
+
+// UnsafePostServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PostServiceServer will
+// result in compilation errors.
+type UnsafePostServiceServer interface {
+	mustEmbedUnimplementedPostServiceServer()
+}
+
+func RegisterPostServiceServer(s grpc.ServiceRegistrar, srv PostServiceServer) {
+	s.RegisterService(&PostService_ServiceDesc, srv)
+}
+
+func _PostService_CreatePosts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePostsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).CreatePosts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PostService_CreatePosts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).CreatePosts(ctx, req.(*CreatePostsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostService_GetPost_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).GetPost(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PostService_GetPost_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).GetPost(ctx, req.(*GetPostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PostService_UpdatePost_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PostServiceServer).UpdatePost(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PostService_UpdatePost_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PostServiceServer).UpdatePost(ctx, req.(*UpdatePostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PostService_ServiceDesc is the grpc.ServiceDesc for PostService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PostService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "forum.PostService",
+	HandlerType: (*PostServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreatePosts",
+			Handler:    _PostService_CreatePosts_Handler,
+		},
+		{
+			MethodName: "GetPost",
+			Handler:    _PostService_GetPost_Handler,
+		},
+		{
+			MethodName: "UpdatePost",
+			Handler:    _PostService_UpdatePost_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "forum.proto",
+}
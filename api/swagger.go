@@ -0,0 +1,8 @@
+package api
+
+import _ "embed"
+
+// Spec is the API contract the contract checker holds the server to.
+//
+//go:embed swagger.yml
+var Spec []byte
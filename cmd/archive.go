@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	archiveRepository "technopark-dbms-forum/internal/archive/repository"
+	archiveUsecase "technopark-dbms-forum/internal/archive/usecase"
+	"technopark-dbms-forum/internal/config"
+	roleRepository "technopark-dbms-forum/internal/roles/repository"
+	roleUsecase "technopark-dbms-forum/internal/roles/usecase"
+	logger "technopark-dbms-forum/pkg"
+)
+
+func newArchiveUsecase(cfg *config.Config) (*archiveUsecase.ArchiveUsecase, func(), error) {
+	url := cfg.PostgresURL()
+
+	archives, err := archiveRepository.NewPostgres(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	roles, err := roleRepository.NewPostgres(url)
+	if err != nil {
+		archives.Close()
+		return nil, nil, err
+	}
+
+	closeAll := func() {
+		archives.Close()
+		roles.Close()
+	}
+
+	return archiveUsecase.NewArchiveUsecase(archives, roleUsecase.NewRoleUsecase(roles)), closeAll, nil
+}
+
+func export(cfg *config.Config, args []string) error {
+	flags := newFlagSet("export")
+	slug := flags.String("forum", "", "slug of the forum to export")
+	out := flags.String("out", "", "archive file, - for stdout (default <forum>.tar.gz)")
+	flags.Parse(args)
+
+	if *slug == "" {
+		return errors.New("export needs -forum")
+	}
+	if *out == "" {
+		*out = *slug + ".tar.gz"
+	}
+
+	archives, closeAll, err := newArchiveUsecase(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	// The command line is trusted like the admin API.
+	if err = archives.ExportTrusted(*slug, w); err != nil {
+		if *out != "-" {
+			os.Remove(*out)
+		}
+		return err
+	}
+	if *out != "-" {
+		logger.GetInstance().Infof("export: forum %s written to %s", *slug, *out)
+	}
+
+	return nil
+}
+
+func importArchive(cfg *config.Config, args []string) error {
+	flags := newFlagSet("import")
+	in := flags.String("in", "-", "archive file, - for stdin")
+	flags.Parse(args)
+
+	archives, closeAll, err := newArchiveUsecase(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+
+	var r io.Reader = os.Stdin
+	if *in != "-" {
+		file, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	summary, err := archives.Import(r)
+	if err != nil {
+		return err
+	}
+	logger.GetInstance().Infof(
+		"import: forum %s with %d threads, %d posts and %d votes; %d new users",
+		summary.Forum, summary.Threads, summary.Posts, summary.Votes, summary.Users,
+	)
+
+	return nil
+}
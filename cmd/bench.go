@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"technopark-dbms-forum/internal/bench"
+	"technopark-dbms-forum/internal/config"
+	seedData "technopark-dbms-forum/internal/seed"
+	logger "technopark-dbms-forum/pkg"
+)
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// compareReports prints how the new report differs from the old one and
+// fails when a route regressed, so that scripts can stop on it.
+func compareReports(old, new *bench.Report, threshold float64) error {
+	deltas := bench.Compare(old, new, threshold)
+
+	fmt.Println()
+	if err := bench.WriteComparison(os.Stdout, deltas); err != nil {
+		return err
+	}
+
+	regressed := 0
+	for _, d := range deltas {
+		if d.Regressed {
+			regressed++
+		}
+	}
+	if regressed > 0 {
+		return fmt.Errorf("bench: %d routes regressed by more than %.0f%%", regressed, threshold*100)
+	}
+
+	return nil
+}
+
+func runBench(cfg *config.Config, args []string) error {
+	if len(args) > 0 && args[0] == "compare" {
+		return benchCompare(args[1:])
+	}
+
+	benchCfg := bench.DefaultConfig()
+	benchCfg.URL = "http://localhost:" + cfg.Port + "/api"
+
+	flags := newFlagSet("bench")
+	flags.StringVar(&benchCfg.URL, "url", benchCfg.URL, "API root of the server under test")
+	flags.StringVar(&benchCfg.Token, "token", "", "bearer token sent with every request; the server should run with AUTH_MODE=open otherwise")
+	flags.StringVar(&benchCfg.Mix, "mix", benchCfg.Mix, "comma separated route=weight pairs")
+	flags.Float64Var(&benchCfg.RPS, "rps", benchCfg.RPS, "target requests per second")
+	flags.DurationVar(&benchCfg.Duration, "duration", benchCfg.Duration, "measured run time")
+	flags.DurationVar(&benchCfg.Warmup, "warmup", benchCfg.Warmup, "unmeasured run time before the measurement")
+	flags.IntVar(&benchCfg.Workers, "workers", benchCfg.Workers, "maximum requests in flight")
+	flags.DurationVar(&benchCfg.Timeout, "timeout", benchCfg.Timeout, "request timeout")
+	flags.Int64Var(&benchCfg.Seed, "seed", benchCfg.Seed, "random seed")
+	manifestPath := flags.String("manifest", "seed.json", "manifest written by seed -manifest")
+	out := flags.String("out", "", "write the JSON report to this file")
+	baseline := flags.String("baseline", "", "compare the run with this JSON report")
+	threshold := flags.Float64("threshold", 0.1, "relative p95 or p99 growth that counts as a regression")
+	flags.Parse(args)
+
+	manifest := &seedData.Manifest{}
+	if err := readJSON(*manifestPath, manifest); err != nil {
+		return fmt.Errorf("read seed manifest: %w", err)
+	}
+
+	var old *bench.Report
+	if *baseline != "" {
+		old = &bench.Report{}
+		if err := readJSON(*baseline, old); err != nil {
+			return fmt.Errorf("read baseline: %w", err)
+		}
+	}
+
+	b, err := bench.New(benchCfg, manifest)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	logger.GetInstance().Infof("bench: %.0f rps for %s after %s of warmup", benchCfg.RPS, benchCfg.Duration, benchCfg.Warmup)
+	report, err := b.Run(ctx)
+	if err != nil && err != context.Canceled {
+		return err
+	}
+
+	if err = report.WriteText(os.Stdout); err != nil {
+		return err
+	}
+	if *out != "" {
+		if err = writeJSON(*out, report); err != nil {
+			return err
+		}
+	}
+
+	if old != nil {
+		return compareReports(old, report, *threshold)
+	}
+
+	return nil
+}
+
+func benchCompare(args []string) error {
+	flags := newFlagSet("bench compare")
+	threshold := flags.Float64("threshold", 0.1, "relative p95 or p99 growth that counts as a regression")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s bench compare [flags] old.json new.json\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	old, new := &bench.Report{}, &bench.Report{}
+	if err := readJSON(flags.Arg(0), old); err != nil {
+		return err
+	}
+	if err := readJSON(flags.Arg(1), new); err != nil {
+		return err
+	}
+
+	return compareReports(old, new, *threshold)
+}
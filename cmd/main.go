@@ -1,36 +1,71 @@
 package main
 
 import (
-	"github.com/labstack/echo-contrib/prometheus"
-	"github.com/labstack/echo/v4"
-	"github.com/labstack/gommon/log"
+	"flag"
+	"fmt"
 	"os"
-	service "technopark-dbms-forum/internal/init"
+	"strings"
+
+	"technopark-dbms-forum/internal/config"
 	logger "technopark-dbms-forum/pkg"
 )
 
-func main() {
-	l := logger.GetInstance()
-
-	e := echo.New()
+type command struct {
+	name    string
+	summary string
+	run     func(cfg *config.Config, args []string) error
+}
 
-	prometheusEcho := echo.New()
-	p := prometheus.NewPrometheus("echo", nil)
-	e.Use(p.HandlerFunc)
-	p.SetMetricsPath(prometheusEcho)
+var commands = []command{
+	{"serve", "start the API server (the default)", serve},
+	{"migrate", "apply db/db.sql to the database", migrate},
+	{"seed", "fill the database with synthetic data", seed},
+	{"recount", "rebuild denormalized counters from source rows", recount},
+	{"export", "write a forum archive", export},
+	{"import", "recreate a forum from an archive", importArchive},
+	{"bench", "replay a route mix against a server and report latencies", runBench},
+}
 
-	e.Logger = l
-	prometheusEcho.Logger = l
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [command] [flags]\n\ncommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the command's flags. Every command reads the same environment as the server.\n", os.Args[0])
+}
 
-	s := service.NewServer(e)
+// newFlagSet returns a flag set that prints the command name in its usage.
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet(os.Args[0]+" "+name, flag.ExitOnError)
+}
 
-	pgURL := "host=" + os.Getenv("DB_HOST") + " port=" + os.Getenv("DB_PORT") + " user=" + os.Getenv("DB_USER") + " password=" + os.Getenv("DB_PASSWORD") + " dbname=forum-task sslmode=disable"
+func main() {
+	l := logger.GetInstance()
 
-	l.Infof("%s: %s", "POSTGRES_URL", pgURL)
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		usage()
+		return
+	}
 
-	go func() { prometheusEcho.Logger.Fatal(prometheusEcho.Start(":" + os.Getenv("METRICS_PORT"))) }()
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
 
-	if err := s.Start(":"+os.Getenv("PORT"), pgURL); err != nil {
-		log.Error(err)
+		cfg, err := config.Load()
+		if err != nil {
+			l.Fatal(err)
+		}
+		if err = cmd.run(cfg, args); err != nil {
+			l.Fatal(err)
+		}
+		return
 	}
+
+	usage()
+	os.Exit(2)
 }
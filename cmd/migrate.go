@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"technopark-dbms-forum/db"
+	"technopark-dbms-forum/internal/config"
+	systemRepository "technopark-dbms-forum/internal/system/repository"
+	logger "technopark-dbms-forum/pkg"
+)
+
+func migrate(cfg *config.Config, args []string) error {
+	flags := newFlagSet("migrate")
+	file := flags.String("file", "", "schema file to apply instead of the built-in db/db.sql")
+	reset := flags.Bool("reset", false, "confirm that existing tables may be dropped")
+	flags.Parse(args)
+
+	if !*reset {
+		return errors.New("migrate recreates every table and drops its data; pass -reset to confirm")
+	}
+
+	schema := db.Schema
+	if *file != "" {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		schema = string(data)
+	}
+
+	repo, err := systemRepository.NewPostgres(cfg.PostgresURL())
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	if err = repo.ApplySchema(schema); err != nil {
+		return err
+	}
+	logger.GetInstance().Info("migrate: schema applied")
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"technopark-dbms-forum/internal/config"
+	counterRepository "technopark-dbms-forum/internal/counters/repository"
+	counterUsecase "technopark-dbms-forum/internal/counters/usecase"
+	logger "technopark-dbms-forum/pkg"
+)
+
+func recount(cfg *config.Config, args []string) error {
+	flags := newFlagSet("recount")
+	dryRun := flags.Bool("dry-run", false, "report the drift without fixing it")
+	batch := flags.Int("batch", cfg.CounterReconcileBatch, "forums or threads checked per batch")
+	report := flags.Bool("report", false, "print the full JSON report to stdout")
+	flags.Parse(args)
+
+	repo, err := counterRepository.NewPostgres(cfg.PostgresURL())
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	result, err := counterUsecase.NewCounterUsecase(repo, *batch).Reconcile(!*dryRun)
+	if err != nil {
+		return err
+	}
+
+	l := logger.GetInstance()
+	for _, counter := range result.Counters {
+		l.Infof("recount: %s: %d checked, %d drifted by %d, %d fixed", counter.Counter, counter.Checked, counter.Drifted, counter.Drift, counter.Fixed)
+	}
+
+	if *report {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"technopark-dbms-forum/internal/config"
+	forumRepository "technopark-dbms-forum/internal/forums/repository"
+	seedData "technopark-dbms-forum/internal/seed"
+	threadRepository "technopark-dbms-forum/internal/threads/repository"
+	userRepository "technopark-dbms-forum/internal/users/repository"
+	logger "technopark-dbms-forum/pkg"
+)
+
+func seed(cfg *config.Config, args []string) error {
+	seedCfg := seedData.DefaultConfig()
+
+	flags := newFlagSet("seed")
+	flags.StringVar(&seedCfg.Prefix, "prefix", seedCfg.Prefix, "prefix of generated nicknames and slugs")
+	flags.Int64Var(&seedCfg.Seed, "seed", seedCfg.Seed, "random seed")
+	flags.IntVar(&seedCfg.Users, "users", seedCfg.Users, "number of users")
+	flags.IntVar(&seedCfg.Forums, "forums", seedCfg.Forums, "number of forums")
+	flags.IntVar(&seedCfg.Threads, "threads", seedCfg.Threads, "number of threads")
+	flags.IntVar(&seedCfg.Posts, "posts", seedCfg.Posts, "number of posts")
+	flags.IntVar(&seedCfg.Votes, "votes", seedCfg.Votes, "number of votes")
+	flags.Float64Var(&seedCfg.ForumSkew, "forum-skew", seedCfg.ForumSkew, "Zipf exponent of threads per forum; 1 or less is uniform")
+	flags.Float64Var(&seedCfg.ThreadSkew, "thread-skew", seedCfg.ThreadSkew, "Zipf exponent of posts per thread; 1 or less is uniform")
+	flags.Float64Var(&seedCfg.ReplyRatio, "reply-ratio", seedCfg.ReplyRatio, "share of posts that reply to an earlier post")
+	flags.IntVar(&seedCfg.MaxDepth, "max-depth", seedCfg.MaxDepth, "maximum depth of a post tree")
+	flags.Float64Var(&seedCfg.SlugRatio, "slug-ratio", seedCfg.SlugRatio, "share of threads that get a slug")
+	flags.IntVar(&seedCfg.BatchSize, "batch", seedCfg.BatchSize, "posts per insert")
+	out := flags.String("manifest", "", "write the list of created users, forums and threads to this file")
+	flags.Parse(args)
+
+	url := cfg.PostgresURL()
+	users, err := userRepository.NewPostgres(url)
+	if err != nil {
+		return err
+	}
+	defer users.Close()
+	forums, err := forumRepository.NewPostgres(url)
+	if err != nil {
+		return err
+	}
+	defer forums.Close()
+	threads, err := threadRepository.NewPostgres(url)
+	if err != nil {
+		return err
+	}
+	defer threads.Close()
+
+	l := logger.GetInstance()
+	seeder := seedData.NewSeeder(seedCfg, users, forums, threads)
+	seeder.Progress = l.Infof
+
+	manifest, err := seeder.Run()
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(*out, data, 0o644); err != nil {
+			return err
+		}
+		l.Infof("seed: manifest written to %s", *out)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"time"
+
+	"github.com/labstack/echo-contrib/prometheus"
+	"github.com/labstack/echo/v4"
+
+	"technopark-dbms-forum/internal/config"
+	eventUsecase "technopark-dbms-forum/internal/events/usecase"
+	service "technopark-dbms-forum/internal/init"
+	logger "technopark-dbms-forum/pkg"
+)
+
+func serve(cfg *config.Config, args []string) error {
+	newFlagSet("serve").Parse(args)
+	if err := cfg.CheckServe(); err != nil {
+		return err
+	}
+
+	l := logger.GetInstance()
+
+	e := echo.New()
+
+	prometheusEcho := echo.New()
+	p := prometheus.NewPrometheus("echo", nil)
+	e.Use(p.HandlerFunc)
+	p.SetMetricsPath(prometheusEcho)
+
+	e.Logger = l
+	prometheusEcho.Logger = l
+
+	s := service.NewServer(e, cfg)
+
+	if cfg.EventsFile != "" {
+		s.AddEventSink(eventUsecase.NewFileSink(cfg.EventsFile))
+	}
+	if cfg.EventsWebhook != "" {
+		s.AddEventSink(eventUsecase.NewWebhookSink(cfg.EventsWebhook, 10*time.Second))
+	}
+
+	pgURL := cfg.PostgresURL()
+
+	l.Infof("%s: %s", "POSTGRES_URL", pgURL)
+
+	go func() { prometheusEcho.Logger.Fatal(prometheusEcho.Start(":" + cfg.MetricsPort)) }()
+
+	return s.Start(":"+cfg.Port, pgURL)
+}
@@ -4,6 +4,25 @@ DROP TABLE IF EXISTS forums CASCADE;
 DROP TABLE IF EXISTS threads CASCADE;
 DROP TABLE IF EXISTS posts CASCADE;
 DROP TABLE IF EXISTS votes CASCADE;
+DROP TABLE IF EXISTS thread_revisions CASCADE;
+DROP TABLE IF EXISTS thread_slug_redirects CASCADE;
+DROP TABLE IF EXISTS idempotency_keys CASCADE;
+DROP TABLE IF EXISTS events CASCADE;
+DROP TABLE IF EXISTS event_cursors CASCADE;
+DROP TABLE IF EXISTS notifications CASCADE;
+DROP TABLE IF EXISTS thread_subscriptions CASCADE;
+DROP TABLE IF EXISTS forum_follows CASCADE;
+DROP TABLE IF EXISTS user_credentials CASCADE;
+DROP TABLE IF EXISTS site_admins CASCADE;
+DROP TABLE IF EXISTS forum_moderators CASCADE;
+DROP TABLE IF EXISTS admin_audit CASCADE;
+DROP TABLE IF EXISTS bans CASCADE;
+DROP TABLE IF EXISTS forum_rate_limits CASCADE;
+DROP TABLE IF EXISTS rate_buckets CASCADE;
+DROP TABLE IF EXISTS moderation_queue CASCADE;
+DROP TABLE IF EXISTS message_fingerprints CASCADE;
+DROP TABLE IF EXISTS attachments CASCADE;
+DROP TABLE IF EXISTS attachment_objects CASCADE;
 
 CREATE EXTENSION IF NOT EXISTS citext;
 
@@ -12,7 +31,14 @@ CREATE TABLE IF NOT EXISTS users
     nickname citext COLLATE "ucs_basic" PRIMARY KEY NOT NULL,
     fullname VARCHAR                                NOT NULL,
     about    VARCHAR                                NOT NULL,
-    email    citext UNIQUE                          NOT NULL
+    email    citext UNIQUE                          NOT NULL,
+    version  BIGINT  DEFAULT 1                      NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_credentials
+(
+    nickname      citext REFERENCES users (nickname) ON DELETE CASCADE PRIMARY KEY NOT NULL,
+    password_hash VARCHAR                                                          NOT NULL
 );
 
 CREATE TABLE IF NOT EXISTS forums
@@ -21,7 +47,8 @@ CREATE TABLE IF NOT EXISTS forums
     author_nickname citext REFERENCES users (nickname) ON DELETE NO ACTION NOT NULL,
     slug            citext PRIMARY KEY                                     NOT NULL,
     posts           INTEGER DEFAULT 0                                      NOT NULL,
-    threads         INTEGER DEFAULT 0                                      NOT NULL
+    threads         INTEGER DEFAULT 0                                      NOT NULL,
+    version         BIGINT  DEFAULT 1                                      NOT NULL
 );
 
 CREATE TABLE IF NOT EXISTS user_forum
@@ -40,9 +67,14 @@ CREATE TABLE IF NOT EXISTS threads
     slug            citext,
     votes           INTEGER                  DEFAULT 0,
     message         VARCHAR                                                NOT NULL,
-    created         TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP     NOT NULL
+    created         TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP     NOT NULL,
+    is_closed       BOOLEAN                  DEFAULT FALSE                 NOT NULL,
+    is_pinned       BOOLEAN                  DEFAULT FALSE                 NOT NULL,
+    version         BIGINT                   DEFAULT 1                     NOT NULL
 );
 
+CREATE UNIQUE INDEX IF NOT EXISTS index_threads_slug_unique ON threads (slug) WHERE slug <> '';
+
 CREATE TABLE IF NOT EXISTS votes
 (
     nickname  citext REFERENCES users (nickname) ON DELETE NO ACTION NOT NULL,
@@ -60,8 +92,194 @@ CREATE TABLE IF NOT EXISTS posts
     thread_id       BIGSERIAL REFERENCES threads (id) ON DELETE CASCADE    NOT NULL,
     parent_id       BIGINT                   DEFAULT 0,
     is_edited       BOOLEAN                  DEFAULT FALSE                 NOT NULL,
+    is_hidden       BOOLEAN                  DEFAULT FALSE                 NOT NULL,
+    created         TIMESTAMP WITH TIME ZONE DEFAULT now()                 NOT NULL,
+    path            BIGINT[]                 DEFAULT ARRAY []::BIGINT[],
+    version         BIGINT                   DEFAULT 1                     NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS thread_revisions
+(
+    id        BIGSERIAL PRIMARY KEY                                  NOT NULL,
+    thread_id BIGINT REFERENCES threads (id) ON DELETE CASCADE       NOT NULL,
+    title     VARCHAR                                                NOT NULL,
+    message   VARCHAR                                                NOT NULL,
+    slug      citext,
+    edited    TIMESTAMP WITH TIME ZONE DEFAULT now()                 NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS thread_slug_redirects
+(
+    slug      citext PRIMARY KEY                                     NOT NULL,
+    thread_id BIGINT REFERENCES threads (id) ON DELETE CASCADE       NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys
+(
+    principal    citext                   DEFAULT ''                  NOT NULL,
+    key          VARCHAR(255)                                         NOT NULL,
+    request_hash BYTEA                                                NOT NULL,
+    status       INTEGER,
+    content_type VARCHAR,
+    location     VARCHAR,
+    etag         VARCHAR,
+    body         BYTEA,
+    created      TIMESTAMP WITH TIME ZONE DEFAULT now()               NOT NULL,
+    expires      TIMESTAMP WITH TIME ZONE                             NOT NULL,
+    PRIMARY KEY (principal, key)
+);
+
+CREATE TABLE IF NOT EXISTS events
+(
+    id       BIGSERIAL PRIMARY KEY                                  NOT NULL,
+    position BIGINT UNIQUE,
+    tx_id    BIGINT                   DEFAULT txid_current()        NOT NULL,
+    type     VARCHAR                                                NOT NULL,
+    payload  JSONB                                                  NOT NULL,
+    created  TIMESTAMP WITH TIME ZONE DEFAULT now()                 NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS index_events_unsequenced ON events (id) WHERE position IS NULL;
+-- Finds the event of a created post, which SSE streams resume from.
+CREATE INDEX IF NOT EXISTS index_events_post ON events (((payload ->> 'id')::BIGINT)) WHERE type = 'post.created';
+
+CREATE TABLE IF NOT EXISTS event_cursors
+(
+    sink     VARCHAR PRIMARY KEY                                    NOT NULL,
+    position BIGINT                   DEFAULT 0                     NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS notifications
+(
+    id              BIGSERIAL PRIMARY KEY                                  NOT NULL,
+    nickname        citext REFERENCES users (nickname) ON DELETE CASCADE   NOT NULL,
+    type            VARCHAR                                                NOT NULL,
+    author_nickname citext                                                 NOT NULL,
+    forum_slug      citext                                                 NOT NULL,
+    thread_id       BIGINT                                                 NOT NULL,
+    post_id         BIGINT REFERENCES posts (id) ON DELETE CASCADE,
+    is_read         BOOLEAN                  DEFAULT FALSE                 NOT NULL,
     created         TIMESTAMP WITH TIME ZONE DEFAULT now()                 NOT NULL,
-    path            BIGINT[]                 DEFAULT ARRAY []::BIGINT[]
+    UNIQUE (nickname, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS index_notifications_nickname ON notifications (nickname, id);
+
+CREATE TABLE IF NOT EXISTS thread_subscriptions
+(
+    nickname  citext REFERENCES users (nickname) ON DELETE CASCADE   NOT NULL,
+    thread_id BIGINT REFERENCES threads (id) ON DELETE CASCADE       NOT NULL,
+    created   TIMESTAMP WITH TIME ZONE DEFAULT now()                 NOT NULL,
+    PRIMARY KEY (thread_id, nickname)
+);
+
+CREATE TABLE IF NOT EXISTS forum_follows
+(
+    nickname   citext REFERENCES users (nickname) ON DELETE CASCADE  NOT NULL,
+    forum_slug citext REFERENCES forums (slug) ON DELETE CASCADE     NOT NULL,
+    created    TIMESTAMP WITH TIME ZONE DEFAULT now()                NOT NULL,
+    PRIMARY KEY (forum_slug, nickname)
+);
+
+CREATE INDEX IF NOT EXISTS index_forum_follows_nickname ON forum_follows (nickname);
+
+CREATE TABLE IF NOT EXISTS site_admins
+(
+    nickname citext REFERENCES users (nickname) ON DELETE CASCADE PRIMARY KEY NOT NULL,
+    created  TIMESTAMP WITH TIME ZONE DEFAULT now()                        NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS forum_moderators
+(
+    forum_slug citext REFERENCES forums (slug) ON DELETE CASCADE     NOT NULL,
+    nickname   citext REFERENCES users (nickname) ON DELETE CASCADE  NOT NULL,
+    granted_by citext,
+    created    TIMESTAMP WITH TIME ZONE DEFAULT now()                NOT NULL,
+    PRIMARY KEY (forum_slug, nickname)
+);
+
+CREATE TABLE IF NOT EXISTS admin_audit
+(
+    id      BIGSERIAL PRIMARY KEY                                  NOT NULL,
+    actor   citext                                                 NOT NULL,
+    method  VARCHAR                                                NOT NULL,
+    path    VARCHAR                                                NOT NULL,
+    status  INTEGER                                                NOT NULL,
+    created TIMESTAMP WITH TIME ZONE DEFAULT now()                 NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS bans
+(
+    id         BIGSERIAL PRIMARY KEY                                  NOT NULL,
+    nickname   citext REFERENCES users (nickname) ON DELETE CASCADE   NOT NULL,
+    forum_slug citext REFERENCES forums (slug) ON DELETE CASCADE,
+    reason     VARCHAR                  DEFAULT ''                    NOT NULL,
+    banned_by  citext,
+    expires    TIMESTAMP WITH TIME ZONE                               NOT NULL,
+    created    TIMESTAMP WITH TIME ZONE DEFAULT now()                 NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS index_bans_nickname ON bans (nickname, expires);
+
+CREATE TABLE IF NOT EXISTS forum_rate_limits
+(
+    forum_slug citext REFERENCES forums (slug) ON DELETE CASCADE     NOT NULL,
+    action     VARCHAR                                               NOT NULL,
+    per_minute DOUBLE PRECISION                                      NOT NULL,
+    burst      INTEGER                                               NOT NULL,
+    PRIMARY KEY (forum_slug, action)
+);
+
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_buckets
+(
+    key     VARCHAR PRIMARY KEY                                    NOT NULL,
+    tokens  DOUBLE PRECISION                                       NOT NULL,
+    updated TIMESTAMP WITH TIME ZONE DEFAULT now()                 NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS moderation_queue
+(
+    id              BIGSERIAL PRIMARY KEY                                  NOT NULL,
+    forum_slug      citext REFERENCES forums (slug) ON DELETE CASCADE      NOT NULL,
+    thread_id       BIGINT REFERENCES threads (id) ON DELETE CASCADE       NOT NULL,
+    post_id         BIGINT REFERENCES posts (id) ON DELETE CASCADE,
+    author_nickname citext                                                 NOT NULL,
+    reasons         TEXT[]                                                 NOT NULL,
+    created         TIMESTAMP WITH TIME ZONE DEFAULT now()                 NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS index_moderation_queue_forum ON moderation_queue (forum_slug, id);
+
+CREATE UNLOGGED TABLE IF NOT EXISTS message_fingerprints
+(
+    author_nickname citext                                                 NOT NULL,
+    hash            BYTEA                                                  NOT NULL,
+    created         TIMESTAMP WITH TIME ZONE DEFAULT now()                 NOT NULL,
+    PRIMARY KEY (author_nickname, hash)
+);
+
+CREATE TABLE IF NOT EXISTS attachments
+(
+    id        BIGSERIAL PRIMARY KEY                                  NOT NULL,
+    post_id   BIGINT REFERENCES posts (id) ON DELETE CASCADE         NOT NULL,
+    uploader  citext                                                 NOT NULL,
+    filename  VARCHAR                                                NOT NULL,
+    mime_type VARCHAR                                                NOT NULL,
+    size      BIGINT                                                 NOT NULL,
+    hash      VARCHAR                                                NOT NULL,
+    created   TIMESTAMP WITH TIME ZONE DEFAULT now()                 NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS index_attachments_post ON attachments (post_id);
+CREATE INDEX IF NOT EXISTS index_attachments_hash ON attachments (hash);
+
+-- Every stored object has a row here, touched by each upload that uses it.
+-- Objects no attachment refers to are deleted once untouched for a while,
+-- which covers failed uploads as well as deleted posts and forums.
+CREATE TABLE IF NOT EXISTS attachment_objects
+(
+    hash    VARCHAR PRIMARY KEY                    NOT NULL,
+    touched TIMESTAMP WITH TIME ZONE DEFAULT now() NOT NULL
 );
 
 CREATE OR REPLACE FUNCTION update_path_trigger() RETURNS TRIGGER AS
@@ -166,6 +384,59 @@ CREATE TRIGGER update_trigger_user_forum_posts
     FOR EACH ROW
 EXECUTE PROCEDURE update_trigger_user_forum_posts();
 
+CREATE OR REPLACE FUNCTION update_trigger_thread_revisions() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF old.title IS DISTINCT FROM new.title
+        OR old.message IS DISTINCT FROM new.message
+        OR old.slug IS DISTINCT FROM new.slug THEN
+        INSERT INTO thread_revisions (thread_id, title, message, slug)
+        VALUES (old.id, old.title, old.message, old.slug);
+    END IF;
+    RETURN new;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER update_trigger_thread_revisions
+    AFTER UPDATE
+    ON threads
+    FOR EACH ROW
+EXECUTE PROCEDURE update_trigger_thread_revisions();
+
+CREATE OR REPLACE FUNCTION update_trigger_version() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF new IS DISTINCT FROM old THEN
+        new.version = old.version + 1;
+    END IF;
+    RETURN new;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER update_trigger_users_version
+    BEFORE UPDATE
+    ON users
+    FOR EACH ROW
+EXECUTE PROCEDURE update_trigger_version();
+
+CREATE TRIGGER update_trigger_forums_version
+    BEFORE UPDATE
+    ON forums
+    FOR EACH ROW
+EXECUTE PROCEDURE update_trigger_version();
+
+CREATE TRIGGER update_trigger_threads_version
+    BEFORE UPDATE
+    ON threads
+    FOR EACH ROW
+EXECUTE PROCEDURE update_trigger_version();
+
+CREATE TRIGGER update_trigger_posts_version
+    BEFORE UPDATE
+    ON posts
+    FOR EACH ROW
+EXECUTE PROCEDURE update_trigger_version();
+
 -- -- INDEXES
 --
 -- -- Forum
@@ -0,0 +1,8 @@
+package db
+
+import _ "embed"
+
+// Schema recreates every table from scratch; applying it drops existing data.
+//
+//go:embed db.sql
+var Schema string
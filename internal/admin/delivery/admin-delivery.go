@@ -0,0 +1,102 @@
+package adminDelivery
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	internalErrors "technopark-dbms-forum/internal"
+	adminRepository "technopark-dbms-forum/internal/admin/repository"
+	systemRepository "technopark-dbms-forum/internal/system/repository"
+)
+
+type Handler struct {
+	adminRepo  *adminRepository.Postgres
+	systemRepo *systemRepository.Postgres
+}
+
+func NewHandler(adminRepo *adminRepository.Postgres, systemRepo *systemRepository.Postgres) *Handler {
+	return &Handler{
+		adminRepo:  adminRepo,
+		systemRepo: systemRepo,
+	}
+}
+
+func (h *Handler) Clear(c echo.Context) error {
+	if err := h.systemRepo.ClearAll(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, "OK")
+}
+
+func (h *Handler) PurgeForum(c echo.Context) error {
+	slug := c.Param("slug")
+
+	purge, err := h.adminRepo.PurgeForum(slug)
+	if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find forum with slug: %s", slug))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, purge)
+}
+
+func (h *Handler) GetStats(c echo.Context) error {
+	stats, err := h.adminRepo.GetStats()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
+func (h *Handler) GetAudit(c echo.Context) error {
+	limit, err := strconv.ParseInt(c.QueryParam("limit"), 10, 64)
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	before, err := strconv.ParseUint(c.QueryParam("before"), 10, 64)
+	if err != nil {
+		before = 0
+	}
+
+	entries, err := h.adminRepo.GetAudit(limit, before)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, entries)
+}
+
+func (h *Handler) AddSiteAdmin(c echo.Context) error {
+	nickname := c.Param("nickname")
+
+	created, err := h.adminRepo.AddSiteAdmin(nickname)
+	if err == internalErrors.ErrUserNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find user with nickname: %s", nickname))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	if created {
+		return c.JSON(http.StatusCreated, "OK")
+	}
+	return c.JSON(http.StatusOK, "OK")
+}
+
+func (h *Handler) RemoveSiteAdmin(c echo.Context) error {
+	nickname := c.Param("nickname")
+
+	err := h.adminRepo.RemoveSiteAdmin(nickname)
+	if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("User %s is not a site admin", nickname))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, "OK")
+}
@@ -0,0 +1,181 @@
+package adminRepository
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	internalErrors "technopark-dbms-forum/internal"
+	"technopark-dbms-forum/pkg/models"
+)
+
+type Postgres struct {
+	sqlx *sqlx.DB
+}
+
+func NewPostgres(url string) (*Postgres, error) {
+	newSQLX, err := sqlx.Connect("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = newSQLX.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &Postgres{sqlx: newSQLX}, nil
+}
+
+func (p *Postgres) Close() error {
+	return p.sqlx.Close()
+}
+
+func (p *Postgres) InsertAudit(entry *models.AuditEntry) error {
+	_, err := p.sqlx.Exec(
+		`
+			INSERT INTO admin_audit (actor, method, path, status)
+			VALUES ($1, $2, $3, $4)
+		`,
+		entry.Actor,
+		entry.Method,
+		entry.Path,
+		entry.Status,
+	)
+
+	return err
+}
+
+func (p *Postgres) GetAudit(limit int64, before uint64) ([]*models.AuditEntry, error) {
+	entries := make([]*models.AuditEntry, 0)
+	err := p.sqlx.Select(
+		&entries,
+		`
+			SELECT id, actor, method, path, status, created
+			FROM admin_audit
+			WHERE $1::BIGINT = 0 OR id < $1
+			ORDER BY id DESC
+			LIMIT $2
+		`,
+		before,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (p *Postgres) GetStats() (*models.AdminStats, error) {
+	stats := models.AdminStats{}
+	err := p.sqlx.Get(
+		&stats,
+		`
+			SELECT (SELECT COUNT(*) FROM users)                                 AS users,
+			       (SELECT COUNT(*) FROM forums)                                AS forums,
+			       (SELECT COUNT(*) FROM threads)                               AS threads,
+			       (SELECT COUNT(*) FROM posts)                                 AS posts,
+			       (SELECT COUNT(*) FROM votes)                                 AS votes,
+			       (SELECT COUNT(*) FROM site_admins)                           AS admins,
+			       (SELECT COUNT(*) FROM forum_moderators)                      AS moderators,
+			       (SELECT COUNT(*) FROM notifications)                         AS notifications,
+			       (SELECT COUNT(*) FROM events WHERE position IS NULL)         AS pending_events,
+			       (SELECT COUNT(*) FROM idempotency_keys)                      AS idempotency_keys,
+			       pg_database_size(current_database())                         AS database_size
+		`,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// PurgeForum deletes the forum with all of its threads, posts and votes.
+func (p *Postgres) PurgeForum(slug string) (*models.ForumPurge, error) {
+	tx, err := p.sqlx.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	purge := models.ForumPurge{}
+	err = tx.Get(
+		&purge,
+		`
+			SELECT f.slug,
+			       (SELECT COUNT(*) FROM threads WHERE forum = f.slug)    AS threads,
+			       (SELECT COUNT(*) FROM posts WHERE forum_slug = f.slug) AS posts
+			FROM forums f
+			WHERE f.slug = $1
+			FOR UPDATE
+		`,
+		slug,
+	)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return nil, internalErrors.ErrNoRows
+	} else if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if _, err = tx.Exec("DELETE FROM notifications WHERE forum_slug = $1", purge.Forum); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if _, err = tx.Exec("DELETE FROM forums WHERE slug = $1", purge.Forum); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &purge, nil
+}
+
+func (p *Postgres) AddSiteAdmin(nickname string) (bool, error) {
+	res, err := p.sqlx.Exec(
+		`
+			INSERT INTO site_admins (nickname)
+			VALUES ($1)
+			ON CONFLICT DO NOTHING
+		`,
+		nickname,
+	)
+	if err != nil {
+		pgErr, ok := err.(*pq.Error)
+		if ok && pgErr.Code == "23503" {
+			return false, internalErrors.ErrUserNotFound
+		}
+		return false, err
+	}
+
+	created, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return created != 0, nil
+}
+
+func (p *Postgres) RemoveSiteAdmin(nickname string) error {
+	res, err := p.sqlx.Exec(
+		"DELETE FROM site_admins WHERE nickname = $1",
+		nickname,
+	)
+	if err != nil {
+		return err
+	}
+
+	if deleted, err := res.RowsAffected(); err != nil {
+		return err
+	} else if deleted == 0 {
+		return internalErrors.ErrNoRows
+	}
+
+	return nil
+}
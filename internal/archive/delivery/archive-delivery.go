@@ -0,0 +1,80 @@
+package archiveDelivery
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	internalErrors "technopark-dbms-forum/internal"
+	archiveUsecase "technopark-dbms-forum/internal/archive/usecase"
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
+)
+
+type Handler struct {
+	archiveUsecase *archiveUsecase.ArchiveUsecase
+}
+
+func NewHandler(archiveUsecase *archiveUsecase.ArchiveUsecase) *Handler {
+	return &Handler{archiveUsecase: archiveUsecase}
+}
+
+// downloadWriter sends the response headers with the first byte of the
+// archive, so errors found before that still get a proper status.
+type downloadWriter struct {
+	res      *echo.Response
+	filename string
+}
+
+func (w *downloadWriter) Write(p []byte) (int, error) {
+	if !w.res.Committed {
+		header := w.res.Header()
+		header.Set(echo.HeaderContentType, "application/gzip")
+		header.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": w.filename}))
+		w.res.WriteHeader(http.StatusOK)
+	}
+
+	return w.res.Write(p)
+}
+
+func (h *Handler) Export(c echo.Context) error {
+	slug := c.Param("slug")
+	principal, _ := authDelivery.Principal(c)
+
+	err := h.archiveUsecase.Export(principal, slug, &downloadWriter{res: c.Response(), filename: slug + ".tar.gz"})
+	if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find forum with slug: %s", slug))
+	} else if err == internalErrors.ErrUnauthorized {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	} else if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't export forum with slug: %s", slug))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// Import reads an export archive from the request body.
+func (h *Handler) Import(c echo.Context) error {
+	summary, err := h.archiveUsecase.Import(c.Request().Body)
+	if err == internalErrors.ErrBadArchive {
+		return echo.NewHTTPError(http.StatusBadRequest, "Malformed archive")
+	} else if err == internalErrors.ErrAlreadyExist {
+		return echo.NewHTTPError(http.StatusConflict, "Forum already exists")
+	} else if err == internalErrors.ErrSlugAlreadyExist {
+		return echo.NewHTTPError(http.StatusConflict, "Thread slug already exists")
+	} else if err == internalErrors.ErrConflictEmail {
+		return echo.NewHTTPError(http.StatusConflict, "User email is taken by another user")
+	} else if err == internalErrors.ErrUserNotFound || err == internalErrors.ErrPostAuthorNotFound ||
+		err == internalErrors.ErrNoRowsByID || err == internalErrors.ErrNoParentPost {
+		return echo.NewHTTPError(http.StatusBadRequest, "Archive refers to missing rows")
+	} else if err == internalErrors.ErrImportTimeout {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "Archive is too large to import in time")
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusCreated, summary)
+}
@@ -0,0 +1,436 @@
+package archiveRepository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	internalErrors "technopark-dbms-forum/internal"
+	"technopark-dbms-forum/pkg/models"
+)
+
+// EmitFunc receives exported rows one at a time.
+type EmitFunc func(section string, row interface{}) error
+
+type archivePost struct {
+	models.ArchivePost
+	Path pq.Int64Array `json:"path" db:"path"`
+}
+
+type Postgres struct {
+	sqlx *sqlx.DB
+}
+
+func NewPostgres(url string) (*Postgres, error) {
+	newSQLX, err := sqlx.Connect("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = newSQLX.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &Postgres{sqlx: newSQLX}, nil
+}
+
+func (p *Postgres) Close() error {
+	return p.sqlx.Close()
+}
+
+func stream(tx *sqlx.Tx, section string, emit EmitFunc, newRow func() interface{}, query string, args ...interface{}) error {
+	rows, err := tx.Queryx(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := newRow()
+		if err = rows.StructScan(row); err != nil {
+			return err
+		}
+		if err = emit(section, row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Export reads the forum from one snapshot and emits its rows section by
+// section, users first since the forum refers to its author. Threads and
+// posts come in id order, so parents always precede their replies.
+func (p *Postgres) Export(slug string, emit EmitFunc) error {
+	tx, err := p.sqlx.BeginTxx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	forum := models.ForumResponse{}
+	err = tx.Get(&forum, "SELECT title, author_nickname, slug, threads, posts FROM forums WHERE slug = $1", slug)
+	if err == sql.ErrNoRows {
+		return internalErrors.ErrNoRows
+	} else if err != nil {
+		return err
+	}
+
+	if err = stream(
+		tx,
+		models.SectionUsers,
+		emit,
+		func() interface{} { return &models.User{} },
+		`
+			SELECT nickname, fullname, about, email
+			FROM users
+			WHERE nickname IN (
+				SELECT author_nickname FROM forums WHERE slug = $1
+				UNION SELECT nickname FROM user_forum WHERE forum_slug = $1
+				UNION SELECT author_nickname FROM threads WHERE forum = $1
+				UNION SELECT author_nickname FROM posts WHERE forum_slug = $1
+				UNION SELECT v.nickname FROM votes v JOIN threads t ON t.id = v.thread_id WHERE t.forum = $1
+			)
+			ORDER BY nickname
+		`,
+		forum.Slug,
+	); err != nil {
+		return err
+	}
+
+	if err = emit(models.SectionForum, &forum); err != nil {
+		return err
+	}
+
+	if err = stream(
+		tx,
+		models.SectionThreads,
+		emit,
+		func() interface{} { return &models.ThreadResponse{} },
+		`
+			SELECT id, author_nickname, created, forum, message, slug, title, votes, is_closed, is_pinned
+			FROM threads
+			WHERE forum = $1
+			ORDER BY id
+		`,
+		forum.Slug,
+	); err != nil {
+		return err
+	}
+
+	if err = stream(
+		tx,
+		models.SectionPosts,
+		emit,
+		func() interface{} { return &archivePost{} },
+		`
+			SELECT id, author_nickname, created, message, parent_id, thread_id, path, is_edited, is_hidden
+			FROM posts
+			WHERE forum_slug = $1
+			ORDER BY id
+		`,
+		forum.Slug,
+	); err != nil {
+		return err
+	}
+
+	if err = stream(
+		tx,
+		models.SectionVotes,
+		emit,
+		func() interface{} { return &models.ArchiveVote{} },
+		`
+			SELECT v.nickname, v.thread_id, v.voice
+			FROM votes v
+			JOIN threads t ON t.id = v.thread_id
+			WHERE t.forum = $1
+			ORDER BY v.thread_id, v.nickname
+		`,
+		forum.Slug,
+	); err != nil {
+		return err
+	}
+
+	return stream(
+		tx,
+		models.SectionUserForum,
+		emit,
+		func() interface{} { return &models.ArchiveUserForum{} },
+		"SELECT nickname FROM user_forum WHERE forum_slug = $1 ORDER BY nickname",
+		forum.Slug,
+	)
+}
+
+// importTimeout bounds an import transaction. While it runs, events of every
+// later transaction wait to be sequenced (see eventRepository.Sequence), so
+// the feed, streams and notifications stall for at most this long.
+const importTimeout = 2 * time.Minute
+
+// Importer recreates an exported forum inside one transaction. Thread and
+// post ids are assigned by the target database; the importer remembers the
+// mapping so replies, votes and paths point at the new rows.
+type Importer struct {
+	tx      *sqlx.Tx
+	ctx     context.Context
+	cancel  context.CancelFunc
+	forum   string
+	threads map[uint64]uint64
+	posts   map[uint64]uint64
+	summary models.ArchiveImport
+}
+
+// BeginImport starts an import that is rolled back once importTimeout passes.
+func (p *Postgres) BeginImport() (*Importer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), importTimeout)
+	tx, err := p.sqlx.BeginTxx(ctx, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &Importer{
+		tx:      tx,
+		ctx:     ctx,
+		cancel:  cancel,
+		threads: make(map[uint64]uint64),
+		posts:   make(map[uint64]uint64),
+	}, nil
+}
+
+// User creates the user unless the nickname is already taken, in which case
+// the existing user is kept.
+func (i *Importer) User(user *models.User) error {
+	res, err := i.tx.Exec(
+		`
+			INSERT INTO users (nickname, fullname, about, email)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (nickname) DO NOTHING
+		`,
+		user.Nickname,
+		user.FullName,
+		user.About,
+		user.Email,
+	)
+	if err != nil {
+		pgErr, ok := err.(*pq.Error)
+		if ok && pgErr.Code == "23505" {
+			return internalErrors.ErrConflictEmail
+		}
+		return err
+	}
+
+	created, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	i.summary.Users += created
+
+	return nil
+}
+
+// Forum creates the forum with empty counters; the insert triggers count the
+// threads and posts as they are imported.
+func (i *Importer) Forum(forum *models.ForumResponse) error {
+	if i.forum != "" {
+		return internalErrors.ErrBadArchive
+	}
+
+	_, err := i.tx.Exec(
+		"INSERT INTO forums (title, author_nickname, slug) VALUES ($1, $2, $3)",
+		forum.Title,
+		forum.User,
+		forum.Slug,
+	)
+	if err != nil {
+		pgErr, ok := err.(*pq.Error)
+		if ok && pgErr.Code == "23505" {
+			return internalErrors.ErrAlreadyExist
+		} else if ok && pgErr.Code == "23503" {
+			return internalErrors.ErrUserNotFound
+		}
+		return err
+	}
+
+	i.forum = forum.Slug
+	i.summary.Forum = forum.Slug
+
+	return nil
+}
+
+// Thread creates the thread, refusing slugs that are taken in the target
+// instance, including by a redirect.
+func (i *Importer) Thread(thread *models.ThreadResponse) error {
+	if i.forum == "" {
+		return internalErrors.ErrBadArchive
+	}
+
+	if thread.Slug != "" {
+		var taken bool
+		err := i.tx.Get(
+			&taken,
+			`
+				SELECT EXISTS (SELECT 1 FROM threads WHERE slug = $1)
+				    OR EXISTS (SELECT 1 FROM thread_slug_redirects WHERE slug = $1)
+			`,
+			thread.Slug,
+		)
+		if err != nil {
+			return err
+		} else if taken {
+			return internalErrors.ErrSlugAlreadyExist
+		}
+	}
+
+	var id uint64
+	err := i.tx.QueryRowx(
+		`
+			INSERT INTO threads (author_nickname, created, forum, message, slug, title, is_closed, is_pinned)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id
+		`,
+		thread.Author,
+		thread.Created,
+		i.forum,
+		thread.Message,
+		thread.Slug,
+		thread.Title,
+		thread.Closed,
+		thread.Pinned,
+	).Scan(&id)
+	if err != nil {
+		pgErr, ok := err.(*pq.Error)
+		if ok && pgErr.Code == "23503" {
+			return internalErrors.ErrUserNotFound
+		} else if ok && pgErr.Code == "23505" {
+			return internalErrors.ErrSlugAlreadyExist
+		}
+		return err
+	}
+
+	i.threads[thread.ID] = id
+	i.summary.Threads++
+
+	return nil
+}
+
+// Post inserts a reply after its parent, so the path trigger rebuilds the
+// path from the already imported ancestors. New ids keep the original
+// order, which keeps tree and parent_tree listings identical.
+func (i *Importer) Post(post *models.ArchivePost) error {
+	thread, ok := i.threads[post.Thread]
+	if !ok {
+		return internalErrors.ErrNoRowsByID
+	}
+
+	var parent uint64
+	if post.Parent != 0 {
+		if parent, ok = i.posts[post.Parent]; !ok {
+			return internalErrors.ErrNoParentPost
+		}
+	}
+
+	var id uint64
+	err := i.tx.QueryRowx(
+		`
+			INSERT INTO posts (author_nickname, created, forum_slug, message, parent_id, thread_id, is_edited, is_hidden)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id
+		`,
+		post.Author,
+		post.Created,
+		i.forum,
+		post.Message,
+		parent,
+		thread,
+		post.IsEdited,
+		post.IsHidden,
+	).Scan(&id)
+	if err != nil {
+		pgErr, ok := err.(*pq.Error)
+		if ok && pgErr.Code == "23503" {
+			return internalErrors.ErrPostAuthorNotFound
+		}
+		return err
+	}
+
+	i.posts[post.ID] = id
+	i.summary.Posts++
+
+	return nil
+}
+
+// Vote inserts the vote; the vote trigger rebuilds threads.votes from them.
+func (i *Importer) Vote(vote *models.ArchiveVote) error {
+	thread, ok := i.threads[vote.Thread]
+	if !ok {
+		return internalErrors.ErrNoRowsByID
+	}
+
+	_, err := i.tx.Exec(
+		"INSERT INTO votes (nickname, thread_id, voice) VALUES ($1, $2, $3)",
+		vote.Nickname,
+		thread,
+		vote.Voice,
+	)
+	if err != nil {
+		pgErr, ok := err.(*pq.Error)
+		if ok && pgErr.Code == "23503" {
+			return internalErrors.ErrUserNotFound
+		}
+		return err
+	}
+
+	i.summary.Votes++
+
+	return nil
+}
+
+// UserForum keeps forum members that the thread and post triggers did not
+// already add.
+func (i *Importer) UserForum(member *models.ArchiveUserForum) error {
+	if i.forum == "" {
+		return internalErrors.ErrBadArchive
+	}
+
+	_, err := i.tx.Exec(
+		"INSERT INTO user_forum (nickname, forum_slug) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		member.Nickname,
+		i.forum,
+	)
+
+	return err
+}
+
+// Commit fails for an archive that had no forum in it.
+func (i *Importer) Commit() (*models.ArchiveImport, error) {
+	defer i.cancel()
+
+	if i.forum == "" {
+		i.tx.Rollback()
+		return nil, internalErrors.ErrBadArchive
+	}
+
+	if err := i.tx.Commit(); i.Expired() {
+		return nil, internalErrors.ErrImportTimeout
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &i.summary, nil
+}
+
+func (i *Importer) Rollback() error {
+	defer i.cancel()
+
+	return i.tx.Rollback()
+}
+
+// Expired reports whether the import ran out of time, which fails whatever
+// statement was running then.
+func (i *Importer) Expired() bool {
+	return errors.Is(i.ctx.Err(), context.DeadlineExceeded)
+}
@@ -0,0 +1,115 @@
+package archiveUsecase
+
+import (
+	"encoding/json"
+	"io"
+
+	internalErrors "technopark-dbms-forum/internal"
+	archiveRepository "technopark-dbms-forum/internal/archive/repository"
+	roleUsecase "technopark-dbms-forum/internal/roles/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+type ArchiveUsecase struct {
+	r     *archiveRepository.Postgres
+	roles *roleUsecase.RoleUsecase
+}
+
+func NewArchiveUsecase(repo *archiveRepository.Postgres, roles *roleUsecase.RoleUsecase) *ArchiveUsecase {
+	return &ArchiveUsecase{r: repo, roles: roles}
+}
+
+// Export writes the forum as a tar.gz archive. The archive carries user
+// emails, so only a signed-in user who may moderate the forum can export it,
+// whatever the auth mode. Nothing is written to w if the forum doesn't exist.
+func (u *ArchiveUsecase) Export(actor, slug string, w io.Writer) error {
+	if actor == "" {
+		return internalErrors.ErrUnauthorized
+	}
+	if err := u.roles.CanModerate(actor, slug); err != nil {
+		return err
+	}
+
+	return u.ExportTrusted(slug, w)
+}
+
+// ExportTrusted is Export without the role check, for callers that act
+// outside of any user, like the command line.
+func (u *ArchiveUsecase) ExportTrusted(slug string, w io.Writer) error {
+	archive := newArchiveWriter(w, slug)
+	if err := u.r.Export(slug, archive.Write); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func decode(dec *json.Decoder, row interface{}) error {
+	if err := dec.Decode(row); err != nil {
+		return internalErrors.ErrBadArchive
+	}
+
+	return nil
+}
+
+// Import recreates an exported forum in one transaction, so a failed import
+// leaves nothing behind. An import that runs out of time fails with
+// ErrImportTimeout.
+func (u *ArchiveUsecase) Import(r io.Reader) (*models.ArchiveImport, error) {
+	importer, err := u.r.BeginImport()
+	if err != nil {
+		return nil, err
+	}
+
+	err = readArchive(r, func(section string, dec *json.Decoder) error {
+		switch section {
+		case models.SectionUsers:
+			user := models.User{}
+			if err := decode(dec, &user); err != nil {
+				return err
+			}
+			return importer.User(&user)
+		case models.SectionForum:
+			forum := models.ForumResponse{}
+			if err := decode(dec, &forum); err != nil {
+				return err
+			}
+			return importer.Forum(&forum)
+		case models.SectionThreads:
+			thread := models.ThreadResponse{}
+			if err := decode(dec, &thread); err != nil {
+				return err
+			}
+			return importer.Thread(&thread)
+		case models.SectionPosts:
+			post := models.ArchivePost{}
+			if err := decode(dec, &post); err != nil {
+				return err
+			}
+			return importer.Post(&post)
+		case models.SectionVotes:
+			vote := models.ArchiveVote{}
+			if err := decode(dec, &vote); err != nil {
+				return err
+			}
+			return importer.Vote(&vote)
+		case models.SectionUserForum:
+			member := models.ArchiveUserForum{}
+			if err := decode(dec, &member); err != nil {
+				return err
+			}
+			return importer.UserForum(&member)
+		}
+
+		return internalErrors.ErrBadArchive
+	})
+	if err != nil {
+		importer.Rollback()
+		if importer.Expired() {
+			return nil, internalErrors.ErrImportTimeout
+		}
+		return nil, err
+	}
+
+	return importer.Commit()
+}
@@ -0,0 +1,207 @@
+package archiveUsecase
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	internalErrors "technopark-dbms-forum/internal"
+	"technopark-dbms-forum/pkg/models"
+)
+
+const (
+	manifestName = "manifest.json"
+	// chunkRows bounds how many rows of a section are buffered before they
+	// are written out as one tar entry.
+	chunkRows = 10000
+)
+
+// sections lists the archive sections in the order they must appear.
+var sections = []string{
+	models.SectionUsers,
+	models.SectionForum,
+	models.SectionThreads,
+	models.SectionPosts,
+	models.SectionVotes,
+	models.SectionUserForum,
+}
+
+func sectionIndex(section string) int {
+	for i, s := range sections {
+		if s == section {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// archiveWriter streams rows into a tar.gz as newline-delimited JSON. Each
+// section is split into numbered entries of at most chunkRows rows, since a
+// tar entry's size has to be known before it is written.
+type archiveWriter struct {
+	gz  *gzip.Writer
+	tar *tar.Writer
+
+	manifest        models.ArchiveManifest
+	manifestWritten bool
+
+	section string
+	chunk   int
+	rows    int
+	buf     bytes.Buffer
+}
+
+func newArchiveWriter(w io.Writer, forum string) *archiveWriter {
+	gz := gzip.NewWriter(w)
+
+	return &archiveWriter{
+		gz:  gz,
+		tar: tar.NewWriter(gz),
+		manifest: models.ArchiveManifest{
+			Format:   models.ArchiveFormat,
+			Forum:    forum,
+			Exported: time.Now().UTC(),
+		},
+	}
+}
+
+func (w *archiveWriter) writeEntry(name string, data []byte) error {
+	if err := w.tar.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: w.manifest.Exported,
+	}); err != nil {
+		return err
+	}
+
+	_, err := w.tar.Write(data)
+	return err
+}
+
+// writeManifest writes the manifest ahead of the first row. Nothing reaches
+// the underlying writer before that, so a missing forum can still be
+// reported as an error.
+func (w *archiveWriter) writeManifest() error {
+	if w.manifestWritten {
+		return nil
+	}
+	w.manifestWritten = true
+
+	data, err := json.Marshal(&w.manifest)
+	if err != nil {
+		return err
+	}
+
+	return w.writeEntry(manifestName, data)
+}
+
+func (w *archiveWriter) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+
+	w.chunk++
+	if err := w.writeEntry(fmt.Sprintf("%s-%05d.ndjson", w.section, w.chunk), w.buf.Bytes()); err != nil {
+		return err
+	}
+	w.buf.Reset()
+	w.rows = 0
+
+	return nil
+}
+
+func (w *archiveWriter) Write(section string, row interface{}) error {
+	if err := w.writeManifest(); err != nil {
+		return err
+	}
+
+	if section != w.section || w.rows >= chunkRows {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+	if section != w.section {
+		w.section, w.chunk = section, 0
+	}
+
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	w.buf.Write(data)
+	w.buf.WriteByte('\n')
+	w.rows++
+
+	return nil
+}
+
+func (w *archiveWriter) Close() error {
+	if err := w.writeManifest(); err != nil {
+		return err
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	if err := w.tar.Close(); err != nil {
+		return err
+	}
+
+	return w.gz.Close()
+}
+
+// readArchive checks the manifest and calls fn for every row with a decoder
+// positioned at it, enforcing the section order the importer relies on.
+func readArchive(r io.Reader, fn func(section string, dec *json.Decoder) error) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return internalErrors.ErrBadArchive
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != manifestName {
+		return internalErrors.ErrBadArchive
+	}
+	manifest := models.ArchiveManifest{}
+	if err = json.NewDecoder(tr).Decode(&manifest); err != nil || manifest.Format != models.ArchiveFormat {
+		return internalErrors.ErrBadArchive
+	}
+
+	last := 0
+	for {
+		header, err = tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return internalErrors.ErrBadArchive
+		}
+
+		name := strings.TrimSuffix(header.Name, ".ndjson")
+		dash := strings.LastIndexByte(name, '-')
+		if dash < 0 || name == header.Name {
+			return internalErrors.ErrBadArchive
+		}
+		section := name[:dash]
+
+		index := sectionIndex(section)
+		if index < last {
+			return internalErrors.ErrBadArchive
+		}
+		last = index
+
+		dec := json.NewDecoder(tr)
+		for dec.More() {
+			if err = fn(section, dec); err != nil {
+				return err
+			}
+		}
+	}
+}
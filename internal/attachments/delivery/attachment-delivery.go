@@ -0,0 +1,127 @@
+package attachmentDelivery
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	internalErrors "technopark-dbms-forum/internal"
+	attachmentUsecase "technopark-dbms-forum/internal/attachments/usecase"
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
+	"technopark-dbms-forum/pkg/etag"
+)
+
+const (
+	// maxFiles is how many files one upload request may carry.
+	maxFiles = 10
+	// formOverhead leaves room for multipart boundaries and part headers.
+	formOverhead = 1 << 20
+)
+
+type Handler struct {
+	attachmentUsecase *attachmentUsecase.AttachmentUsecase
+}
+
+func NewHandler(attachmentUsecase *attachmentUsecase.AttachmentUsecase) *Handler {
+	return &Handler{attachmentUsecase: attachmentUsecase}
+}
+
+// Upload attaches the files sent in the "file" fields of a multipart form to the post.
+func (h *Handler) Upload(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, h.attachmentUsecase.MaxSize()*maxFiles+formOverhead)
+
+	var tooLarge *http.MaxBytesError
+
+	form, err := c.MultipartForm()
+	if errors.As(err, &tooLarge) {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "Upload is too large")
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Can't read multipart form: %s", err))
+	}
+	defer form.RemoveAll()
+
+	files := form.File["file"]
+	if len(files) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "No file in the request")
+	} else if len(files) > maxFiles {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("At most %d files per upload", maxFiles))
+	}
+
+	uploads := make([]*attachmentUsecase.File, 0, len(files))
+	for _, header := range files {
+		file, err := header.Open()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+		defer file.Close()
+
+		uploads = append(uploads, &attachmentUsecase.File{Name: header.Filename, Size: header.Size, Content: file})
+	}
+
+	actor := authDelivery.Actor(c)
+
+	var refused *attachmentUsecase.FileError
+
+	attachments, err := h.attachmentUsecase.Upload(actor, id, uploads)
+	if errors.As(err, &refused) {
+		if refused.Err == internalErrors.ErrAttachmentTooLarge {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("File is too large: %s", refused.Filename))
+		} else if refused.Err == internalErrors.ErrAttachmentType {
+			return echo.NewHTTPError(http.StatusUnsupportedMediaType, fmt.Sprintf("File type is not allowed: %s", refused.Filename))
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, refused.Err)
+	} else if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find post with id: %d", id))
+	} else if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't edit post with id: %d", id))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusCreated, attachments)
+}
+
+// Get serves the attachment contents. Range and conditional requests are
+// handled by http.ServeContent; an attachment never changes once uploaded, so
+// it may be cached indefinitely.
+func (h *Handler) Get(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	attachment, file, err := h.attachmentUsecase.Open(authDelivery.Actor(c), id)
+	if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find attachment with id: %d", id))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	defer file.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.MimeType, "image/") {
+		disposition = "inline"
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, attachment.MimeType)
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	header.Set(etag.HeaderETag, `"`+attachment.Hash+`"`)
+	header.Set(echo.HeaderCacheControl, "public, max-age=31536000, immutable")
+
+	http.ServeContent(c.Response(), c.Request(), "", attachment.Created, file)
+
+	return nil
+}
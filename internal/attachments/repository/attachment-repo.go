@@ -0,0 +1,192 @@
+package attachmentRepository
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	internalErrors "technopark-dbms-forum/internal"
+	"technopark-dbms-forum/pkg/models"
+)
+
+type Postgres struct {
+	sqlx *sqlx.DB
+}
+
+func NewPostgres(url string) (*Postgres, error) {
+	newSQLX, err := sqlx.Connect("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = newSQLX.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &Postgres{sqlx: newSQLX}, nil
+}
+
+func (p *Postgres) Close() error {
+	return p.sqlx.Close()
+}
+
+// Insert records the attachments of one post in a single transaction and
+// bumps the post version, so that cached copies of the post are revalidated.
+func (p *Postgres) Insert(attachments []*models.Attachment) ([]*models.Attachment, error) {
+	tx, err := p.sqlx.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	inserted := make([]*models.Attachment, 0, len(attachments))
+	for _, a := range attachments {
+		attachment := models.Attachment{}
+		err = tx.Get(
+			&attachment,
+			`
+				INSERT INTO attachments (post_id, uploader, filename, mime_type, size, hash)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				RETURNING id, post_id, uploader, filename, mime_type, size, hash, created
+			`,
+			a.Post,
+			a.Uploader,
+			a.Filename,
+			a.MimeType,
+			a.Size,
+			a.Hash,
+		)
+		if err != nil {
+			tx.Rollback()
+
+			pgErr, ok := err.(*pq.Error)
+			if ok && pgErr.Code == "23503" {
+				return nil, internalErrors.ErrNoRows
+			}
+			return nil, err
+		}
+		inserted = append(inserted, &attachment)
+	}
+
+	if len(inserted) > 0 {
+		if _, err = tx.Exec("UPDATE posts SET version = version + 1 WHERE id = $1", inserted[0].Post); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return inserted, nil
+}
+
+// Touch records that an upload is about to use the objects. An object
+// touched within the grace period of DeleteOrphans is kept whether or not
+// anything refers to it yet. Touching waits for a collection that is deleting
+// the object, so afterwards the caller sees whether the object is still there.
+func (p *Postgres) Touch(hashes []string) error {
+	sorted := append([]string(nil), hashes...)
+	sort.Strings(sorted)
+
+	_, err := p.sqlx.Exec(
+		`
+			INSERT INTO attachment_objects (hash, touched)
+			SELECT DISTINCT hash, now() FROM unnest($1::VARCHAR[]) AS hash ORDER BY hash
+			ON CONFLICT (hash) DO UPDATE SET touched = now()
+		`,
+		pq.Array(sorted),
+	)
+
+	return err
+}
+
+// DeleteOrphans deletes up to limit objects that no attachment refers to and
+// no upload touched within grace. remove deletes an object from storage; it
+// is called while the object's row is locked, so an upload of the same
+// contents either touches it first and keeps it, or waits and stores it
+// again. Rows another run has locked are skipped.
+func (p *Postgres) DeleteOrphans(grace time.Duration, limit int, remove func(hash string) error) (int64, error) {
+	tx, err := p.sqlx.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	hashes := make([]string, 0)
+	err = tx.Select(
+		&hashes,
+		`
+			DELETE FROM attachment_objects
+			WHERE hash IN (
+			    SELECT o.hash
+			    FROM attachment_objects o
+			    WHERE o.touched < now() - $1 * INTERVAL '1 second'
+			      AND NOT EXISTS (SELECT 1 FROM attachments WHERE hash = o.hash)
+			    ORDER BY o.hash
+			    LIMIT $2
+			    FOR UPDATE SKIP LOCKED
+			)
+			RETURNING hash
+		`,
+		grace.Seconds(),
+		limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, hash := range hashes {
+		if err = remove(hash); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int64(len(hashes)), nil
+}
+
+func (p *Postgres) GetByID(id uint64) (*models.Attachment, error) {
+	attachment := models.Attachment{}
+	err := p.sqlx.Get(
+		&attachment,
+		`
+			SELECT id, post_id, uploader, filename, mime_type, size, hash, created
+			FROM attachments
+			WHERE id = $1
+		`,
+		id,
+	)
+	if err == sql.ErrNoRows {
+		return nil, internalErrors.ErrNoRows
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+func (p *Postgres) GetForPosts(ids []int64) ([]*models.Attachment, error) {
+	attachments := make([]*models.Attachment, 0)
+	err := p.sqlx.Select(
+		&attachments,
+		`
+			SELECT id, post_id, uploader, filename, mime_type, size, hash, created
+			FROM attachments
+			WHERE post_id = ANY($1)
+			ORDER BY id
+		`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
@@ -0,0 +1,336 @@
+package attachmentRepository
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3 stores objects in a bucket of an S3-compatible service such as MinIO,
+// addressed path-style under the endpoint and fanned out like Filesystem.
+// Requests are signed with AWS Signature Version 4.
+type S3 struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewS3(endpoint, bucket, region, accessKey, secretKey string) (*S3, error) {
+	parsed, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "" {
+		return nil, fmt.Errorf("S3 endpoint must be an http or https URL: %s", endpoint)
+	}
+	if bucket == "" {
+		return nil, errors.New("S3 bucket is required")
+	}
+
+	return &S3{
+		endpoint:  parsed,
+		bucket:    bucket,
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{},
+		now:       time.Now,
+	}, nil
+}
+
+// s3Error is an answer the store did not expect.
+type s3Error struct {
+	method string
+	hash   string
+	status int
+}
+
+func (e *s3Error) Error() string {
+	return fmt.Sprintf("s3: %s %s: %d %s", e.method, e.hash, e.status, http.StatusText(e.status))
+}
+
+func (s *S3) objectURL(hash string) (string, error) {
+	if len(hash) != 64 {
+		return "", errBadHash
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", errBadHash
+	}
+
+	return s.endpoint.String() + "/" + s.bucket + "/" + hash[:2] + "/" + hash, nil
+}
+
+// do sends a signed request for the object. payloadHash is the hex SHA-256
+// of body.
+func (s *S3) do(method, hash string, body io.Reader, size int64, payloadHash string, header http.Header) (*http.Response, error) {
+	target, err := s.objectURL(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	s.sign(req, payloadHash, s.now())
+
+	return s.client.Do(req)
+}
+
+var emptyPayloadHash = hex.EncodeToString(sha256.New().Sum(nil))
+
+func (s *S3) Exists(hash string) (bool, error) {
+	resp, err := s.do(http.MethodHead, hash, nil, 0, emptyPayloadHash, nil)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, &s3Error{method: http.MethodHead, hash: hash, status: resp.StatusCode}
+	}
+}
+
+// Put uploads the object in one request. Objects are named by the SHA-256 of
+// their contents, which is also the payload hash the service checks the
+// upload against, so a corrupted upload is refused.
+func (s *S3) Put(hash string, r io.Reader) error {
+	var size int64
+	if seeker, ok := r.(io.Seeker); ok {
+		current, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+		if _, err = seeker.Seek(current, io.SeekStart); err != nil {
+			return err
+		}
+		size = end - current
+	} else {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		r, size = bytes.NewReader(data), int64(len(data))
+	}
+
+	resp, err := s.do(http.MethodPut, hash, io.NopCloser(r), size, hash, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &s3Error{method: http.MethodPut, hash: hash, status: resp.StatusCode}
+	}
+
+	return nil
+}
+
+// Open looks the object up and returns a reader that fetches it with range
+// requests from wherever it was last seeked to. A missing object is
+// fs.ErrNotExist, as with Filesystem.
+func (s *S3) Open(hash string) (io.ReadSeekCloser, error) {
+	resp, err := s.do(http.MethodHead, hash, nil, 0, emptyPayloadHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, &fs.PathError{Op: "open", Path: hash, Err: fs.ErrNotExist}
+	default:
+		return nil, &s3Error{method: http.MethodHead, hash: hash, status: resp.StatusCode}
+	}
+
+	return &s3Object{s: s, hash: hash, size: resp.ContentLength}, nil
+}
+
+// Delete removes the object; a missing one is not an error.
+func (s *S3) Delete(hash string) error {
+	resp, err := s.do(http.MethodDelete, hash, nil, 0, emptyPayloadHash, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return &s3Error{method: http.MethodDelete, hash: hash, status: resp.StatusCode}
+	}
+
+	return nil
+}
+
+// s3Object reads an object lazily: nothing is fetched until the first Read
+// after a Seek, so serving a range only downloads that range.
+type s3Object struct {
+	s      *S3
+	hash   string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func (o *s3Object) Read(p []byte) (int, error) {
+	if o.offset >= o.size {
+		return 0, io.EOF
+	}
+
+	if o.body == nil {
+		header := http.Header{"Range": {"bytes=" + strconv.FormatInt(o.offset, 10) + "-"}}
+		resp, err := o.s.do(http.MethodGet, o.hash, nil, 0, emptyPayloadHash, header)
+		if err != nil {
+			return 0, err
+		}
+		if resp.StatusCode != http.StatusPartialContent && !(resp.StatusCode == http.StatusOK && o.offset == 0) {
+			resp.Body.Close()
+			return 0, &s3Error{method: http.MethodGet, hash: o.hash, status: resp.StatusCode}
+		}
+		o.body = resp.Body
+	}
+
+	n, err := o.body.Read(p)
+	o.offset += int64(n)
+	if err == io.EOF && o.offset < o.size {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return n, err
+}
+
+func (o *s3Object) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += o.offset
+	case io.SeekEnd:
+		offset += o.size
+	}
+	if offset < 0 {
+		return 0, errors.New("s3: negative position")
+	}
+
+	if offset != o.offset && o.body != nil {
+		o.body.Close()
+		o.body = nil
+	}
+	o.offset = offset
+
+	return offset, nil
+}
+
+func (o *s3Object) Close() error {
+	if o.body == nil {
+		return nil
+	}
+
+	return o.body.Close()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// sign adds the AWS Signature Version 4 headers to the request, signing the
+// host and every header already set.
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	params := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range query[key] {
+			params = append(params, escape(key)+"="+escape(value))
+		}
+	}
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		strings.Join(params, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.accessKey+"/"+scope+",SignedHeaders="+signedHeaders+",Signature="+signature)
+}
+
+// escape encodes a query component the way Signature Version 4 expects.
+func escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,111 @@
+package attachmentRepository
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var errBadHash = errors.New("malformed attachment hash")
+
+// Storage keeps attachment contents addressed by their hex SHA-256 hash, so
+// identical uploads share one object.
+type Storage interface {
+	Exists(hash string) (bool, error)
+	Put(hash string, r io.Reader) error
+	Open(hash string) (io.ReadSeekCloser, error)
+	Delete(hash string) error
+}
+
+// Filesystem stores objects under dir, fanned out by the first hash byte.
+type Filesystem struct {
+	dir string
+}
+
+func NewFilesystem(dir string) (*Filesystem, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Filesystem{dir: dir}, nil
+}
+
+func (f *Filesystem) path(hash string) (string, error) {
+	if len(hash) != 64 {
+		return "", errBadHash
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", errBadHash
+	}
+
+	return filepath.Join(f.dir, hash[:2], hash), nil
+}
+
+func (f *Filesystem) Exists(hash string) (bool, error) {
+	path, err := f.path(hash)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Put writes the object to a temporary file first, so a reader never sees a
+// partially written one.
+func (f *Filesystem) Put(hash string, r io.Reader) error {
+	path, err := f.path(hash)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (f *Filesystem) Open(hash string) (io.ReadSeekCloser, error) {
+	path, err := f.path(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(path)
+}
+
+// Delete removes the object; a missing one is not an error.
+func (f *Filesystem) Delete(hash string) error {
+	path, err := f.path(hash)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package attachmentRepository_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	attachmentRepository "technopark-dbms-forum/internal/attachments/repository"
+)
+
+// s3StandIn keeps objects in memory and answers the part of the S3 API the
+// store uses. It refuses unsigned requests and uploads whose body does not
+// match the payload hash they were signed with.
+type s3StandIn struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	ranged  int
+}
+
+func (s *s3StandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") ||
+		!strings.Contains(r.Header.Get("Authorization"), "/us-east-1/s3/aws4_request,") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != r.Header.Get("X-Amz-Content-Sha256") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.objects[r.URL.Path] = data
+	case http.MethodGet, http.MethodHead:
+		data, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Range") != "" {
+			s.ranged++
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestStorage(t *testing.T) {
+	t.Run("filesystem", func(t *testing.T) {
+		storage, err := attachmentRepository.NewFilesystem(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		testStorage(t, storage)
+	})
+
+	t.Run("s3", func(t *testing.T) {
+		standIn := &s3StandIn{objects: map[string][]byte{}}
+		server := httptest.NewServer(standIn)
+		defer server.Close()
+
+		storage, err := attachmentRepository.NewS3(server.URL, "forum", "us-east-1", "key", "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		testStorage(t, storage)
+
+		if standIn.ranged == 0 {
+			t.Error("the object was not read with range requests")
+		}
+	})
+}
+
+func testStorage(t *testing.T, storage attachmentRepository.Storage) {
+	data := []byte("fifteen men on the dead man's chest")
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	if ok, err := storage.Exists(hash); err != nil || ok {
+		t.Fatalf("before upload: got %v, %v, want false", ok, err)
+	}
+	if _, err := storage.Open(hash); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("opening a missing object: got %v, want fs.ErrNotExist", err)
+	}
+
+	if err := storage.Put(hash, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := storage.Exists(hash); err != nil || !ok {
+		t.Fatalf("after upload: got %v, %v, want true", ok, err)
+	}
+
+	object, err := storage.Open(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := io.ReadAll(object)
+	if err != nil || !bytes.Equal(read, data) {
+		t.Errorf("got %q, %v, want %q", read, err, data)
+	}
+
+	if _, err = object.Seek(-int64(len("chest")), io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	if read, err = io.ReadAll(object); err != nil || string(read) != "chest" {
+		t.Errorf("after seeking: got %q, %v, want %q", read, err, "chest")
+	}
+	object.Close()
+
+	if err = storage.Put("not a hash", bytes.NewReader(data)); err == nil {
+		t.Error("a malformed hash was accepted")
+	}
+
+	if err = storage.Delete(hash); err != nil {
+		t.Fatal(err)
+	}
+	if err = storage.Delete(hash); err != nil {
+		t.Errorf("deleting a missing object: %v", err)
+	}
+	if ok, err := storage.Exists(hash); err != nil || ok {
+		t.Errorf("after delete: got %v, %v, want false", ok, err)
+	}
+}
@@ -0,0 +1,258 @@
+package attachmentUsecase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	internalErrors "technopark-dbms-forum/internal"
+	attachmentRepository "technopark-dbms-forum/internal/attachments/repository"
+	postRepository "technopark-dbms-forum/internal/posts/repository"
+	roleUsecase "technopark-dbms-forum/internal/roles/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+const (
+	// sniffLength is how much of a file http.DetectContentType looks at.
+	sniffLength = 512
+	// orphanBatch is how many objects DeleteOrphans deletes per transaction.
+	orphanBatch = 500
+)
+
+type AttachmentUsecase struct {
+	r       *attachmentRepository.Postgres
+	storage attachmentRepository.Storage
+	posts   *postRepository.Postgres
+	roles   *roleUsecase.RoleUsecase
+
+	maxSize int64
+	types   map[string]bool
+}
+
+func NewAttachmentUsecase(
+	repo *attachmentRepository.Postgres,
+	storage attachmentRepository.Storage,
+	posts *postRepository.Postgres,
+	roles *roleUsecase.RoleUsecase,
+	maxSize int64,
+	types []string,
+) *AttachmentUsecase {
+	allowed := make(map[string]bool, len(types))
+	for _, mimeType := range types {
+		allowed[mimeType] = true
+	}
+
+	return &AttachmentUsecase{
+		r:       repo,
+		storage: storage,
+		posts:   posts,
+		roles:   roles,
+		maxSize: maxSize,
+		types:   allowed,
+	}
+}
+
+func (u *AttachmentUsecase) MaxSize() int64 {
+	return u.maxSize
+}
+
+func withURL(attachment *models.Attachment) *models.Attachment {
+	attachment.URL = "/api/attachment/" + strconv.FormatUint(attachment.ID, 10)
+	return attachment
+}
+
+// File is one uploaded file.
+type File struct {
+	Name    string
+	Size    int64
+	Content io.ReadSeeker
+}
+
+// FileError tells which file of an upload was refused.
+type FileError struct {
+	Filename string
+	Err      error
+}
+
+func (e *FileError) Error() string {
+	return e.Filename + ": " + e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// Upload stores the files and attaches them to the post, all of them or none.
+// MIME types are sniffed from the contents rather than trusted from the
+// client, and a file already in storage is not written again. Refused files
+// come back as *FileError.
+func (u *AttachmentUsecase) Upload(actor string, postID uint64, files []*File) ([]*models.Attachment, error) {
+	post, err := u.posts.GetByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err = u.roles.CanEdit(actor, post.Author, post.Forum); err != nil {
+		return nil, err
+	}
+
+	uploader := actor
+	if uploader == models.ActorAnonymous {
+		uploader = post.Author
+	}
+
+	attachments := make([]*models.Attachment, 0, len(files))
+	for _, file := range files {
+		attachment, err := u.inspect(file)
+		if err != nil {
+			return nil, &FileError{Filename: file.Name, Err: err}
+		}
+		attachment.Post, attachment.Uploader = post.ID, uploader
+		attachments = append(attachments, attachment)
+	}
+
+	// The objects are touched before storage is asked about them, so that
+	// DeleteOrphans leaves them alone until the rows refer to them. Objects
+	// of a failed upload are left for it to collect.
+	hashes := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		hashes = append(hashes, attachment.Hash)
+	}
+	if err = u.r.Touch(hashes); err != nil {
+		return nil, err
+	}
+
+	for index, attachment := range attachments {
+		if err = u.store(attachment.Hash, files[index].Content); err != nil {
+			return nil, err
+		}
+	}
+
+	inserted, err := u.r.Insert(attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range inserted {
+		withURL(attachment)
+	}
+
+	return inserted, nil
+}
+
+// inspect checks the size and type of the file and hashes it.
+func (u *AttachmentUsecase) inspect(file *File) (*models.Attachment, error) {
+	if file.Size > u.maxSize {
+		return nil, internalErrors.ErrAttachmentTooLarge
+	}
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(file.Content, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	mimeType, _, err := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if err != nil || !u.types[mimeType] {
+		return nil, internalErrors.ErrAttachmentType
+	}
+
+	if _, err = file.Content.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	hasher := sha256.New()
+	if _, err = io.Copy(hasher, file.Content); err != nil {
+		return nil, err
+	}
+
+	return &models.Attachment{
+		Filename: file.Name,
+		MimeType: mimeType,
+		Size:     file.Size,
+		Hash:     hex.EncodeToString(hasher.Sum(nil)),
+	}, nil
+}
+
+// store writes the contents unless storage already has them.
+func (u *AttachmentUsecase) store(hash string, content io.ReadSeeker) error {
+	exists, err := u.storage.Exists(hash)
+	if err != nil || exists {
+		return err
+	}
+
+	if _, err = content.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	return u.storage.Put(hash, content)
+}
+
+// DeleteOrphans deletes the stored objects that no attachment has referred
+// to for the grace period: those of failed uploads and of deleted posts. The
+// grace period must be longer than an upload takes.
+func (u *AttachmentUsecase) DeleteOrphans(grace time.Duration) (int64, error) {
+	var total int64
+	for {
+		deleted, err := u.r.DeleteOrphans(grace, orphanBatch, u.storage.Delete)
+		total += deleted
+		if err != nil || deleted < orphanBatch {
+			return total, err
+		}
+	}
+}
+
+// Fill sets the attachments of the posts with a single query.
+func (u *AttachmentUsecase) Fill(posts []*models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	byID := make(map[uint64]*models.Post, len(posts))
+	ids := make([]int64, 0, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+		ids = append(ids, int64(post.ID))
+	}
+
+	attachments, err := u.r.GetForPosts(ids)
+	if err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		post := byID[attachment.Post]
+		post.Attachments = append(post.Attachments, withURL(attachment))
+	}
+
+	return nil
+}
+
+// Open returns the attachment metadata and a reader over its contents. The
+// attachments of a hidden post are ErrNoRows to those who may not moderate
+// its forum.
+func (u *AttachmentUsecase) Open(actor string, id uint64) (*models.Attachment, io.ReadSeekCloser, error) {
+	attachment, err := u.r.GetByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	post, err := u.posts.GetByID(attachment.Post)
+	if err != nil {
+		return nil, nil, err
+	}
+	if post.IsHidden {
+		if err = u.roles.CanModerate(actor, post.Forum); err == internalErrors.ErrForbidden {
+			return nil, nil, internalErrors.ErrNoRows
+		} else if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	file, err := u.storage.Open(attachment.Hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return withURL(attachment), file, nil
+}
@@ -0,0 +1,69 @@
+package authDelivery
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	internalErrors "technopark-dbms-forum/internal"
+	authUsecase "technopark-dbms-forum/internal/auth/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+type Handler struct {
+	authUsecase *authUsecase.AuthUsecase
+}
+
+func NewHandler(authUsecase *authUsecase.AuthUsecase) *Handler {
+	return &Handler{
+		authUsecase: authUsecase,
+	}
+}
+
+func (h *Handler) Login(c echo.Context) error {
+	credentials := models.Credentials{}
+
+	if err := c.Bind(&credentials); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	token, err := h.authUsecase.Login(&credentials)
+	if err == internalErrors.ErrWrongCredentials {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong nickname or password")
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, token)
+}
+
+func (h *Handler) SetPassword(c echo.Context) error {
+	nickname := c.Param("nickname")
+
+	change := models.PasswordChange{}
+
+	if err := c.Bind(&change); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if change.Password == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Password can't be empty")
+	}
+
+	principal, _ := Principal(c)
+
+	err := h.authUsecase.SetPassword(nickname, &change, principal)
+	if err == internalErrors.ErrUnauthorized {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	} else if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't change password of user: %s", nickname))
+	} else if err == internalErrors.ErrWrongCredentials {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong old password")
+	} else if err == internalErrors.ErrUserNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find user by nickname: %s", nickname))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, "OK")
+}
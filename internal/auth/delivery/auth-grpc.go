@@ -0,0 +1,100 @@
+package authDelivery
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	authUsecase "technopark-dbms-forum/internal/auth/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+const metadataAuthorization = "authorization"
+
+type (
+	principalContextKey struct{}
+	anonymousContextKey struct{}
+)
+
+// PrincipalFromContext returns the nickname of the user authenticated by
+// UnaryInterceptor or StreamInterceptor, if any.
+func PrincipalFromContext(ctx context.Context) (string, bool) {
+	nickname, ok := ctx.Value(principalContextKey{}).(string)
+	return nickname, ok && nickname != ""
+}
+
+// ActorFromContext is Actor for a gRPC call.
+func ActorFromContext(ctx context.Context) string {
+	if nickname, ok := PrincipalFromContext(ctx); ok {
+		return nickname
+	}
+	if anonymous, _ := ctx.Value(anonymousContextKey{}).(bool); anonymous {
+		return models.ActorAnonymous
+	}
+	return ""
+}
+
+// authenticate follows Middleware and Required for a gRPC call; required
+// lists the full names of the methods that reject anonymous calls.
+func authenticate(ctx context.Context, u *authUsecase.AuthUsecase, required map[string]bool, method string) (context.Context, error) {
+	var header string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(metadataAuthorization); len(values) > 0 {
+			header = values[0]
+		}
+	}
+
+	if header != "" {
+		if !strings.HasPrefix(header, "Bearer ") {
+			return nil, status.Error(codes.Unauthenticated, "Authorization must be a bearer token")
+		}
+
+		nickname, err := u.Verify(strings.TrimPrefix(header, "Bearer "))
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "Token is invalid or expired")
+		}
+
+		return context.WithValue(ctx, principalContextKey{}, nickname), nil
+	}
+
+	if required[method] && !u.IsOpen() {
+		return nil, status.Error(codes.Unauthenticated, "Authentication required")
+	}
+
+	return context.WithValue(ctx, anonymousContextKey{}, u.IsOpen()), nil
+}
+
+func UnaryInterceptor(u *authUsecase.AuthUsecase, required map[string]bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, u, required, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func StreamInterceptor(u *authUsecase.AuthUsecase, required map[string]bool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(stream.Context(), u, required, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
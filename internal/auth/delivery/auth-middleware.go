@@ -0,0 +1,77 @@
+package authDelivery
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	authUsecase "technopark-dbms-forum/internal/auth/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+const (
+	principalKey = "principal"
+	anonymousKey = "anonymous"
+)
+
+// Principal returns the nickname of the authenticated user, if any.
+func Principal(c echo.Context) (string, bool) {
+	nickname, ok := c.Get(principalKey).(string)
+	return nickname, ok && nickname != ""
+}
+
+// Actor is who the request acts as for permission checks: the authenticated
+// user, models.ActorAnonymous for an anonymous request in open mode, or
+// nobody, which every check denies.
+func Actor(c echo.Context) string {
+	if nickname, ok := Principal(c); ok {
+		return nickname
+	}
+	if anonymous, _ := c.Get(anonymousKey).(bool); anonymous {
+		return models.ActorAnonymous
+	}
+	return ""
+}
+
+// Middleware authenticates requests that carry a bearer token. Requests
+// without one pass through anonymously, and act as models.ActorAnonymous
+// when authentication is open; a bad token is rejected.
+func Middleware(u *authUsecase.AuthUsecase) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Request().Header.Get(echo.HeaderAuthorization)
+			if header == "" {
+				c.Set(anonymousKey, u.IsOpen())
+				return next(c)
+			}
+
+			if !strings.HasPrefix(header, "Bearer ") {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Authorization must be a bearer token")
+			}
+			token := strings.TrimPrefix(header, "Bearer ")
+
+			nickname, err := u.Verify(token)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Token is invalid or expired")
+			}
+
+			c.Set(principalKey, nickname)
+
+			return next(c)
+		}
+	}
+}
+
+// Required rejects anonymous requests unless the server runs in open mode.
+func Required(u *authUsecase.AuthUsecase) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if _, ok := Principal(c); !ok && !u.IsOpen() {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+			}
+
+			return next(c)
+		}
+	}
+}
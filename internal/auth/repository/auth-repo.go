@@ -0,0 +1,73 @@
+package authRepository
+
+import (
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	internalErrors "technopark-dbms-forum/internal"
+)
+
+type Postgres struct {
+	sqlx *sqlx.DB
+}
+
+func NewPostgres(url string) (*Postgres, error) {
+	newSQLX, err := sqlx.Connect("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = newSQLX.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &Postgres{sqlx: newSQLX}, nil
+}
+
+func (p *Postgres) Close() error {
+	return p.sqlx.Close()
+}
+
+// GetPasswordHash returns the stored hash together with the canonical nickname.
+func (p *Postgres) GetPasswordHash(nickname string) (string, string, error) {
+	var credentials struct {
+		Nickname     string `db:"nickname"`
+		PasswordHash string `db:"password_hash"`
+	}
+	err := p.sqlx.Get(
+		&credentials,
+		"SELECT nickname, password_hash FROM user_credentials WHERE nickname = $1",
+		nickname,
+	)
+	if err == sql.ErrNoRows {
+		return "", "", internalErrors.ErrNoRows
+	} else if err != nil {
+		return "", "", err
+	}
+
+	return credentials.Nickname, credentials.PasswordHash, nil
+}
+
+func (p *Postgres) SetPasswordHash(nickname, hash string) error {
+	_, err := p.sqlx.Exec(
+		`
+			INSERT INTO user_credentials (nickname, password_hash)
+			VALUES ($1, $2)
+			ON CONFLICT (nickname) DO UPDATE
+			SET password_hash = $2
+		`,
+		nickname,
+		hash,
+	)
+	if err != nil {
+		pgErr, ok := err.(*pq.Error)
+		if ok && pgErr.Code == "23503" {
+			return internalErrors.ErrUserNotFound
+		}
+		return err
+	}
+
+	return nil
+}
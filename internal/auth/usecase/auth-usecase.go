@@ -0,0 +1,155 @@
+package authUsecase
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	internalErrors "technopark-dbms-forum/internal"
+	authRepository "technopark-dbms-forum/internal/auth/repository"
+	roleUsecase "technopark-dbms-forum/internal/roles/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+var errNoSecret = errors.New("no secret to sign tokens with")
+
+type claims struct {
+	Subject string `json:"sub"`
+	Expires int64  `json:"exp"`
+}
+
+type AuthUsecase struct {
+	r      *authRepository.Postgres
+	roles  *roleUsecase.RoleUsecase
+	secret []byte
+	ttl    time.Duration
+	open   bool
+}
+
+func NewAuthUsecase(repo *authRepository.Postgres, roles *roleUsecase.RoleUsecase, secret string, ttl time.Duration, open bool) *AuthUsecase {
+	return &AuthUsecase{
+		r:      repo,
+		roles:  roles,
+		secret: []byte(secret),
+		ttl:    ttl,
+		open:   open,
+	}
+}
+
+// IsOpen reports whether writes may act on behalf of any nickname without a token.
+func (a *AuthUsecase) IsOpen() bool {
+	return a.open
+}
+
+func (a *AuthUsecase) sign(payload string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (a *AuthUsecase) issue(nickname string) (*models.Token, error) {
+	if len(a.secret) == 0 {
+		return nil, errNoSecret
+	}
+
+	expires := time.Now().Add(a.ttl)
+
+	data, err := json.Marshal(&claims{Subject: nickname, Expires: expires.Unix()})
+	if err != nil {
+		return nil, err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+
+	return &models.Token{
+		Token:   payload + "." + a.sign(payload),
+		Expires: expires.Truncate(time.Second),
+	}, nil
+}
+
+// Verify checks the token signature and expiry and returns its nickname.
+// Without a secret every token is rejected, as anyone could sign one.
+func (a *AuthUsecase) Verify(token string) (string, error) {
+	if len(a.secret) == 0 {
+		return "", internalErrors.ErrUnauthorized
+	}
+
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(a.sign(payload))) {
+		return "", internalErrors.ErrUnauthorized
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", internalErrors.ErrUnauthorized
+	}
+
+	c := claims{}
+	if err = json.Unmarshal(data, &c); err != nil {
+		return "", internalErrors.ErrUnauthorized
+	}
+	if c.Subject == "" || time.Now().Unix() >= c.Expires {
+		return "", internalErrors.ErrUnauthorized
+	}
+
+	return c.Subject, nil
+}
+
+func (a *AuthUsecase) Login(credentials *models.Credentials) (*models.Token, error) {
+	nickname, hash, err := a.r.GetPasswordHash(credentials.Nickname)
+	if err == internalErrors.ErrNoRows {
+		return nil, internalErrors.ErrWrongCredentials
+	} else if err != nil {
+		return nil, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(credentials.Password)) != nil {
+		return nil, internalErrors.ErrWrongCredentials
+	}
+
+	return a.issue(nickname)
+}
+
+// SetPassword sets the user's password on behalf of the user, who must also
+// give the old password, or of a site admin. Users get their first password
+// when they are created.
+func (a *AuthUsecase) SetPassword(nickname string, change *models.PasswordChange, principal string) error {
+	if principal == "" {
+		return internalErrors.ErrUnauthorized
+	}
+
+	self := strings.EqualFold(principal, nickname)
+	if !self {
+		admin, err := a.roles.IsAdmin(principal)
+		if err != nil {
+			return err
+		}
+		if !admin {
+			return internalErrors.ErrForbidden
+		}
+	}
+
+	_, hash, err := a.r.GetPasswordHash(nickname)
+	if err != nil && err != internalErrors.ErrNoRows {
+		return err
+	}
+
+	if err == nil && self {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(change.OldPassword)) != nil {
+			return internalErrors.ErrWrongCredentials
+		}
+	}
+
+	newHash, err := bcrypt.GenerateFromPassword([]byte(change.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return a.r.SetPasswordHash(nickname, string(newHash))
+}
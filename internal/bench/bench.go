@@ -0,0 +1,366 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	seedData "technopark-dbms-forum/internal/seed"
+)
+
+// DefaultMix weighs the API calls roughly like forum traffic: mostly reads
+// of threads and posts, with a steady trickle of posting and voting.
+const DefaultMix = "forum_details=5,forum_threads=10,forum_users=5,thread_details=10," +
+	"thread_posts_flat=10,thread_posts_tree=10,thread_posts_parent_tree=10," +
+	"post_details=15,user_profile=10,post_create=10,thread_vote=5"
+
+type Config struct {
+	URL      string
+	Token    string
+	Mix      string
+	RPS      float64
+	Duration time.Duration
+	// Requests sent during Warmup are not recorded.
+	Warmup  time.Duration
+	Workers int
+	Timeout time.Duration
+	Seed    int64
+}
+
+func DefaultConfig() Config {
+	return Config{
+		URL:      "http://localhost:8080/api",
+		Mix:      DefaultMix,
+		RPS:      100,
+		Duration: time.Minute,
+		Warmup:   5 * time.Second,
+		Workers:  64,
+		Timeout:  5 * time.Second,
+		Seed:     1,
+	}
+}
+
+// picker chooses the entities a request addresses from the seed manifest.
+// Threads are picked in proportion to their posts, so hot threads get the
+// traffic they would get on a real forum.
+type picker struct {
+	rand     *rand.Rand
+	manifest *seedData.Manifest
+	weights  []int
+}
+
+func (p *picker) user() string {
+	return p.manifest.Users[p.rand.Intn(len(p.manifest.Users))]
+}
+
+func (p *picker) forum() string {
+	return p.manifest.Forums[p.rand.Intn(len(p.manifest.Forums))]
+}
+
+func (p *picker) thread() string {
+	n := p.rand.Intn(p.weights[len(p.weights)-1])
+	thread := p.manifest.Threads[sort.SearchInts(p.weights, n+1)]
+	if thread.Slug != "" && p.rand.Intn(2) == 0 {
+		return thread.Slug
+	}
+
+	return strconv.FormatUint(thread.ID, 10)
+}
+
+func (p *picker) post() string {
+	first, last := p.manifest.FirstPost, p.manifest.LastPost
+	return strconv.FormatUint(first+uint64(p.rand.Int63n(int64(last-first+1))), 10)
+}
+
+type route struct {
+	method string
+	path   func(p *picker) string
+	body   func(p *picker) interface{}
+	// needs reports whether the manifest has what the route addresses.
+	needs func(m *seedData.Manifest) bool
+}
+
+func hasThreads(m *seedData.Manifest) bool { return len(m.Threads) > 0 }
+func hasPosts(m *seedData.Manifest) bool   { return m.LastPost > 0 }
+
+var routes = map[string]route{
+	"forum_details": {
+		method: http.MethodGet,
+		path:   func(p *picker) string { return "/forum/" + p.forum() + "/details" },
+	},
+	"forum_threads": {
+		method: http.MethodGet,
+		path:   func(p *picker) string { return "/forum/" + p.forum() + "/threads?limit=20&desc=true" },
+	},
+	"forum_users": {
+		method: http.MethodGet,
+		path:   func(p *picker) string { return "/forum/" + p.forum() + "/users?limit=20" },
+	},
+	"thread_details": {
+		method: http.MethodGet,
+		path:   func(p *picker) string { return "/thread/" + p.thread() + "/details" },
+		needs:  hasThreads,
+	},
+	"thread_posts_flat": {
+		method: http.MethodGet,
+		path:   func(p *picker) string { return "/thread/" + p.thread() + "/posts?limit=20&sort=flat" },
+		needs:  hasThreads,
+	},
+	"thread_posts_tree": {
+		method: http.MethodGet,
+		path:   func(p *picker) string { return "/thread/" + p.thread() + "/posts?limit=20&sort=tree" },
+		needs:  hasThreads,
+	},
+	"thread_posts_parent_tree": {
+		method: http.MethodGet,
+		path:   func(p *picker) string { return "/thread/" + p.thread() + "/posts?limit=3&sort=parent_tree" },
+		needs:  hasThreads,
+	},
+	"post_details": {
+		method: http.MethodGet,
+		path:   func(p *picker) string { return "/post/" + p.post() + "/details?related=user,thread,forum" },
+		needs:  hasPosts,
+	},
+	"user_profile": {
+		method: http.MethodGet,
+		path:   func(p *picker) string { return "/user/" + p.user() + "/profile" },
+	},
+	"post_create": {
+		method: http.MethodPost,
+		path:   func(p *picker) string { return "/thread/" + p.thread() + "/create" },
+		body: func(p *picker) interface{} {
+			return []map[string]interface{}{{"author": p.user(), "message": "bench " + strconv.FormatInt(p.rand.Int63(), 36)}}
+		},
+		needs: hasThreads,
+	},
+	"thread_vote": {
+		method: http.MethodPost,
+		path:   func(p *picker) string { return "/thread/" + p.thread() + "/vote" },
+		body: func(p *picker) interface{} {
+			return map[string]interface{}{"nickname": p.user(), "voice": 1 - 2*p.rand.Intn(2)}
+		},
+		needs: hasThreads,
+	},
+}
+
+// Routes lists the route names a mix may use.
+func Routes() []string {
+	names := make([]string, 0, len(routes))
+	for name := range routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+type weighted struct {
+	name   string
+	weight int
+}
+
+// parseMix reads a comma separated list of route=weight pairs.
+func parseMix(mix string, manifest *seedData.Manifest) ([]weighted, int, error) {
+	parsed := make([]weighted, 0)
+	total := 0
+	for _, part := range strings.Split(mix, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+
+		name, value, found := strings.Cut(part, "=")
+		weight := 1
+		if found {
+			var err error
+			if weight, err = strconv.Atoi(value); err != nil || weight < 0 {
+				return nil, 0, fmt.Errorf("bad weight in mix: %s", part)
+			}
+		}
+
+		r, ok := routes[name]
+		if !ok {
+			return nil, 0, fmt.Errorf("unknown route %q, expected one of %s", name, strings.Join(Routes(), ", "))
+		}
+		if weight == 0 {
+			continue
+		}
+		if r.needs != nil && !r.needs(manifest) {
+			return nil, 0, fmt.Errorf("route %s needs threads and posts in the seed manifest", name)
+		}
+
+		total += weight
+		parsed = append(parsed, weighted{name: name, weight: total})
+	}
+	if total == 0 {
+		return nil, 0, fmt.Errorf("the mix has no routes")
+	}
+
+	return parsed, total, nil
+}
+
+type job struct {
+	route     string
+	scheduled time.Time
+	record    bool
+}
+
+// Bench replays the route mix against a running server at a fixed rate.
+type Bench struct {
+	cfg      Config
+	manifest *seedData.Manifest
+	mix      []weighted
+	total    int
+	weights  []int
+	client   *http.Client
+	recorder *recorder
+}
+
+func New(cfg Config, manifest *seedData.Manifest) (*Bench, error) {
+	if len(manifest.Users) == 0 || len(manifest.Forums) == 0 {
+		return nil, fmt.Errorf("the seed manifest has no users or forums")
+	}
+	if cfg.RPS <= 0 || cfg.Workers <= 0 {
+		return nil, fmt.Errorf("rps and workers must be positive")
+	}
+
+	mix, total, err := parseMix(cfg.Mix, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	weights := make([]int, len(manifest.Threads))
+	sum := 0
+	for i, thread := range manifest.Threads {
+		sum += thread.Posts + 1
+		weights[i] = sum
+	}
+
+	return &Bench{
+		cfg:      cfg,
+		manifest: manifest,
+		mix:      mix,
+		total:    total,
+		weights:  weights,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			Transport: &http.Transport{
+				MaxIdleConns:        cfg.Workers,
+				MaxIdleConnsPerHost: cfg.Workers,
+			},
+		},
+		recorder: newRecorder(),
+	}, nil
+}
+
+func (b *Bench) pickRoute(r *rand.Rand) string {
+	n := r.Intn(b.total)
+	i := sort.Search(len(b.mix), func(i int) bool { return b.mix[i].weight > n })
+
+	return b.mix[i].name
+}
+
+// Run sends requests until the warmup and the duration have passed or the
+// context is done. The schedule is open-loop: latency is measured from the
+// moment a request was due, so a slow server cannot hide its queueing by
+// slowing the generator down. Requests that find every worker busy are
+// counted as dropped.
+func (b *Bench) Run(ctx context.Context) (*Report, error) {
+	jobs := make(chan job, b.cfg.Workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < b.cfg.Workers; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			b.work(jobs, &picker{rand: rand.New(rand.NewSource(seed)), manifest: b.manifest, weights: b.weights})
+		}(b.cfg.Seed + int64(i) + 1)
+	}
+
+	r := rand.New(rand.NewSource(b.cfg.Seed))
+	start := time.Now()
+	measured := start.Add(b.cfg.Warmup)
+	end := measured.Add(b.cfg.Duration)
+	interval := time.Duration(float64(time.Second) / b.cfg.RPS)
+
+	var dropped int64
+	next := start
+loop:
+	for next.Before(end) {
+		if wait := time.Until(next); wait > 0 {
+			select {
+			case <-ctx.Done():
+				break loop
+			case <-time.After(wait):
+			}
+		}
+
+		j := job{route: b.pickRoute(r), scheduled: next, record: !next.Before(measured)}
+		select {
+		case jobs <- j:
+		default:
+			if j.record {
+				dropped++
+			}
+		}
+		next = next.Add(interval)
+	}
+	close(jobs)
+	wg.Wait()
+
+	finished := time.Now()
+	if finished.After(end) {
+		finished = end
+	}
+
+	return b.recorder.report(b.cfg, measured, finished, dropped), ctx.Err()
+}
+
+func (b *Bench) work(jobs <-chan job, p *picker) {
+	for j := range jobs {
+		ok := b.do(routes[j.route], p)
+		if j.record {
+			b.recorder.add(j.route, time.Since(j.scheduled), ok)
+		}
+	}
+}
+
+// do sends one request and reports whether it got a 2xx answer.
+func (b *Bench) do(r route, p *picker) bool {
+	var body io.Reader
+	if r.body != nil {
+		data, err := json.Marshal(r.body(p))
+		if err != nil {
+			return false
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(r.method, strings.TrimSuffix(b.cfg.URL, "/")+r.path(p), body)
+	if err != nil {
+		return false
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if b.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.cfg.Token)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return false
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
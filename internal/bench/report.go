@@ -0,0 +1,259 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// TotalRoute names the row that sums up every route.
+const TotalRoute = "total"
+
+// RouteStats holds one route's latency percentiles in milliseconds.
+type RouteStats struct {
+	Route    string  `json:"route"`
+	Requests int64   `json:"requests"`
+	Errors   int64   `json:"errors"`
+	Mean     float64 `json:"meanMs"`
+	P50      float64 `json:"p50Ms"`
+	P95      float64 `json:"p95Ms"`
+	P99      float64 `json:"p99Ms"`
+	Max      float64 `json:"maxMs"`
+}
+
+type Report struct {
+	Started     time.Time `json:"started"`
+	URL         string    `json:"url"`
+	Mix         string    `json:"mix"`
+	Duration    float64   `json:"durationSeconds"`
+	TargetRPS   float64   `json:"targetRps"`
+	AchievedRPS float64   `json:"achievedRps"`
+	// Dropped counts requests that were due while every worker was busy.
+	Dropped int64         `json:"dropped"`
+	Routes  []*RouteStats `json:"routes"`
+	Total   *RouteStats   `json:"total"`
+}
+
+type samples struct {
+	latencies []time.Duration
+	errors    int64
+}
+
+type recorder struct {
+	mu     sync.Mutex
+	routes map[string]*samples
+}
+
+func newRecorder() *recorder {
+	return &recorder{routes: make(map[string]*samples)}
+}
+
+func (r *recorder) add(route string, latency time.Duration, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, found := r.routes[route]
+	if !found {
+		s = &samples{}
+		r.routes[route] = s
+	}
+	s.latencies = append(s.latencies, latency)
+	if !ok {
+		s.errors++
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*1000) / 1000
+}
+
+// percentile takes the nearest rank from sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+func stats(route string, s *samples) *RouteStats {
+	stats := &RouteStats{Route: route, Requests: int64(len(s.latencies)), Errors: s.errors}
+	if len(s.latencies) == 0 {
+		return stats
+	}
+
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+	var sum time.Duration
+	for _, latency := range s.latencies {
+		sum += latency
+	}
+
+	stats.Mean = milliseconds(sum / time.Duration(len(s.latencies)))
+	stats.P50 = milliseconds(percentile(s.latencies, 50))
+	stats.P95 = milliseconds(percentile(s.latencies, 95))
+	stats.P99 = milliseconds(percentile(s.latencies, 99))
+	stats.Max = milliseconds(s.latencies[len(s.latencies)-1])
+
+	return stats
+}
+
+func (r *recorder) report(cfg Config, started, finished time.Time, dropped int64) *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &Report{
+		Started:   started,
+		URL:       cfg.URL,
+		Mix:       cfg.Mix,
+		Duration:  finished.Sub(started).Seconds(),
+		TargetRPS: cfg.RPS,
+		Dropped:   dropped,
+		Routes:    make([]*RouteStats, 0, len(r.routes)),
+	}
+
+	total := &samples{}
+	for route, s := range r.routes {
+		total.latencies = append(total.latencies, s.latencies...)
+		total.errors += s.errors
+		report.Routes = append(report.Routes, stats(route, s))
+	}
+	sort.Slice(report.Routes, func(i, j int) bool { return report.Routes[i].Route < report.Routes[j].Route })
+	report.Total = stats(TotalRoute, total)
+
+	if report.Duration > 0 {
+		report.AchievedRPS = math.Round(float64(report.Total.Requests)/report.Duration*100) / 100
+	}
+
+	return report
+}
+
+// WriteText prints the report as a table.
+func (r *Report) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "%s, %.0fs at %.0f rps target, %.2f rps achieved, %d dropped\n\n", r.URL, r.Duration, r.TargetRPS, r.AchievedRPS, r.Dropped)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "route\trequests\terrors\tmean ms\tp50 ms\tp95 ms\tp99 ms\tmax ms\t")
+	for _, s := range append(r.Routes, r.Total) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t\n", s.Route, s.Requests, s.Errors, s.Mean, s.P50, s.P95, s.P99, s.Max)
+	}
+
+	return tw.Flush()
+}
+
+// Delta compares one route across two runs. Changes are relative, so 0.1
+// means ten percent slower.
+type Delta struct {
+	Route     string  `json:"route"`
+	OldP50    float64 `json:"oldP50Ms"`
+	NewP50    float64 `json:"newP50Ms"`
+	OldP95    float64 `json:"oldP95Ms"`
+	NewP95    float64 `json:"newP95Ms"`
+	OldP99    float64 `json:"oldP99Ms"`
+	NewP99    float64 `json:"newP99Ms"`
+	P95Change float64 `json:"p95Change"`
+	P99Change float64 `json:"p99Change"`
+	OldErrors float64 `json:"oldErrorRate"`
+	NewErrors float64 `json:"newErrorRate"`
+	// Dropped is only set on the total row: drops belong to the run, not to
+	// a route.
+	OldDropped int64 `json:"oldDropped,omitempty"`
+	NewDropped int64 `json:"newDropped,omitempty"`
+	Regressed  bool  `json:"regressed"`
+}
+
+func change(old, new float64) float64 {
+	if old == 0 {
+		return 0
+	}
+
+	return (new - old) / old
+}
+
+// errorRate counts dropped requests as failed ones: a request that was due
+// and never sent is no better than one that timed out.
+func errorRate(s *RouteStats, dropped int64) float64 {
+	if s.Requests+dropped == 0 {
+		return 0
+	}
+
+	return float64(s.Errors+dropped) / float64(s.Requests+dropped)
+}
+
+// Compare lines up the routes both runs have. A route regressed when its p95
+// or p99 grew by more than threshold, or its error rate by more than a
+// percentage point. Dropped requests count as errors of the total row, and
+// the total regressed whenever the new run dropped more than the old one.
+func Compare(old, new *Report, threshold float64) []*Delta {
+	oldRoutes := make(map[string]*RouteStats, len(old.Routes)+1)
+	for _, s := range append(old.Routes, old.Total) {
+		oldRoutes[s.Route] = s
+	}
+
+	deltas := make([]*Delta, 0, len(new.Routes)+1)
+	for _, s := range append(new.Routes, new.Total) {
+		o, ok := oldRoutes[s.Route]
+		if !ok || o.Requests == 0 || s.Requests == 0 {
+			continue
+		}
+
+		var oldDropped, newDropped int64
+		if s.Route == TotalRoute {
+			oldDropped, newDropped = old.Dropped, new.Dropped
+		}
+
+		d := &Delta{
+			Route:      s.Route,
+			OldP50:     o.P50,
+			NewP50:     s.P50,
+			OldP95:     o.P95,
+			NewP95:     s.P95,
+			OldP99:     o.P99,
+			NewP99:     s.P99,
+			P95Change:  change(o.P95, s.P95),
+			P99Change:  change(o.P99, s.P99),
+			OldErrors:  errorRate(o, oldDropped),
+			NewErrors:  errorRate(s, newDropped),
+			OldDropped: oldDropped,
+			NewDropped: newDropped,
+		}
+		d.Regressed = d.P95Change > threshold || d.P99Change > threshold || d.NewErrors-d.OldErrors > 0.01 ||
+			d.NewDropped > d.OldDropped
+		deltas = append(deltas, d)
+	}
+
+	return deltas
+}
+
+// WriteComparison prints the deltas and marks regressed routes with "!".
+func WriteComparison(w io.Writer, deltas []*Delta) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "route\tp50 ms\tp95 ms\tp95\tp99 ms\tp99\terrors\tdropped\t\t")
+	for _, d := range deltas {
+		mark := ""
+		if d.Regressed {
+			mark = "!"
+		}
+		dropped := ""
+		if d.Route == TotalRoute {
+			dropped = fmt.Sprintf("%d → %d", d.OldDropped, d.NewDropped)
+		}
+		fmt.Fprintf(
+			tw,
+			"%s\t%.2f → %.2f\t%.2f → %.2f\t%+.1f%%\t%.2f → %.2f\t%+.1f%%\t%.1f%% → %.1f%%\t%s\t%s\t\n",
+			d.Route,
+			d.OldP50, d.NewP50,
+			d.OldP95, d.NewP95, d.P95Change*100,
+			d.OldP99, d.NewP99, d.P99Change*100,
+			d.OldErrors*100, d.NewErrors*100,
+			dropped,
+			mark,
+		)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,244 @@
+package config
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+const (
+	// AuthOpen trusts nicknames sent in request bodies, as the benchmark harness expects.
+	AuthOpen = "open"
+	// AuthToken takes the acting user from a signed token.
+	AuthToken = "token"
+
+	// ContractOff leaves requests and responses unchecked.
+	ContractOff = "off"
+	// ContractReport checks them against api/swagger.yml and logs violations.
+	ContractReport = "report"
+	// ContractStrict also answers 500 instead of a response that breaks the spec.
+	ContractStrict = "strict"
+)
+
+type Config struct {
+	Port        string
+	MetricsPort string
+	AdminPort   string
+	GRPCPort    string
+
+	DBHost     string
+	DBPort     string
+	DBUser     string
+	DBPassword string
+	DBName     string
+
+	AuthMode     string
+	AuthSecret   string
+	AuthTokenTTL time.Duration
+	AdminToken   string
+
+	// RateLimitPerMinute and RateLimitBurst are the default token bucket for
+	// posting, thread creation and voting; forums may override them. A zero
+	// burst turns the default limit off.
+	RateLimitPerMinute float64
+	RateLimitBurst     int
+	// TrustedProxies are the networks whose X-Forwarded-For is believed when
+	// finding the client address the limits count on. Without them the
+	// address is the peer's.
+	TrustedProxies []*net.IPNet
+
+	// Content filters are off while their threshold is empty or zero.
+	FilterBlocklist       []string
+	FilterBlocklistAction string
+	FilterMaxLinks        int
+	FilterLinksAction     string
+	FilterDuplicateWindow time.Duration
+	FilterDuplicateAction string
+	FilterMaxLength       int
+	FilterMaxLengthAction string
+
+	// AttachmentDir is the root of the local attachment store. Attachments
+	// go to the S3-compatible bucket AttachmentS3Bucket instead when it is set.
+	AttachmentDir         string
+	AttachmentS3Endpoint  string
+	AttachmentS3Bucket    string
+	AttachmentS3Region    string
+	AttachmentS3AccessKey string
+	AttachmentS3SecretKey string
+	AttachmentMaxSize     int64
+	AttachmentTypes       []string
+
+	// CounterReconcileEvery schedules the counter reconciliation; zero turns
+	// the schedule off. Without CounterReconcileFix it only reports drift.
+	CounterReconcileEvery time.Duration
+	CounterReconcileBatch int
+	CounterReconcileFix   bool
+
+	// ContractCheck is ContractOff, ContractReport or ContractStrict.
+	ContractCheck string
+
+	// GraphQLMaxDepth and GraphQLMaxComplexity bound the queries /api/graphql
+	// runs; zero turns a bound off.
+	GraphQLMaxDepth      int
+	GraphQLMaxComplexity int
+
+	EventsFile    string
+	EventsWebhook string
+}
+
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+// Load reads the configuration from the environment.
+func Load() (*Config, error) {
+	c := &Config{
+		Port:          getenv("PORT", "8080"),
+		MetricsPort:   getenv("METRICS_PORT", "9090"),
+		AdminPort:     os.Getenv("ADMIN_PORT"),
+		GRPCPort:      os.Getenv("GRPC_PORT"),
+		DBHost:        os.Getenv("DB_HOST"),
+		DBPort:        os.Getenv("DB_PORT"),
+		DBUser:        os.Getenv("DB_USER"),
+		DBPassword:    os.Getenv("DB_PASSWORD"),
+		DBName:        getenv("DB_NAME", "forum-task"),
+		AuthMode:      getenv("AUTH_MODE", AuthToken),
+		AuthSecret:    os.Getenv("AUTH_SECRET"),
+		AdminToken:    os.Getenv("ADMIN_TOKEN"),
+		EventsFile:    os.Getenv("EVENTS_FILE"),
+		EventsWebhook: os.Getenv("EVENTS_WEBHOOK"),
+		AttachmentDir: getenv("ATTACHMENT_DIR", "attachments"),
+		ContractCheck: getenv("CONTRACT_CHECK", ContractOff),
+
+		FilterBlocklistAction: getenv("FILTER_BLOCKLIST_ACTION", models.FilterRewrite),
+		FilterLinksAction:     getenv("FILTER_LINKS_ACTION", models.FilterFlag),
+		FilterDuplicateAction: getenv("FILTER_DUPLICATE_ACTION", models.FilterReject),
+		FilterMaxLengthAction: getenv("FILTER_MAX_LENGTH_ACTION", models.FilterReject),
+
+		AttachmentS3Endpoint:  os.Getenv("ATTACHMENT_S3_ENDPOINT"),
+		AttachmentS3Bucket:    os.Getenv("ATTACHMENT_S3_BUCKET"),
+		AttachmentS3Region:    getenv("ATTACHMENT_S3_REGION", "us-east-1"),
+		AttachmentS3AccessKey: os.Getenv("ATTACHMENT_S3_ACCESS_KEY"),
+		AttachmentS3SecretKey: os.Getenv("ATTACHMENT_S3_SECRET_KEY"),
+	}
+
+	ttl, err := time.ParseDuration(getenv("AUTH_TOKEN_TTL", "24h"))
+	if err != nil {
+		return nil, errors.New("AUTH_TOKEN_TTL: " + err.Error())
+	}
+	c.AuthTokenTTL = ttl
+
+	if c.RateLimitPerMinute, err = strconv.ParseFloat(getenv("RATE_LIMIT_PER_MINUTE", "0"), 64); err != nil {
+		return nil, errors.New("RATE_LIMIT_PER_MINUTE: " + err.Error())
+	}
+	if c.RateLimitBurst, err = strconv.Atoi(getenv("RATE_LIMIT_BURST", "0")); err != nil {
+		return nil, errors.New("RATE_LIMIT_BURST: " + err.Error())
+	}
+	if c.RateLimitBurst > 0 && c.RateLimitPerMinute <= 0 {
+		return nil, errors.New("RATE_LIMIT_PER_MINUTE must be positive when RATE_LIMIT_BURST is set")
+	}
+
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, errors.New("TRUSTED_PROXIES: " + err.Error())
+		}
+		c.TrustedProxies = append(c.TrustedProxies, network)
+	}
+
+	for _, word := range strings.Split(os.Getenv("FILTER_BLOCKLIST"), ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			c.FilterBlocklist = append(c.FilterBlocklist, word)
+		}
+	}
+	if c.FilterMaxLinks, err = strconv.Atoi(getenv("FILTER_MAX_LINKS", "0")); err != nil {
+		return nil, errors.New("FILTER_MAX_LINKS: " + err.Error())
+	}
+	if c.FilterDuplicateWindow, err = time.ParseDuration(getenv("FILTER_DUPLICATE_WINDOW", "0")); err != nil {
+		return nil, errors.New("FILTER_DUPLICATE_WINDOW: " + err.Error())
+	}
+	if c.FilterMaxLength, err = strconv.Atoi(getenv("FILTER_MAX_LENGTH", "0")); err != nil {
+		return nil, errors.New("FILTER_MAX_LENGTH: " + err.Error())
+	}
+	for key, action := range map[string]string{
+		"FILTER_BLOCKLIST_ACTION":  c.FilterBlocklistAction,
+		"FILTER_LINKS_ACTION":      c.FilterLinksAction,
+		"FILTER_DUPLICATE_ACTION":  c.FilterDuplicateAction,
+		"FILTER_MAX_LENGTH_ACTION": c.FilterMaxLengthAction,
+	} {
+		if action != models.FilterReject && action != models.FilterFlag && action != models.FilterRewrite {
+			return nil, errors.New(key + " must be " + models.FilterReject + ", " + models.FilterFlag + " or " + models.FilterRewrite)
+		}
+	}
+
+	if c.AttachmentMaxSize, err = strconv.ParseInt(getenv("ATTACHMENT_MAX_SIZE", "10485760"), 10, 64); err != nil {
+		return nil, errors.New("ATTACHMENT_MAX_SIZE: " + err.Error())
+	}
+	for _, mimeType := range strings.Split(getenv("ATTACHMENT_TYPES", "image/png,image/jpeg,image/gif,image/webp,application/pdf,text/plain"), ",") {
+		if mimeType = strings.TrimSpace(mimeType); mimeType != "" {
+			c.AttachmentTypes = append(c.AttachmentTypes, mimeType)
+		}
+	}
+
+	if c.CounterReconcileEvery, err = time.ParseDuration(getenv("COUNTER_RECONCILE_EVERY", "1h")); err != nil {
+		return nil, errors.New("COUNTER_RECONCILE_EVERY: " + err.Error())
+	}
+	if c.CounterReconcileBatch, err = strconv.Atoi(getenv("COUNTER_RECONCILE_BATCH", "500")); err != nil {
+		return nil, errors.New("COUNTER_RECONCILE_BATCH: " + err.Error())
+	}
+	if c.CounterReconcileFix, err = strconv.ParseBool(getenv("COUNTER_RECONCILE_FIX", "true")); err != nil {
+		return nil, errors.New("COUNTER_RECONCILE_FIX: " + err.Error())
+	}
+
+	if c.GraphQLMaxDepth, err = strconv.Atoi(getenv("GRAPHQL_MAX_DEPTH", "10")); err != nil {
+		return nil, errors.New("GRAPHQL_MAX_DEPTH: " + err.Error())
+	}
+	if c.GraphQLMaxComplexity, err = strconv.Atoi(getenv("GRAPHQL_MAX_COMPLEXITY", "5000")); err != nil {
+		return nil, errors.New("GRAPHQL_MAX_COMPLEXITY: " + err.Error())
+	}
+
+	if c.ContractCheck != ContractOff && c.ContractCheck != ContractReport && c.ContractCheck != ContractStrict {
+		return nil, errors.New("CONTRACT_CHECK must be " + ContractOff + ", " + ContractReport + " or " + ContractStrict)
+	}
+
+	if c.AuthMode != AuthOpen && c.AuthMode != AuthToken {
+		return nil, errors.New("AUTH_MODE must be " + AuthOpen + " or " + AuthToken)
+	}
+
+	return c, nil
+}
+
+// CheckServe checks the settings only the server needs, so the other
+// commands run without them.
+func (c *Config) CheckServe() error {
+	// Tokens are accepted in both modes, by the admin API too, so an empty
+	// secret would let anyone sign one.
+	if c.AuthSecret == "" {
+		return errors.New("AUTH_SECRET is required")
+	}
+
+	return nil
+}
+
+func (c *Config) PostgresURL() string {
+	return "host=" + c.DBHost + " port=" + c.DBPort + " user=" + c.DBUser + " password=" + c.DBPassword + " dbname=" + c.DBName + " sslmode=disable"
+}
@@ -0,0 +1,136 @@
+package contractDelivery
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	contractUsecase "technopark-dbms-forum/internal/contract/usecase"
+	"technopark-dbms-forum/pkg/etag"
+)
+
+// recorder holds the response back until it has been checked.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
+
+// Flush is a no-op: streamed routes are not recorded.
+func (r *recorder) Flush() {}
+
+// Middleware checks requests to the routes api/swagger.yml documents, and
+// the responses given to valid ones, and reports what does not match. A
+// request that breaks the spec has no contract for its response; its
+// violations carry the status it got, which tells whether the server turned
+// it away too. Routes that stream are passed through, and only their
+// status is checked. Other routes are reported as undocumented. In strict
+// mode a response that breaks the spec is replaced with a 500 naming the
+// violations, so that a test client sees the mismatch.
+func Middleware(checker *contractUsecase.Checker, strict bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if !checker.Documented(req.Method, c.Path()) {
+				err := next(c)
+				// Paths no route matches reach the middleware through the
+				// group's catch-all routes; they are not routes to document.
+				// A later middleware may turn them away before the catch-all
+				// answers 404.
+				if err != echo.ErrNotFound && err != echo.ErrMethodNotAllowed && !strings.HasSuffix(c.Path(), "/*") {
+					checker.Report(checker.CheckRoute(req.Method, c.Path()))
+				}
+				return err
+			}
+
+			params := make(map[string]string, len(c.ParamNames()))
+			for i, name := range c.ParamNames() {
+				params[name] = c.ParamValues()[i]
+			}
+
+			if checker.Streams(req.Method, c.Path()) {
+				violations := checker.CheckRequest(req.Method, c.Path(), params, c.QueryParams(), nil)
+				if err := next(c); err != nil {
+					c.Error(err)
+				}
+
+				res := c.Response()
+				for _, violation := range violations {
+					violation.Status = res.Status
+				}
+				// A hijacked connection, such as a WebSocket, was answered
+				// past echo and has no status to check.
+				if len(violations) == 0 && res.Committed {
+					violations = checker.CheckStatus(req.Method, c.Path(), res.Status)
+				}
+				checker.Report(violations)
+				return nil
+			}
+
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, err)
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			violations := checker.CheckRequest(req.Method, c.Path(), params, c.QueryParams(), body)
+			if len(violations) > 0 {
+				if err = next(c); err != nil {
+					c.Error(err)
+				}
+				for _, violation := range violations {
+					violation.Status = c.Response().Status
+				}
+				checker.Report(violations)
+				return nil
+			}
+
+			res := c.Response()
+			rec := &recorder{ResponseWriter: res.Writer, status: http.StatusOK}
+			res.Writer = rec
+
+			if err = next(c); err != nil {
+				c.Error(err)
+			}
+			res.Writer = rec.ResponseWriter
+
+			violations = checker.CheckResponse(req.Method, c.Path(), rec.status, rec.body.Bytes())
+			checker.Report(violations)
+
+			if strict && len(violations) > 0 {
+				messages := make([]string, 0, len(violations))
+				for _, violation := range violations {
+					messages = append(messages, violation.String())
+				}
+
+				header := res.Header()
+				for _, name := range []string{echo.HeaderContentLength, echo.HeaderLocation, etag.HeaderETag} {
+					header.Del(name)
+				}
+				header.Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+				res.Status = http.StatusInternalServerError
+
+				data, _ := json.Marshal(map[string]interface{}{"message": "Response breaks the API contract", "violations": messages})
+				res.Writer.WriteHeader(http.StatusInternalServerError)
+				_, err = res.Writer.Write(data)
+				return err
+			}
+
+			res.Writer.WriteHeader(rec.status)
+			_, err = res.Writer.Write(rec.body.Bytes())
+			return err
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package contractUsecase
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var violationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "forum_contract_violations_total",
+	Help: "Requests and responses that broke api/swagger.yml, by operation.",
+}, []string{"operation", "in"})
@@ -0,0 +1,225 @@
+package contractUsecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checker collects what is wrong with one message.
+type checker struct {
+	doc *document
+	// request skips required properties marked readOnly: the server fills
+	// them in, so clients leave them out.
+	request bool
+	errs    []string
+}
+
+func (c *checker) fail(at, format string, args ...interface{}) {
+	c.errs = append(c.errs, at+": "+fmt.Sprintf(format, args...))
+}
+
+func (c *checker) resolve(s *schema) *schema {
+	if s.Ref == "" {
+		return s
+	}
+
+	// References were resolved by compile.
+	resolved, _ := c.doc.definition(s.Ref)
+	return resolved
+}
+
+// value checks a decoded JSON value; numbers must be decoded as json.Number.
+// Swagger 2.0 has no null type, so null is only accepted where the spec says
+// x-isnullable.
+func (c *checker) value(s *schema, v interface{}, at string) {
+	s = c.resolve(s)
+	if v == nil {
+		if !s.Nullable {
+			c.fail(at, "must not be null")
+		}
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			c.fail(at, "must be an object")
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok = object[name]; ok {
+				continue
+			}
+			if property := s.Properties[name]; c.request && property != nil && c.resolve(property).ReadOnly {
+				continue
+			}
+			c.fail(at+"."+name, "is required")
+		}
+
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := s.Properties[name]; ok {
+				c.value(property, object[name], at+"."+name)
+			}
+		}
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			c.fail(at, "must be an array")
+			return
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				c.value(s.Items, item, at+"["+strconv.Itoa(i)+"]")
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			c.fail(at, "must be a string")
+			return
+		}
+		c.string(s, str, at)
+	case "number", "integer":
+		number, ok := v.(json.Number)
+		if !ok {
+			c.fail(at, "must be a number")
+			return
+		}
+		c.number(s, number, at)
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			c.fail(at, "must be a boolean")
+		}
+	}
+}
+
+func (c *checker) string(s *schema, str, at string) {
+	if len(s.Enum) > 0 && !inEnum(s.Enum, str) {
+		c.fail(at, "%q is not one of %v", str, s.Enum)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		c.fail(at, "%q does not match %s", str, s.Pattern)
+	}
+	if s.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+			c.fail(at, "%q is not a date-time", str)
+		}
+	}
+}
+
+func (c *checker) number(s *schema, number json.Number, at string) {
+	f, err := number.Float64()
+	if err != nil {
+		c.fail(at, "%s is not a number", number)
+		return
+	}
+
+	integer := s.Type == "integer" || s.Format == "int32" || s.Format == "int64"
+	if integer && f != math.Trunc(f) {
+		c.fail(at, "%s is not an integer", number)
+	} else if s.Format == "int32" && (f < math.MinInt32 || f > math.MaxInt32) {
+		c.fail(at, "%s does not fit int32", number)
+	}
+	if s.Minimum != nil && f < *s.Minimum {
+		c.fail(at, "%s is less than %v", number, *s.Minimum)
+	}
+	if s.Maximum != nil && f > *s.Maximum {
+		c.fail(at, "%s is greater than %v", number, *s.Maximum)
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, f) {
+		c.fail(at, "%s is not one of %v", number, s.Enum)
+	}
+}
+
+func inEnum(enum []interface{}, v interface{}) bool {
+	for _, option := range enum {
+		switch o := option.(type) {
+		case int:
+			if f, ok := v.(float64); ok && f == float64(o) {
+				return true
+			}
+		case float64:
+			if f, ok := v.(float64); ok && f == o {
+				return true
+			}
+		default:
+			if fmt.Sprint(o) == fmt.Sprint(v) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// scalar converts a path or query value to what a JSON body would carry.
+func scalar(typ, raw string) (interface{}, bool) {
+	switch typ {
+	case "number", "integer":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return nil, false
+		}
+		return json.Number(raw), true
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		return b, err == nil
+	default:
+		return raw, true
+	}
+}
+
+// parameter checks the raw values of a path or query parameter.
+func (c *checker) parameter(p *parameter, values []string) {
+	at := p.In + "." + p.Name
+	if len(values) == 0 {
+		if p.Required {
+			c.fail(at, "is required")
+		}
+		return
+	}
+
+	s := &schema{Type: p.Type, Format: p.Format, Enum: p.Enum, Minimum: p.Minimum, Maximum: p.Maximum, Items: p.Items}
+	if p.Type != "array" {
+		v, ok := scalar(p.Type, values[0])
+		if !ok {
+			c.fail(at, "%q is not a %s", values[0], p.Type)
+			return
+		}
+		c.value(s, v, at)
+		return
+	}
+
+	if p.CollectionFormat != "multi" {
+		separator := map[string]string{"ssv": " ", "tsv": "\t", "pipes": "|"}[p.CollectionFormat]
+		if separator == "" {
+			separator = ","
+		}
+		values = strings.Split(values[0], separator)
+	}
+
+	itemType := "string"
+	if p.Items != nil {
+		itemType = c.resolve(p.Items).Type
+	}
+	items := make([]interface{}, 0, len(values))
+	for i, raw := range values {
+		v, ok := scalar(itemType, raw)
+		if !ok {
+			c.fail(at+"["+strconv.Itoa(i)+"]", "%q is not a %s", raw, itemType)
+			return
+		}
+		items = append(items, v)
+	}
+	c.value(s, items, at)
+}
@@ -0,0 +1,140 @@
+package contractUsecase
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The subset of Swagger 2.0 that api/swagger.yml uses.
+
+type schema struct {
+	Ref        string             `yaml:"$ref"`
+	Type       string             `yaml:"type"`
+	Format     string             `yaml:"format"`
+	Properties map[string]*schema `yaml:"properties"`
+	Required   []string           `yaml:"required"`
+	Items      *schema            `yaml:"items"`
+	Enum       []interface{}      `yaml:"enum"`
+	Pattern    string             `yaml:"pattern"`
+	Minimum    *float64           `yaml:"minimum"`
+	Maximum    *float64           `yaml:"maximum"`
+	ReadOnly   bool               `yaml:"readOnly"`
+	Nullable   bool               `yaml:"x-isnullable"`
+
+	pattern *regexp.Regexp
+}
+
+type parameter struct {
+	Name             string        `yaml:"name"`
+	In               string        `yaml:"in"`
+	Required         bool          `yaml:"required"`
+	Type             string        `yaml:"type"`
+	Format           string        `yaml:"format"`
+	Enum             []interface{} `yaml:"enum"`
+	Items            *schema       `yaml:"items"`
+	CollectionFormat string        `yaml:"collectionFormat"`
+	Minimum          *float64      `yaml:"minimum"`
+	Maximum          *float64      `yaml:"maximum"`
+	Schema           *schema       `yaml:"schema"`
+}
+
+type response struct {
+	Ref    string  `yaml:"$ref"`
+	Schema *schema `yaml:"schema"`
+}
+
+type operation struct {
+	ID         string               `yaml:"operationId"`
+	Produces   []string             `yaml:"produces"`
+	Parameters []*parameter         `yaml:"parameters"`
+	Responses  map[string]*response `yaml:"responses"`
+}
+
+type pathItem struct {
+	Get        *operation   `yaml:"get"`
+	Post       *operation   `yaml:"post"`
+	Put        *operation   `yaml:"put"`
+	Delete     *operation   `yaml:"delete"`
+	Parameters []*parameter `yaml:"parameters"`
+}
+
+type document struct {
+	Swagger     string               `yaml:"swagger"`
+	BasePath    string               `yaml:"basePath"`
+	Paths       map[string]*pathItem `yaml:"paths"`
+	Definitions map[string]*schema   `yaml:"definitions"`
+	Responses   map[string]*response `yaml:"responses"`
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// route turns a spec path into the echo route it is served on, so that
+// operations can be found by c.Path().
+func route(basePath, path string) string {
+	return strings.TrimSuffix(basePath, "/") + pathParam.ReplaceAllString(path, ":$1")
+}
+
+func parseDocument(data []byte) (*document, error) {
+	doc := &document{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+	if doc.Swagger != "2.0" {
+		return nil, fmt.Errorf("unsupported spec version %q", doc.Swagger)
+	}
+
+	return doc, nil
+}
+
+func (d *document) definition(ref string) (*schema, error) {
+	name := strings.TrimPrefix(ref, "#/definitions/")
+	s, ok := d.Definitions[name]
+	if !ok || name == ref {
+		return nil, fmt.Errorf("unresolved reference %s", ref)
+	}
+
+	return s, nil
+}
+
+// compile resolves references and patterns once, so that checking a
+// message does not fail on a broken spec halfway through.
+func (d *document) compile(s *schema) error {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		_, err := d.definition(s.Ref)
+		return err
+	}
+	if s.Pattern != "" && s.pattern == nil {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("pattern %s: %w", s.Pattern, err)
+		}
+		s.pattern = pattern
+	}
+	for _, property := range s.Properties {
+		if err := d.compile(property); err != nil {
+			return err
+		}
+	}
+
+	return d.compile(s.Items)
+}
+
+func (d *document) resolveResponse(r *response) (*response, error) {
+	if r.Ref == "" {
+		return r, nil
+	}
+
+	name := strings.TrimPrefix(r.Ref, "#/responses/")
+	resolved, ok := d.Responses[name]
+	if !ok || name == r.Ref {
+		return nil, fmt.Errorf("unresolved reference %s", r.Ref)
+	}
+
+	return resolved, nil
+}
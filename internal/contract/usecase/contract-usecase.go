@@ -0,0 +1,254 @@
+package contractUsecase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+// Reporter receives every violation the checker finds.
+type Reporter func(violation *models.ContractViolation)
+
+type endpoint struct {
+	method     string
+	path       string
+	id         string
+	parameters []*parameter
+	body       *parameter
+	responses  map[string]*response
+	// streams is set for operations that produce something other than JSON.
+	streams bool
+}
+
+func streams(produces []string) bool {
+	for _, mimeType := range produces {
+		if mimeType == "application/json" {
+			return false
+		}
+	}
+
+	return len(produces) > 0
+}
+
+// Checker holds requests and responses of the documented routes to the spec.
+// Routes the spec does not document are reported as such and not checked.
+type Checker struct {
+	doc       *document
+	endpoints map[string]*endpoint
+	reporters []Reporter
+}
+
+func NewChecker(spec []byte) (*Checker, error) {
+	doc, err := parseDocument(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, definition := range doc.Definitions {
+		if err = doc.compile(definition); err != nil {
+			return nil, fmt.Errorf("definition %s: %w", name, err)
+		}
+	}
+
+	c := &Checker{doc: doc, endpoints: make(map[string]*endpoint)}
+	for path, item := range doc.Paths {
+		for method, op := range map[string]*operation{
+			http.MethodGet:    item.Get,
+			http.MethodPost:   item.Post,
+			http.MethodPut:    item.Put,
+			http.MethodDelete: item.Delete,
+		} {
+			if op == nil {
+				continue
+			}
+
+			e := &endpoint{method: method, path: path, id: op.ID, responses: make(map[string]*response), streams: streams(op.Produces)}
+			for _, p := range append(append([]*parameter{}, item.Parameters...), op.Parameters...) {
+				if err = doc.compile(p.Schema); err != nil {
+					return nil, fmt.Errorf("%s %s: %w", method, path, err)
+				}
+				if err = doc.compile(p.Items); err != nil {
+					return nil, fmt.Errorf("%s %s: %w", method, path, err)
+				}
+				if p.In == "body" {
+					e.body = p
+				} else {
+					e.parameters = append(e.parameters, p)
+				}
+			}
+			for status, r := range op.Responses {
+				if r, err = doc.resolveResponse(r); err != nil {
+					return nil, fmt.Errorf("%s %s: %w", method, path, err)
+				}
+				if err = doc.compile(r.Schema); err != nil {
+					return nil, fmt.Errorf("%s %s: %w", method, path, err)
+				}
+				e.responses[status] = r
+			}
+
+			c.endpoints[method+" "+route(doc.BasePath, path)] = e
+		}
+	}
+
+	return c, nil
+}
+
+// AddReporter registers a reporter; it must be called before the checker is used.
+func (c *Checker) AddReporter(r Reporter) {
+	c.reporters = append(c.reporters, r)
+}
+
+// Documented reports whether the spec documents the echo route.
+func (c *Checker) Documented(method, route string) bool {
+	_, ok := c.endpoints[method+" "+route]
+	return ok
+}
+
+// Streams reports whether the documented route answers with something other
+// than JSON, such as an event stream or a file. Such answers are not held
+// back for checking; only their status is checked.
+func (c *Checker) Streams(method, route string) bool {
+	e, ok := c.endpoints[method+" "+route]
+	return ok && e.streams
+}
+
+// CheckRoute reports the echo route if the spec does not document it.
+func (c *Checker) CheckRoute(method, route string) []*models.ContractViolation {
+	if c.Documented(method, route) {
+		return nil
+	}
+
+	return []*models.ContractViolation{{
+		Method:  method,
+		Path:    route,
+		In:      models.ContractRoute,
+		Message: "is not documented",
+	}}
+}
+
+func (c *Checker) violations(e *endpoint, in string, status int, errs []string) []*models.ContractViolation {
+	violations := make([]*models.ContractViolation, 0, len(errs))
+	for _, err := range errs {
+		violations = append(violations, &models.ContractViolation{
+			Operation: e.id,
+			Method:    e.method,
+			Path:      e.path,
+			In:        in,
+			Status:    status,
+			Message:   err,
+		})
+	}
+
+	return violations
+}
+
+func decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v interface{}
+	err := decoder.Decode(&v)
+	return v, err
+}
+
+// CheckRequest checks the parameters and the body of a request to a
+// documented route. params holds the path parameters by name.
+func (c *Checker) CheckRequest(method, route string, params map[string]string, query url.Values, body []byte) []*models.ContractViolation {
+	e, ok := c.endpoints[method+" "+route]
+	if !ok {
+		return nil
+	}
+
+	check := &checker{doc: c.doc, request: true}
+	for _, p := range e.parameters {
+		switch p.In {
+		case "path":
+			check.parameter(p, []string{params[p.Name]})
+		case "query":
+			check.parameter(p, query[p.Name])
+		}
+	}
+
+	if e.body != nil {
+		if len(bytes.TrimSpace(body)) == 0 {
+			if e.body.Required {
+				check.fail("body", "is required")
+			}
+		} else if v, err := decode(body); err != nil {
+			check.fail("body", "is not JSON: %s", err)
+		} else if e.body.Schema != nil {
+			check.value(e.body.Schema, v, "body")
+		}
+	}
+
+	return c.violations(e, models.ContractRequest, 0, check.errs)
+}
+
+// response returns what the spec documents for the status, failing the
+// check if it documents nothing.
+func (e *endpoint) response(check *checker, status int) *response {
+	r, ok := e.responses[strconv.Itoa(status)]
+	if !ok {
+		r, ok = e.responses["default"]
+	}
+	if !ok {
+		documented := make([]string, 0, len(e.responses))
+		for code := range e.responses {
+			documented = append(documented, code)
+		}
+		sort.Strings(documented)
+		check.fail("status", "%d is not documented, expected one of %v", status, documented)
+	}
+
+	return r
+}
+
+// CheckResponse checks that the status is documented for the route and that
+// the body matches the schema given for it.
+func (c *Checker) CheckResponse(method, route string, status int, body []byte) []*models.ContractViolation {
+	e, ok := c.endpoints[method+" "+route]
+	if !ok {
+		return nil
+	}
+
+	check := &checker{doc: c.doc}
+	if r := e.response(check, status); r != nil && r.Schema != nil {
+		if v, err := decode(body); err != nil {
+			check.fail("body", "is not JSON: %s", err)
+		} else {
+			check.value(r.Schema, v, "body")
+		}
+	}
+
+	return c.violations(e, models.ContractResponse, status, check.errs)
+}
+
+// CheckStatus checks only that the status is documented for the route, for
+// answers that are streamed.
+func (c *Checker) CheckStatus(method, route string, status int) []*models.ContractViolation {
+	e, ok := c.endpoints[method+" "+route]
+	if !ok {
+		return nil
+	}
+
+	check := &checker{doc: c.doc}
+	e.response(check, status)
+
+	return c.violations(e, models.ContractResponse, status, check.errs)
+}
+
+// Report counts the violations and hands them to the reporters.
+func (c *Checker) Report(violations []*models.ContractViolation) {
+	for _, violation := range violations {
+		violationsTotal.WithLabelValues(violation.Operation, violation.In).Inc()
+		for _, report := range c.reporters {
+			report(violation)
+		}
+	}
+}
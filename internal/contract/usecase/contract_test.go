@@ -0,0 +1,153 @@
+package contractUsecase_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"technopark-dbms-forum/api"
+	contractUsecase "technopark-dbms-forum/internal/contract/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+func messages(violations []*models.ContractViolation) string {
+	parts := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		parts = append(parts, violation.Message)
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+func newChecker(t *testing.T) *contractUsecase.Checker {
+	t.Helper()
+
+	checker, err := contractUsecase.NewChecker(api.Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return checker
+}
+
+func TestDocumented(t *testing.T) {
+	checker := newChecker(t)
+
+	if !checker.Documented("GET", "/api/thread/:slug_or_id/posts") {
+		t.Error("thread posts are documented")
+	}
+	if !checker.Documented("GET", "/api/admin/stats") {
+		t.Error("admin stats are documented")
+	}
+	if checker.Documented("DELETE", "/api/forum/:slug/details") {
+		t.Error("forum deletion is not documented")
+	}
+
+	if got := messages(checker.CheckRoute("POST", "/api/service/clear")); got != "is not documented" {
+		t.Errorf("service clear: got %q", got)
+	}
+	if got := checker.CheckRoute("GET", "/api/thread/:slug_or_id/posts"); len(got) != 0 {
+		t.Errorf("thread posts: got %q", messages(got))
+	}
+}
+
+func TestCheckRequest(t *testing.T) {
+	checker := newChecker(t)
+
+	cases := []struct {
+		name   string
+		method string
+		route  string
+		params map[string]string
+		query  string
+		body   string
+		want   string
+	}{
+		{"valid posts", "POST", "/api/thread/:slug_or_id/create", map[string]string{"slug_or_id": "42"}, "", `[{"author": "a", "message": "m", "parent": 1}]`, ""},
+		{"missing author", "POST", "/api/thread/:slug_or_id/create", map[string]string{"slug_or_id": "42"}, "", `[{"message": "m"}]`, "body[0].author: is required"},
+		{"read-only slug may be sent", "POST", "/api/forum/:slug/create", map[string]string{"slug": "f"}, "", `{"title": "t", "author": "a", "message": "m", "slug": "s"}`, ""},
+		{"null title", "POST", "/api/thread/:slug_or_id/details", map[string]string{"slug_or_id": "42"}, "", `{"title": null}`, "body.title: must not be null"},
+		{"bad voice", "POST", "/api/thread/:slug_or_id/vote", map[string]string{"slug_or_id": "42"}, "", `{"nickname": "a", "voice": 2}`, "body.voice: 2 is not one of [-1 1]"},
+		{"fractional parent", "POST", "/api/thread/:slug_or_id/create", map[string]string{"slug_or_id": "42"}, "", `[{"author": "a", "message": "m", "parent": 1.5}]`, "body[0].parent: 1.5 is not an integer"},
+		{"missing body", "POST", "/api/forum/create", nil, "", "", "body: is required"},
+		{"broken body", "POST", "/api/forum/create", nil, "", `{"title":`, "body: is not JSON"},
+		{"valid query", "GET", "/api/thread/:slug_or_id/posts", map[string]string{"slug_or_id": "42"}, "limit=10&since=3&sort=tree&desc=true", "", ""},
+		{"bad sort", "GET", "/api/thread/:slug_or_id/posts", map[string]string{"slug_or_id": "42"}, "sort=random", "", `query.sort: "random" is not one of [flat tree parent_tree]`},
+		{"bad limit", "GET", "/api/thread/:slug_or_id/posts", map[string]string{"slug_or_id": "42"}, "limit=ten", "", `query.limit: "ten" is not a number`},
+		{"bad since", "GET", "/api/forum/:slug/threads", map[string]string{"slug": "f"}, "since=yesterday", "", `query.since: "yesterday" is not a date-time`},
+		{"bad related", "GET", "/api/post/:id/details", map[string]string{"id": "1"}, "related=user,author", "", `query.related[1]: "author" is not one of [user forum thread]`},
+		{"bad limit action", "POST", "/api/forum/:slug/limits", map[string]string{"slug": "f"}, "", `{"action": "sail", "perMinute": 60, "burst": 10}`, `body.action: "sail" is not one of [post thread vote]`},
+		{"follow without body", "POST", "/api/forum/:slug/follow", map[string]string{"slug": "f"}, "", "", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			query, err := url.ParseQuery(c.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := messages(checker.CheckRequest(c.method, c.route, c.params, query, []byte(c.body)))
+			if c.want == "" && got != "" || !strings.HasPrefix(got, c.want) {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	checker := newChecker(t)
+
+	thread := `{"id": 1, "author": "a", "forum": "f", "message": "m", "title": "t", "votes": 0, "created": "2017-01-01T00:00:00.000Z"}`
+	cases := []struct {
+		name   string
+		method string
+		route  string
+		status int
+		body   string
+		want   string
+	}{
+		{"thread", "GET", "/api/thread/:slug_or_id/details", 200, thread, ""},
+		{"moved thread", "GET", "/api/thread/:slug_or_id/details", 301, thread, ""},
+		{"not modified", "GET", "/api/thread/:slug_or_id/details", 304, "", ""},
+		{"empty slug", "GET", "/api/thread/:slug_or_id/details", 200, strings.Replace(thread, `"id"`, `"slug": "", "id"`, 1), `body.slug: "" does not match`},
+		{"bad created", "GET", "/api/thread/:slug_or_id/details", 200, strings.Replace(thread, "2017-01-01T00:00:00.000Z", "yesterday", 1), `body.created: "yesterday" is not a date-time`},
+		{"error", "GET", "/api/thread/:slug_or_id/details", 404, `{"message": "Can't find thread"}`, ""},
+		{"server error", "GET", "/api/thread/:slug_or_id/details", 500, `{"message": "boom"}`, "status: 500 is not documented"},
+		{"conflicting users", "POST", "/api/user/:nickname/create", 409, `[{"nickname": "a", "fullname": "A", "email": "a@a", "about": ""}]`, ""},
+		{"conflicting user as object", "POST", "/api/user/:nickname/create", 409, `{"nickname": "a", "fullname": "A", "email": "a@a", "about": ""}`, "body: must be an array"},
+		{"missing read-only field", "POST", "/api/forum/create", 201, `{"title": "t", "user": "a"}`, "body.slug: is required"},
+		{"status", "GET", "/api/service/status", 200, `{"user": 1, "forum": 1, "thread": 1, "post": 1}`, ""},
+		{"unban", "DELETE", "/api/forum/:slug/bans/:nickname", 200, `"OK"`, ""},
+		{"queue without reasons", "GET", "/api/forum/:slug/queue", 200, `[{"id": 1, "forum": "f", "thread": 1, "author": "a", "reasons": null, "created": "2017-01-01T00:00:00Z"}]`, ""},
+		{"graphql errors", "POST", "/api/graphql", 200, `{"data": null, "errors": [{"message": "bad"}]}`, ""},
+		{"admin rejection", "GET", "/api/admin/stats", 403, `{"message": "Site admin role required"}`, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := messages(checker.CheckResponse(c.method, c.route, c.status, []byte(c.body)))
+			if c.want == "" && got != "" || !strings.HasPrefix(got, c.want) {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCheckStatus(t *testing.T) {
+	checker := newChecker(t)
+
+	if !checker.Streams("GET", "/api/thread/:slug_or_id/stream") {
+		t.Error("thread stream streams")
+	}
+	if checker.Streams("GET", "/api/thread/:slug_or_id/posts") {
+		t.Error("thread posts don't stream")
+	}
+
+	if got := checker.CheckStatus("GET", "/api/attachment/:id", 206); len(got) != 0 {
+		t.Errorf("partial attachment: got %q", messages(got))
+	}
+	if got := messages(checker.CheckStatus("GET", "/api/forum/:slug/export", 500)); !strings.HasPrefix(got, "status: 500 is not documented") {
+		t.Errorf("failed export: got %q", got)
+	}
+}
@@ -0,0 +1,38 @@
+package counterDelivery
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	counterUsecase "technopark-dbms-forum/internal/counters/usecase"
+)
+
+type Handler struct {
+	counterUsecase *counterUsecase.CounterUsecase
+}
+
+func NewHandler(counterUsecase *counterUsecase.CounterUsecase) *Handler {
+	return &Handler{
+		counterUsecase: counterUsecase,
+	}
+}
+
+// GetDrift reports the drift without fixing it.
+func (h *Handler) GetDrift(c echo.Context) error {
+	report, err := h.counterUsecase.Reconcile(false)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+func (h *Handler) Reconcile(c echo.Context) error {
+	report, err := h.counterUsecase.Reconcile(true)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
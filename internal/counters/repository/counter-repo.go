@@ -0,0 +1,324 @@
+package counterRepository
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+// reconcileLock is the advisory lock key taken by runs that fix counters.
+const reconcileLock int64 = 0x636f756e74657273
+
+type Postgres struct {
+	sqlx *sqlx.DB
+}
+
+func NewPostgres(url string) (*Postgres, error) {
+	newSQLX, err := sqlx.Connect("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = newSQLX.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &Postgres{sqlx: newSQLX}, nil
+}
+
+func (p *Postgres) Close() error {
+	return p.sqlx.Close()
+}
+
+// Lock waits for and takes the advisory lock that serializes fixing runs of
+// every process on the database: the scheduled run, the recount command and
+// other instances. A fix adds the drift a run has read, so two runs applying
+// the same drift would skew the counter the other way. The lock is held by a
+// connection of its own; when unlock can't release the lock it drops the
+// connection, which releases it too.
+func (p *Postgres) Lock() (unlock func(), err error) {
+	ctx := context.Background()
+
+	conn, err := p.sqlx.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", reconcileLock); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", reconcileLock); err != nil {
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}, nil
+}
+
+// GetForumCounts checks the next batch of forums in slug order, starting
+// after the given slug. It only reads, so a batch never blocks writers.
+func (p *Postgres) GetForumCounts(after string, limit int) ([]*models.ForumCounts, error) {
+	counts := make([]*models.ForumCounts, 0, limit)
+	err := p.sqlx.Select(
+		&counts,
+		`
+			WITH batch AS (
+			    SELECT slug, posts, threads
+			    FROM forums
+			    WHERE slug > $1::citext
+			    ORDER BY slug
+			    LIMIT $2
+			), members AS (
+			    SELECT author_nickname AS nickname, forum AS forum_slug
+			    FROM threads
+			    WHERE forum IN (SELECT slug FROM batch)
+			    UNION
+			    SELECT author_nickname, forum_slug
+			    FROM posts
+			    WHERE forum_slug IN (SELECT slug FROM batch)
+			)
+			SELECT b.slug,
+			       b.posts,
+			       b.threads,
+			       (SELECT COUNT(*) FROM posts WHERE forum_slug = b.slug)     AS actual_posts,
+			       (SELECT COUNT(*) FROM threads WHERE forum = b.slug)        AS actual_threads,
+			       (SELECT COUNT(*) FROM user_forum WHERE forum_slug = b.slug) AS members,
+			       (SELECT COUNT(*)
+			        FROM members m
+			        WHERE m.forum_slug = b.slug
+			          AND NOT EXISTS (SELECT 1 FROM user_forum uf WHERE uf.forum_slug = m.forum_slug AND uf.nickname = m.nickname)
+			       ) AS missing_members,
+			       (SELECT COUNT(*)
+			        FROM user_forum uf
+			        WHERE uf.forum_slug = b.slug
+			          AND NOT EXISTS (SELECT 1 FROM members m WHERE m.forum_slug = uf.forum_slug AND m.nickname = uf.nickname)
+			       ) AS stale_members
+			FROM batch b
+			ORDER BY b.slug
+		`,
+		after,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// FixForums applies the drift found by GetForumCounts. The counts come from
+// one statement, so actual minus stored is the exact error at that snapshot;
+// the triggers bump both sides for every later insert, so adding it to the
+// current value stays correct without recounting, provided the caller holds
+// Lock from the read to the fix. Missing members are
+// inserted without a lock. Stale members are found outside the lock too and
+// only rechecked and deleted under it: a new post or thread updates the
+// forum row, so none can commit between the recheck and the delete.
+func (p *Postgres) FixForums(counts []*models.ForumCounts) (*models.ForumFix, error) {
+	result := models.ForumFix{}
+
+	locked := make([]string, 0, len(counts))
+	missing := make([]string, 0)
+	stale := make([]string, 0)
+	slugs := make([]string, 0, len(counts))
+	posts := make([]int64, 0, len(counts))
+	threads := make([]int64, 0, len(counts))
+	for _, forum := range counts {
+		if forum.MissingMembers > 0 {
+			missing = append(missing, forum.Slug)
+		}
+		if forum.StaleMembers > 0 {
+			stale = append(stale, forum.Slug)
+		}
+
+		postsDelta := forum.ActualPosts - forum.Posts
+		threadsDelta := forum.ActualThreads - forum.Threads
+		if postsDelta != 0 {
+			result.Posts++
+		}
+		if threadsDelta != 0 {
+			result.Threads++
+		}
+		if postsDelta != 0 || threadsDelta != 0 {
+			slugs = append(slugs, forum.Slug)
+			posts = append(posts, postsDelta)
+			threads = append(threads, threadsDelta)
+		}
+		if postsDelta != 0 || threadsDelta != 0 || forum.StaleMembers > 0 {
+			locked = append(locked, forum.Slug)
+		}
+	}
+
+	if len(missing) > 0 {
+		res, err := p.sqlx.Exec(
+			`
+				INSERT INTO user_forum (nickname, forum_slug)
+				SELECT author_nickname, forum FROM threads WHERE forum = ANY($1::citext[])
+				UNION
+				SELECT author_nickname, forum_slug FROM posts WHERE forum_slug = ANY($1::citext[])
+				ON CONFLICT DO NOTHING
+			`,
+			pq.Array(missing),
+		)
+		if err != nil {
+			return nil, err
+		}
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		result.Members += inserted
+	}
+
+	staleMembers := make([]models.ForumMember, 0)
+	if len(stale) > 0 {
+		err := p.sqlx.Select(
+			&staleMembers,
+			`
+				SELECT uf.nickname, uf.forum_slug
+				FROM user_forum uf
+				WHERE uf.forum_slug = ANY($1::citext[])
+				  AND NOT EXISTS (SELECT 1 FROM threads WHERE forum = uf.forum_slug AND author_nickname = uf.nickname)
+				  AND NOT EXISTS (SELECT 1 FROM posts WHERE forum_slug = uf.forum_slug AND author_nickname = uf.nickname)
+			`,
+			pq.Array(stale),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(locked) == 0 {
+		return &result, nil
+	}
+
+	tx, err := p.sqlx.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(
+		"SELECT slug FROM forums WHERE slug = ANY($1::citext[]) ORDER BY slug FOR UPDATE",
+		pq.Array(locked),
+	); err != nil {
+		return nil, err
+	}
+
+	if len(staleMembers) > 0 {
+		nicknames := make([]string, 0, len(staleMembers))
+		forums := make([]string, 0, len(staleMembers))
+		for _, member := range staleMembers {
+			nicknames = append(nicknames, member.Nickname)
+			forums = append(forums, member.Forum)
+		}
+
+		res, err := tx.Exec(
+			`
+				DELETE FROM user_forum uf
+				USING unnest($1::citext[], $2::citext[]) AS m(nickname, forum_slug)
+				WHERE uf.nickname = m.nickname AND uf.forum_slug = m.forum_slug
+				  AND NOT EXISTS (SELECT 1 FROM threads WHERE forum = uf.forum_slug AND author_nickname = uf.nickname)
+				  AND NOT EXISTS (SELECT 1 FROM posts WHERE forum_slug = uf.forum_slug AND author_nickname = uf.nickname)
+			`,
+			pq.Array(nicknames),
+			pq.Array(forums),
+		)
+		if err != nil {
+			return nil, err
+		}
+		deleted, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		result.Members += deleted
+	}
+
+	if len(slugs) > 0 {
+		if _, err = tx.Exec(
+			`
+				UPDATE forums f
+				SET posts = f.posts + d.posts, threads = f.threads + d.threads
+				FROM unnest($1::citext[], $2::bigint[], $3::bigint[]) AS d(slug, posts, threads)
+				WHERE f.slug = d.slug
+			`,
+			pq.Array(slugs),
+			pq.Array(posts),
+			pq.Array(threads),
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetThreadVotes checks the next batch of threads in id order.
+func (p *Postgres) GetThreadVotes(after uint64, limit int) ([]*models.ThreadVotes, error) {
+	votes := make([]*models.ThreadVotes, 0, limit)
+	err := p.sqlx.Select(
+		&votes,
+		`
+			SELECT t.id, COALESCE(t.votes, 0) AS votes, COALESCE(SUM(v.voice), 0) AS actual_votes
+			FROM (
+			    SELECT id, votes
+			    FROM threads
+			    WHERE id > $1
+			    ORDER BY id
+			    LIMIT $2
+			) t
+			LEFT JOIN votes v ON v.thread_id = t.id
+			GROUP BY t.id, t.votes
+			ORDER BY t.id
+		`,
+		after,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return votes, nil
+}
+
+// FixThreadVotes applies the drift found by GetThreadVotes the same way
+// FixForums does. It is a single statement, so each thread row is only
+// locked while its own update runs.
+func (p *Postgres) FixThreadVotes(votes []*models.ThreadVotes) (int64, error) {
+	ids := make([]int64, 0, len(votes))
+	deltas := make([]int64, 0, len(votes))
+	for _, thread := range votes {
+		if delta := thread.ActualVotes - thread.Votes; delta != 0 {
+			ids = append(ids, int64(thread.ID))
+			deltas = append(deltas, delta)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	res, err := p.sqlx.Exec(
+		`
+			UPDATE threads t
+			SET votes = COALESCE(t.votes, 0) + d.delta
+			FROM unnest($1::bigint[], $2::bigint[]) AS d(id, delta)
+			WHERE t.id = d.id
+		`,
+		pq.Array(ids),
+		pq.Array(deltas),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,47 @@
+package counterUsecase
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+// The reconciler's metrics go to the default registry, which the metrics
+// listener serves next to the echo request metrics.
+var (
+	driftedRows = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "forum_counter_drifted_rows",
+		Help: "Rows whose counter disagreed with its source rows in the last reconciliation.",
+	}, []string{"counter"})
+
+	driftTotal = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "forum_counter_drift",
+		Help: "Sum of the absolute counter differences found in the last reconciliation.",
+	}, []string{"counter"})
+
+	fixedRows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "forum_counter_fixed_rows_total",
+		Help: "Rows rewritten by the reconciler.",
+	}, []string{"counter"})
+
+	lastRun = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "forum_counter_reconcile_last_run_timestamp_seconds",
+		Help: "Time the last reconciliation finished.",
+	})
+
+	runDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "forum_counter_reconcile_duration_seconds",
+		Help: "Duration of the last reconciliation.",
+	})
+)
+
+func observe(report *models.CounterReport) {
+	for _, summary := range report.Counters {
+		driftedRows.WithLabelValues(summary.Counter).Set(float64(summary.Drifted))
+		driftTotal.WithLabelValues(summary.Counter).Set(float64(summary.Drift))
+		fixedRows.WithLabelValues(summary.Counter).Add(float64(summary.Fixed))
+	}
+	lastRun.Set(float64(report.Finished.Unix()))
+	runDuration.Set(report.Finished.Sub(report.Started).Seconds())
+}
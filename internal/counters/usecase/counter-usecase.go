@@ -0,0 +1,229 @@
+package counterUsecase
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	counterRepository "technopark-dbms-forum/internal/counters/repository"
+	"technopark-dbms-forum/pkg/models"
+)
+
+// MaxReportedDrift caps the drifted values listed in a report.
+const MaxReportedDrift = 1000
+
+// CounterUsecase reconciles the trigger-maintained counters with the rows
+// they count. Each batch is read without locks; only batches that drifted
+// are rewritten, each in its own short transaction.
+type CounterUsecase struct {
+	r         *counterRepository.Postgres
+	batchSize int
+
+	mu sync.Mutex
+}
+
+func NewCounterUsecase(repo *counterRepository.Postgres, batchSize int) *CounterUsecase {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	return &CounterUsecase{
+		r:         repo,
+		batchSize: batchSize,
+	}
+}
+
+// Tally adds up the values one reconciliation run checks into its report.
+type Tally struct {
+	report    *models.CounterReport
+	summaries map[string]*models.CounterSummary
+}
+
+func NewTally(fix bool) *Tally {
+	t := &Tally{
+		report:    &models.CounterReport{Started: time.Now(), Fix: fix, Drift: make([]models.CounterDrift, 0)},
+		summaries: make(map[string]*models.CounterSummary),
+	}
+	for _, counter := range []string{
+		models.CounterForumPosts,
+		models.CounterForumThreads,
+		models.CounterThreadVotes,
+		models.CounterUserForum,
+	} {
+		summary := &models.CounterSummary{Counter: counter}
+		t.summaries[counter] = summary
+		t.report.Counters = append(t.report.Counters, summary)
+	}
+
+	return t
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+// Check records a value and reports whether it drifted. A user_forum value
+// drifts by the rows missing and stale, which may cancel out in the count.
+func (t *Tally) Check(drift models.CounterDrift) bool {
+	summary := t.summaries[drift.Counter]
+	summary.Checked++
+
+	diff := abs(drift.Stored - drift.Actual)
+	if drift.Counter == models.CounterUserForum {
+		diff = drift.Missing + drift.Stale
+	}
+	if diff == 0 {
+		return false
+	}
+
+	summary.Drifted++
+	summary.Drift += diff
+	if len(t.report.Drift) < MaxReportedDrift {
+		t.report.Drift = append(t.report.Drift, drift)
+	} else {
+		t.report.Truncated = true
+	}
+
+	return true
+}
+
+// Fixed records values of the counter rewritten by a fix.
+func (t *Tally) Fixed(counter string, n int64) {
+	t.summaries[counter].Fixed += n
+}
+
+func (t *Tally) Report() *models.CounterReport {
+	return t.report
+}
+
+// ForumDrift returns the values of a forum's counters to check.
+func ForumDrift(forum *models.ForumCounts) []models.CounterDrift {
+	return []models.CounterDrift{{
+		Counter: models.CounterForumPosts,
+		Key:     forum.Slug,
+		Stored:  forum.Posts,
+		Actual:  forum.ActualPosts,
+	}, {
+		Counter: models.CounterForumThreads,
+		Key:     forum.Slug,
+		Stored:  forum.Threads,
+		Actual:  forum.ActualThreads,
+	}, {
+		Counter: models.CounterUserForum,
+		Key:     forum.Slug,
+		Stored:  forum.Members,
+		Actual:  forum.Members + forum.MissingMembers - forum.StaleMembers,
+		Missing: forum.MissingMembers,
+		Stale:   forum.StaleMembers,
+	}}
+}
+
+func ThreadDrift(thread *models.ThreadVotes) models.CounterDrift {
+	return models.CounterDrift{
+		Counter: models.CounterThreadVotes,
+		Key:     strconv.FormatUint(thread.ID, 10),
+		Stored:  thread.Votes,
+		Actual:  thread.ActualVotes,
+	}
+}
+
+// Reconcile walks every forum and thread in batches and reports the drift it
+// finds; with fix set it also rewrites the drifted batches. Runs are
+// serialized, fixing runs across processes too, and the result is exported
+// as metrics.
+func (u *CounterUsecase) Reconcile(fix bool) (*models.CounterReport, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if fix {
+		unlock, err := u.r.Lock()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	t := NewTally(fix)
+
+	if err := u.reconcileForums(t); err != nil {
+		return nil, err
+	}
+	if err := u.reconcileThreads(t); err != nil {
+		return nil, err
+	}
+
+	report := t.Report()
+	report.Finished = time.Now()
+	observe(report)
+
+	return report, nil
+}
+
+func (u *CounterUsecase) reconcileForums(t *Tally) error {
+	for after := ""; ; {
+		batch, err := u.r.GetForumCounts(after, u.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		drifted := make([]*models.ForumCounts, 0)
+		for _, forum := range batch {
+			forumDrifted := false
+			for _, drift := range ForumDrift(forum) {
+				// Every counter is checked, so none may short-circuit.
+				forumDrifted = t.Check(drift) || forumDrifted
+			}
+			if forumDrifted {
+				drifted = append(drifted, forum)
+			}
+		}
+
+		if t.report.Fix && len(drifted) > 0 {
+			fixed, err := u.r.FixForums(drifted)
+			if err != nil {
+				return err
+			}
+			t.Fixed(models.CounterForumPosts, fixed.Posts)
+			t.Fixed(models.CounterForumThreads, fixed.Threads)
+			t.Fixed(models.CounterUserForum, fixed.Members)
+		}
+
+		after = batch[len(batch)-1].Slug
+	}
+}
+
+func (u *CounterUsecase) reconcileThreads(t *Tally) error {
+	for after := uint64(0); ; {
+		batch, err := u.r.GetThreadVotes(after, u.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		drifted := make([]*models.ThreadVotes, 0)
+		for _, thread := range batch {
+			if t.Check(ThreadDrift(thread)) {
+				drifted = append(drifted, thread)
+			}
+		}
+
+		if t.report.Fix && len(drifted) > 0 {
+			fixed, err := u.r.FixThreadVotes(drifted)
+			if err != nil {
+				return err
+			}
+			t.Fixed(models.CounterThreadVotes, fixed)
+		}
+
+		after = batch[len(batch)-1].ID
+	}
+}
@@ -0,0 +1,112 @@
+package counterUsecase_test
+
+import (
+	"testing"
+
+	counterUsecase "technopark-dbms-forum/internal/counters/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+func summaries(report *models.CounterReport) map[string]models.CounterSummary {
+	byCounter := make(map[string]models.CounterSummary, len(report.Counters))
+	for _, summary := range report.Counters {
+		byCounter[summary.Counter] = *summary
+	}
+
+	return byCounter
+}
+
+func TestForumDrift(t *testing.T) {
+	drift := counterUsecase.ForumDrift(&models.ForumCounts{
+		Slug:           "pirates",
+		Posts:          10,
+		Threads:        3,
+		ActualPosts:    12,
+		ActualThreads:  3,
+		Members:        5,
+		MissingMembers: 2,
+		StaleMembers:   1,
+	})
+
+	want := []models.CounterDrift{
+		{Counter: models.CounterForumPosts, Key: "pirates", Stored: 10, Actual: 12},
+		{Counter: models.CounterForumThreads, Key: "pirates", Stored: 3, Actual: 3},
+		{Counter: models.CounterUserForum, Key: "pirates", Stored: 5, Actual: 6, Missing: 2, Stale: 1},
+	}
+	if len(drift) != len(want) {
+		t.Fatalf("got %d values, want %d", len(drift), len(want))
+	}
+	for i := range want {
+		if drift[i] != want[i] {
+			t.Errorf("got %+v, want %+v", drift[i], want[i])
+		}
+	}
+
+	thread := counterUsecase.ThreadDrift(&models.ThreadVotes{ID: 42, Votes: -1, ActualVotes: 2})
+	if want := (models.CounterDrift{Counter: models.CounterThreadVotes, Key: "42", Stored: -1, Actual: 2}); thread != want {
+		t.Errorf("got %+v, want %+v", thread, want)
+	}
+}
+
+func TestTally(t *testing.T) {
+	tally := counterUsecase.NewTally(true)
+
+	cases := []struct {
+		name    string
+		drift   models.CounterDrift
+		drifted bool
+	}{
+		{"posts in step", models.CounterDrift{Counter: models.CounterForumPosts, Stored: 4, Actual: 4}, false},
+		{"posts over", models.CounterDrift{Counter: models.CounterForumPosts, Stored: 7, Actual: 4}, true},
+		{"posts under", models.CounterDrift{Counter: models.CounterForumPosts, Stored: 1, Actual: 3}, true},
+		{"votes below zero", models.CounterDrift{Counter: models.CounterThreadVotes, Stored: -2, Actual: 1}, true},
+		{"members in step", models.CounterDrift{Counter: models.CounterUserForum, Stored: 5, Actual: 5}, false},
+		{"members cancel out", models.CounterDrift{Counter: models.CounterUserForum, Stored: 5, Actual: 5, Missing: 1, Stale: 1}, true},
+	}
+	for _, c := range cases {
+		if got := tally.Check(c.drift); got != c.drifted {
+			t.Errorf("%s: got drifted %v, want %v", c.name, got, c.drifted)
+		}
+	}
+	tally.Fixed(models.CounterForumPosts, 2)
+
+	report := tally.Report()
+	if !report.Fix {
+		t.Error("report is of a fixing run")
+	}
+	if len(report.Drift) != 4 || report.Truncated {
+		t.Errorf("got %d drifted values, truncated %v; want 4, not truncated", len(report.Drift), report.Truncated)
+	}
+
+	want := map[string]models.CounterSummary{
+		models.CounterForumPosts:   {Counter: models.CounterForumPosts, Checked: 3, Drifted: 2, Drift: 5, Fixed: 2},
+		models.CounterForumThreads: {Counter: models.CounterForumThreads},
+		models.CounterThreadVotes:  {Counter: models.CounterThreadVotes, Checked: 1, Drifted: 1, Drift: 3},
+		models.CounterUserForum:    {Counter: models.CounterUserForum, Checked: 2, Drifted: 1, Drift: 2},
+	}
+	got := summaries(report)
+	if len(got) != len(want) {
+		t.Errorf("got %d summaries, want %d", len(got), len(want))
+	}
+	for counter, summary := range want {
+		if got[counter] != summary {
+			t.Errorf("%s: got %+v, want %+v", counter, got[counter], summary)
+		}
+	}
+}
+
+func TestTallyTruncates(t *testing.T) {
+	tally := counterUsecase.NewTally(false)
+
+	for i := 0; i <= counterUsecase.MaxReportedDrift; i++ {
+		tally.Check(models.CounterDrift{Counter: models.CounterThreadVotes, Stored: 1})
+	}
+
+	report := tally.Report()
+	if len(report.Drift) != counterUsecase.MaxReportedDrift || !report.Truncated {
+		t.Errorf("got %d drifted values, truncated %v", len(report.Drift), report.Truncated)
+	}
+	if got := summaries(report)[models.CounterThreadVotes]; got.Drifted != counterUsecase.MaxReportedDrift+1 {
+		t.Errorf("summary counts %d drifted values, want all %d", got.Drifted, counterUsecase.MaxReportedDrift+1)
+	}
+}
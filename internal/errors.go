@@ -1,6 +1,9 @@
 package internalErrors
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrAlreadyExist                  = errors.New("already exists")
@@ -15,4 +18,49 @@ var (
 	ErrSlugAlreadyExist              = errors.New("slug already exists")
 	ErrWrongForumSlug                = errors.New("wrong forum slug")
 	ErrNoParentPost                  = errors.New("no parent post")
+	ErrThreadSlugMoved               = errors.New("thread slug moved")
+	ErrNullField                     = errors.New("field can't be null")
+	ErrPreconditionFailed            = errors.New("precondition failed")
+	ErrNotSubscribed                 = errors.New("not subscribed")
+	ErrWrongCredentials              = errors.New("wrong credentials")
+	ErrUnauthorized                  = errors.New("unauthorized")
+	ErrForbidden                     = errors.New("forbidden")
+	ErrThreadClosed                  = errors.New("thread is closed")
+	ErrNotModerator                  = errors.New("not a moderator")
+	ErrRateLimited                   = errors.New("rate limit exceeded")
+	ErrBanned                        = errors.New("user is banned")
+	ErrContentRejected               = errors.New("content rejected")
+	ErrAttachmentTooLarge            = errors.New("attachment is too large")
+	ErrAttachmentType                = errors.New("attachment type is not allowed")
+	ErrBadArchive                    = errors.New("malformed archive")
+	ErrImportTimeout                 = errors.New("import took too long")
+	ErrBadSlug                       = errors.New("malformed slug")
+	ErrWrongVoice                    = errors.New("voice must be -1 or 1")
 )
+
+// RetryError wraps an error the caller may retry after the given delay.
+type RetryError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// ContentError is returned when a content filter rejects a post or thread.
+type ContentError struct {
+	Reason string
+}
+
+func (e *ContentError) Error() string {
+	return ErrContentRejected.Error() + ": " + e.Reason
+}
+
+func (e *ContentError) Unwrap() error {
+	return ErrContentRejected
+}
@@ -0,0 +1,54 @@
+package eventDelivery
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	eventUsecase "technopark-dbms-forum/internal/events/usecase"
+)
+
+// maxLimit caps the events one request returns; clients page on with after.
+const maxLimit = 1000
+
+type Handler struct {
+	eventUsecase *eventUsecase.EventUsecase
+}
+
+func NewHandler(eventUsecase *eventUsecase.EventUsecase) *Handler {
+	return &Handler{
+		eventUsecase: eventUsecase,
+	}
+}
+
+func (h *Handler) GetAfter(c echo.Context) error {
+	var after uint64
+	var limit int64 = 100
+	var err error
+
+	if c.QueryParam("after") != "" {
+		after, err = strconv.ParseUint(c.QueryParam("after"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+	}
+
+	if c.QueryParam("limit") != "" {
+		limit, err = strconv.ParseInt(c.QueryParam("limit"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		} else if limit < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Limit must not be negative")
+		} else if limit > maxLimit {
+			limit = maxLimit
+		}
+	}
+
+	events, err := h.eventUsecase.GetAfter(after, limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, events)
+}
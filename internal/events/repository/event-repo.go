@@ -0,0 +1,215 @@
+package eventRepository
+
+import (
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+// sequenceLock is the advisory lock key held while events are given positions.
+const sequenceLock = 30030
+
+type Postgres struct {
+	sqlx *sqlx.DB
+}
+
+func NewPostgres(url string) (*Postgres, error) {
+	newSQLX, err := sqlx.Connect("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = newSQLX.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &Postgres{sqlx: newSQLX}, nil
+}
+
+func (p *Postgres) Close() error {
+	return p.sqlx.Close()
+}
+
+// Insert writes events into the outbox. It is meant to be called with the
+// transaction of the mutation the events describe.
+func Insert(tx sqlx.Execer, eventType string, payloads ...interface{}) error {
+	if len(payloads) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(payloads)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`
+			INSERT INTO events (type, payload)
+			SELECT $1, value
+			FROM jsonb_array_elements($2::jsonb) WITH ORDINALITY
+			ORDER BY ordinality
+		`,
+		eventType,
+		data,
+	)
+
+	return err
+}
+
+// Sequence gives feed positions to events of finished transactions, in id order.
+// Events of transactions still running stay unsequenced, so positions have no gaps
+// that a consumer could skip over.
+//
+// The cut-off is the oldest transaction still running anywhere in the database,
+// not just ones that wrote events: every event committed after it started waits
+// until it ends, so one long writer holds back the feed, the post streams and
+// notifications. Long writers must be bounded; an archive import gives up after
+// a few minutes. Read-only transactions, such as exports, hold no transaction id
+// and do not count.
+func (p *Postgres) Sequence(limit int) (int64, error) {
+	tx, err := p.sqlx.Beginx()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = tx.Exec("SELECT pg_advisory_xact_lock($1)", sequenceLock); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	res, err := tx.Exec(
+		`
+			WITH pending AS (
+			    SELECT id, row_number() OVER (ORDER BY id) AS rn
+			    FROM events
+			    WHERE position IS NULL AND tx_id < txid_snapshot_xmin(txid_current_snapshot())
+			    ORDER BY id
+			    LIMIT $1
+			), base AS (
+			    SELECT COALESCE(MAX(position), 0) AS position
+			    FROM events
+			)
+			UPDATE events e
+			SET position = base.position + pending.rn
+			FROM pending, base
+			WHERE e.id = pending.id
+		`,
+		limit,
+	)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// LastPosition returns the feed position of the last sequenced event.
+func (p *Postgres) LastPosition() (uint64, error) {
+	var position uint64
+	if err := p.sqlx.Get(&position, "SELECT COALESCE(MAX(position), 0) FROM events"); err != nil {
+		return 0, err
+	}
+
+	return position, nil
+}
+
+func (p *Postgres) GetAfter(after uint64, limit int64) ([]*models.Event, error) {
+	events := make([]*models.Event, 0)
+	err := p.sqlx.Select(
+		&events,
+		`
+			SELECT position, type, payload, created
+			FROM events
+			WHERE position > $1
+			ORDER BY position
+			LIMIT $2
+		`,
+		after,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Deliver hands the events after the sink's cursor to publish and moves the
+// cursor past them if publish succeeds. A sink locked by another instance is
+// skipped, so every sink has one active publisher.
+func (p *Postgres) Deliver(sink string, limit int64, publish func([]*models.Event) error) (int, error) {
+	tx, err := p.sqlx.Beginx()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = tx.Exec(
+		"INSERT INTO event_cursors (sink) VALUES ($1) ON CONFLICT DO NOTHING",
+		sink,
+	); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	var cursors []uint64
+	if err = tx.Select(
+		&cursors,
+		"SELECT position FROM event_cursors WHERE sink = $1 FOR UPDATE SKIP LOCKED",
+		sink,
+	); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if len(cursors) == 0 {
+		tx.Rollback()
+		return 0, nil
+	}
+
+	events := make([]*models.Event, 0)
+	if err = tx.Select(
+		&events,
+		`
+			SELECT position, type, payload, created
+			FROM events
+			WHERE position > $1
+			ORDER BY position
+			LIMIT $2
+		`,
+		cursors[0],
+		limit,
+	); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if len(events) == 0 {
+		tx.Rollback()
+		return 0, nil
+	}
+
+	if err = publish(events); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if _, err = tx.Exec(
+		"UPDATE event_cursors SET position = $1 WHERE sink = $2",
+		events[len(events)-1].ID,
+		sink,
+	); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(events), nil
+}
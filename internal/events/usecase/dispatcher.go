@@ -0,0 +1,69 @@
+package eventUsecase
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	eventRepository "technopark-dbms-forum/internal/events/repository"
+)
+
+const (
+	sequenceBatch = 1000
+	deliverBatch  = 100
+)
+
+// Dispatcher sequences outbox events and publishes them to every sink in order.
+type Dispatcher struct {
+	r      *eventRepository.Postgres
+	sinks  []Sink
+	every  time.Duration
+	logger echo.Logger
+}
+
+func NewDispatcher(repo *eventRepository.Postgres, every time.Duration, logger echo.Logger) *Dispatcher {
+	return &Dispatcher{
+		r:      repo,
+		every:  every,
+		logger: logger,
+	}
+}
+
+func (d *Dispatcher) AddSink(sink Sink) {
+	d.sinks = append(d.sinks, sink)
+}
+
+func (d *Dispatcher) Run() {
+	ticker := time.NewTicker(d.every)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		d.tick()
+	}
+}
+
+func (d *Dispatcher) tick() {
+	for {
+		sequenced, err := d.r.Sequence(sequenceBatch)
+		if err != nil {
+			d.logger.Error(err)
+			return
+		}
+		if sequenced < sequenceBatch {
+			break
+		}
+	}
+
+	for _, sink := range d.sinks {
+		for {
+			delivered, err := d.r.Deliver(sink.Name(), deliverBatch, sink.Publish)
+			if err != nil {
+				d.logger.Errorf("%s: %s", sink.Name(), err)
+				break
+			}
+			if delivered < deliverBatch {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package eventUsecase
+
+import (
+	eventRepository "technopark-dbms-forum/internal/events/repository"
+	"technopark-dbms-forum/pkg/models"
+)
+
+type EventUsecase struct {
+	r *eventRepository.Postgres
+}
+
+func NewEventUsecase(repo *eventRepository.Postgres) *EventUsecase {
+	return &EventUsecase{r: repo}
+}
+
+func (e *EventUsecase) GetAfter(after uint64, limit int64) ([]*models.Event, error) {
+	return e.r.GetAfter(after, limit)
+}
@@ -0,0 +1,95 @@
+package eventUsecase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+// Sink receives events from the outbox in feed order. Publish must either
+// accept the whole batch or return an error, in which case it is retried.
+type Sink interface {
+	Name() string
+	Publish(events []*models.Event) error
+}
+
+// FileSink appends events to a file as JSON lines.
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+func (s *FileSink) Name() string {
+	return "file:" + s.path
+}
+
+func (s *FileSink) Publish(events []*models.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buf := bytes.Buffer{}
+	encoder := json.NewEncoder(&buf)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// WebhookSink posts each batch of events to a URL as a JSON array.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *WebhookSink) Name() string {
+	return "webhook:" + s.url
+}
+
+func (s *WebhookSink) Publish(events []*models.Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook %s responded with status %d", s.url, res.StatusCode)
+	}
+
+	return nil
+}
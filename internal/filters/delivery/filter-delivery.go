@@ -0,0 +1,71 @@
+package filterDelivery
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	internalErrors "technopark-dbms-forum/internal"
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
+	filterUsecase "technopark-dbms-forum/internal/filters/usecase"
+)
+
+type Handler struct {
+	filterUsecase *filterUsecase.FilterUsecase
+}
+
+func NewHandler(filterUsecase *filterUsecase.FilterUsecase) *Handler {
+	return &Handler{
+		filterUsecase: filterUsecase,
+	}
+}
+
+func (h *Handler) GetQueue(c echo.Context) error {
+	slug := c.Param("slug")
+
+	limit, err := strconv.ParseInt(c.QueryParam("limit"), 10, 64)
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+	since, err := strconv.ParseUint(c.QueryParam("since"), 10, 64)
+	if err != nil {
+		since = 0
+	}
+
+	principal, _ := authDelivery.Principal(c)
+
+	items, err := h.filterUsecase.GetQueue(principal, slug, limit, since)
+	if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't moderate forum: %s", slug))
+	} else if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find forum with slug: %s", slug))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) Dismiss(c echo.Context) error {
+	slug := c.Param("slug")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Wrong queue item id: %s", c.Param("id")))
+	}
+
+	principal, _ := authDelivery.Principal(c)
+
+	err = h.filterUsecase.Dismiss(principal, slug, id)
+	if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't moderate forum: %s", slug))
+	} else if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find queue item with id: %d", id))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, "OK")
+}
@@ -0,0 +1,203 @@
+package filterRepository
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	internalErrors "technopark-dbms-forum/internal"
+	"technopark-dbms-forum/pkg/models"
+)
+
+type Postgres struct {
+	sqlx *sqlx.DB
+}
+
+func NewPostgres(url string) (*Postgres, error) {
+	newSQLX, err := sqlx.Connect("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = newSQLX.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &Postgres{sqlx: newSQLX}, nil
+}
+
+func (p *Postgres) Close() error {
+	return p.sqlx.Close()
+}
+
+// InsertForPosts queues the flagged posts that were just written in tx.
+func InsertForPosts(tx sqlx.Execer, posts []*models.Post) error {
+	for _, post := range posts {
+		if len(post.Flags) == 0 {
+			continue
+		}
+
+		if _, err := tx.Exec(
+			`
+				INSERT INTO moderation_queue (forum_slug, thread_id, post_id, author_nickname, reasons)
+				VALUES ($1, $2, $3, $4, $5)
+			`,
+			post.Forum,
+			post.Thread,
+			post.ID,
+			post.Author,
+			pq.Array(post.Flags),
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InsertForThread queues the thread that was just created in tx if it was flagged.
+func InsertForThread(tx sqlx.Execer, thread *models.ThreadResponse, flags []string) error {
+	if len(flags) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(
+		`
+			INSERT INTO moderation_queue (forum_slug, thread_id, author_nickname, reasons)
+			VALUES ($1, $2, $3, $4)
+		`,
+		thread.Forum,
+		thread.ID,
+		thread.Author,
+		pq.Array(flags),
+	)
+
+	return err
+}
+
+// HasFingerprint reports whether the author sent a message with the given
+// hash within the window.
+func (p *Postgres) HasFingerprint(author string, hash []byte, window time.Duration) (bool, error) {
+	var exists bool
+	err := p.sqlx.Get(
+		&exists,
+		`
+			SELECT EXISTS (
+			    SELECT 1
+			    FROM message_fingerprints
+			    WHERE author_nickname = $1 AND hash = $2 AND created >= now() - $3::DOUBLE PRECISION * INTERVAL '1 second'
+			)
+		`,
+		author,
+		hash,
+		window.Seconds(),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// InsertFingerprint remembers, in the transaction that wrote the message,
+// that the author sent it. A nil hash is skipped.
+func InsertFingerprint(tx sqlx.Execer, author string, hash []byte) error {
+	if hash == nil {
+		return nil
+	}
+
+	_, err := tx.Exec(
+		`
+			INSERT INTO message_fingerprints (author_nickname, hash, created)
+			VALUES ($1, $2, now())
+			ON CONFLICT (author_nickname, hash) DO UPDATE
+			SET created = now()
+		`,
+		author,
+		hash,
+	)
+
+	return err
+}
+
+// InsertFingerprints records the fingerprints of the posts written in tx.
+func InsertFingerprints(tx sqlx.Execer, posts []*models.Post) error {
+	for _, post := range posts {
+		if err := InsertFingerprint(tx, post.Author, post.Fingerprint); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Postgres) DeleteFingerprints(olderThan time.Duration) (int64, error) {
+	res, err := p.sqlx.Exec(
+		"DELETE FROM message_fingerprints WHERE created < now() - $1::DOUBLE PRECISION * INTERVAL '1 second'",
+		olderThan.Seconds(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (p *Postgres) GetQueue(forum string, limit int64, since uint64) ([]*models.ModerationItem, error) {
+	var exists bool
+	if err := p.sqlx.Get(&exists, "SELECT EXISTS (SELECT 1 FROM forums WHERE slug = $1)", forum); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, internalErrors.ErrNoRows
+	}
+
+	rows := make([]struct {
+		models.ModerationItem
+		Reasons pq.StringArray `db:"reasons"`
+	}, 0)
+	err := p.sqlx.Select(
+		&rows,
+		`
+			SELECT id, forum_slug, thread_id, COALESCE(post_id, 0) AS post_id, author_nickname, reasons, created
+			FROM moderation_queue
+			WHERE forum_slug = $1 AND id > $2
+			ORDER BY id
+			LIMIT $3
+		`,
+		forum,
+		since,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*models.ModerationItem, 0, len(rows))
+	for index := range rows {
+		item := rows[index].ModerationItem
+		item.Reasons = rows[index].Reasons
+		items = append(items, &item)
+	}
+
+	return items, nil
+}
+
+func (p *Postgres) DeleteQueueItem(forum string, id uint64) error {
+	res, err := p.sqlx.Exec(
+		"DELETE FROM moderation_queue WHERE forum_slug = $1 AND id = $2",
+		forum,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	if deleted, err := res.RowsAffected(); err != nil {
+		return err
+	} else if deleted == 0 {
+		return internalErrors.ErrNoRows
+	}
+
+	return nil
+}
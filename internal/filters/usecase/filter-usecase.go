@@ -0,0 +1,79 @@
+package filterUsecase
+
+import (
+	"time"
+
+	internalErrors "technopark-dbms-forum/internal"
+	filterRepository "technopark-dbms-forum/internal/filters/repository"
+	roleUsecase "technopark-dbms-forum/internal/roles/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+type FilterUsecase struct {
+	r       *filterRepository.Postgres
+	roles   *roleUsecase.RoleUsecase
+	filters []Filter
+}
+
+func NewFilterUsecase(repo *filterRepository.Postgres, roles *roleUsecase.RoleUsecase) *FilterUsecase {
+	return &FilterUsecase{
+		r:     repo,
+		roles: roles,
+	}
+}
+
+// AddFilter appends a filter to the chain. It must be called before the server starts.
+func (u *FilterUsecase) AddFilter(filter Filter) {
+	u.filters = append(u.filters, filter)
+}
+
+// Enabled reports whether any filter is registered.
+func (u *FilterUsecase) Enabled() bool {
+	return len(u.filters) != 0
+}
+
+// Apply runs the chain over the content, applying rewrites in place. It
+// returns the reasons the content was flagged for moderation, or a
+// *internalErrors.ContentError from the first filter that rejects it.
+func (u *FilterUsecase) Apply(content *models.Content) ([]string, error) {
+	var flags []string
+
+	for _, filter := range u.filters {
+		verdict, err := filter.Check(content)
+		if err != nil {
+			return nil, err
+		}
+		if verdict == nil {
+			continue
+		}
+
+		switch verdict.Action {
+		case models.FilterReject:
+			return nil, &internalErrors.ContentError{Reason: verdict.Reason}
+		case models.FilterFlag:
+			flags = append(flags, verdict.Reason)
+		}
+	}
+
+	return flags, nil
+}
+
+func (u *FilterUsecase) DeleteFingerprints(olderThan time.Duration) (int64, error) {
+	return u.r.DeleteFingerprints(olderThan)
+}
+
+func (u *FilterUsecase) GetQueue(actor, forum string, limit int64, since uint64) ([]*models.ModerationItem, error) {
+	if err := u.roles.CanModerate(actor, forum); err != nil {
+		return nil, err
+	}
+
+	return u.r.GetQueue(forum, limit, since)
+}
+
+func (u *FilterUsecase) Dismiss(actor, forum string, id uint64) error {
+	if err := u.roles.CanModerate(actor, forum); err != nil {
+		return err
+	}
+
+	return u.r.DeleteQueueItem(forum, id)
+}
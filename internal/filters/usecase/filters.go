@@ -0,0 +1,201 @@
+package filterUsecase
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	filterRepository "technopark-dbms-forum/internal/filters/repository"
+	"technopark-dbms-forum/pkg/models"
+)
+
+// Filter inspects a post or thread before it is stored. A filter that
+// rewrites changes content in place and returns a FilterRewrite verdict;
+// a nil verdict lets the content through untouched.
+type Filter interface {
+	Check(content *models.Content) (*models.FilterVerdict, error)
+}
+
+type BlocklistFilter struct {
+	pattern *regexp.Regexp
+	action  string
+}
+
+// NewBlocklistFilter matches the words case-insensitively as whole words, in
+// any script. Rewriting masks them with asterisks.
+func NewBlocklistFilter(words []string, action string) *BlocklistFilter {
+	// Longer words go first, so that a word is not cut short by another one
+	// it starts with.
+	sorted := append([]string(nil), words...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	quoted := make([]string, 0, len(sorted))
+	for _, word := range sorted {
+		quoted = append(quoted, regexp.QuoteMeta(word))
+	}
+
+	return &BlocklistFilter{
+		pattern: regexp.MustCompile(`(?i)(?:` + strings.Join(quoted, "|") + `)`),
+		action:  action,
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// find returns the spans of blocked words in s. RE2's \b only knows ASCII
+// letters, so word boundaries are checked here instead.
+func (f *BlocklistFilter) find(s string) [][]int {
+	var spans [][]int
+
+	for pos := 0; pos < len(s); {
+		loc := f.pattern.FindStringIndex(s[pos:])
+		if loc == nil {
+			break
+		}
+		start, end := pos+loc[0], pos+loc[1]
+
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if start < end && (start == 0 || !isWordRune(before)) && (end == len(s) || !isWordRune(after)) {
+			spans = append(spans, []int{start, end})
+			pos = end
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(s[start:])
+		pos = start + size
+	}
+
+	return spans
+}
+
+func (f *BlocklistFilter) mask(s string, spans [][]int) string {
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		b.WriteString(s[last:span[0]])
+		b.WriteString(strings.Repeat("*", utf8.RuneCountInString(s[span[0]:span[1]])))
+		last = span[1]
+	}
+	b.WriteString(s[last:])
+
+	return b.String()
+}
+
+func (f *BlocklistFilter) Check(content *models.Content) (*models.FilterVerdict, error) {
+	inTitle, inMessage := f.find(content.Title), f.find(content.Message)
+	if len(inTitle) == 0 && len(inMessage) == 0 {
+		return nil, nil
+	}
+
+	if f.action == models.FilterRewrite {
+		content.Title = f.mask(content.Title, inTitle)
+		content.Message = f.mask(content.Message, inMessage)
+	}
+
+	return &models.FilterVerdict{Action: f.action, Reason: "blocked word"}, nil
+}
+
+var linkRegexp = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+type LinkFilter struct {
+	max    int
+	action string
+}
+
+// NewLinkFilter matches messages with more than max links. Rewriting
+// removes the links past the limit.
+func NewLinkFilter(max int, action string) *LinkFilter {
+	return &LinkFilter{max: max, action: action}
+}
+
+func (f *LinkFilter) Check(content *models.Content) (*models.FilterVerdict, error) {
+	if len(linkRegexp.FindAllStringIndex(content.Message, f.max+1)) <= f.max {
+		return nil, nil
+	}
+
+	if f.action == models.FilterRewrite {
+		seen := 0
+		content.Message = linkRegexp.ReplaceAllStringFunc(content.Message, func(link string) string {
+			seen++
+			if seen > f.max {
+				return "[link removed]"
+			}
+			return link
+		})
+	}
+
+	return &models.FilterVerdict{Action: f.action, Reason: fmt.Sprintf("more than %d links", f.max)}, nil
+}
+
+type DuplicateFilter struct {
+	r      *filterRepository.Postgres
+	window time.Duration
+	action string
+}
+
+// NewDuplicateFilter matches a message the same author already sent within
+// the window, or earlier in the same batch. It can't rewrite, so a rewrite
+// action flags instead.
+func NewDuplicateFilter(repo *filterRepository.Postgres, window time.Duration, action string) *DuplicateFilter {
+	if action == models.FilterRewrite {
+		action = models.FilterFlag
+	}
+
+	return &DuplicateFilter{r: repo, window: window, action: action}
+}
+
+func (f *DuplicateFilter) Check(content *models.Content) (*models.FilterVerdict, error) {
+	hash := sha256.Sum256([]byte(strings.TrimSpace(content.Title) + "\n" + strings.TrimSpace(content.Message)))
+
+	// The fingerprint is recorded with the content, so a write that fails
+	// doesn't make its retry look like a duplicate.
+	content.Fingerprint = hash[:]
+
+	for _, previous := range content.Previous {
+		if bytes.Equal(previous, content.Fingerprint) {
+			return &models.FilterVerdict{Action: f.action, Reason: "duplicate message"}, nil
+		}
+	}
+
+	duplicate, err := f.r.HasFingerprint(content.Author, content.Fingerprint, f.window)
+	if err != nil {
+		return nil, err
+	}
+	if !duplicate {
+		return nil, nil
+	}
+
+	return &models.FilterVerdict{Action: f.action, Reason: "duplicate message"}, nil
+}
+
+type LengthFilter struct {
+	max    int
+	action string
+}
+
+// NewLengthFilter matches messages longer than max characters. Rewriting
+// truncates them.
+func NewLengthFilter(max int, action string) *LengthFilter {
+	return &LengthFilter{max: max, action: action}
+}
+
+func (f *LengthFilter) Check(content *models.Content) (*models.FilterVerdict, error) {
+	if utf8.RuneCountInString(content.Message) <= f.max {
+		return nil, nil
+	}
+
+	if f.action == models.FilterRewrite {
+		content.Message = string([]rune(content.Message)[:f.max])
+	}
+
+	return &models.FilterVerdict{Action: f.action, Reason: fmt.Sprintf("longer than %d characters", f.max)}, nil
+}
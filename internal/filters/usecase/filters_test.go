@@ -0,0 +1,95 @@
+package filterUsecase_test
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	filterUsecase "technopark-dbms-forum/internal/filters/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+func TestBlocklistFilter(t *testing.T) {
+	cases := []struct {
+		name    string
+		words   []string
+		message string
+		matched bool
+		masked  string
+	}{
+		{"latin", []string{"spam"}, "no SPAM here", true, "no **** here"},
+		{"cyrillic", []string{"спам"}, "это Спам тут", true, "это **** тут"},
+		{"inside a latin word", []string{"spam"}, "spammer", false, "spammer"},
+		{"inside a cyrillic word", []string{"спам"}, "спамер", false, "спамер"},
+		{"next to punctuation", []string{"спам"}, "(спам), спам!", true, "(****), ****!"},
+		{"repeated", []string{"spam"}, "spam spam", true, "**** ****"},
+		{"longer word first", []string{"sea", "sea dog"}, "old sea dog", true, "old *******"},
+		{"digits are word characters", []string{"42"}, "x42 42", true, "x42 **"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			flag := filterUsecase.NewBlocklistFilter(c.words, models.FilterFlag)
+			verdict, err := flag.Check(&models.Content{Message: c.message})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (verdict != nil) != c.matched {
+				t.Errorf("got verdict %v, want match %v", verdict, c.matched)
+			}
+
+			content := &models.Content{Title: c.message, Message: c.message}
+			rewrite := filterUsecase.NewBlocklistFilter(c.words, models.FilterRewrite)
+			if _, err = rewrite.Check(content); err != nil {
+				t.Fatal(err)
+			}
+			if content.Message != c.masked || content.Title != c.masked {
+				t.Errorf("got title %q and message %q, want %q", content.Title, content.Message, c.masked)
+			}
+		})
+	}
+}
+
+func TestLinkFilter(t *testing.T) {
+	content := &models.Content{Message: "see http://a.example and www.b.example or https://c.example"}
+
+	verdict, err := filterUsecase.NewLinkFilter(3, models.FilterReject).Check(content)
+	if err != nil || verdict != nil {
+		t.Fatalf("three links got %v, %v, want no verdict", verdict, err)
+	}
+
+	verdict, err = filterUsecase.NewLinkFilter(1, models.FilterRewrite).Check(content)
+	if err != nil || verdict == nil {
+		t.Fatalf("got %v, %v, want a verdict", verdict, err)
+	}
+	if want := "see http://a.example and [link removed] or [link removed]"; content.Message != want {
+		t.Errorf("got %q, want %q", content.Message, want)
+	}
+}
+
+func TestLengthFilter(t *testing.T) {
+	content := &models.Content{Message: "йо-хо-хо"}
+
+	if verdict, _ := filterUsecase.NewLengthFilter(8, models.FilterReject).Check(content); verdict != nil {
+		t.Errorf("eight characters got %v, want no verdict", verdict)
+	}
+
+	verdict, _ := filterUsecase.NewLengthFilter(2, models.FilterRewrite).Check(content)
+	if verdict == nil || content.Message != "йо" {
+		t.Errorf("got %v and %q, want a verdict and %q", verdict, content.Message, "йо")
+	}
+}
+
+func TestDuplicateFilterInBatch(t *testing.T) {
+	hash := sha256.Sum256([]byte("\nahoy"))
+	content := &models.Content{Author: "bob", Message: " ahoy ", Previous: [][]byte{hash[:]}}
+
+	// An earlier post of the same batch is found without asking the
+	// repository, which the filter does not get here.
+	verdict, err := filterUsecase.NewDuplicateFilter(nil, 0, models.FilterReject).Check(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verdict == nil || verdict.Action != models.FilterReject {
+		t.Errorf("got %v, want a rejection", verdict)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
 	userUsecase "technopark-dbms-forum/internal/users/usecase"
 
 	internalErrors "technopark-dbms-forum/internal"
@@ -12,7 +14,8 @@ import (
 	"github.com/labstack/echo/v4"
 
 	forumUsecase "technopark-dbms-forum/internal/forums/usecase"
-	"technopark-dbms-forum/internal/models"
+	"technopark-dbms-forum/pkg/etag"
+	"technopark-dbms-forum/pkg/models"
 )
 
 type Handler struct {
@@ -38,6 +41,9 @@ func (h *Handler) Create(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
+	if principal, ok := authDelivery.Principal(c); ok {
+		forum.User = principal
+	}
 
 	user, err := h.userUsecase.GetByNickname(forum.User)
 	if err != nil {
@@ -69,7 +75,7 @@ func (h *Handler) GetDetails(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
-	return c.JSON(http.StatusOK, forum)
+	return etag.JSON(c, http.StatusOK, etag.Make(forum.Version), forum)
 }
 
 func (h *Handler) GetThreads(c echo.Context) error {
@@ -143,3 +149,100 @@ func (h *Handler) GetUsers(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, users)
 }
+
+func (h *Handler) Follow(c echo.Context) error {
+	slug := c.Param("slug")
+
+	follow := models.ForumFollow{}
+
+	if err := c.Bind(&follow); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if principal, ok := authDelivery.Principal(c); ok {
+		follow.Nickname = principal
+	}
+
+	response, created, err := h.forumUsecase.Follow(slug, follow.Nickname)
+	if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find forum with slug: %s", slug))
+	} else if err == internalErrors.ErrUserNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find user with nickname: %s", follow.Nickname))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	if !created {
+		return c.JSON(http.StatusOK, response)
+	}
+
+	return c.JSON(http.StatusCreated, response)
+}
+
+func (h *Handler) Unfollow(c echo.Context) error {
+	slug := c.Param("slug")
+
+	follow := models.ForumFollow{}
+
+	if err := c.Bind(&follow); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if principal, ok := authDelivery.Principal(c); ok {
+		follow.Nickname = principal
+	}
+
+	response, err := h.forumUsecase.Unfollow(slug, follow.Nickname)
+	if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find forum with slug: %s", slug))
+	} else if err == internalErrors.ErrNotSubscribed {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("User %s doesn't follow forum: %s", follow.Nickname, slug))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// GetFeed lists threads of the forums the user follows, newest first unless desc=false.
+func (h *Handler) GetFeed(c echo.Context) error {
+	nickname := c.Param("nickname")
+
+	var limit int64 = 100
+	desc := true
+	since := time.Time{}
+	var err error
+
+	if c.QueryParam("limit") != "" {
+		limit, err = strconv.ParseInt(c.QueryParam("limit"), 10, 64)
+		if err != nil || limit <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Wrong limit: %s", c.QueryParam("limit")))
+		}
+	}
+
+	if c.QueryParam("desc") != "" {
+		desc, err = strconv.ParseBool(c.QueryParam("desc"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Wrong desc: %s", c.QueryParam("desc")))
+		}
+	}
+
+	if c.QueryParam("since") != "" {
+		since, err = time.Parse(time.RFC3339, c.QueryParam("since"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Wrong since: %s", c.QueryParam("since")))
+		}
+	}
+
+	user, err := h.userUsecase.GetByNickname(nickname)
+	if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find user with nickname: %s", nickname))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	threads, err := h.forumUsecase.GetFeed(user.Nickname, limit, since, desc)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, threads)
+}
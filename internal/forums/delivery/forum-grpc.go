@@ -0,0 +1,116 @@
+package forumDelivery
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"technopark-dbms-forum/api/forumpb"
+	internalErrors "technopark-dbms-forum/internal"
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
+	forumUsecase "technopark-dbms-forum/internal/forums/usecase"
+	userUsecase "technopark-dbms-forum/internal/users/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+// GRPCServer serves forumpb.ForumService with the usecases of Handler.
+type GRPCServer struct {
+	forumpb.UnimplementedForumServiceServer
+
+	forumUsecase *forumUsecase.ForumUsecase
+	userUsecase  *userUsecase.UserUsecase
+}
+
+func NewGRPCServer(forumUsecase *forumUsecase.ForumUsecase, userUsecase *userUsecase.UserUsecase) *GRPCServer {
+	return &GRPCServer{
+		forumUsecase: forumUsecase,
+		userUsecase:  userUsecase,
+	}
+}
+
+func (s *GRPCServer) CreateForum(ctx context.Context, req *forumpb.CreateForumRequest) (*forumpb.Forum, error) {
+	forum := req.GetForum().Model()
+	if principal, ok := authDelivery.PrincipalFromContext(ctx); ok {
+		forum.User = principal
+	}
+
+	user, err := s.userUsecase.GetByNickname(forum.User)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "Can't find user with nickname: %s", forum.User)
+	}
+	forum.User = user.Nickname
+
+	response, err := s.forumUsecase.Create(forum)
+	if err == internalErrors.ErrAlreadyExist {
+		existing, _ := response.(*models.ForumResponse)
+		st, detailErr := status.Newf(codes.AlreadyExists, "Forum slug is already taken: %s", forum.Slug).
+			WithDetails(forumpb.FromForumResponse(existing))
+		if detailErr != nil {
+			return nil, status.Error(codes.Internal, detailErr.Error())
+		}
+		return nil, st.Err()
+	} else if err == internalErrors.ErrUserNotFound || err == internalErrors.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "Can't find user with nickname: %s", forum.User)
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	created, _ := response.(*models.Forum)
+
+	return forumpb.FromForum(created), nil
+}
+
+func (s *GRPCServer) GetForum(ctx context.Context, req *forumpb.GetForumRequest) (*forumpb.Forum, error) {
+	slug := req.GetSlug()
+
+	forum, err := s.forumUsecase.GetFullBySlug(slug)
+	if err == internalErrors.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "Can't find forum with slug: %s", slug)
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return forumpb.FromForumResponse(forum), nil
+}
+
+func (s *GRPCServer) ListThreads(ctx context.Context, req *forumpb.ListThreadsRequest) (*forumpb.Threads, error) {
+	slug := req.GetSlug()
+
+	limit := req.GetLimit()
+	if limit <= 0 {
+		limit = 100
+	}
+	since := ""
+	if req.GetSince() != nil {
+		since = req.GetSince().AsTime().Format(time.RFC3339Nano)
+	}
+
+	threads, err := s.forumUsecase.GetThreadsBySlug(slug, limit, since, req.GetDesc())
+	if err == internalErrors.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "Can't find forum with slug: %s", slug)
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return forumpb.FromThreads(threads), nil
+}
+
+func (s *GRPCServer) ListUsers(ctx context.Context, req *forumpb.ListUsersRequest) (*forumpb.Users, error) {
+	slug := req.GetSlug()
+
+	limit := req.GetLimit()
+	if limit <= 0 {
+		limit = 100
+	}
+
+	users, err := s.forumUsecase.GetUsersBySlug(slug, limit, req.GetSince(), req.GetDesc())
+	if err == internalErrors.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "Can't find forum by slug: %s", slug)
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return forumpb.FromUsers(users), nil
+}
@@ -0,0 +1,87 @@
+package forumDelivery
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+
+	internalErrors "technopark-dbms-forum/internal"
+)
+
+const (
+	activityBuffer     = 64
+	activityPingEvery  = 30 * time.Second
+	activityWriteLimit = 10 * time.Second
+	activityReadLimit  = 512
+)
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// Activity streams new threads, new posts, votes and thread edits of a forum
+// over a WebSocket. The types query parameter limits the stream to the listed
+// activity types, comma separated.
+func (h *Handler) Activity(c echo.Context) error {
+	slug := c.Param("slug")
+
+	forum, err := h.forumUsecase.GetBySlug(slug)
+	if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find forum with slug: %s", slug))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	var types []string
+	if c.QueryParam("types") != "" {
+		types = strings.Split(c.QueryParam("types"), ",")
+	}
+
+	conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	sub := h.forumUsecase.SubscribeActivity(forum.Slug, types, activityBuffer)
+	defer h.forumUsecase.UnsubscribeActivity(sub)
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+
+		conn.SetReadLimit(activityReadLimit)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(activityPingEvery)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return nil
+		case <-ping.C:
+			if err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(activityWriteLimit)); err != nil {
+				return nil
+			}
+		case msg, ok := <-sub.Messages:
+			if !ok {
+				return nil
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(activityWriteLimit))
+			if err = conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return nil
+			}
+		}
+	}
+}
@@ -2,14 +2,16 @@ package forumRepository
 
 import (
 	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/lib/pq"
 
 	internalErrors "technopark-dbms-forum/internal"
+	eventRepository "technopark-dbms-forum/internal/events/repository"
 
 	"github.com/jmoiron/sqlx"
-	"technopark-dbms-forum/internal/models"
+	"technopark-dbms-forum/pkg/models"
 )
 
 type Postgres struct {
@@ -46,12 +48,19 @@ func (p *Postgres) Create(f *models.Forum) (*models.Forum, error) {
 		return nil, err
 	}
 
-	if _, err = p.sqlx.Exec(
+	tx, err := p.sqlx.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.Exec(
 		"INSERT INTO forums (title, author_nickname, slug) VALUES ($1, $2, $3)",
 		f.Title,
 		f.User,
 		f.Slug,
 	); err != nil {
+		tx.Rollback()
+
 		pgErr, ok := err.(*pq.Error)
 		if ok && pgErr.Code == "23503" {
 			return nil, internalErrors.ErrUserNotFound
@@ -60,6 +69,15 @@ func (p *Postgres) Create(f *models.Forum) (*models.Forum, error) {
 		return nil, err
 	}
 
+	if err = eventRepository.Insert(tx, models.EventForumCreated, f); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	return f, nil
 }
 
@@ -82,7 +100,7 @@ func (p *Postgres) GetFullBySlug(slug string) (*models.ForumResponse, error) {
 	err := p.sqlx.Get(
 		&forum,
 		`
-			SELECT f.title, f.author_nickname, f.slug, f.posts, f.threads 
+			SELECT f.title, f.author_nickname, f.slug, f.posts, f.threads, f.version
 			FROM forums as f 
 			WHERE f.slug = $1
 		`,
@@ -95,6 +113,25 @@ func (p *Postgres) GetFullBySlug(slug string) (*models.ForumResponse, error) {
 	return &forum, nil
 }
 
+// GetFullBySlugs returns the forums found among the slugs, in no particular order.
+func (p *Postgres) GetFullBySlugs(slugs []string) ([]*models.ForumResponse, error) {
+	forums := make([]*models.ForumResponse, 0, len(slugs))
+	err := p.sqlx.Select(
+		&forums,
+		`
+			SELECT f.title, f.author_nickname, f.slug, f.posts, f.threads, f.version
+			FROM forums as f
+			WHERE f.slug = ANY($1::citext[])
+		`,
+		pq.Array(slugs),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return forums, nil
+}
+
 func (p *Postgres) GetUsersBySlug(slug string, limit int64, since string, desc bool) ([]*models.User, error) {
 	users := make([]*models.User, 0)
 
@@ -185,7 +222,7 @@ func (p *Postgres) GetThreadsBySlug(slug string, limit int64, since time.Time, d
 			err := p.sqlx.Select(
 				&threads,
 				`
-					SELECT t.id, t.author_nickname, t.forum, t.message, t.slug, t.title, t.created, t.votes
+					SELECT t.id, t.author_nickname, t.forum, t.message, t.slug, t.title, t.created, t.votes, t.is_closed, t.is_pinned
 					FROM threads t
 					WHERE t.forum = $1 AND t.created <= $2
 					ORDER BY t.created desc
@@ -202,7 +239,7 @@ func (p *Postgres) GetThreadsBySlug(slug string, limit int64, since time.Time, d
 			err := p.sqlx.Select(
 				&threads,
 				`
-					SELECT t.id, t.author_nickname, t.forum, t.message, t.slug, t.title, t.created, t.votes
+					SELECT t.id, t.author_nickname, t.forum, t.message, t.slug, t.title, t.created, t.votes, t.is_closed, t.is_pinned
 					FROM threads t
 					WHERE t.forum = $1 AND t.created >= $2
 					ORDER BY t.created
@@ -221,7 +258,7 @@ func (p *Postgres) GetThreadsBySlug(slug string, limit int64, since time.Time, d
 			err := p.sqlx.Select(
 				&threads,
 				`
-					SELECT t.id, t.author_nickname, t.forum, t.message, t.slug, t.title, t.created, t.votes
+					SELECT t.id, t.author_nickname, t.forum, t.message, t.slug, t.title, t.created, t.votes, t.is_closed, t.is_pinned
 					FROM threads t
 					WHERE t.forum = $1
 					ORDER BY t.created desc
@@ -237,7 +274,7 @@ func (p *Postgres) GetThreadsBySlug(slug string, limit int64, since time.Time, d
 			err := p.sqlx.Select(
 				&threads,
 				`
-					SELECT t.id, t.author_nickname, t.forum, t.message, t.slug, t.title, t.created, t.votes
+					SELECT t.id, t.author_nickname, t.forum, t.message, t.slug, t.title, t.created, t.votes, t.is_closed, t.is_pinned
 					FROM threads t
 					WHERE t.forum = $1
 					ORDER BY t.created
@@ -254,3 +291,89 @@ func (p *Postgres) GetThreadsBySlug(slug string, limit int64, since time.Time, d
 
 	return threads, nil
 }
+
+func (p *Postgres) Follow(slug, nickname string) (bool, error) {
+	res, err := p.sqlx.Exec(
+		`
+			INSERT INTO forum_follows (nickname, forum_slug)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`,
+		nickname,
+		slug,
+	)
+	if err != nil {
+		pgErr, ok := err.(*pq.Error)
+		if ok && pgErr.Code == "23503" {
+			return false, internalErrors.ErrUserNotFound
+		}
+		return false, err
+	}
+
+	created, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return created != 0, nil
+}
+
+func (p *Postgres) Unfollow(slug, nickname string) error {
+	res, err := p.sqlx.Exec(
+		"DELETE FROM forum_follows WHERE forum_slug = $1 AND nickname = $2",
+		slug,
+		nickname,
+	)
+	if err != nil {
+		return err
+	}
+
+	if deleted, err := res.RowsAffected(); err != nil {
+		return err
+	} else if deleted == 0 {
+		return internalErrors.ErrNotSubscribed
+	}
+
+	return nil
+}
+
+func (p *Postgres) GetFeed(nickname string, limit int64, since time.Time, desc bool) ([]*models.ThreadResponse, error) {
+	threads := make([]*models.ThreadResponse, 0)
+
+	query := `
+		SELECT t.id, t.author_nickname, t.forum, t.message, t.slug, t.title, t.created, t.votes, t.is_closed, t.is_pinned
+		FROM threads t
+		JOIN forum_follows f ON f.forum_slug = t.forum
+		WHERE f.nickname = $1
+	`
+	args := []interface{}{nickname}
+
+	if since != (time.Time{}) {
+		args = append(args, since)
+		if desc {
+			query += " AND t.created <= $2"
+		} else {
+			query += " AND t.created >= $2"
+		}
+	}
+
+	if desc {
+		query += " ORDER BY t.created DESC, t.id DESC"
+	} else {
+		query += " ORDER BY t.created, t.id"
+	}
+
+	args = append(args, limit)
+	query += fmt.Sprintf(" LIMIT $%d", len(args))
+
+	err := p.sqlx.Select(
+		&threads,
+		query,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return threads, nil
+}
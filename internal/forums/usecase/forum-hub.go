@@ -0,0 +1,127 @@
+package forumUsecase
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+// ActivityResync is sent instead of the events a subscriber was too slow to
+// receive. The client should reload the forum state it shows.
+const ActivityResync = "resync"
+
+// ActivitySubscription receives encoded models.ForumActivity messages of one
+// forum. Messages is closed when the subscription ends.
+type ActivitySubscription struct {
+	Messages chan []byte
+
+	forum string
+	types map[string]bool
+}
+
+func (s *ActivitySubscription) wants(activityType string) bool {
+	return len(s.types) == 0 || s.types[activityType]
+}
+
+func (s *ActivitySubscription) send(msg []byte) {
+	select {
+	case s.Messages <- msg:
+		return
+	default:
+	}
+
+	for drained := false; !drained; {
+		select {
+		case <-s.Messages:
+		default:
+			drained = true
+		}
+	}
+
+	resync, _ := json.Marshal(&models.ForumActivity{Type: ActivityResync})
+	select {
+	case s.Messages <- resync:
+	default:
+	}
+}
+
+// ForumHub fans out activity from the write paths of this instance to the
+// forum's subscribers. A subscriber that falls behind gets a single resync
+// message in place of everything it missed.
+type ForumHub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*ActivitySubscription]struct{}
+}
+
+func NewForumHub() *ForumHub {
+	return &ForumHub{
+		subscribers: make(map[string]map[*ActivitySubscription]struct{}),
+	}
+}
+
+func (h *ForumHub) Subscribe(forum string, types []string, buffer int) *ActivitySubscription {
+	sub := &ActivitySubscription{
+		Messages: make(chan []byte, buffer),
+		forum:    strings.ToLower(forum),
+		types:    make(map[string]bool),
+	}
+	for _, t := range types {
+		if t != "" {
+			sub.types[t] = true
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subscribers[sub.forum] == nil {
+		h.subscribers[sub.forum] = make(map[*ActivitySubscription]struct{})
+	}
+	h.subscribers[sub.forum][sub] = struct{}{}
+
+	return sub
+}
+
+func (h *ForumHub) Unsubscribe(sub *ActivitySubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs, ok := h.subscribers[sub.forum]
+	if !ok {
+		return
+	}
+	if _, ok = subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscribers, sub.forum)
+	}
+	close(sub.Messages)
+}
+
+func (h *ForumHub) Publish(forum, activityType string, data interface{}) {
+	forum = strings.ToLower(forum)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs := h.subscribers[forum]
+	if len(subs) == 0 {
+		return
+	}
+
+	msg, err := json.Marshal(&models.ForumActivity{Type: activityType, Data: data})
+	if err != nil {
+		return
+	}
+
+	for sub := range subs {
+		if sub.wants(activityType) {
+			sub.send(msg)
+		}
+	}
+}
@@ -6,15 +6,16 @@ import (
 
 	internalErrors "technopark-dbms-forum/internal"
 	forumRepository "technopark-dbms-forum/internal/forums/repository"
-	"technopark-dbms-forum/internal/models"
+	"technopark-dbms-forum/pkg/models"
 )
 
 type ForumUsecase struct {
-	r *forumRepository.Postgres
+	r   *forumRepository.Postgres
+	hub *ForumHub
 }
 
-func NewForumUsecase(repo *forumRepository.Postgres) *ForumUsecase {
-	return &ForumUsecase{r: repo}
+func NewForumUsecase(repo *forumRepository.Postgres, hub *ForumHub) *ForumUsecase {
+	return &ForumUsecase{r: repo, hub: hub}
 }
 
 func (f *ForumUsecase) Create(forum *models.Forum) (interface{}, error) {
@@ -46,6 +47,10 @@ func (f *ForumUsecase) GetFullBySlug(slug string) (*models.ForumResponse, error)
 	return res, err
 }
 
+func (f *ForumUsecase) GetFullBySlugs(slugs []string) ([]*models.ForumResponse, error) {
+	return f.r.GetFullBySlugs(slugs)
+}
+
 func (f *ForumUsecase) GetThreadsBySlug(slug string, limit int64, since string, desc bool) ([]*models.ThreadResponse, error) {
 	_, err := f.r.GetBySlug(slug)
 	if err == sql.ErrNoRows {
@@ -79,3 +84,48 @@ func (f *ForumUsecase) GetUsersBySlug(slug string, limit int64, since string, de
 	}
 	return res, err
 }
+
+func (f *ForumUsecase) PublishActivity(slug, activityType string, data interface{}) {
+	f.hub.Publish(slug, activityType, data)
+}
+
+func (f *ForumUsecase) SubscribeActivity(slug string, types []string, buffer int) *ActivitySubscription {
+	return f.hub.Subscribe(slug, types, buffer)
+}
+
+func (f *ForumUsecase) UnsubscribeActivity(sub *ActivitySubscription) {
+	f.hub.Unsubscribe(sub)
+}
+
+func (f *ForumUsecase) Follow(slug, nickname string) (*models.ForumFollow, bool, error) {
+	forum, err := f.GetBySlug(slug)
+	if err != nil {
+		return nil, false, err
+	}
+
+	created, err := f.r.Follow(forum.Slug, nickname)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &models.ForumFollow{Nickname: nickname, Forum: forum.Slug}, created, nil
+}
+
+func (f *ForumUsecase) Unfollow(slug, nickname string) (*models.ForumFollow, error) {
+	forum, err := f.GetBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = f.r.Unfollow(forum.Slug, nickname); err != nil {
+		return nil, err
+	}
+
+	return &models.ForumFollow{Nickname: nickname, Forum: forum.Slug}, nil
+}
+
+// GetFeed lists threads of the forums the user follows; a zero since means
+// from the newest (or the oldest) thread on.
+func (f *ForumUsecase) GetFeed(nickname string, limit int64, since time.Time, desc bool) ([]*models.ThreadResponse, error) {
+	return f.r.GetFeed(nickname, limit, since, desc)
+}
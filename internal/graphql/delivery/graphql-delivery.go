@@ -0,0 +1,41 @@
+package graphqlDelivery
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
+	graphqlUsecase "technopark-dbms-forum/internal/graphql/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+type Handler struct {
+	graphqlUsecase *graphqlUsecase.GraphQLUsecase
+}
+
+func NewHandler(graphqlUsecase *graphqlUsecase.GraphQLUsecase) *Handler {
+	return &Handler{
+		graphqlUsecase: graphqlUsecase,
+	}
+}
+
+// Query answers 200 with the data and errors of any query it could read,
+// as GraphQL clients expect, and 400 only when there is no query to run.
+func (h *Handler) Query(c echo.Context) error {
+	request := models.GraphQLRequest{}
+	if err := c.Bind(&request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	if variables := c.QueryParam("variables"); c.Request().Method == http.MethodGet && variables != "" {
+		if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+	}
+	if request.Query == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Query is required")
+	}
+
+	return c.JSON(http.StatusOK, h.graphqlUsecase.Execute(c.Request().Context(), authDelivery.Actor(c), &request))
+}
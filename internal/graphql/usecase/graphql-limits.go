@@ -0,0 +1,193 @@
+package graphqlUsecase
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// measure finds how deep a query goes and how many fields it may resolve.
+// Every field costs one, and the fields under a list cost as many times
+// over as the list may hold items, so that a page of threads with a page of
+// posts each costs what it would take to serve. Introspection is free.
+type measure struct {
+	schema    graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+
+	depth int
+}
+
+// measureQuery measures the operation of a validated document that would
+// run, or returns zero when there is none.
+func measureQuery(schema graphql.Schema, doc *ast.Document, operationName string, variables map[string]interface{}) (depth, complexity int) {
+	m := &measure{
+		schema:    schema,
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: make(map[string]interface{}, len(variables)),
+	}
+	for name, value := range variables {
+		m.variables[name] = value
+	}
+
+	var operation *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			m.fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operationName == "" || definition.Name != nil && definition.Name.Value == operationName {
+				operation = definition
+			}
+		}
+	}
+	if operation == nil || schema.QueryType() == nil {
+		return 0, 0
+	}
+
+	for _, definition := range operation.VariableDefinitions {
+		name := definition.Variable.Name.Value
+		if _, ok := m.variables[name]; !ok && definition.DefaultValue != nil {
+			m.variables[name] = definition.DefaultValue.GetValue()
+		}
+	}
+
+	complexity = m.selectionSet(operation.SelectionSet, schema.QueryType(), 1)
+	return m.depth, complexity
+}
+
+func (m *measure) selectionSet(set *ast.SelectionSet, parent *graphql.Object, depth int) int {
+	if set == nil || parent == nil {
+		return 0
+	}
+
+	cost := 0
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+			def := parent.Fields()[selection.Name.Value]
+			if def == nil {
+				continue
+			}
+			if depth > m.depth {
+				m.depth = depth
+			}
+
+			children := 0
+			if object, ok := graphql.GetNamed(def.Type).(*graphql.Object); ok {
+				children = m.selectionSet(selection.SelectionSet, object, depth+1)
+			}
+			cost = add(cost, add(1, multiply(m.items(selection, def), children)))
+		case *ast.InlineFragment:
+			cost = add(cost, m.selectionSet(selection.SelectionSet, m.object(selection.TypeCondition, parent), depth))
+		case *ast.FragmentSpread:
+			if fragment := m.fragments[selection.Name.Value]; fragment != nil {
+				cost = add(cost, m.selectionSet(fragment.SelectionSet, m.object(fragment.TypeCondition, parent), depth))
+			}
+		}
+	}
+
+	return cost
+}
+
+func (m *measure) object(condition *ast.Named, parent *graphql.Object) *graphql.Object {
+	if condition == nil {
+		return parent
+	}
+	object, _ := m.schema.Type(condition.Name.Value).(*graphql.Object)
+
+	return object
+}
+
+// items is how many items a field may hold: the limit of a list, one for
+// anything else.
+func (m *measure) items(field *ast.Field, def *graphql.FieldDefinition) int {
+	t := def.Type
+	if nonNull, ok := t.(*graphql.NonNull); ok {
+		t = nonNull.OfType
+	}
+	if _, ok := t.(*graphql.List); !ok {
+		return 1
+	}
+
+	var limit interface{}
+	for _, arg := range def.Args {
+		if arg.Name() == "limit" {
+			limit = arg.DefaultValue
+		}
+	}
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		if variable, ok := arg.Value.(*ast.Variable); ok {
+			limit = m.variables[variable.Name.Value]
+		} else {
+			limit = arg.Value.GetValue()
+		}
+	}
+
+	items := 1
+	switch limit := limit.(type) {
+	case int:
+		items = limit
+	case float64:
+		if limit > maxCost {
+			return maxCost
+		}
+		items = int(limit)
+	case string:
+		items, _ = strconv.Atoi(limit)
+	}
+	if items < 1 {
+		return 1
+	}
+
+	return capped(items)
+}
+
+// Costs saturate at maxCost, so that huge limits cannot overflow them.
+const maxCost = math.MaxInt32
+
+func capped(cost int) int {
+	if cost > maxCost {
+		return maxCost
+	}
+
+	return cost
+}
+
+func add(a, b int) int {
+	return capped(a + b)
+}
+
+func multiply(a, b int) int {
+	if b != 0 && a > maxCost/b {
+		return maxCost
+	}
+
+	return capped(a * b)
+}
+
+// checkLimits rejects a query deeper or more complex than allowed.
+func (u *GraphQLUsecase) checkLimits(doc *ast.Document, operationName string, variables map[string]interface{}) []gqlerrors.FormattedError {
+	depth, complexity := measureQuery(u.schema, doc, operationName, variables)
+
+	errs := make([]gqlerrors.FormattedError, 0)
+	if u.maxDepth > 0 && depth > u.maxDepth {
+		errs = append(errs, gqlerrors.NewFormattedError(fmt.Sprintf("Query is %d levels deep, at most %d allowed", depth, u.maxDepth)))
+	}
+	if u.maxComplexity > 0 && complexity > u.maxComplexity {
+		errs = append(errs, gqlerrors.NewFormattedError(fmt.Sprintf("Query complexity is %d, at most %d allowed", complexity, u.maxComplexity)))
+	}
+
+	return errs
+}
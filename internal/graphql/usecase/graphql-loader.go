@@ -0,0 +1,168 @@
+package graphqlUsecase
+
+import (
+	"context"
+	"strings"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+// loader batches the lookups of one kind of object within a query. A
+// resolver queues its key and returns a thunk; graphql-go calls the thunks
+// only after resolving the whole level of the query, so the first of them
+// that needs a key fetches every key queued by then with one query.
+// Execution runs on a single goroutine, so loaders need no locking.
+type loader[K comparable, V any] struct {
+	fetch func(keys []K) ([]V, error)
+	key   func(V) K
+
+	queued  []K
+	values  map[K]V
+	missing map[K]error
+}
+
+func newLoader[K comparable, V any](fetch func(keys []K) ([]V, error), key func(V) K) *loader[K, V] {
+	return &loader[K, V]{
+		fetch:   fetch,
+		key:     key,
+		values:  make(map[K]V),
+		missing: make(map[K]error),
+	}
+}
+
+// prime stores values fetched some other way, such as a page of a list.
+func (l *loader[K, V]) prime(values ...V) {
+	for _, value := range values {
+		l.values[l.key(value)] = value
+	}
+}
+
+func (l *loader[K, V]) fetched(key K) bool {
+	_, found := l.values[key]
+	_, missing := l.missing[key]
+
+	return found || missing
+}
+
+func (l *loader[K, V]) flush() {
+	keys := l.queued
+	l.queued = nil
+
+	values, err := l.fetch(keys)
+	if err != nil {
+		for _, key := range keys {
+			l.missing[key] = err
+		}
+		return
+	}
+
+	l.prime(values...)
+	for _, key := range keys {
+		if _, ok := l.values[key]; !ok {
+			l.missing[key] = nil
+		}
+	}
+}
+
+// load returns a thunk for the value of the key; a key that is not found
+// resolves to null.
+func (l *loader[K, V]) load(key K) func() (interface{}, error) {
+	if !l.fetched(key) {
+		l.queued = append(l.queued, key)
+	}
+
+	return func() (interface{}, error) {
+		if !l.fetched(key) {
+			l.flush()
+		}
+		if value, ok := l.values[key]; ok {
+			return value, nil
+		}
+
+		return nil, l.missing[key]
+	}
+}
+
+// loaders are the loaders of one query. fetches counts the usecase calls
+// the query made, through the loaders or for the lists it pages.
+type loaders struct {
+	users       *loader[string, *models.User]
+	forums      *loader[string, *models.ForumResponse]
+	threads     *loader[int64, *models.ThreadResponse]
+	posts       *loader[int64, *models.Post]
+	attachments *loader[int64, *models.Post]
+
+	fetches int
+}
+
+// counted counts the calls of fetch on the loaders.
+func counted[K comparable, V any](l *loaders, fetch func(keys []K) ([]V, error)) func(keys []K) ([]V, error) {
+	return func(keys []K) ([]V, error) {
+		l.fetches++
+		return fetch(keys)
+	}
+}
+
+type loadersKey struct{}
+
+// Nicknames and slugs are case-insensitive, so their loaders are keyed by
+// the lower case.
+func (u *GraphQLUsecase) newLoaders(actor string) *loaders {
+	l := &loaders{}
+	l.users = newLoader(counted(l, u.userUsecase.GetByNicknames), func(user *models.User) string {
+		return strings.ToLower(user.Nickname)
+	})
+	l.forums = newLoader(counted(l, u.forumUsecase.GetFullBySlugs), func(forum *models.ForumResponse) string {
+		return strings.ToLower(forum.Slug)
+	})
+	l.threads = newLoader(counted(l, u.threadUsecase.GetByIDs), func(thread *models.ThreadResponse) int64 {
+		return int64(thread.ID)
+	})
+	l.posts = newLoader(counted(l, func(ids []int64) ([]*models.Post, error) {
+		posts, err := u.postUsecase.GetByIDs(ids)
+		if err != nil {
+			return nil, err
+		}
+		return u.postUsecase.Visible(posts, actor)
+	}), func(post *models.Post) int64 {
+		return int64(post.ID)
+	})
+	l.attachments = newLoader(counted(l, u.fillAttachments), func(post *models.Post) int64 {
+		return int64(post.ID)
+	})
+
+	return l
+}
+
+// fillAttachments returns a stand-in post with its attachments for each id.
+func (u *GraphQLUsecase) fillAttachments(ids []int64) ([]*models.Post, error) {
+	posts := make([]*models.Post, 0, len(ids))
+	for _, id := range ids {
+		posts = append(posts, &models.Post{ID: uint64(id)})
+	}
+	if err := u.attachments.Fill(posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+func (l *loaders) user(nickname string) func() (interface{}, error) {
+	return l.users.load(strings.ToLower(nickname))
+}
+
+func (l *loaders) forum(slug string) func() (interface{}, error) {
+	return l.forums.load(strings.ToLower(slug))
+}
+
+func (l *loaders) thread(id uint64) func() (interface{}, error) {
+	return l.threads.load(int64(id))
+}
+
+func (l *loaders) post(id uint64) func() (interface{}, error) {
+	return l.posts.load(int64(id))
+}
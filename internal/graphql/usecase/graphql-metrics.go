@@ -0,0 +1,17 @@
+package graphqlUsecase
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// QueryFetchesMetric counts the usecase calls each query made. With the
+// loaders batching every level, it grows with the depth of a query, not with
+// the rows it returns.
+const QueryFetchesMetric = "forum_graphql_query_fetches"
+
+var queryFetches = promauto.NewHistogram(prometheus.HistogramOpts{
+	Name:    QueryFetchesMetric,
+	Help:    "Usecase calls made to resolve one GraphQL query.",
+	Buckets: prometheus.ExponentialBuckets(1, 2, 8),
+})
@@ -0,0 +1,342 @@
+package graphqlUsecase
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/graphql-go/graphql"
+
+	internalErrors "technopark-dbms-forum/internal"
+	"technopark-dbms-forum/pkg/models"
+)
+
+// defaultLimit is the page size of list fields, as in the REST API.
+const defaultLimit = 100
+
+var errLimit = errors.New("limit must be positive")
+
+var postSort = graphql.NewEnum(graphql.EnumConfig{
+	Name: "PostSort",
+	Values: graphql.EnumValueConfigMap{
+		"FLAT":        &graphql.EnumValueConfig{Value: "flat"},
+		"TREE":        &graphql.EnumValueConfig{Value: "tree"},
+		"PARENT_TREE": &graphql.EnumValueConfig{Value: "parent_tree"},
+	},
+})
+
+var attachmentType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Attachment",
+	Fields: graphql.Fields{
+		"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"uploader": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"filename": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"mimeType": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"size":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"url":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"created":  &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+	},
+})
+
+// limitArg returns the limit argument of a list field.
+func limitArg(p graphql.ResolveParams) (int, error) {
+	limit, _ := p.Args["limit"].(int)
+	if limit < 1 {
+		return 0, errLimit
+	}
+
+	return limit, nil
+}
+
+// newSchema mirrors pkg/models, with the nicknames, slugs and ids that link
+// the models turned into fields of the linked type.
+func (u *GraphQLUsecase) newSchema() (graphql.Schema, error) {
+	var forumType, threadType, postType *graphql.Object
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"nickname": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"email":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"fullname": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"about":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	forumType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Forum",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"slug":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"title": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"user": &graphql.Field{
+					Type: graphql.NewNonNull(userType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return loadersFrom(p.Context).user(p.Source.(*models.ForumResponse).User), nil
+					},
+				},
+				"threadCount": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(*models.ForumResponse).Threads, nil
+					},
+				},
+				"postCount": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(*models.ForumResponse).Posts, nil
+					},
+				},
+				"threads": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(threadType))),
+					Args: graphql.FieldConfigArgument{
+						"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultLimit},
+						"since": &graphql.ArgumentConfig{Type: graphql.DateTime},
+						"desc":  &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+					},
+					Resolve: u.resolveForumThreads,
+				},
+				"users": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))),
+					Args: graphql.FieldConfigArgument{
+						"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultLimit},
+						"since": &graphql.ArgumentConfig{Type: graphql.String},
+						"desc":  &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+					},
+					Resolve: u.resolveForumUsers,
+				},
+			}
+		}),
+	})
+
+	threadType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Thread",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"slug": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						if slug := p.Source.(*models.ThreadResponse).Slug; slug != "" {
+							return slug, nil
+						}
+						return nil, nil
+					},
+				},
+				"title":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"message": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"created": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+				"votes":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"closed":  &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+				"pinned":  &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+				"author": &graphql.Field{
+					Type: graphql.NewNonNull(userType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return loadersFrom(p.Context).user(p.Source.(*models.ThreadResponse).Author), nil
+					},
+				},
+				"forum": &graphql.Field{
+					Type: graphql.NewNonNull(forumType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return loadersFrom(p.Context).forum(p.Source.(*models.ThreadResponse).Forum), nil
+					},
+				},
+				"posts": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(postType))),
+					Args: graphql.FieldConfigArgument{
+						"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultLimit},
+						"since": &graphql.ArgumentConfig{Type: graphql.Int},
+						"sort":  &graphql.ArgumentConfig{Type: postSort, DefaultValue: "flat"},
+						"desc":  &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false},
+					},
+					Resolve: u.resolveThreadPosts,
+				},
+			}
+		}),
+	})
+
+	postType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Post",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"message":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"isEdited": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+				"isHidden": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+				"created": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.DateTime),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return time.Parse(time.RFC3339Nano, p.Source.(*models.Post).Created)
+					},
+				},
+				"author": &graphql.Field{
+					Type: graphql.NewNonNull(userType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return loadersFrom(p.Context).user(p.Source.(*models.Post).Author), nil
+					},
+				},
+				"forum": &graphql.Field{
+					Type: graphql.NewNonNull(forumType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return loadersFrom(p.Context).forum(p.Source.(*models.Post).Forum), nil
+					},
+				},
+				"thread": &graphql.Field{
+					Type: graphql.NewNonNull(threadType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return loadersFrom(p.Context).thread(p.Source.(*models.Post).Thread), nil
+					},
+				},
+				"parent": &graphql.Field{
+					Type: postType,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						if parent := p.Source.(*models.Post).Parent; parent != 0 {
+							return loadersFrom(p.Context).post(parent), nil
+						}
+						return nil, nil
+					},
+				},
+				"attachments": &graphql.Field{
+					Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(attachmentType))),
+					Resolve: resolvePostAttachments,
+				},
+			}
+		}),
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"nickname": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersFrom(p.Context).user(p.Args["nickname"].(string)), nil
+				},
+			},
+			"forum": &graphql.Field{
+				Type: forumType,
+				Args: graphql.FieldConfigArgument{
+					"slug": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersFrom(p.Context).forum(p.Args["slug"].(string)), nil
+				},
+			},
+			"thread": &graphql.Field{
+				Type: threadType,
+				Args: graphql.FieldConfigArgument{
+					"slugOrId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: u.resolveThread,
+			},
+			"post": &graphql.Field{
+				Type: postType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return loadersFrom(p.Context).post(uint64(p.Args["id"].(int))), nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// resolveThread answers for an old slug of the thread too, like GetThread of
+// the gRPC API.
+func (u *GraphQLUsecase) resolveThread(p graphql.ResolveParams) (interface{}, error) {
+	loadersFrom(p.Context).fetches++
+	thread, err := u.threadUsecase.GetBySlugOrID(p.Args["slugOrId"].(string))
+	if err == internalErrors.ErrNoRowsBySlug || err == internalErrors.ErrNoRowsByID {
+		return nil, nil
+	} else if err != nil && err != internalErrors.ErrThreadSlugMoved {
+		return nil, err
+	}
+
+	loadersFrom(p.Context).threads.prime(thread)
+	return thread, nil
+}
+
+func (u *GraphQLUsecase) resolveForumThreads(p graphql.ResolveParams) (interface{}, error) {
+	limit, err := limitArg(p)
+	if err != nil {
+		return nil, err
+	}
+	since := ""
+	if t, ok := p.Args["since"].(time.Time); ok {
+		since = t.Format(time.RFC3339Nano)
+	}
+	desc, _ := p.Args["desc"].(bool)
+
+	loadersFrom(p.Context).fetches++
+	threads, err := u.forumUsecase.GetThreadsBySlug(p.Source.(*models.ForumResponse).Slug, int64(limit), since, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	loadersFrom(p.Context).threads.prime(threads...)
+	return threads, nil
+}
+
+func (u *GraphQLUsecase) resolveForumUsers(p graphql.ResolveParams) (interface{}, error) {
+	limit, err := limitArg(p)
+	if err != nil {
+		return nil, err
+	}
+	since, _ := p.Args["since"].(string)
+	desc, _ := p.Args["desc"].(bool)
+
+	loadersFrom(p.Context).fetches++
+	users, err := u.forumUsecase.GetUsersBySlug(p.Source.(*models.ForumResponse).Slug, int64(limit), since, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	loadersFrom(p.Context).users.prime(users...)
+	return users, nil
+}
+
+func (u *GraphQLUsecase) resolveThreadPosts(p graphql.ResolveParams) (interface{}, error) {
+	limit, err := limitArg(p)
+	if err != nil {
+		return nil, err
+	}
+	since, _ := p.Args["since"].(int)
+	sort, ok := p.Args["sort"].(string)
+	if !ok {
+		sort = "flat"
+	}
+	desc, _ := p.Args["desc"].(bool)
+
+	thread := p.Source.(*models.ThreadResponse)
+	loadersFrom(p.Context).fetches++
+	posts, err := u.threadUsecase.GetPosts(strconv.FormatUint(thread.ID, 10), uint64(limit), uint64(since), sort, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	loadersFrom(p.Context).posts.prime(posts...)
+	return posts, nil
+}
+
+func resolvePostAttachments(p graphql.ResolveParams) (interface{}, error) {
+	thunk := loadersFrom(p.Context).attachments.load(int64(p.Source.(*models.Post).ID))
+
+	return func() (interface{}, error) {
+		post, err := thunk()
+		if post == nil || err != nil {
+			return nil, err
+		}
+
+		attachments := post.(*models.Post).Attachments
+		if attachments == nil {
+			attachments = []*models.Attachment{}
+		}
+		return attachments, nil
+	}, nil
+}
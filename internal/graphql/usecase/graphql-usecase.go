@@ -0,0 +1,91 @@
+package graphqlUsecase
+
+import (
+	"context"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+
+	attachmentUsecase "technopark-dbms-forum/internal/attachments/usecase"
+	forumUsecase "technopark-dbms-forum/internal/forums/usecase"
+	postUsecase "technopark-dbms-forum/internal/posts/usecase"
+	threadUsecase "technopark-dbms-forum/internal/threads/usecase"
+	userUsecase "technopark-dbms-forum/internal/users/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+type GraphQLUsecase struct {
+	schema graphql.Schema
+
+	userUsecase   *userUsecase.UserUsecase
+	forumUsecase  *forumUsecase.ForumUsecase
+	threadUsecase *threadUsecase.ThreadUsecase
+	postUsecase   *postUsecase.PostUsecase
+	attachments   *attachmentUsecase.AttachmentUsecase
+
+	maxDepth      int
+	maxComplexity int
+}
+
+// NewGraphQLUsecase builds the schema over the usecases; a zero maxDepth or
+// maxComplexity leaves that bound off.
+func NewGraphQLUsecase(
+	userUsecase *userUsecase.UserUsecase,
+	forumUsecase *forumUsecase.ForumUsecase,
+	threadUsecase *threadUsecase.ThreadUsecase,
+	postUsecase *postUsecase.PostUsecase,
+	attachments *attachmentUsecase.AttachmentUsecase,
+	maxDepth, maxComplexity int,
+) (*GraphQLUsecase, error) {
+	u := &GraphQLUsecase{
+		userUsecase:   userUsecase,
+		forumUsecase:  forumUsecase,
+		threadUsecase: threadUsecase,
+		postUsecase:   postUsecase,
+		attachments:   attachments,
+		maxDepth:      maxDepth,
+		maxComplexity: maxComplexity,
+	}
+
+	schema, err := u.newSchema()
+	if err != nil {
+		return nil, err
+	}
+	u.schema = schema
+
+	return u, nil
+}
+
+// Execute runs a query that parses, validates and keeps within the bounds,
+// with loaders of its own that show hidden posts only to those who may
+// moderate them. Whatever goes wrong is in the errors of the result.
+func (u *GraphQLUsecase) Execute(ctx context.Context, actor string, request *models.GraphQLRequest) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(request.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	validation := graphql.ValidateDocument(&u.schema, doc, nil)
+	if !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+	if errs := u.checkLimits(doc, request.OperationName, request.Variables); len(errs) > 0 {
+		return &graphql.Result{Errors: errs}
+	}
+
+	l := u.newLoaders(actor)
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        u.schema,
+		AST:           doc,
+		OperationName: request.OperationName,
+		Args:          request.Variables,
+		Context:       context.WithValue(ctx, loadersKey{}, l),
+	})
+	queryFetches.Observe(float64(l.fetches))
+
+	return result
+}
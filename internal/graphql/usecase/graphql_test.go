@@ -0,0 +1,61 @@
+package graphqlUsecase_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	graphqlUsecase "technopark-dbms-forum/internal/graphql/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+// The queries below are turned away before they run, so the usecase needs no
+// database behind it.
+func newUsecase(t *testing.T) *graphqlUsecase.GraphQLUsecase {
+	t.Helper()
+
+	u, err := graphqlUsecase.NewGraphQLUsecase(nil, nil, nil, nil, nil, 5, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return u
+}
+
+func TestLimits(t *testing.T) {
+	u := newUsecase(t)
+
+	cases := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      string
+	}{
+		{"syntax", `{ thread(`, nil, "Syntax Error"},
+		{"unknown field", `{ thread(slugOrId: "t") { nope } }`, nil, `Cannot query field "nope" on type "Thread".`},
+		{"depth", `{ post(id: 1) { parent { parent { parent { parent { id } } } } } }`, nil, "Query is 6 levels deep, at most 5 allowed"},
+		{"default limits", `{ forum(slug: "f") { threads { posts { id } } } }`, nil, "Query complexity is 10102, at most 500 allowed"},
+		{"limit", `{ thread(slugOrId: "t") { posts(limit: 1000) { id } } }`, nil, "Query complexity is 1002, at most 500 allowed"},
+		{"variable limit", `query($n: Int) { thread(slugOrId: "t") { posts(limit: $n) { id author { nickname } } } }`, map[string]interface{}{"n": float64(1000)}, "Query complexity is 3002, at most 500 allowed"},
+		{"default variable", `query($n: Int = 1000) { thread(slugOrId: "t") { posts(limit: $n) { id author { nickname } } } }`, nil, "Query complexity is 3002, at most 500 allowed"},
+		{"fragment", `{ thread(slugOrId: "t") { ...page } } fragment page on Thread { posts(limit: 300) { id message } }`, nil, "Query complexity is 602, at most 500 allowed"},
+		{"huge limits", `{ forum(slug: "f") { threads(limit: 2000000000) { posts(limit: 2000000000) { id } } } }`, nil, "Query complexity is 2147483647, at most 500 allowed"},
+		{"introspection", `{ __schema { types { name fields { name type { name ofType { name ofType { name } } } } } } }`, nil, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result := u.Execute(context.Background(), "", &models.GraphQLRequest{Query: c.query, Variables: c.variables})
+
+			if c.want == "" {
+				if len(result.Errors) != 0 || result.Data == nil {
+					t.Fatalf("got errors %v, want data", result.Errors)
+				}
+				return
+			}
+			if len(result.Errors) == 0 || !strings.Contains(result.Errors[0].Message, c.want) {
+				t.Fatalf("got errors %v, want %q", result.Errors, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package idempotencyDelivery
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	internalErrors "technopark-dbms-forum/internal"
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
+	idempotencyRepository "technopark-dbms-forum/internal/idempotency/repository"
+	limitDelivery "technopark-dbms-forum/internal/limits/delivery"
+	"technopark-dbms-forum/pkg/etag"
+	"technopark-dbms-forum/pkg/models"
+)
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	HeaderReplayed       = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+type recorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func requestHash(principal string, req *http.Request, body []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(principal + "\n" + req.Method + " " + req.URL.Path + "\n"))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// Middleware stores the first response given under an Idempotency-Key header
+// and replays it for retries. Keys belong to the authenticated principal, so
+// one user can neither replay nor block another user's request. Requests
+// without the header pass through. Answers that carry Retry-After are not
+// stored, since the retry they ask for must be able to get through.
+func Middleware(repo *idempotencyRepository.Postgres, ttl time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxKeyLength {
+				return echo.NewHTTPError(http.StatusBadRequest, "Idempotency key is too long")
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, err)
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			principal, _ := authDelivery.Principal(c)
+			hash := requestHash(principal, c.Request(), body)
+
+			stored, err := repo.Reserve(principal, key, hash, ttl)
+			if err == internalErrors.ErrAlreadyExist {
+				if !bytes.Equal(stored.RequestHash, hash) {
+					return echo.NewHTTPError(http.StatusUnprocessableEntity, "Idempotency key was used with another request")
+				}
+				if !stored.Status.Valid {
+					return echo.NewHTTPError(http.StatusConflict, "Request with this idempotency key is in progress")
+				}
+
+				header := c.Response().Header()
+				if stored.Location != "" {
+					header.Set(echo.HeaderLocation, stored.Location)
+				}
+				if stored.ETag != "" {
+					header.Set(etag.HeaderETag, stored.ETag)
+				}
+				header.Set(HeaderReplayed, "true")
+				return c.Blob(int(stored.Status.Int64), stored.ContentType, stored.Body)
+			} else if err != nil {
+				return echo.NewHTTPError(http.StatusInternalServerError, err)
+			}
+
+			res := c.Response()
+			rec := &recorder{ResponseWriter: res.Writer}
+			res.Writer = rec
+			defer func() { res.Writer = rec.ResponseWriter }()
+
+			// The error is handled here so that its response gets recorded;
+			// returning it as well would make echo write a second response.
+			if err = next(c); err != nil {
+				c.Error(err)
+			}
+
+			// Server failures and rate limit or ban rejections are not the
+			// request's answer: the key is freed so that a retry runs again.
+			if res.Status >= http.StatusInternalServerError || res.Header().Get(limitDelivery.HeaderRetryAfter) != "" {
+				if releaseErr := repo.Release(principal, key); releaseErr != nil {
+					c.Logger().Error(releaseErr)
+				}
+				return nil
+			}
+
+			header := res.Header()
+			if completeErr := repo.Complete(&models.IdempotencyKey{
+				Principal:   principal,
+				Key:         key,
+				Status:      sql.NullInt64{Int64: int64(res.Status), Valid: true},
+				ContentType: header.Get(echo.HeaderContentType),
+				Location:    header.Get(echo.HeaderLocation),
+				ETag:        header.Get(etag.HeaderETag),
+				Body:        rec.body.Bytes(),
+			}); completeErr != nil {
+				c.Logger().Error(completeErr)
+			}
+
+			return nil
+		}
+	}
+}
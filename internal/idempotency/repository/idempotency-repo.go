@@ -0,0 +1,120 @@
+package idempotencyRepository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	internalErrors "technopark-dbms-forum/internal"
+	"technopark-dbms-forum/pkg/models"
+)
+
+type Postgres struct {
+	sqlx *sqlx.DB
+}
+
+func NewPostgres(url string) (*Postgres, error) {
+	newSQLX, err := sqlx.Connect("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = newSQLX.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &Postgres{sqlx: newSQLX}, nil
+}
+
+func (p *Postgres) Close() error {
+	return p.sqlx.Close()
+}
+
+// Reserve claims key for a new request of principal; keys of different
+// principals never collide. Expired keys are taken over, so ErrAlreadyExist
+// means a live key is present and the stored one is returned.
+func (p *Postgres) Reserve(principal, key string, requestHash []byte, ttl time.Duration) (*models.IdempotencyKey, error) {
+	var reserved string
+	err := p.sqlx.Get(
+		&reserved,
+		`
+			INSERT INTO idempotency_keys (principal, key, request_hash, expires)
+			VALUES ($1, $2, $3, now() + $4 * INTERVAL '1 second')
+			ON CONFLICT (principal, key) DO UPDATE
+			SET request_hash = EXCLUDED.request_hash,
+			    status = NULL,
+			    content_type = NULL,
+			    location = NULL,
+			    etag = NULL,
+			    body = NULL,
+			    created = now(),
+			    expires = EXCLUDED.expires
+			WHERE idempotency_keys.expires < now()
+			RETURNING key
+		`,
+		principal,
+		key,
+		requestHash,
+		int64(ttl/time.Second),
+	)
+	if err == nil {
+		return nil, nil
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	stored := models.IdempotencyKey{}
+	err = p.sqlx.Get(
+		&stored,
+		`
+			SELECT principal, key, request_hash, status, COALESCE(content_type, '') as content_type,
+			       COALESCE(location, '') as location, COALESCE(etag, '') as etag, body
+			FROM idempotency_keys
+			WHERE principal = $1 AND key = $2
+		`,
+		principal,
+		key,
+	)
+	if err == sql.ErrNoRows {
+		return nil, internalErrors.ErrNoRows
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &stored, internalErrors.ErrAlreadyExist
+}
+
+func (p *Postgres) Complete(stored *models.IdempotencyKey) error {
+	_, err := p.sqlx.Exec(
+		`
+			UPDATE idempotency_keys
+			SET status = $3, content_type = $4, location = NULLIF($5, ''), etag = NULLIF($6, ''), body = $7
+			WHERE principal = $1 AND key = $2
+		`,
+		stored.Principal,
+		stored.Key,
+		stored.Status,
+		stored.ContentType,
+		stored.Location,
+		stored.ETag,
+		stored.Body,
+	)
+
+	return err
+}
+
+func (p *Postgres) Release(principal, key string) error {
+	_, err := p.sqlx.Exec("DELETE FROM idempotency_keys WHERE principal = $1 AND key = $2", principal, key)
+
+	return err
+}
+
+func (p *Postgres) DeleteExpired() (int64, error) {
+	res, err := p.sqlx.Exec("DELETE FROM idempotency_keys WHERE expires < now()")
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
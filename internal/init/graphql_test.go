@@ -0,0 +1,169 @@
+package service_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	graphqlUsecase "technopark-dbms-forum/internal/graphql/usecase"
+	"technopark-dbms-forum/internal/testenv"
+)
+
+type graphqlResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func query(t *testing.T, s *testenv.Server, q string, variables object) *graphqlResponse {
+	t.Helper()
+
+	resp := s.Do(t, http.MethodPost, "/graphql", object{"query": q, "variables": variables})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("got %d: %s", resp.Status, bytes.TrimSpace(resp.Body))
+	}
+
+	result := &graphqlResponse{}
+	resp.JSON(t, result)
+	return result
+}
+
+func TestGraphQL(t *testing.T) {
+	s := testenv.StartServer(t)
+	run(t, s, fixture)
+
+	result := query(t, s, `
+		query($slug: String!) {
+			thread(slugOrId: $slug) {
+				title
+				author { fullname }
+				forum { slug threadCount user { nickname } }
+				posts(limit: 2, sort: PARENT_TREE) {
+					id
+					author { nickname }
+					parent { id author { nickname } }
+				}
+			}
+		}
+	`, object{"slug": "jolly-roger"})
+	if len(result.Errors) != 0 {
+		t.Fatalf("got errors %v", result.Errors)
+	}
+
+	want := object{
+		"thread": object{
+			"title":  "Flag",
+			"author": object{"fullname": "Alice"},
+			"forum":  object{"slug": "pirates", "threadCount": float64(2), "user": object{"nickname": "alice"}},
+			"posts": []interface{}{
+				object{"id": float64(1), "author": object{"nickname": "alice"}, "parent": nil},
+				object{"id": float64(3), "author": object{"nickname": "bob"}, "parent": object{"id": float64(1), "author": object{"nickname": "alice"}}},
+				object{"id": float64(5), "author": object{"nickname": "alice"}, "parent": object{"id": float64(3), "author": object{"nickname": "bob"}}},
+				object{"id": float64(4), "author": object{"nickname": "carol"}, "parent": object{"id": float64(1), "author": object{"nickname": "alice"}}},
+				object{"id": float64(2), "author": object{"nickname": "bob"}, "parent": nil},
+				object{"id": float64(6), "author": object{"nickname": "alice"}, "parent": object{"id": float64(2), "author": object{"nickname": "bob"}}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(result.Data, want) {
+		t.Errorf("got %v, want %v", result.Data, want)
+	}
+
+	result = query(t, s, `{ post(id: 4) { thread { id } forum { postCount } } user(nickname: "CAROL") { nickname } forum(slug: "none") { slug } }`, nil)
+	want = object{
+		"post":  object{"thread": object{"id": float64(1)}, "forum": object{"postCount": float64(7)}},
+		"user":  object{"nickname": "carol"},
+		"forum": nil,
+	}
+	if len(result.Errors) != 0 || !reflect.DeepEqual(result.Data, want) {
+		t.Errorf("got %v %v, want %v", result.Data, result.Errors, want)
+	}
+
+	result = query(t, s, `{ forum(slug: "pirates") { threads(limit: 100) { posts(limit: 100) { id } } } }`, nil)
+	if len(result.Errors) != 1 || result.Data != nil {
+		t.Errorf("got %v %v, want the query rejected", result.Data, result.Errors)
+	}
+
+	// A hidden post is null to anyone who may not moderate its forum.
+	alice := []string{"Authorization", s.Login(t, "alice", "parrot")}
+	run(t, s, []routeCase{{"hide", http.MethodPost, "/post/4/moderate", object{"hidden": true}, alice, http.StatusOK}})
+	result = query(t, s, `{ post(id: 4) { id } }`, nil)
+	if len(result.Errors) != 0 || !reflect.DeepEqual(result.Data, object{"post": nil}) {
+		t.Errorf("hidden post got %v %v, want null", result.Data, result.Errors)
+	}
+
+	run(t, s, []routeCase{
+		{"no query", http.MethodPost, "/graphql", object{}, nil, http.StatusBadRequest},
+		{"query in url", http.MethodGet, "/graphql?query=%7Buser(nickname%3A%22bob%22)%7Babout%7D%7D", nil, nil, http.StatusOK},
+	})
+}
+
+// queryFetches runs the query and returns how many usecase calls it made.
+// The server runs in the test binary, so its metrics are at hand.
+func queryFetches(t *testing.T, s *testenv.Server, q string) float64 {
+	t.Helper()
+
+	sum := func() float64 {
+		families, err := prometheus.DefaultGatherer.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, family := range families {
+			if family.GetName() == graphqlUsecase.QueryFetchesMetric {
+				return family.GetMetric()[0].GetHistogram().GetSampleSum()
+			}
+		}
+		return 0
+	}
+
+	before := sum()
+	if result := query(t, s, q, nil); len(result.Errors) != 0 {
+		t.Fatalf("got errors %v", result.Errors)
+	}
+
+	return sum() - before
+}
+
+func TestGraphQLBatching(t *testing.T) {
+	s := testenv.StartServer(t)
+	run(t, s, fixture)
+
+	// Twelve more authors, each with a thread of their own and a reply to
+	// the post before theirs in thread 1.
+	cases := make([]routeCase, 0)
+	for i := 0; i < 12; i++ {
+		nickname := "sailor" + strconv.Itoa(i)
+		cases = append(cases,
+			routeCase{"create " + nickname, http.MethodPost, "/user/" + nickname + "/create", object{"email": nickname + "@example.com", "fullname": nickname, "about": "crew"}, nil, http.StatusCreated},
+			routeCase{"create thread by " + nickname, http.MethodPost, "/forum/pirates/create", object{"title": "Watch", "author": nickname, "message": "On watch"}, nil, http.StatusCreated},
+			routeCase{"create post by " + nickname, http.MethodPost, "/thread/1/create", []object{{"author": nickname, "message": "aye", "parent": 7 + i}}, nil, http.StatusCreated},
+		)
+	}
+	run(t, s, cases)
+
+	queries := []struct {
+		name  string
+		query string
+	}{
+		{"threads", `{ forum(slug: "pirates") { threads(limit: %d) { id author { nickname } forum { slug } } } }`},
+		{"posts", `{ thread(slugOrId: "jolly-roger") { posts(limit: %d, desc: true) { id author { nickname } thread { id } forum { slug } parent { author { nickname } } attachments { id } } } }`},
+	}
+
+	// A level costs one call per kind of object however many rows it holds.
+	// A short page may cost more, for parents the page didn't bring along.
+	for _, q := range queries {
+		t.Run(q.name, func(t *testing.T) {
+			few := queryFetches(t, s, fmt.Sprintf(q.query, 2))
+			many := queryFetches(t, s, fmt.Sprintf(q.query, 100))
+			if few == 0 || many > few {
+				t.Errorf("got %v calls for two rows and %v for all of them, want no more", few, many)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"technopark-dbms-forum/api/forumpb"
+	logger "technopark-dbms-forum/pkg"
+
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
+	forumDelivery "technopark-dbms-forum/internal/forums/delivery"
+	postDelivery "technopark-dbms-forum/internal/posts/delivery"
+	threadDelivery "technopark-dbms-forum/internal/threads/delivery"
+	userDelivery "technopark-dbms-forum/internal/users/delivery"
+)
+
+const (
+	kb = 1024
+	mb = 1024 * kb
+)
+
+// The gRPC metrics follow the echo_* ones of the HTTP API, by status code and
+// full method name.
+var (
+	grpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "How many gRPC calls processed, partitioned by status code and method.",
+	}, []string{"code", "method"})
+
+	grpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "The gRPC call latencies in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"code", "method"})
+
+	grpcRequestSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Subsystem: "grpc",
+		Name:      "request_size_bytes",
+		Help:      "The gRPC request message sizes in bytes, summed over a stream.",
+		Buckets:   []float64{1 * kb, 2 * kb, 5 * kb, 10 * kb, 100 * kb, 500 * kb, 1 * mb, 2.5 * mb, 5 * mb, 10 * mb},
+	}, []string{"code", "method"})
+
+	grpcResponseSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Subsystem: "grpc",
+		Name:      "response_size_bytes",
+		Help:      "The gRPC response message sizes in bytes, summed over a stream.",
+		Buckets:   []float64{1 * kb, 2 * kb, 5 * kb, 10 * kb, 100 * kb, 500 * kb, 1 * mb, 2.5 * mb, 5 * mb, 10 * mb},
+	}, []string{"code", "method"})
+)
+
+func messageSize(msg interface{}) int {
+	if m, ok := msg.(proto.Message); ok {
+		return proto.Size(m)
+	}
+
+	return 0
+}
+
+func observeCall(method string, start time.Time, err error, bytesIn, bytesOut int) {
+	code := status.Code(err).String()
+
+	grpcRequests.WithLabelValues(code, method).Inc()
+	grpcDuration.WithLabelValues(code, method).Observe(time.Since(start).Seconds())
+	grpcRequestSize.WithLabelValues(code, method).Observe(float64(bytesIn))
+	grpcResponseSize.WithLabelValues(code, method).Observe(float64(bytesOut))
+}
+
+func metricsUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	observeCall(info.FullMethod, start, err, messageSize(req), messageSize(resp))
+
+	return resp, err
+}
+
+// measuredStream counts the bytes of the messages a stream carries.
+type measuredStream struct {
+	grpc.ServerStream
+	bytesIn  int
+	bytesOut int
+}
+
+func (s *measuredStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.bytesOut += messageSize(m)
+	}
+
+	return err
+}
+
+func (s *measuredStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.bytesIn += messageSize(m)
+	}
+
+	return err
+}
+
+func metricsStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	measured := &measuredStream{ServerStream: stream}
+	err := handler(srv, measured)
+	observeCall(info.FullMethod, start, err, measured.bytesIn, measured.bytesOut)
+
+	return err
+}
+
+// makeGRPC builds the gRPC server when GRPC_PORT is set. Its calls are
+// counted, logged and authenticated like the requests of makeRoutes, and the
+// methods in authorized need a user just like the routes marked so there.
+func (s *Server) makeGRPC() {
+	if s.config.GRPCPort == "" {
+		return
+	}
+
+	authorized := map[string]bool{
+		forumpb.UserService_UpdateUser_FullMethodName:     true,
+		forumpb.ForumService_CreateForum_FullMethodName:   true,
+		forumpb.ThreadService_CreateThread_FullMethodName: true,
+		forumpb.ThreadService_UpdateThread_FullMethodName: true,
+		forumpb.ThreadService_Vote_FullMethodName:         true,
+		forumpb.PostService_CreatePosts_FullMethodName:    true,
+		forumpb.PostService_UpdatePost_FullMethodName:     true,
+	}
+
+	s.grpcServer = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			metricsUnaryInterceptor,
+			logger.UnaryServerInterceptor(),
+			authDelivery.UnaryInterceptor(s.authUsecase, authorized),
+		),
+		grpc.ChainStreamInterceptor(
+			metricsStreamInterceptor,
+			logger.StreamServerInterceptor(),
+			authDelivery.StreamInterceptor(s.authUsecase, authorized),
+		),
+	)
+
+	forumpb.RegisterUserServiceServer(s.grpcServer, userDelivery.NewGRPCServer(s.userUsecase))
+	forumpb.RegisterForumServiceServer(s.grpcServer, forumDelivery.NewGRPCServer(s.forumUsecase, s.userUsecase))
+	forumpb.RegisterThreadServiceServer(s.grpcServer, threadDelivery.NewGRPCServer(s.threadUsecase, s.forumUsecase, s.attachmentUsecase, s.config.TrustedProxies))
+	forumpb.RegisterPostServiceServer(s.grpcServer, postDelivery.NewGRPCServer(
+		s.postUsecase,
+		s.userUsecase,
+		s.forumUsecase,
+		s.threadUsecase,
+		s.attachmentUsecase,
+		s.config.TrustedProxies,
+	))
+}
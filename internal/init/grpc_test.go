@@ -0,0 +1,222 @@
+package service_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"technopark-dbms-forum/api/forumpb"
+	"technopark-dbms-forum/internal/config"
+	"technopark-dbms-forum/internal/testenv"
+)
+
+// startGRPC starts a test server with the gRPC API on a free local port and
+// connects to it.
+func startGRPC(t *testing.T) (*testenv.Server, *grpc.ClientConn) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	listener.Close()
+
+	s := testenv.StartServer(t, func(cfg *config.Config) { cfg.GRPCPort = port })
+
+	conn, err := grpc.Dial("127.0.0.1:"+port, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return s, conn
+}
+
+func receive(t *testing.T, stream interface{ Recv() (*forumpb.Post, error) }, count int) []uint64 {
+	t.Helper()
+
+	ids := make([]uint64, 0)
+	for count < 0 || len(ids) < count {
+		post, err := stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, post.GetId())
+	}
+
+	return ids
+}
+
+func TestGRPCListPosts(t *testing.T) {
+	s, conn := startGRPC(t)
+	run(t, s, fixture)
+
+	threads := forumpb.NewThreadServiceClient(conn)
+
+	cases := []struct {
+		sort  forumpb.PostSort
+		limit uint64
+		since uint64
+		desc  bool
+		want  []uint64
+	}{
+		{forumpb.PostSort_POST_SORT_FLAT, 0, 0, false, []uint64{1, 2, 3, 4, 5, 6, 7}},
+		{forumpb.PostSort_POST_SORT_FLAT, 2, 3, false, []uint64{4, 5}},
+		{forumpb.PostSort_POST_SORT_TREE, 0, 0, false, []uint64{1, 3, 5, 4, 2, 6, 7}},
+		{forumpb.PostSort_POST_SORT_TREE, 3, 0, true, []uint64{7, 6, 2}},
+		{forumpb.PostSort_POST_SORT_PARENT_TREE, 0, 0, false, []uint64{1, 3, 5, 4, 2, 6, 7}},
+		{forumpb.PostSort_POST_SORT_PARENT_TREE, 2, 0, false, []uint64{1, 3, 5, 4, 2, 6}},
+		{forumpb.PostSort_POST_SORT_PARENT_TREE, 0, 6, true, []uint64{1, 3, 5, 4}},
+	}
+
+	for _, c := range cases {
+		name := c.sort.String() + "/limit=" + strconv.FormatUint(c.limit, 10) + "/since=" + strconv.FormatUint(c.since, 10) + "/desc=" + strconv.FormatBool(c.desc)
+
+		t.Run(name, func(t *testing.T) {
+			stream, err := threads.ListPosts(context.Background(), &forumpb.ListPostsRequest{
+				SlugOrId: "jolly-roger",
+				Limit:    c.limit,
+				Since:    c.since,
+				Sort:     c.sort,
+				Desc:     c.desc,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := receive(t, stream, -1)
+			if len(got) != len(c.want) {
+				t.Fatalf("got posts %v, want %v", got, c.want)
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Fatalf("got posts %v, want %v", got, c.want)
+				}
+			}
+		})
+	}
+}
+
+// receiveUntil reads a subscription until the post with the given id arrives
+// and returns its feed position. Posts created before the subscription may
+// still come first.
+func receiveUntil(t *testing.T, stream forumpb.ThreadService_SubscribePostsClient, id uint64) uint64 {
+	t.Helper()
+
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.GetPost().GetId() == id {
+			return event.GetPosition()
+		}
+	}
+}
+
+func TestGRPCSubscribePosts(t *testing.T) {
+	s, conn := startGRPC(t)
+	run(t, s, fixture)
+
+	threads := forumpb.NewThreadServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := threads.SubscribePosts(ctx, &forumpb.SubscribePostsRequest{SlugOrId: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run(t, s, []routeCase{
+		{"create post", http.MethodPost, "/thread/1/create", []object{{"author": "bob", "message": "p8"}}, nil, http.StatusCreated},
+	})
+	position := receiveUntil(t, stream, 8)
+
+	run(t, s, []routeCase{
+		{"create missed posts", http.MethodPost, "/thread/1/create", []object{{"author": "bob", "message": "p9"}, {"author": "carol", "message": "p10"}}, nil, http.StatusCreated},
+	})
+
+	// A new subscription resumed from the last position first gets the posts
+	// created after it.
+	resumed, err := threads.SubscribePosts(ctx, &forumpb.SubscribePostsRequest{SlugOrId: "1", LastPosition: position})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []uint64{9, 10} {
+		event, err := resumed.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.GetPost().GetId() != id || event.GetPosition() <= position {
+			t.Fatalf("got post %d at %d, want post %d after %d", event.GetPost().GetId(), event.GetPosition(), id, position)
+		}
+		position = event.GetPosition()
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	s, conn := startGRPC(t)
+	run(t, s, fixture)
+
+	users := forumpb.NewUserServiceClient(conn)
+
+	_, err := users.CreateUser(context.Background(), &forumpb.CreateUserRequest{
+		User: &forumpb.User{Nickname: "alice", Email: "bob@example.com"},
+	})
+	st := status.Convert(err)
+	if st.Code() != codes.AlreadyExists {
+		t.Fatalf("got %v, want ALREADY_EXISTS", err)
+	}
+	if details := st.Details(); len(details) != 1 || len(details[0].(*forumpb.Users).GetUsers()) != 2 {
+		t.Errorf("got details %v, want alice and bob", details)
+	}
+
+	_, err = forumpb.NewThreadServiceClient(conn).GetThread(context.Background(), &forumpb.GetThreadRequest{SlugOrId: "no-such-thread"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("got %v, want NOT_FOUND", err)
+	}
+
+	_, err = forumpb.NewPostServiceClient(conn).UpdatePost(context.Background(), &forumpb.UpdatePostRequest{Id: 1, Message: "m", Version: 1000})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("got %v, want FAILED_PRECONDITION", err)
+	}
+}
+
+func TestGRPCForwardedAddress(t *testing.T) {
+	s, conn := startGRPC(t)
+	run(t, s, fixture)
+	alice := []string{"Authorization", s.Login(t, "alice", "parrot")}
+	run(t, s, []routeCase{
+		{"set limit", http.MethodPost, "/forum/pirates/limits", object{"action": "vote", "perMinute": 1, "burst": 1}, alice, http.StatusOK},
+	})
+
+	threads := forumpb.NewThreadServiceClient(conn)
+	from := func(ip string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "x-forwarded-for", ip)
+	}
+
+	if _, err := threads.Vote(from("203.0.113.1"), &forumpb.VoteRequest{SlugOrId: "1", Nickname: "alice", Voice: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// No proxy is trusted, so both votes count on the peer address.
+	_, err := threads.Vote(from("203.0.113.2"), &forumpb.VoteRequest{SlugOrId: "1", Nickname: "bob", Voice: 1})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("got %v, want RESOURCE_EXHAUSTED", err)
+	}
+}
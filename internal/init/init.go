@@ -2,8 +2,22 @@ package service
 
 import (
 	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
 
+	"technopark-dbms-forum/api"
+	"technopark-dbms-forum/internal/config"
 	logger "technopark-dbms-forum/pkg"
+	"technopark-dbms-forum/pkg/models"
+
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
+	authRepository "technopark-dbms-forum/internal/auth/repository"
+	authUsecase "technopark-dbms-forum/internal/auth/usecase"
+
+	adminDelivery "technopark-dbms-forum/internal/admin/delivery"
+	adminRepository "technopark-dbms-forum/internal/admin/repository"
 
 	systemDelivery "technopark-dbms-forum/internal/system/delivery"
 	systemRepository "technopark-dbms-forum/internal/system/repository"
@@ -22,6 +36,47 @@ import (
 
 	forumRepository "technopark-dbms-forum/internal/forums/repository"
 
+	eventDelivery "technopark-dbms-forum/internal/events/delivery"
+	eventRepository "technopark-dbms-forum/internal/events/repository"
+	eventUsecase "technopark-dbms-forum/internal/events/usecase"
+
+	notificationDelivery "technopark-dbms-forum/internal/notifications/delivery"
+	notificationRepository "technopark-dbms-forum/internal/notifications/repository"
+	notificationUsecase "technopark-dbms-forum/internal/notifications/usecase"
+
+	counterDelivery "technopark-dbms-forum/internal/counters/delivery"
+	counterRepository "technopark-dbms-forum/internal/counters/repository"
+	counterUsecase "technopark-dbms-forum/internal/counters/usecase"
+
+	archiveDelivery "technopark-dbms-forum/internal/archive/delivery"
+	archiveRepository "technopark-dbms-forum/internal/archive/repository"
+	archiveUsecase "technopark-dbms-forum/internal/archive/usecase"
+
+	attachmentDelivery "technopark-dbms-forum/internal/attachments/delivery"
+	attachmentRepository "technopark-dbms-forum/internal/attachments/repository"
+	attachmentUsecase "technopark-dbms-forum/internal/attachments/usecase"
+
+	filterDelivery "technopark-dbms-forum/internal/filters/delivery"
+	filterRepository "technopark-dbms-forum/internal/filters/repository"
+	filterUsecase "technopark-dbms-forum/internal/filters/usecase"
+
+	limitDelivery "technopark-dbms-forum/internal/limits/delivery"
+	limitRepository "technopark-dbms-forum/internal/limits/repository"
+	limitUsecase "technopark-dbms-forum/internal/limits/usecase"
+
+	roleDelivery "technopark-dbms-forum/internal/roles/delivery"
+	roleRepository "technopark-dbms-forum/internal/roles/repository"
+	roleUsecase "technopark-dbms-forum/internal/roles/usecase"
+
+	contractDelivery "technopark-dbms-forum/internal/contract/delivery"
+	contractUsecase "technopark-dbms-forum/internal/contract/usecase"
+
+	graphqlDelivery "technopark-dbms-forum/internal/graphql/delivery"
+	graphqlUsecase "technopark-dbms-forum/internal/graphql/usecase"
+
+	idempotencyDelivery "technopark-dbms-forum/internal/idempotency/delivery"
+	idempotencyRepository "technopark-dbms-forum/internal/idempotency/repository"
+
 	threadUsecase "technopark-dbms-forum/internal/threads/usecase"
 
 	postUsecase "technopark-dbms-forum/internal/posts/usecase"
@@ -31,15 +86,48 @@ import (
 	forumUsecase "technopark-dbms-forum/internal/forums/usecase"
 
 	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"google.golang.org/grpc"
+)
+
+const (
+	idempotencyTTL          = 24 * time.Hour
+	idempotencyCleanupEvery = time.Hour
+	eventDispatchEvery      = time.Second
+	rateBucketIdle          = 24 * time.Hour
+	rateBucketCleanupEvery  = time.Hour
+	fingerprintCleanupEvery = time.Hour
+	// attachmentGrace keeps unreferenced attachment objects for longer than
+	// any upload takes.
+	attachmentGrace        = time.Hour
+	attachmentCleanupEvery = time.Hour
+	renderCacheSize        = 10000
+	// grpcStopTimeout bounds how long Start waits for gRPC calls in flight
+	// once echo is closed; streams may never end on their own.
+	grpcStopTimeout = 10 * time.Second
 )
 
 type Server struct {
-	echo *echo.Echo
+	echo       *echo.Echo
+	adminEcho  *echo.Echo
+	grpcServer *grpc.Server
+	config     *config.Config
 
 	forumUsecase  *forumUsecase.ForumUsecase
 	userUsecase   *userUsecase.UserUsecase
 	postUsecase   *postUsecase.PostUsecase
 	threadUsecase *threadUsecase.ThreadUsecase
+	eventUsecase  *eventUsecase.EventUsecase
+
+	notificationUsecase *notificationUsecase.NotificationUsecase
+	authUsecase         *authUsecase.AuthUsecase
+	roleUsecase         *roleUsecase.RoleUsecase
+	limitUsecase        *limitUsecase.LimitUsecase
+	filterUsecase       *filterUsecase.FilterUsecase
+	attachmentUsecase   *attachmentUsecase.AttachmentUsecase
+	archiveUsecase      *archiveUsecase.ArchiveUsecase
+	counterUsecase      *counterUsecase.CounterUsecase
+	graphqlUsecase      *graphqlUsecase.GraphQLUsecase
 
 	forumRepo  *forumRepository.Postgres
 	userRepo   *userRepository.Postgres
@@ -47,19 +135,65 @@ type Server struct {
 	threadRepo *threadRepository.Postgres
 	systemRepo *systemRepository.Postgres
 
+	idempotencyRepo  *idempotencyRepository.Postgres
+	eventRepo        *eventRepository.Postgres
+	notificationRepo *notificationRepository.Postgres
+	authRepo         *authRepository.Postgres
+	roleRepo         *roleRepository.Postgres
+	adminRepo        *adminRepository.Postgres
+	limitRepo        *limitRepository.Postgres
+	filterRepo       *filterRepository.Postgres
+	attachmentRepo   *attachmentRepository.Postgres
+	attachmentStore  attachmentRepository.Storage
+	archiveRepo      *archiveRepository.Postgres
+	counterRepo      *counterRepository.Postgres
+	postsListener    *pq.Listener
+
 	forumHandler  *forumDelivery.Handler
 	userHanlder   *userDelivery.Handler
 	postHandler   *postDelivery.Handler
 	threadHandler *threadDelivery.Handler
 	systemHandler *systemDelivery.Handler
+	eventHandler  *eventDelivery.Handler
+
+	notificationHandler *notificationDelivery.Handler
+	authHandler         *authDelivery.Handler
+	roleHandler         *roleDelivery.Handler
+	adminHandler        *adminDelivery.Handler
+	limitHandler        *limitDelivery.Handler
+	filterHandler       *filterDelivery.Handler
+	attachmentHandler   *attachmentDelivery.Handler
+	archiveHandler      *archiveDelivery.Handler
+	counterHandler      *counterDelivery.Handler
+	graphqlHandler      *graphqlDelivery.Handler
+
+	eventDispatcher *eventUsecase.Dispatcher
+	postStream      *threadUsecase.PostStream
+	renderer        *postUsecase.Renderer
+	eventSinks      []eventUsecase.Sink
+
+	contractChecker   *contractUsecase.Checker
+	contractReporters []contractUsecase.Reporter
 }
 
-func NewServer(newEcho *echo.Echo) *Server {
+func NewServer(newEcho *echo.Echo, cfg *config.Config) *Server {
 	return &Server{
-		echo: newEcho,
+		echo:   newEcho,
+		config: cfg,
 	}
 }
 
+// AddEventSink registers a sink for the event outbox. It must be called before Start.
+func (s *Server) AddEventSink(sink eventUsecase.Sink) {
+	s.eventSinks = append(s.eventSinks, sink)
+}
+
+// AddContractReporter registers a reporter for contract violations. It must be
+// called before Start and only takes effect when CONTRACT_CHECK is not off.
+func (s *Server) AddContractReporter(r contractUsecase.Reporter) {
+	s.contractReporters = append(s.contractReporters, r)
+}
+
 func (s *Server) Start(addr, pgURL string) error {
 	if s.echo == nil {
 		return errors.New("initialize server first")
@@ -68,21 +202,149 @@ func (s *Server) Start(addr, pgURL string) error {
 		return errors.New("initialize server error: " + err.Error())
 	}
 
-	return s.echo.Start(addr)
+	if s.adminEcho != nil {
+		go func() {
+			if err := s.adminEcho.Start(":" + s.config.AdminPort); err != http.ErrServerClosed {
+				s.adminEcho.Logger.Fatal(err)
+			}
+		}()
+	}
+	if s.grpcServer != nil {
+		listener, err := net.Listen("tcp", ":"+s.config.GRPCPort)
+		if err != nil {
+			return errors.New("grpc listen error: " + err.Error())
+		}
+		go func() {
+			if err := s.grpcServer.Serve(listener); err != nil {
+				s.echo.Logger.Fatal(err)
+			}
+		}()
+	}
+
+	err := s.echo.Start(addr)
+	s.stop()
+
+	return err
+}
+
+// stop closes the listeners started next to echo once echo is closed.
+func (s *Server) stop() {
+	if s.adminEcho != nil {
+		s.adminEcho.Close()
+	}
+	if s.grpcServer == nil {
+		return
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(grpcStopTimeout):
+		s.grpcServer.Stop()
+	}
 }
 
 func (s *Server) init(pgURL string) error {
 	if err := s.makeRepositories(pgURL); err != nil {
 		return err
 	}
+	if s.config.ContractCheck != config.ContractOff {
+		if err := s.makeContractChecker(); err != nil {
+			return err
+		}
+	}
 
 	s.makeUseCases()
+	if err := s.makeGraphQL(); err != nil {
+		return err
+	}
 	s.makeHandlers()
+	s.makeIPExtractor()
 	s.makeRoutes()
+	s.makeAdminRoutes()
+	s.makeGRPC()
+
+	go s.cleanIdempotencyKeys()
+	go s.cleanRateBuckets()
+	go s.cleanAttachments()
+	if s.config.FilterDuplicateWindow > 0 {
+		go s.cleanFingerprints()
+	}
+	if s.config.CounterReconcileEvery > 0 {
+		go s.reconcileCounters()
+	}
+	go s.eventDispatcher.Run()
+	go s.postStream.Run()
 
 	return nil
 }
 
+func (s *Server) cleanIdempotencyKeys() {
+	ticker := time.NewTicker(idempotencyCleanupEvery)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.idempotencyRepo.DeleteExpired(); err != nil {
+			s.echo.Logger.Error(err)
+		}
+	}
+}
+
+func (s *Server) cleanRateBuckets() {
+	ticker := time.NewTicker(rateBucketCleanupEvery)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.limitUsecase.DeleteIdleBuckets(rateBucketIdle); err != nil {
+			s.echo.Logger.Error(err)
+		}
+	}
+}
+
+func (s *Server) cleanFingerprints() {
+	ticker := time.NewTicker(fingerprintCleanupEvery)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.filterUsecase.DeleteFingerprints(s.config.FilterDuplicateWindow); err != nil {
+			s.echo.Logger.Error(err)
+		}
+	}
+}
+
+func (s *Server) cleanAttachments() {
+	ticker := time.NewTicker(attachmentCleanupEvery)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.attachmentUsecase.DeleteOrphans(attachmentGrace); err != nil {
+			s.echo.Logger.Error(err)
+		}
+	}
+}
+
+func (s *Server) reconcileCounters() {
+	ticker := time.NewTicker(s.config.CounterReconcileEvery)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		report, err := s.counterUsecase.Reconcile(s.config.CounterReconcileFix)
+		if err != nil {
+			s.echo.Logger.Error(err)
+			continue
+		}
+		for _, counter := range report.Counters {
+			if counter.Drifted > 0 {
+				s.echo.Logger.Warnf("counters: %s drifted in %d rows by %d, %d fixed", counter.Counter, counter.Drifted, counter.Drift, counter.Fixed)
+			}
+		}
+	}
+}
+
 func (s *Server) makeRepositories(url string) (err error) {
 	if s.forumRepo, err = forumRepository.NewPostgres(url); err != nil {
 		return err
@@ -99,48 +361,278 @@ func (s *Server) makeRepositories(url string) (err error) {
 	if s.systemRepo, err = systemRepository.NewPostgres(url); err != nil {
 		return err
 	}
+	if s.idempotencyRepo, err = idempotencyRepository.NewPostgres(url); err != nil {
+		return err
+	}
+	if s.eventRepo, err = eventRepository.NewPostgres(url); err != nil {
+		return err
+	}
+	if s.postsListener, err = threadRepository.NewPostsListener(url); err != nil {
+		return err
+	}
+	if s.notificationRepo, err = notificationRepository.NewPostgres(url); err != nil {
+		return err
+	}
+	if s.authRepo, err = authRepository.NewPostgres(url); err != nil {
+		return err
+	}
+	if s.roleRepo, err = roleRepository.NewPostgres(url); err != nil {
+		return err
+	}
+	if s.adminRepo, err = adminRepository.NewPostgres(url); err != nil {
+		return err
+	}
+	if s.limitRepo, err = limitRepository.NewPostgres(url); err != nil {
+		return err
+	}
+	if s.filterRepo, err = filterRepository.NewPostgres(url); err != nil {
+		return err
+	}
+	if s.attachmentRepo, err = attachmentRepository.NewPostgres(url); err != nil {
+		return err
+	}
+	if s.config.AttachmentS3Bucket != "" {
+		if s.attachmentStore, err = attachmentRepository.NewS3(s.config.AttachmentS3Endpoint, s.config.AttachmentS3Bucket, s.config.AttachmentS3Region, s.config.AttachmentS3AccessKey, s.config.AttachmentS3SecretKey); err != nil {
+			return err
+		}
+	} else if s.attachmentStore, err = attachmentRepository.NewFilesystem(s.config.AttachmentDir); err != nil {
+		return err
+	}
+	if s.archiveRepo, err = archiveRepository.NewPostgres(url); err != nil {
+		return err
+	}
+	if s.counterRepo, err = counterRepository.NewPostgres(url); err != nil {
+		return err
+	}
 
 	return nil
 }
 
 func (s *Server) makeUseCases() {
-	s.forumUsecase = forumUsecase.NewForumUsecase(s.forumRepo)
-	s.userUsecase = userUsecase.NewUserUsecase(s.userRepo)
-	s.postUsecase = postUsecase.NewPostUsecase(s.postRepo)
-	s.threadUsecase = threadUsecase.NewThreadUsecase(s.threadRepo, s.postRepo)
+	s.roleUsecase = roleUsecase.NewRoleUsecase(s.roleRepo)
+	s.limitUsecase = limitUsecase.NewLimitUsecase(s.limitRepo, s.roleUsecase, s.config.RateLimitPerMinute, s.config.RateLimitBurst)
+	s.filterUsecase = s.makeFilters()
+	s.forumUsecase = forumUsecase.NewForumUsecase(s.forumRepo, forumUsecase.NewForumHub())
+	s.userUsecase = userUsecase.NewUserUsecase(s.userRepo, s.roleUsecase)
+	s.postUsecase = postUsecase.NewPostUsecase(s.postRepo, s.roleUsecase, s.filterUsecase)
+	s.archiveUsecase = archiveUsecase.NewArchiveUsecase(s.archiveRepo, s.roleUsecase)
+	s.counterUsecase = counterUsecase.NewCounterUsecase(s.counterRepo, s.config.CounterReconcileBatch)
+	s.renderer = postUsecase.NewRenderer(s.postRepo, renderCacheSize)
+	s.attachmentUsecase = attachmentUsecase.NewAttachmentUsecase(
+		s.attachmentRepo,
+		s.attachmentStore,
+		s.postRepo,
+		s.roleUsecase,
+		s.config.AttachmentMaxSize,
+		s.config.AttachmentTypes,
+	)
+	s.postStream = threadUsecase.NewPostStream(s.threadRepo, s.eventRepo, s.postsListener, s.echo.Logger)
+	s.threadUsecase = threadUsecase.NewThreadUsecase(s.threadRepo, s.postRepo, s.postStream, s.roleUsecase, s.limitUsecase, s.filterUsecase)
+	s.eventUsecase = eventUsecase.NewEventUsecase(s.eventRepo)
+	s.notificationUsecase = notificationUsecase.NewNotificationUsecase(s.notificationRepo)
+	s.authUsecase = authUsecase.NewAuthUsecase(s.authRepo, s.roleUsecase, s.config.AuthSecret, s.config.AuthTokenTTL, s.config.AuthMode == config.AuthOpen)
+
+	s.eventDispatcher = eventUsecase.NewDispatcher(s.eventRepo, eventDispatchEvery, s.echo.Logger)
+	for _, sink := range s.eventSinks {
+		s.eventDispatcher.AddSink(sink)
+	}
+}
+
+func (s *Server) makeContractChecker() (err error) {
+	if s.contractChecker, err = contractUsecase.NewChecker(api.Spec); err != nil {
+		return errors.New("api contract: " + err.Error())
+	}
+
+	// Undocumented routes are logged once each; the metric counts every
+	// request to them.
+	var undocumented sync.Map
+	s.contractChecker.AddReporter(func(violation *models.ContractViolation) {
+		if violation.In == models.ContractRoute {
+			if _, logged := undocumented.LoadOrStore(violation.String(), true); logged {
+				return
+			}
+		}
+		s.echo.Logger.Warnf("contract: %s", violation)
+	})
+	for _, r := range s.contractReporters {
+		s.contractChecker.AddReporter(r)
+	}
+
+	return nil
+}
+
+func (s *Server) makeGraphQL() (err error) {
+	if s.graphqlUsecase, err = graphqlUsecase.NewGraphQLUsecase(
+		s.userUsecase,
+		s.forumUsecase,
+		s.threadUsecase,
+		s.postUsecase,
+		s.attachmentUsecase,
+		s.config.GraphQLMaxDepth,
+		s.config.GraphQLMaxComplexity,
+	); err != nil {
+		return errors.New("graphql schema: " + err.Error())
+	}
+
+	return nil
+}
+
+// makeFilters builds the content filter chain; cheap rewrites run before the
+// duplicate check so that it fingerprints the stored text.
+func (s *Server) makeFilters() *filterUsecase.FilterUsecase {
+	filters := filterUsecase.NewFilterUsecase(s.filterRepo, s.roleUsecase)
+	cfg := s.config
+
+	if cfg.FilterMaxLength > 0 {
+		filters.AddFilter(filterUsecase.NewLengthFilter(cfg.FilterMaxLength, cfg.FilterMaxLengthAction))
+	}
+	if len(cfg.FilterBlocklist) > 0 {
+		filters.AddFilter(filterUsecase.NewBlocklistFilter(cfg.FilterBlocklist, cfg.FilterBlocklistAction))
+	}
+	if cfg.FilterMaxLinks > 0 {
+		filters.AddFilter(filterUsecase.NewLinkFilter(cfg.FilterMaxLinks, cfg.FilterLinksAction))
+	}
+	if cfg.FilterDuplicateWindow > 0 {
+		filters.AddFilter(filterUsecase.NewDuplicateFilter(s.filterRepo, cfg.FilterDuplicateWindow, cfg.FilterDuplicateAction))
+	}
+
+	return filters
 }
 
 func (s *Server) makeHandlers() {
 	s.forumHandler = forumDelivery.NewHandler(s.forumUsecase, s.userUsecase)
 	s.userHanlder = userDelivery.NewHandler(s.userUsecase)
-	s.postHandler = postDelivery.NewHandler(s.postUsecase, s.userUsecase, s.forumUsecase, s.threadUsecase)
-	s.threadHandler = threadDelivery.NewHandler(s.threadUsecase, s.forumUsecase)
+	s.postHandler = postDelivery.NewHandler(s.postUsecase, s.userUsecase, s.forumUsecase, s.threadUsecase, s.renderer, s.attachmentUsecase)
+	s.threadHandler = threadDelivery.NewHandler(s.threadUsecase, s.forumUsecase, s.renderer, s.attachmentUsecase)
 	s.systemHandler = systemDelivery.NewHandler(s.systemRepo)
+	s.eventHandler = eventDelivery.NewHandler(s.eventUsecase)
+	s.notificationHandler = notificationDelivery.NewHandler(s.notificationUsecase)
+	s.authHandler = authDelivery.NewHandler(s.authUsecase)
+	s.roleHandler = roleDelivery.NewHandler(s.roleUsecase)
+	s.adminHandler = adminDelivery.NewHandler(s.adminRepo, s.systemRepo)
+	s.limitHandler = limitDelivery.NewHandler(s.limitUsecase)
+	s.filterHandler = filterDelivery.NewHandler(s.filterUsecase)
+	s.attachmentHandler = attachmentDelivery.NewHandler(s.attachmentUsecase)
+	s.archiveHandler = archiveDelivery.NewHandler(s.archiveUsecase)
+	s.counterHandler = counterDelivery.NewHandler(s.counterUsecase)
+	s.graphqlHandler = graphqlDelivery.NewHandler(s.graphqlUsecase)
+}
+
+// makeIPExtractor only believes X-Forwarded-For from the trusted proxies, so
+// clients can't choose the address their rate limits are counted on.
+func (s *Server) makeIPExtractor() {
+	if len(s.config.TrustedProxies) == 0 {
+		s.echo.IPExtractor = echo.ExtractIPDirect()
+		return
+	}
+
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, network := range s.config.TrustedProxies {
+		options = append(options, echo.TrustIPRange(network))
+	}
+	s.echo.IPExtractor = echo.ExtractIPFromXFFHeader(options...)
 }
 
 func (s *Server) makeRoutes() {
 	api := s.echo.Group("/api")
 	api.Use(logger.Middleware())
+	if s.contractChecker != nil {
+		api.Use(contractDelivery.Middleware(s.contractChecker, s.config.ContractCheck == config.ContractStrict))
+	}
+	api.Use(authDelivery.Middleware(s.authUsecase))
+
+	authorized := authDelivery.Required(s.authUsecase)
+	idempotent := idempotencyDelivery.Middleware(s.idempotencyRepo, idempotencyTTL)
+
+	api.POST("/auth/login", s.authHandler.Login)
 
-	api.POST("/forum/create", s.forumHandler.Create)
+	api.POST("/forum/create", s.forumHandler.Create, authorized, idempotent)
 	api.GET("/forum/:slug/details", s.forumHandler.GetDetails)
-	api.POST("/forum/:slug/create", s.threadHandler.Create)
+	api.POST("/forum/:slug/create", s.threadHandler.Create, authorized, idempotent)
 	api.GET("/forum/:slug/users", s.forumHandler.GetUsers)
 	api.GET("/forum/:slug/threads", s.forumHandler.GetThreads)
+	api.GET("/forum/:slug/ws", s.forumHandler.Activity)
+	api.POST("/forum/:slug/follow", s.forumHandler.Follow, authorized)
+	api.DELETE("/forum/:slug/follow", s.forumHandler.Unfollow, authorized)
+	api.GET("/forum/:slug/moderators", s.roleHandler.GetModerators)
+	api.POST("/forum/:slug/moderators/:nickname", s.roleHandler.Grant, authorized)
+	api.DELETE("/forum/:slug/moderators/:nickname", s.roleHandler.Revoke, authorized)
+	api.GET("/forum/:slug/bans", s.limitHandler.GetBans)
+	api.POST("/forum/:slug/bans", s.limitHandler.Ban, authorized)
+	api.DELETE("/forum/:slug/bans/:nickname", s.limitHandler.Unban, authorized)
+	api.GET("/forum/:slug/limits", s.limitHandler.GetLimits)
+	api.POST("/forum/:slug/limits", s.limitHandler.SetLimit, authorized)
+	api.DELETE("/forum/:slug/limits/:action", s.limitHandler.DeleteLimit, authorized)
+	api.GET("/forum/:slug/queue", s.filterHandler.GetQueue, authorized)
+	api.DELETE("/forum/:slug/queue/:id", s.filterHandler.Dismiss, authorized)
+	api.GET("/forum/:slug/export", s.archiveHandler.Export, authorized)
 
 	api.GET("/post/:id/details", s.postHandler.GetInfo)
-	api.POST("/post/:id/details", s.postHandler.Update)
+	api.POST("/post/:id/details", s.postHandler.Update, authorized)
+	api.POST("/post/:id/moderate", s.postHandler.Moderate, authorized)
+	api.POST("/post/:id/attachments", s.attachmentHandler.Upload, authorized)
 
-	api.POST("/thread/:slug_or_id/create", s.threadHandler.CreatePosts)
+	api.GET("/attachment/:id", s.attachmentHandler.Get)
+
+	api.POST("/thread/:slug_or_id/create", s.threadHandler.CreatePosts, authorized, idempotent)
 	api.GET("/thread/:slug_or_id/details", s.threadHandler.GetDetails)
-	api.POST("/thread/:slug_or_id/details", s.threadHandler.Update)
+	api.POST("/thread/:slug_or_id/details", s.threadHandler.Update, authorized)
 	api.GET("/thread/:slug_or_id/posts", s.threadHandler.GetPosts)
-	api.POST("/thread/:slug_or_id/vote", s.threadHandler.Vote)
-
-	api.POST("/user/:nickname/create", s.userHanlder.Create)
+	api.POST("/thread/:slug_or_id/vote", s.threadHandler.Vote, authorized, idempotent)
+	api.GET("/thread/:slug_or_id/history", s.threadHandler.GetHistory)
+	api.POST("/thread/:slug_or_id/slug", s.threadHandler.ChangeSlug, authorized)
+	api.POST("/thread/:slug_or_id/moderate", s.threadHandler.Moderate, authorized)
+	api.GET("/thread/:slug_or_id/stream", s.threadHandler.Stream)
+	api.POST("/thread/:slug_or_id/subscribe", s.threadHandler.Subscribe, authorized)
+	api.DELETE("/thread/:slug_or_id/subscribe", s.threadHandler.Unsubscribe, authorized)
+
+	api.POST("/user/:nickname/create", s.userHanlder.Create, idempotent)
 	api.GET("/user/:nickname/profile", s.userHanlder.Get)
-	api.POST("/user/:nickname/profile", s.userHanlder.Update)
+	api.POST("/user/:nickname/profile", s.userHanlder.Update, authorized)
+	api.POST("/user/:nickname/password", s.authHandler.SetPassword, authorized)
+	api.GET("/user/:nickname/notifications", s.notificationHandler.Get, authorized)
+	api.POST("/user/:nickname/notifications/read", s.notificationHandler.MarkRead, authorized)
+	api.GET("/user/:nickname/feed", s.forumHandler.GetFeed)
 
 	api.GET("/service/status", s.systemHandler.GetInfo)
-	api.POST("/service/clear", s.systemHandler.Clear)
+
+	api.GET("/events", s.eventHandler.GetAfter)
+
+	api.GET("/graphql", s.graphqlHandler.Query)
+	api.POST("/graphql", s.graphqlHandler.Query)
+}
+
+// makeAdminRoutes mounts the admin API on its own listener when ADMIN_PORT is
+// set, and under /api/admin of the main server otherwise. Only the latter
+// matches the paths api/swagger.yml gives it, so only there is it checked
+// against the spec.
+func (s *Server) makeAdminRoutes() {
+	var admin *echo.Group
+	if s.config.AdminPort != "" {
+		s.adminEcho = echo.New()
+		s.adminEcho.Logger = s.echo.Logger
+		s.adminEcho.IPExtractor = s.echo.IPExtractor
+		admin = s.adminEcho.Group("/admin")
+		admin.Use(logger.Middleware())
+	} else {
+		admin = s.echo.Group("/api/admin")
+		admin.Use(logger.Middleware())
+		if s.contractChecker != nil {
+			admin.Use(contractDelivery.Middleware(s.contractChecker, s.config.ContractCheck == config.ContractStrict))
+		}
+	}
+	admin.Use(adminDelivery.Middleware(s.adminRepo, s.authUsecase, s.roleUsecase, s.config.AdminToken))
+
+	admin.POST("/clear", s.adminHandler.Clear)
+	admin.DELETE("/forum/:slug", s.adminHandler.PurgeForum)
+	admin.POST("/forum/import", s.archiveHandler.Import)
+	admin.GET("/counters/drift", s.counterHandler.GetDrift)
+	admin.POST("/counters/reconcile", s.counterHandler.Reconcile)
+	admin.GET("/stats", s.adminHandler.GetStats)
+	admin.GET("/audit", s.adminHandler.GetAudit)
+	admin.POST("/admins/:nickname", s.adminHandler.AddSiteAdmin)
+	admin.DELETE("/admins/:nickname", s.adminHandler.RemoveSiteAdmin)
+	admin.GET("/bans", s.limitHandler.GetBans)
+	admin.POST("/bans", s.limitHandler.AdminBan)
+	admin.DELETE("/bans/:nickname", s.limitHandler.AdminUnban)
 }
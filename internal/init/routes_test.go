@@ -0,0 +1,851 @@
+package service_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"io/fs"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	adminDelivery "technopark-dbms-forum/internal/admin/delivery"
+	attachmentRepository "technopark-dbms-forum/internal/attachments/repository"
+	attachmentUsecase "technopark-dbms-forum/internal/attachments/usecase"
+	"technopark-dbms-forum/internal/config"
+	counterRepository "technopark-dbms-forum/internal/counters/repository"
+	counterUsecase "technopark-dbms-forum/internal/counters/usecase"
+	"technopark-dbms-forum/internal/testenv"
+	"technopark-dbms-forum/pkg/models"
+)
+
+func TestMain(m *testing.M) {
+	testenv.Main(m)
+}
+
+type object = map[string]interface{}
+
+type routeCase struct {
+	name    string
+	method  string
+	path    string
+	body    interface{}
+	headers []string
+	status  int
+}
+
+// run sends the cases in order; later cases rely on what earlier ones created.
+func run(t *testing.T, s *testenv.Server, cases []routeCase) {
+	t.Helper()
+
+	for _, c := range cases {
+		resp := s.Do(t, c.method, c.path, c.body, c.headers...)
+		if resp.Status != c.status {
+			t.Errorf("%s: %s %s: got %d, want %d: %s", c.name, c.method, c.path, resp.Status, c.status, bytes.TrimSpace(resp.Body))
+		}
+	}
+}
+
+// fixture creates three users with the passwords "parrot", "anchor" and
+// "galley", forum "pirates", thread 1 "jolly-roger" and
+// the slugless thread 2, and posts 1 to 7 in thread 1 shaped as
+//
+//	1        2        7
+//	├── 3    └── 6
+//	│   └── 5
+//	└── 4
+var fixture = []routeCase{
+	{"create alice", http.MethodPost, "/user/alice/create", object{"email": "alice@example.com", "fullname": "Alice", "about": "captain", "password": "parrot"}, nil, http.StatusCreated},
+	{"create bob", http.MethodPost, "/user/bob/create", object{"email": "bob@example.com", "fullname": "Bob", "about": "mate", "password": "anchor"}, nil, http.StatusCreated},
+	{"create carol", http.MethodPost, "/user/carol/create", object{"email": "carol@example.com", "fullname": "Carol", "about": "cook", "password": "galley"}, nil, http.StatusCreated},
+	{"create forum", http.MethodPost, "/forum/create", object{"title": "Pirates", "user": "alice", "slug": "pirates"}, nil, http.StatusCreated},
+	{"create thread", http.MethodPost, "/forum/pirates/create", object{"title": "Flag", "author": "alice", "message": "Which flag?", "slug": "jolly-roger"}, nil, http.StatusCreated},
+	{"create thread without slug", http.MethodPost, "/forum/pirates/create", object{"title": "Rum", "author": "bob", "message": "Out of rum"}, nil, http.StatusCreated},
+	{"create roots", http.MethodPost, "/thread/jolly-roger/create", []object{{"author": "alice", "message": "p1"}, {"author": "bob", "message": "p2"}}, nil, http.StatusCreated},
+	{"create replies", http.MethodPost, "/thread/1/create", []object{{"author": "bob", "message": "p3", "parent": 1}, {"author": "carol", "message": "p4", "parent": 1}}, nil, http.StatusCreated},
+	{"create nested replies", http.MethodPost, "/thread/1/create", []object{{"author": "alice", "message": "p5", "parent": 3}, {"author": "alice", "message": "p6", "parent": 2}}, nil, http.StatusCreated},
+	{"create last root", http.MethodPost, "/thread/jolly-roger/create", []object{{"author": "carol", "message": "p7"}}, nil, http.StatusCreated},
+}
+
+func multipartFile(t *testing.T, name string, content []byte) (*bytes.Buffer, string) {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	if content != nil {
+		part, err := w.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(content)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return body, w.FormDataContentType()
+}
+
+func TestRoutes(t *testing.T) {
+	s := testenv.StartServer(t)
+	run(t, s, fixture)
+	alice := []string{"Authorization", s.Login(t, "alice", "parrot")}
+	bob := []string{"Authorization", s.Login(t, "bob", "anchor")}
+	carol := []string{"Authorization", s.Login(t, "carol", "galley")}
+	// Alice owns the forum and carol is a site admin, who also sees forums
+	// that don't exist.
+	run(t, s, []routeCase{
+		{"make carol admin", http.MethodPost, "/admin/admins/carol", nil, []string{"Authorization", "Bearer " + testenv.AdminToken}, http.StatusCreated},
+	})
+
+	text, textType := multipartFile(t, "notes.txt", []byte("plain notes"))
+	missing, missingType := multipartFile(t, "notes.txt", []byte("plain notes"))
+	pdf, pdfType := multipartFile(t, "map.pdf", []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n"))
+	empty, emptyType := multipartFile(t, "", nil)
+
+	groups := []struct {
+		name  string
+		cases []routeCase
+	}{
+		{"users", []routeCase{
+			{"duplicate", http.MethodPost, "/user/alice/create", object{"email": "alice@example.com", "fullname": "Alice", "about": "captain"}, nil, http.StatusConflict},
+			{"profile", http.MethodGet, "/user/alice/profile", nil, nil, http.StatusOK},
+			{"missing profile", http.MethodGet, "/user/nobody/profile", nil, nil, http.StatusNotFound},
+			{"update", http.MethodPost, "/user/alice/profile", object{"about": "admiral"}, nil, http.StatusOK},
+			{"update missing", http.MethodPost, "/user/nobody/profile", object{"about": "ghost"}, nil, http.StatusNotFound},
+			{"update taken email", http.MethodPost, "/user/bob/profile", object{"email": "alice@example.com"}, nil, http.StatusConflict},
+			{"update stale", http.MethodPost, "/user/bob/profile", object{"about": "late"}, []string{"If-Match", `"999"`}, http.StatusPreconditionFailed},
+			{"update bad tag", http.MethodPost, "/user/bob/profile", object{"about": "late"}, []string{"If-Match", `"x"`}, http.StatusPreconditionFailed},
+			{"update weak tag", http.MethodPost, "/user/bob/profile", object{"about": "late"}, []string{"If-Match", `W/"1"`}, http.StatusPreconditionFailed},
+			{"feed", http.MethodGet, "/user/bob/feed", nil, nil, http.StatusOK},
+			{"feed bad limit", http.MethodGet, "/user/bob/feed?limit=many", nil, nil, http.StatusBadRequest},
+			{"feed bad since", http.MethodGet, "/user/bob/feed?since=yesterday", nil, nil, http.StatusBadRequest},
+			{"feed bad desc", http.MethodGet, "/user/bob/feed?desc=maybe", nil, nil, http.StatusBadRequest},
+			{"missing feed", http.MethodGet, "/user/nobody/feed", nil, nil, http.StatusNotFound},
+		}},
+		{"forums", []routeCase{
+			{"duplicate", http.MethodPost, "/forum/create", object{"title": "Pirates", "user": "alice", "slug": "pirates"}, nil, http.StatusConflict},
+			{"missing author", http.MethodPost, "/forum/create", object{"title": "Ghosts", "user": "nobody", "slug": "ghosts"}, nil, http.StatusNotFound},
+			{"details", http.MethodGet, "/forum/pirates/details", nil, nil, http.StatusOK},
+			{"details not modified", http.MethodGet, "/forum/pirates/details", nil, []string{"If-None-Match", "*"}, http.StatusNotModified},
+			{"missing details", http.MethodGet, "/forum/nowhere/details", nil, nil, http.StatusNotFound},
+			{"threads", http.MethodGet, "/forum/pirates/threads?limit=1&desc=true", nil, nil, http.StatusOK},
+			{"missing threads", http.MethodGet, "/forum/nowhere/threads", nil, nil, http.StatusNotFound},
+			{"users", http.MethodGet, "/forum/pirates/users", nil, nil, http.StatusOK},
+			{"missing users", http.MethodGet, "/forum/nowhere/users", nil, nil, http.StatusNotFound},
+			{"activity without upgrade", http.MethodGet, "/forum/pirates/ws", nil, nil, http.StatusBadRequest},
+			{"missing activity", http.MethodGet, "/forum/nowhere/ws", nil, nil, http.StatusNotFound},
+			{"follow", http.MethodPost, "/forum/pirates/follow", object{"nickname": "bob"}, nil, http.StatusCreated},
+			{"follow again", http.MethodPost, "/forum/pirates/follow", object{"nickname": "bob"}, nil, http.StatusOK},
+			{"follow missing forum", http.MethodPost, "/forum/nowhere/follow", object{"nickname": "bob"}, nil, http.StatusNotFound},
+			{"follow missing user", http.MethodPost, "/forum/pirates/follow", object{"nickname": "nobody"}, nil, http.StatusNotFound},
+			{"unfollow", http.MethodDelete, "/forum/pirates/follow?nickname=bob", nil, nil, http.StatusOK},
+			{"unfollow again", http.MethodDelete, "/forum/pirates/follow?nickname=bob", nil, nil, http.StatusNotFound},
+			{"export", http.MethodGet, "/forum/pirates/export", nil, alice, http.StatusOK},
+			{"anonymous export", http.MethodGet, "/forum/pirates/export", nil, nil, http.StatusUnauthorized},
+			{"export as user", http.MethodGet, "/forum/pirates/export", nil, bob, http.StatusForbidden},
+			{"missing export", http.MethodGet, "/forum/nowhere/export", nil, carol, http.StatusNotFound},
+		}},
+		{"moderation", []routeCase{
+			{"moderators", http.MethodGet, "/forum/pirates/moderators", nil, nil, http.StatusOK},
+			{"missing moderators", http.MethodGet, "/forum/nowhere/moderators", nil, nil, http.StatusNotFound},
+			{"grant", http.MethodPost, "/forum/pirates/moderators/bob", nil, alice, http.StatusCreated},
+			{"grant again", http.MethodPost, "/forum/pirates/moderators/bob", nil, alice, http.StatusOK},
+			{"grant anonymously", http.MethodPost, "/forum/pirates/moderators/carol", nil, nil, http.StatusForbidden},
+			{"grant missing user", http.MethodPost, "/forum/pirates/moderators/nobody", nil, alice, http.StatusNotFound},
+			{"grant missing forum", http.MethodPost, "/forum/nowhere/moderators/bob", nil, carol, http.StatusNotFound},
+			{"revoke", http.MethodDelete, "/forum/pirates/moderators/bob", nil, alice, http.StatusOK},
+			{"revoke again", http.MethodDelete, "/forum/pirates/moderators/bob", nil, alice, http.StatusNotFound},
+			{"revoke as user", http.MethodDelete, "/forum/pirates/moderators/alice", nil, bob, http.StatusForbidden},
+			{"bans", http.MethodGet, "/forum/pirates/bans", nil, nil, http.StatusOK},
+			{"ban", http.MethodPost, "/forum/pirates/bans", object{"nickname": "carol", "duration": "1h", "reason": "mutiny"}, alice, http.StatusCreated},
+			{"ban anonymously", http.MethodPost, "/forum/pirates/bans", object{"nickname": "bob", "duration": "1h"}, nil, http.StatusForbidden},
+			{"ban as user", http.MethodPost, "/forum/pirates/bans", object{"nickname": "alice", "duration": "1h"}, bob, http.StatusForbidden},
+			{"ban bad duration", http.MethodPost, "/forum/pirates/bans", object{"nickname": "carol", "duration": "forever"}, alice, http.StatusBadRequest},
+			{"ban missing user", http.MethodPost, "/forum/pirates/bans", object{"nickname": "nobody", "duration": "1h"}, alice, http.StatusNotFound},
+			{"ban missing forum", http.MethodPost, "/forum/nowhere/bans", object{"nickname": "carol", "duration": "1h"}, carol, http.StatusNotFound},
+			{"unban", http.MethodDelete, "/forum/pirates/bans/carol", nil, alice, http.StatusOK},
+			{"unban again", http.MethodDelete, "/forum/pirates/bans/carol", nil, alice, http.StatusNotFound},
+			{"unban anonymously", http.MethodDelete, "/forum/pirates/bans/carol", nil, nil, http.StatusForbidden},
+			{"limits", http.MethodGet, "/forum/pirates/limits", nil, nil, http.StatusOK},
+			{"missing limits", http.MethodGet, "/forum/nowhere/limits", nil, nil, http.StatusNotFound},
+			{"set limit", http.MethodPost, "/forum/pirates/limits", object{"action": "vote", "perMinute": 60, "burst": 10}, alice, http.StatusOK},
+			{"set limit anonymously", http.MethodPost, "/forum/pirates/limits", object{"action": "vote", "perMinute": 1, "burst": 1}, nil, http.StatusForbidden},
+			{"set bad action", http.MethodPost, "/forum/pirates/limits", object{"action": "sail", "perMinute": 60, "burst": 10}, alice, http.StatusBadRequest},
+			{"set missing forum", http.MethodPost, "/forum/nowhere/limits", object{"action": "vote", "perMinute": 60, "burst": 10}, carol, http.StatusNotFound},
+			{"delete limit", http.MethodDelete, "/forum/pirates/limits/vote", nil, alice, http.StatusOK},
+			{"delete limit anonymously", http.MethodDelete, "/forum/pirates/limits/vote", nil, nil, http.StatusForbidden},
+			{"queue", http.MethodGet, "/forum/pirates/queue", nil, alice, http.StatusOK},
+			{"queue anonymously", http.MethodGet, "/forum/pirates/queue", nil, nil, http.StatusForbidden},
+			{"missing queue", http.MethodGet, "/forum/nowhere/queue", nil, carol, http.StatusNotFound},
+			{"dismiss missing", http.MethodDelete, "/forum/pirates/queue/999", nil, alice, http.StatusNotFound},
+			{"dismiss bad id", http.MethodDelete, "/forum/pirates/queue/first", nil, alice, http.StatusBadRequest},
+		}},
+		{"threads", []routeCase{
+			{"duplicate slug", http.MethodPost, "/forum/pirates/create", object{"title": "Flag", "author": "alice", "message": "Again", "slug": "jolly-roger"}, nil, http.StatusConflict},
+			{"missing forum", http.MethodPost, "/forum/nowhere/create", object{"title": "Lost", "author": "alice", "message": "Lost"}, nil, http.StatusNotFound},
+			{"missing author", http.MethodPost, "/forum/pirates/create", object{"title": "Lost", "author": "nobody", "message": "Lost"}, nil, http.StatusNotFound},
+			{"details by slug", http.MethodGet, "/thread/jolly-roger/details", nil, nil, http.StatusOK},
+			{"details by id", http.MethodGet, "/thread/2/details", nil, nil, http.StatusOK},
+			{"missing details by slug", http.MethodGet, "/thread/nowhere/details", nil, nil, http.StatusNotFound},
+			{"missing details by id", http.MethodGet, "/thread/999/details", nil, nil, http.StatusNotFound},
+			{"update", http.MethodPost, "/thread/1/details", object{"title": "Black flag"}, nil, http.StatusOK},
+			{"update null", http.MethodPost, "/thread/1/details", object{"title": nil}, nil, http.StatusBadRequest},
+			{"update stale", http.MethodPost, "/thread/1/details", object{"title": "White flag"}, []string{"If-Match", `"999"`}, http.StatusPreconditionFailed},
+			{"update missing", http.MethodPost, "/thread/999/details", object{"title": "Lost"}, nil, http.StatusNotFound},
+			{"history", http.MethodGet, "/thread/1/history", nil, nil, http.StatusOK},
+			{"missing history", http.MethodGet, "/thread/999/history", nil, nil, http.StatusNotFound},
+			{"vote", http.MethodPost, "/thread/1/vote", object{"nickname": "bob", "voice": 1}, nil, http.StatusOK},
+			{"vote missing user", http.MethodPost, "/thread/1/vote", object{"nickname": "nobody", "voice": 1}, nil, http.StatusNotFound},
+			{"vote missing thread", http.MethodPost, "/thread/999/vote", object{"nickname": "bob", "voice": 1}, nil, http.StatusNotFound},
+			{"vote bad voice", http.MethodPost, "/thread/1/vote", object{"nickname": "bob", "voice": 2}, nil, http.StatusBadRequest},
+			{"subscribe", http.MethodPost, "/thread/1/subscribe", object{"nickname": "bob"}, nil, http.StatusCreated},
+			{"subscribe again", http.MethodPost, "/thread/1/subscribe", object{"nickname": "bob"}, nil, http.StatusOK},
+			{"subscribe missing user", http.MethodPost, "/thread/1/subscribe", object{"nickname": "nobody"}, nil, http.StatusNotFound},
+			{"subscribe missing thread", http.MethodPost, "/thread/999/subscribe", object{"nickname": "bob"}, nil, http.StatusNotFound},
+			{"unsubscribe", http.MethodDelete, "/thread/1/subscribe?nickname=bob", nil, nil, http.StatusOK},
+			{"unsubscribe again", http.MethodDelete, "/thread/1/subscribe?nickname=bob", nil, nil, http.StatusNotFound},
+			{"stream bad last id", http.MethodGet, "/thread/1/stream", nil, []string{"Last-Event-ID", "first"}, http.StatusBadRequest},
+			{"stream unknown last id", http.MethodGet, "/thread/1/stream", nil, []string{"Last-Event-ID", "999"}, http.StatusBadRequest},
+			{"missing stream", http.MethodGet, "/thread/999/stream", nil, nil, http.StatusNotFound},
+		}},
+		{"posts", []routeCase{
+			{"missing thread by id", http.MethodPost, "/thread/999/create", []object{{"author": "alice", "message": "lost"}}, nil, http.StatusNotFound},
+			{"missing thread by slug", http.MethodPost, "/thread/nowhere/create", []object{{"author": "alice", "message": "lost"}}, nil, http.StatusNotFound},
+			{"missing author", http.MethodPost, "/thread/1/create", []object{{"author": "nobody", "message": "lost"}}, nil, http.StatusNotFound},
+			{"missing parent", http.MethodPost, "/thread/1/create", []object{{"author": "alice", "message": "lost", "parent": 999}}, nil, http.StatusConflict},
+			{"list", http.MethodGet, "/thread/1/posts", nil, nil, http.StatusOK},
+			{"list missing by id", http.MethodGet, "/thread/999/posts", nil, nil, http.StatusNotFound},
+			{"list missing by slug", http.MethodGet, "/thread/nowhere/posts?sort=tree", nil, nil, http.StatusNotFound},
+			{"details", http.MethodGet, "/post/1/details", nil, nil, http.StatusOK},
+			{"details related", http.MethodGet, "/post/1/details?related=user,thread,forum", nil, nil, http.StatusOK},
+			{"missing details", http.MethodGet, "/post/999/details", nil, nil, http.StatusNotFound},
+			{"update", http.MethodPost, "/post/1/details", object{"message": "p1 edited"}, nil, http.StatusOK},
+			{"update stale", http.MethodPost, "/post/1/details", object{"message": "p1 again"}, []string{"If-Match", `"999"`}, http.StatusPreconditionFailed},
+			{"update missing", http.MethodPost, "/post/999/details", object{"message": "lost"}, nil, http.StatusNotFound},
+			{"moderate", http.MethodPost, "/post/6/moderate", object{"hidden": true}, alice, http.StatusOK},
+			{"moderate anonymously", http.MethodPost, "/post/6/moderate", object{"hidden": false}, nil, http.StatusForbidden},
+			{"moderate missing", http.MethodPost, "/post/999/moderate", object{"hidden": true}, alice, http.StatusNotFound},
+			{"hidden details", http.MethodGet, "/post/6/details", nil, nil, http.StatusNotFound},
+			{"hidden details for a user", http.MethodGet, "/post/6/details", nil, bob, http.StatusNotFound},
+			{"hidden details for a moderator", http.MethodGet, "/post/6/details", nil, alice, http.StatusOK},
+			{"attach", http.MethodPost, "/post/1/attachments", text, []string{"Content-Type", textType}, http.StatusCreated},
+			{"attach missing post", http.MethodPost, "/post/999/attachments", missing, []string{"Content-Type", missingType}, http.StatusNotFound},
+			{"attach bad type", http.MethodPost, "/post/1/attachments", pdf, []string{"Content-Type", pdfType}, http.StatusUnsupportedMediaType},
+			{"attach nothing", http.MethodPost, "/post/1/attachments", empty, []string{"Content-Type", emptyType}, http.StatusBadRequest},
+			{"attachment", http.MethodGet, "/attachment/1", nil, nil, http.StatusOK},
+			{"missing attachment", http.MethodGet, "/attachment/999", nil, nil, http.StatusNotFound},
+		}},
+		{"auth", []routeCase{
+			{"set password anonymously", http.MethodPost, "/user/alice/password", object{"password": "kraken"}, nil, http.StatusUnauthorized},
+			{"set empty password", http.MethodPost, "/user/alice/password", object{"password": ""}, alice, http.StatusBadRequest},
+			{"set wrong old password", http.MethodPost, "/user/alice/password", object{"password": "kraken", "old_password": "cannon"}, alice, http.StatusUnauthorized},
+			{"set foreign password", http.MethodPost, "/user/bob/password", object{"password": "kraken"}, alice, http.StatusForbidden},
+			{"set password", http.MethodPost, "/user/alice/password", object{"password": "kraken", "old_password": "parrot"}, alice, http.StatusOK},
+			{"login", http.MethodPost, "/auth/login", object{"nickname": "alice", "password": "kraken"}, nil, http.StatusOK},
+			{"login old password", http.MethodPost, "/auth/login", object{"nickname": "alice", "password": "parrot"}, nil, http.StatusUnauthorized},
+		}},
+		{"closing", []routeCase{
+			{"change slug taken", http.MethodPost, "/thread/2/slug", object{"slug": "jolly-roger"}, nil, http.StatusConflict},
+			{"change slug empty", http.MethodPost, "/thread/1/slug", object{"slug": ""}, nil, http.StatusBadRequest},
+			{"change slug numeric", http.MethodPost, "/thread/1/slug", object{"slug": "42"}, nil, http.StatusBadRequest},
+			{"change slug malformed", http.MethodPost, "/thread/1/slug", object{"slug": "black flag"}, nil, http.StatusBadRequest},
+			{"change slug missing", http.MethodPost, "/thread/999/slug", object{"slug": "lost"}, nil, http.StatusNotFound},
+			{"change slug", http.MethodPost, "/thread/1/slug", object{"slug": "black-flag"}, nil, http.StatusOK},
+			{"moved slug", http.MethodGet, "/thread/jolly-roger/details", nil, nil, http.StatusMovedPermanently},
+			{"moved slug posts", http.MethodGet, "/thread/jolly-roger/posts", nil, nil, http.StatusOK},
+			{"moved slug create", http.MethodPost, "/thread/jolly-roger/create", []object{{"author": "bob", "message": "p8"}}, nil, http.StatusCreated},
+			{"moved slug vote", http.MethodPost, "/thread/jolly-roger/vote", object{"nickname": "carol", "voice": 1}, nil, http.StatusOK},
+			{"moved slug history", http.MethodGet, "/thread/jolly-roger/history", nil, nil, http.StatusOK},
+			{"close anonymously", http.MethodPost, "/thread/2/moderate", object{"closed": true}, nil, http.StatusForbidden},
+			{"close", http.MethodPost, "/thread/2/moderate", object{"closed": true}, alice, http.StatusOK},
+			{"close missing", http.MethodPost, "/thread/999/moderate", object{"closed": true}, alice, http.StatusNotFound},
+			{"post in closed", http.MethodPost, "/thread/2/create", []object{{"author": "bob", "message": "more rum"}}, nil, http.StatusForbidden},
+			{"events", http.MethodGet, "/events?limit=10", nil, nil, http.StatusOK},
+			{"events bad after", http.MethodGet, "/events?after=start", nil, nil, http.StatusBadRequest},
+			{"events bad limit", http.MethodGet, "/events?limit=all", nil, nil, http.StatusBadRequest},
+			{"events negative limit", http.MethodGet, "/events?limit=-1", nil, nil, http.StatusBadRequest},
+			{"events limit above the cap", http.MethodGet, "/events?limit=1000000000", nil, nil, http.StatusOK},
+			{"status", http.MethodGet, "/service/status", nil, nil, http.StatusOK},
+			{"public clear", http.MethodPost, "/service/clear", nil, nil, http.StatusNotFound},
+			{"not cleared", http.MethodGet, "/forum/pirates/details", nil, nil, http.StatusOK},
+		}},
+	}
+
+	for _, group := range groups {
+		t.Run(group.name, func(t *testing.T) { run(t, s, group.cases) })
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	s := testenv.StartServer(t)
+	run(t, s, fixture)
+	alice := []string{"Authorization", s.Login(t, "alice", "parrot")}
+
+	run(t, s, []routeCase{
+		{"notifications", http.MethodGet, "/user/alice/notifications?unread=true", nil, alice, http.StatusOK},
+		{"notifications other case", http.MethodGet, "/user/ALICE/notifications", nil, alice, http.StatusOK},
+		{"notifications bad limit", http.MethodGet, "/user/alice/notifications?limit=many", nil, alice, http.StatusBadRequest},
+		{"anonymous notifications", http.MethodGet, "/user/alice/notifications", nil, nil, http.StatusUnauthorized},
+		{"foreign notifications", http.MethodGet, "/user/bob/notifications", nil, alice, http.StatusForbidden},
+		{"read", http.MethodPost, "/user/alice/notifications/read", object{"ids": []int{}}, alice, http.StatusOK},
+		{"anonymous read", http.MethodPost, "/user/alice/notifications/read", object{"ids": []int{}}, nil, http.StatusUnauthorized},
+		{"foreign read", http.MethodPost, "/user/bob/notifications/read", object{"ids": []int{}}, alice, http.StatusForbidden},
+	})
+}
+
+func TestThreadStream(t *testing.T) {
+	s := testenv.StartServer(t)
+	run(t, s, fixture)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The stream never ends, so only the head of the answer is read.
+	resp := s.Send(t, s.NewRequest(t, http.MethodGet, "/thread/jolly-roger/stream", nil).WithContext(ctx))
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("got content type %q", contentType)
+	}
+
+	// Posts are replayed from the event feed, so wait until the fixture posts
+	// are sequenced.
+	for posted := 0; posted < 7; {
+		var events []models.Event
+		s.Do(t, http.MethodGet, "/events?limit=1000", nil).JSON(t, &events)
+
+		posted = 0
+		for _, event := range events {
+			if event.Type == models.EventPostCreated {
+				posted++
+			}
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("only %d posts were sequenced", posted)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// Resuming from a post id first replays the posts created after it, with
+	// their ids as event ids.
+	req := s.NewRequest(t, http.MethodGet, "/thread/jolly-roger/stream", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", "4")
+	resumed := s.Send(t, req)
+	defer resumed.Body.Close()
+
+	if resumed.StatusCode != http.StatusOK {
+		t.Fatalf("resumed: got %d, want %d", resumed.StatusCode, http.StatusOK)
+	}
+
+	var ids []string
+	lines := bufio.NewScanner(resumed.Body)
+	for len(ids) < 3 && lines.Scan() {
+		if id := strings.TrimPrefix(lines.Text(), "id: "); id != lines.Text() {
+			ids = append(ids, id)
+		}
+	}
+	if want := []string{"5", "6", "7"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got event ids %v, want %v", ids, want)
+	}
+}
+
+func TestPostSorts(t *testing.T) {
+	s := testenv.StartServer(t)
+	run(t, s, fixture)
+
+	cases := []struct {
+		sort  string
+		limit int
+		since uint64
+		desc  bool
+		want  []uint64
+	}{
+		{"flat", 100, 0, false, []uint64{1, 2, 3, 4, 5, 6, 7}},
+		{"flat", 100, 0, true, []uint64{7, 6, 5, 4, 3, 2, 1}},
+		{"flat", 2, 3, false, []uint64{4, 5}},
+		{"flat", 100, 5, true, []uint64{4, 3, 2, 1}},
+		{"tree", 100, 0, false, []uint64{1, 3, 5, 4, 2, 6, 7}},
+		{"tree", 100, 0, true, []uint64{7, 6, 2, 4, 5, 3, 1}},
+		{"tree", 3, 3, false, []uint64{5, 4, 2}},
+		{"tree", 100, 4, true, []uint64{5, 3, 1}},
+		{"parent_tree", 2, 0, false, []uint64{1, 3, 5, 4, 2, 6}},
+		{"parent_tree", 2, 0, true, []uint64{7, 2, 6}},
+		{"parent_tree", 1, 3, false, []uint64{2, 6}},
+		{"parent_tree", 2, 6, true, []uint64{1, 3, 5, 4}},
+	}
+
+	for _, c := range cases {
+		path := "/thread/1/posts?sort=" + c.sort + "&limit=" + strconv.Itoa(c.limit) + "&desc=" + strconv.FormatBool(c.desc)
+		if c.since != 0 {
+			path += "&since=" + strconv.FormatUint(c.since, 10)
+		}
+
+		t.Run(path, func(t *testing.T) {
+			resp := s.Do(t, http.MethodGet, path, nil)
+			if resp.Status != http.StatusOK {
+				t.Fatalf("got %d: %s", resp.Status, bytes.TrimSpace(resp.Body))
+			}
+
+			posts := []struct {
+				ID uint64 `json:"id"`
+			}{}
+			resp.JSON(t, &posts)
+
+			got := make([]uint64, 0, len(posts))
+			for _, post := range posts {
+				got = append(got, post.ID)
+			}
+			if len(got) != len(c.want) {
+				t.Fatalf("got posts %v, want %v", got, c.want)
+			}
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Fatalf("got posts %v, want %v", got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	s := testenv.StartServer(t)
+	run(t, s, fixture[:3])
+
+	forum := object{"title": "Pirates", "user": "alice", "slug": "pirates"}
+	first := s.Do(t, http.MethodPost, "/forum/create", forum, "Idempotency-Key", "forum-1")
+	if first.Status != http.StatusCreated {
+		t.Fatalf("got %d, want %d: %s", first.Status, http.StatusCreated, bytes.TrimSpace(first.Body))
+	}
+
+	retry := s.Do(t, http.MethodPost, "/forum/create", forum, "Idempotency-Key", "forum-1")
+	if retry.Status != first.Status || !bytes.Equal(retry.Body, first.Body) {
+		t.Errorf("retry got %d %s, want %d %s", retry.Status, retry.Body, first.Status, first.Body)
+	}
+	if retry.Header.Get("Idempotent-Replayed") != "true" {
+		t.Error("retry was not replayed")
+	}
+
+	other := object{"title": "Ghosts", "user": "alice", "slug": "ghosts"}
+	if resp := s.Do(t, http.MethodPost, "/forum/create", other, "Idempotency-Key", "forum-1"); resp.Status != http.StatusUnprocessableEntity {
+		t.Errorf("reused key got %d, want %d", resp.Status, http.StatusUnprocessableEntity)
+	}
+
+	// A failed request is answered once and recorded like any other.
+	missing := object{"title": "Ghosts", "user": "nobody", "slug": "ghosts"}
+	for i := 0; i < 2; i++ {
+		if resp := s.Do(t, http.MethodPost, "/forum/create", missing, "Idempotency-Key", "forum-2"); resp.Status != http.StatusNotFound {
+			t.Errorf("attempt %d got %d, want %d", i, resp.Status, http.StatusNotFound)
+		}
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	// Each vote takes a token from the voter's bucket and the client
+	// address's; with a burst of one, every bucket holds a single vote.
+	limit := object{"action": "vote", "perMinute": 1, "burst": 1}
+	from := func(ip string) []string { return []string{"X-Forwarded-For", ip} }
+
+	t.Run("trusted proxy", func(t *testing.T) {
+		s := testenv.StartServer(t, func(cfg *config.Config) {
+			_, loopback, _ := net.ParseCIDR("127.0.0.1/32")
+			cfg.TrustedProxies = []*net.IPNet{loopback}
+		})
+		run(t, s, fixture)
+		alice := []string{"Authorization", s.Login(t, "alice", "parrot")}
+
+		run(t, s, []routeCase{
+			{"set limit", http.MethodPost, "/forum/pirates/limits", limit, alice, http.StatusOK},
+			{"vote", http.MethodPost, "/thread/1/vote", object{"nickname": "alice", "voice": 1}, from("203.0.113.1"), http.StatusOK},
+			{"address exhausted", http.MethodPost, "/thread/1/vote", object{"nickname": "bob", "voice": 1}, from("203.0.113.1"), http.StatusTooManyRequests},
+			// The rejected vote took nothing from bob's bucket.
+			{"other address", http.MethodPost, "/thread/1/vote", object{"nickname": "bob", "voice": 1}, from("203.0.113.2"), http.StatusOK},
+			{"bad voice", http.MethodPost, "/thread/1/vote", object{"nickname": "carol", "voice": 2}, from("203.0.113.3"), http.StatusBadRequest},
+			{"address untouched", http.MethodPost, "/thread/1/vote", object{"nickname": "carol", "voice": 1}, from("203.0.113.3"), http.StatusOK},
+		})
+	})
+
+	t.Run("untrusted client", func(t *testing.T) {
+		s := testenv.StartServer(t)
+		run(t, s, fixture)
+		alice := []string{"Authorization", s.Login(t, "alice", "parrot")}
+
+		run(t, s, []routeCase{
+			{"set limit", http.MethodPost, "/forum/pirates/limits", limit, alice, http.StatusOK},
+			{"vote", http.MethodPost, "/thread/1/vote", object{"nickname": "alice", "voice": 1}, from("203.0.113.1"), http.StatusOK},
+			{"forged address", http.MethodPost, "/thread/1/vote", object{"nickname": "bob", "voice": 1}, from("203.0.113.2"), http.StatusTooManyRequests},
+		})
+	})
+
+	t.Run("post batch", func(t *testing.T) {
+		s := testenv.StartServer(t)
+		run(t, s, fixture)
+		alice := []string{"Authorization", s.Login(t, "alice", "parrot")}
+
+		// Every post of a batch is charged, however many share an author.
+		run(t, s, []routeCase{
+			{"set limit", http.MethodPost, "/forum/pirates/limits", object{"action": "post", "perMinute": 1, "burst": 2}, alice, http.StatusOK},
+			{"batch", http.MethodPost, "/thread/1/create", []object{{"author": "bob", "message": "a"}, {"author": "bob", "message": "b"}}, nil, http.StatusCreated},
+			{"batch spent the burst", http.MethodPost, "/thread/1/create", []object{{"author": "bob", "message": "c"}}, nil, http.StatusTooManyRequests},
+		})
+	})
+
+	t.Run("retried create", func(t *testing.T) {
+		s := testenv.StartServer(t)
+		run(t, s, fixture)
+		alice := []string{"Authorization", s.Login(t, "alice", "parrot")}
+		run(t, s, []routeCase{
+			{"set limit", http.MethodPost, "/forum/pirates/limits", object{"action": "post", "perMinute": 60, "burst": 1}, alice, http.StatusOK},
+			{"spend the burst", http.MethodPost, "/thread/1/create", []object{{"author": "bob", "message": "a"}}, nil, http.StatusCreated},
+		})
+
+		// A rejection is not what the key stands for: once the limit allows
+		// it, the retry creates the post instead of replaying the 429.
+		posts := []object{{"author": "bob", "message": "b"}}
+		limited := s.Do(t, http.MethodPost, "/thread/1/create", posts, "Idempotency-Key", "post-1")
+		if limited.Status != http.StatusTooManyRequests {
+			t.Fatalf("got %d, want %d: %s", limited.Status, http.StatusTooManyRequests, bytes.TrimSpace(limited.Body))
+		}
+		seconds, err := strconv.Atoi(limited.Header.Get("Retry-After"))
+		if err != nil {
+			t.Fatalf("bad Retry-After %q", limited.Header.Get("Retry-After"))
+		}
+		time.Sleep(time.Duration(seconds) * time.Second)
+
+		retry := s.Do(t, http.MethodPost, "/thread/1/create", posts, "Idempotency-Key", "post-1")
+		if retry.Status != http.StatusCreated || retry.Header.Get("Idempotent-Replayed") != "" {
+			t.Errorf("retry got %d replayed %q, want %d: %s", retry.Status, retry.Header.Get("Idempotent-Replayed"), http.StatusCreated, bytes.TrimSpace(retry.Body))
+		}
+	})
+}
+
+func TestDuplicateFilter(t *testing.T) {
+	s := testenv.StartServer(t, func(cfg *config.Config) { cfg.FilterDuplicateWindow = time.Hour })
+	run(t, s, fixture)
+
+	run(t, s, []routeCase{
+		{"failed create", http.MethodPost, "/thread/1/create", []object{{"author": "bob", "message": "ahoy", "parent": 999}}, nil, http.StatusConflict},
+		{"retry", http.MethodPost, "/thread/1/create", []object{{"author": "bob", "message": "ahoy"}}, nil, http.StatusCreated},
+		{"duplicate", http.MethodPost, "/thread/1/create", []object{{"author": "bob", "message": "ahoy"}}, nil, http.StatusUnprocessableEntity},
+		{"other author", http.MethodPost, "/thread/1/create", []object{{"author": "carol", "message": "ahoy"}}, nil, http.StatusCreated},
+		{"duplicate in a batch", http.MethodPost, "/thread/1/create", []object{{"author": "alice", "message": "avast"}, {"author": "Alice", "message": "avast"}}, nil, http.StatusUnprocessableEntity},
+	})
+}
+
+func TestAttachmentUpload(t *testing.T) {
+	var dir string
+	s := testenv.StartServer(t, func(cfg *config.Config) { dir = cfg.AttachmentDir })
+	run(t, s, fixture)
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	for name, content := range map[string]string{"notes.txt": "plain notes", "map.pdf": "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n"} {
+		part, err := w.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// One refused file fails the whole upload, before anything is stored.
+	run(t, s, []routeCase{
+		{"attach with a bad file", http.MethodPost, "/post/1/attachments", body, []string{"Content-Type", w.FormDataContentType()}, http.StatusUnsupportedMediaType},
+		{"nothing attached", http.MethodGet, "/attachment/1", nil, nil, http.StatusNotFound},
+	})
+
+	if stored := countFiles(dir); stored != 0 {
+		t.Errorf("got %d stored files, want none", stored)
+	}
+}
+
+func countFiles(dir string) int {
+	count := 0
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			count++
+		}
+		return err
+	})
+
+	return count
+}
+
+func TestAttachmentOrphans(t *testing.T) {
+	var dir string
+	s := testenv.StartServer(t, func(cfg *config.Config) { dir = cfg.AttachmentDir })
+	run(t, s, fixture)
+	admin := []string{"Authorization", "Bearer " + testenv.AdminToken}
+
+	text, textType := multipartFile(t, "notes.txt", []byte("plain notes"))
+	run(t, s, []routeCase{
+		{"attach", http.MethodPost, "/post/1/attachments", text, []string{"Content-Type", textType}, http.StatusCreated},
+		{"purge", http.MethodDelete, "/admin/forum/pirates", nil, admin, http.StatusOK},
+	})
+
+	repo, err := attachmentRepository.NewPostgres(s.DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	storage, err := attachmentRepository.NewFilesystem(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attachments := attachmentUsecase.NewAttachmentUsecase(repo, storage, nil, nil, 0, nil)
+
+	// The scheduled collection waits out a grace period; the file of the
+	// purged post is only kept until then.
+	if deleted, err := attachments.DeleteOrphans(time.Hour); err != nil || deleted != 0 {
+		t.Errorf("within the grace period got %d deleted, %v, want none", deleted, err)
+	}
+	if deleted, err := attachments.DeleteOrphans(0); err != nil || deleted != 1 {
+		t.Errorf("got %d deleted, %v, want 1", deleted, err)
+	}
+	if stored := countFiles(dir); stored != 0 {
+		t.Errorf("got %d stored files, want none", stored)
+	}
+}
+
+func TestAdminAudit(t *testing.T) {
+	s := testenv.StartServer(t)
+	run(t, s, fixture[:3])
+	bob := []string{"Authorization", s.Login(t, "bob", "anchor")}
+	admin := []string{"Authorization", "Bearer " + testenv.AdminToken}
+
+	run(t, s, []routeCase{
+		{"anonymous", http.MethodGet, "/admin/stats", nil, nil, http.StatusUnauthorized},
+		{"bad token", http.MethodGet, "/admin/stats", nil, []string{"Authorization", "Bearer forged"}, http.StatusUnauthorized},
+		{"not an admin", http.MethodGet, "/admin/stats", nil, bob, http.StatusForbidden},
+		{"admin", http.MethodGet, "/admin/stats", nil, admin, http.StatusOK},
+	})
+
+	resp := s.Do(t, http.MethodGet, "/admin/audit", nil, admin...)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("got %d, want %d: %s", resp.Status, http.StatusOK, bytes.TrimSpace(resp.Body))
+	}
+	entries := make([]*models.AuditEntry, 0)
+	resp.JSON(t, &entries)
+
+	// The newest entry comes first.
+	want := []models.AuditEntry{
+		{Actor: adminDelivery.TokenActor, Status: http.StatusOK},
+		{Actor: "bob", Status: http.StatusForbidden},
+		{Actor: "", Status: http.StatusUnauthorized},
+		{Actor: "", Status: http.StatusUnauthorized},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.Actor != want[i].Actor || entry.Status != want[i].Status || entry.Path != "/api/admin/stats" {
+			t.Errorf("entry %d: got %s %s %d, want %s %d", i, entry.Actor, entry.Path, entry.Status, want[i].Actor, want[i].Status)
+		}
+	}
+}
+
+func TestAdminRoutes(t *testing.T) {
+	s := testenv.StartServer(t)
+	run(t, s, fixture)
+	alice := []string{"Authorization", s.Login(t, "alice", "parrot")}
+	bob := []string{"Authorization", s.Login(t, "bob", "anchor")}
+	admin := []string{"Authorization", "Bearer " + testenv.AdminToken}
+
+	export := s.Do(t, http.MethodGet, "/forum/pirates/export", nil, alice...)
+	if export.Status != http.StatusOK {
+		t.Fatalf("export: got %d: %s", export.Status, bytes.TrimSpace(export.Body))
+	}
+	archive := func() *bytes.Reader { return bytes.NewReader(export.Body) }
+
+	run(t, s, []routeCase{
+		{"stats", http.MethodGet, "/admin/stats", nil, admin, http.StatusOK},
+		{"stats anonymously", http.MethodGet, "/admin/stats", nil, nil, http.StatusUnauthorized},
+		{"stats as user", http.MethodGet, "/admin/stats", nil, bob, http.StatusForbidden},
+		{"audit", http.MethodGet, "/admin/audit?limit=10", nil, admin, http.StatusOK},
+		{"audit as user", http.MethodGet, "/admin/audit", nil, bob, http.StatusForbidden},
+		{"drift", http.MethodGet, "/admin/counters/drift", nil, admin, http.StatusOK},
+		{"reconcile", http.MethodPost, "/admin/counters/reconcile", nil, admin, http.StatusOK},
+		{"reconcile as user", http.MethodPost, "/admin/counters/reconcile", nil, bob, http.StatusForbidden},
+		{"removed recompute", http.MethodPost, "/admin/counters/recompute", nil, admin, http.StatusNotFound},
+		{"add admin", http.MethodPost, "/admin/admins/bob", nil, admin, http.StatusCreated},
+		{"add admin again", http.MethodPost, "/admin/admins/bob", nil, admin, http.StatusOK},
+		{"add missing admin", http.MethodPost, "/admin/admins/nobody", nil, admin, http.StatusNotFound},
+		{"stats as new admin", http.MethodGet, "/admin/stats", nil, bob, http.StatusOK},
+		{"remove admin", http.MethodDelete, "/admin/admins/bob", nil, admin, http.StatusOK},
+		{"remove admin again", http.MethodDelete, "/admin/admins/bob", nil, admin, http.StatusNotFound},
+		{"stats as former admin", http.MethodGet, "/admin/stats", nil, bob, http.StatusForbidden},
+		{"bans", http.MethodGet, "/admin/bans", nil, admin, http.StatusOK},
+		{"ban", http.MethodPost, "/admin/bans", object{"nickname": "carol", "duration": "1h", "reason": "mutiny"}, admin, http.StatusCreated},
+		{"ban as user", http.MethodPost, "/admin/bans", object{"nickname": "alice", "duration": "1h"}, bob, http.StatusForbidden},
+		{"ban bad duration", http.MethodPost, "/admin/bans", object{"nickname": "carol", "duration": "forever"}, admin, http.StatusBadRequest},
+		{"ban missing user", http.MethodPost, "/admin/bans", object{"nickname": "nobody", "duration": "1h"}, admin, http.StatusNotFound},
+		{"unban", http.MethodDelete, "/admin/bans/carol", nil, admin, http.StatusOK},
+		{"unban again", http.MethodDelete, "/admin/bans/carol", nil, admin, http.StatusNotFound},
+		{"purge missing", http.MethodDelete, "/admin/forum/nowhere", nil, admin, http.StatusNotFound},
+		{"purge as user", http.MethodDelete, "/admin/forum/pirates", nil, bob, http.StatusForbidden},
+		{"purge", http.MethodDelete, "/admin/forum/pirates", nil, admin, http.StatusOK},
+		{"purged", http.MethodGet, "/forum/pirates/details", nil, nil, http.StatusNotFound},
+		{"import as user", http.MethodPost, "/admin/forum/import", archive(), bob, http.StatusForbidden},
+		{"import malformed", http.MethodPost, "/admin/forum/import", bytes.NewReader([]byte("not an archive")), admin, http.StatusBadRequest},
+		{"import", http.MethodPost, "/admin/forum/import", archive(), admin, http.StatusCreated},
+		{"imported", http.MethodGet, "/forum/pirates/details", nil, nil, http.StatusOK},
+		{"import existing", http.MethodPost, "/admin/forum/import", archive(), admin, http.StatusConflict},
+		{"clear anonymously", http.MethodPost, "/admin/clear", nil, nil, http.StatusUnauthorized},
+		{"clear as user", http.MethodPost, "/admin/clear", nil, bob, http.StatusForbidden},
+		{"clear", http.MethodPost, "/admin/clear", nil, admin, http.StatusOK},
+		{"cleared", http.MethodGet, "/forum/pirates/details", nil, nil, http.StatusNotFound},
+	})
+}
+
+func TestTokenAuth(t *testing.T) {
+	s := testenv.StartServer(t, func(cfg *config.Config) { cfg.AuthMode = config.AuthToken })
+	run(t, s, fixture[:3])
+	alice := []string{"Authorization", s.Login(t, "alice", "parrot")}
+	bob := []string{"Authorization", s.Login(t, "bob", "anchor")}
+
+	forum := object{"title": "Pirates", "user": "alice", "slug": "pirates"}
+	thread := object{"title": "Flag", "author": "alice", "message": "Which flag?", "slug": "jolly-roger"}
+	posts := []object{{"author": "alice", "message": "p1"}}
+
+	run(t, s, []routeCase{
+		{"create forum anonymously", http.MethodPost, "/forum/create", forum, nil, http.StatusUnauthorized},
+		{"create forum with basic auth", http.MethodPost, "/forum/create", forum, []string{"Authorization", "Basic YWxpY2U6cGFycm90"}, http.StatusUnauthorized},
+		{"create forum with a forged token", http.MethodPost, "/forum/create", forum, []string{"Authorization", "Bearer forged"}, http.StatusUnauthorized},
+		{"create forum", http.MethodPost, "/forum/create", forum, alice, http.StatusCreated},
+		{"create thread anonymously", http.MethodPost, "/forum/pirates/create", thread, nil, http.StatusUnauthorized},
+		{"create thread", http.MethodPost, "/forum/pirates/create", thread, alice, http.StatusCreated},
+		{"create posts anonymously", http.MethodPost, "/thread/1/create", posts, nil, http.StatusUnauthorized},
+		{"create posts", http.MethodPost, "/thread/1/create", posts, alice, http.StatusCreated},
+		{"vote anonymously", http.MethodPost, "/thread/1/vote", object{"nickname": "bob", "voice": 1}, nil, http.StatusUnauthorized},
+		{"vote", http.MethodPost, "/thread/1/vote", object{"nickname": "bob", "voice": 1}, bob, http.StatusOK},
+		{"update post anonymously", http.MethodPost, "/post/1/details", object{"message": "mutiny"}, nil, http.StatusUnauthorized},
+		{"update foreign post", http.MethodPost, "/post/1/details", object{"message": "mutiny"}, bob, http.StatusForbidden},
+		{"update post", http.MethodPost, "/post/1/details", object{"message": "p1 edited"}, alice, http.StatusOK},
+		{"update profile anonymously", http.MethodPost, "/user/bob/profile", object{"about": "bosun"}, nil, http.StatusUnauthorized},
+		{"update foreign profile", http.MethodPost, "/user/alice/profile", object{"about": "mutineer"}, bob, http.StatusForbidden},
+		{"update profile", http.MethodPost, "/user/bob/profile", object{"about": "bosun"}, bob, http.StatusOK},
+		{"close as user", http.MethodPost, "/thread/1/moderate", object{"closed": true}, bob, http.StatusForbidden},
+		{"grant on an unknown forum", http.MethodPost, "/forum/ghosts/moderators/bob", nil, alice, http.StatusNotFound},
+		{"revoke on an unknown forum", http.MethodDelete, "/forum/ghosts/moderators/bob", nil, alice, http.StatusNotFound},
+		{"follow anonymously", http.MethodPost, "/forum/pirates/follow", object{"nickname": "bob"}, nil, http.StatusUnauthorized},
+		{"read anonymously", http.MethodGet, "/forum/pirates/details", nil, nil, http.StatusOK},
+	})
+
+	// The token names the actor whatever the body says.
+	resp := s.Do(t, http.MethodPost, "/forum/create", object{"title": "Ghosts", "user": "bob", "slug": "ghosts"}, alice...)
+	created := models.ForumResponse{}
+	resp.JSON(t, &created)
+	if resp.Status != http.StatusCreated || created.User != "alice" {
+		t.Errorf("got %d for user %q, want %d for alice", resp.Status, created.User, http.StatusCreated)
+	}
+}
+
+func TestConflictBodies(t *testing.T) {
+	s := testenv.StartServer(t)
+	run(t, s, fixture)
+
+	// A duplicate user gets back every user its nickname or email collides
+	// with.
+	resp := s.Do(t, http.MethodPost, "/user/alice/create", object{"email": "bob@example.com", "fullname": "Alice", "about": "captain"})
+	users := make([]models.User, 0)
+	resp.JSON(t, &users)
+	nicknames := make(map[string]bool, len(users))
+	for _, user := range users {
+		nicknames[user.Nickname] = true
+	}
+	if resp.Status != http.StatusConflict || len(users) != 2 || !nicknames["alice"] || !nicknames["bob"] {
+		t.Errorf("duplicate user: got %d %s, want %d with alice and bob", resp.Status, bytes.TrimSpace(resp.Body), http.StatusConflict)
+	}
+
+	// A duplicate forum or thread gets back the one that exists.
+	resp = s.Do(t, http.MethodPost, "/forum/create", object{"title": "Privateers", "user": "bob", "slug": "PIRATES"})
+	forum := models.ForumResponse{}
+	resp.JSON(t, &forum)
+	if resp.Status != http.StatusConflict || forum.Slug != "pirates" || forum.User != "alice" || forum.Title != "Pirates" {
+		t.Errorf("duplicate forum: got %d %s, want %d with alice's pirates", resp.Status, bytes.TrimSpace(resp.Body), http.StatusConflict)
+	}
+
+	resp = s.Do(t, http.MethodPost, "/forum/pirates/create", object{"title": "Skull", "author": "bob", "message": "Mine", "slug": "jolly-roger"})
+	thread := models.Thread{}
+	resp.JSON(t, &thread)
+	if resp.Status != http.StatusConflict || thread.ID != 1 || thread.Author != "alice" || thread.Title != "Flag" {
+		t.Errorf("duplicate thread: got %d %s, want %d with thread 1", resp.Status, bytes.TrimSpace(resp.Body), http.StatusConflict)
+	}
+
+	// The other conflicts explain themselves.
+	messages := []struct {
+		name    string
+		method  string
+		path    string
+		body    interface{}
+		message string
+	}{
+		{"taken email", http.MethodPost, "/user/bob/profile", object{"email": "alice@example.com"}, "This email is already registered by user: bob"},
+		{"missing parent", http.MethodPost, "/thread/1/create", []object{{"author": "alice", "message": "lost", "parent": 999}}, "Post was created in another thread"},
+		{"taken slug", http.MethodPost, "/thread/2/slug", object{"slug": "jolly-roger"}, "Thread slug is already taken: jolly-roger"},
+	}
+	for _, m := range messages {
+		resp := s.Do(t, m.method, m.path, m.body)
+		body := struct {
+			Message string `json:"message"`
+		}{}
+		resp.JSON(t, &body)
+		if resp.Status != http.StatusConflict || body.Message != m.message {
+			t.Errorf("%s: got %d %q, want %d %q", m.name, resp.Status, body.Message, http.StatusConflict, m.message)
+		}
+	}
+}
+
+func TestConcurrentReconcile(t *testing.T) {
+	s := testenv.StartServer(t)
+	run(t, s, fixture)
+
+	db, err := sql.Open("postgres", s.DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err = db.Exec("UPDATE forums SET posts = posts + 10 WHERE slug = 'pirates'"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each run has a usecase of its own, as the recount command and other
+	// instances do, so only the database serializes them. Had two of them
+	// applied the same drift, the count would end up 10 short.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		repo, err := counterRepository.NewPostgres(s.DSN)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer repo.Close()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := counterUsecase.NewCounterUsecase(repo, 1).Reconcile(true); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	forum := models.ForumResponse{}
+	s.Do(t, http.MethodGet, "/forum/pirates/details", nil).JSON(t, &forum)
+	if forum.Posts != 7 {
+		t.Errorf("got %d posts, want 7", forum.Posts)
+	}
+}
@@ -0,0 +1,203 @@
+package limitDelivery
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	internalErrors "technopark-dbms-forum/internal"
+	adminDelivery "technopark-dbms-forum/internal/admin/delivery"
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
+	limitUsecase "technopark-dbms-forum/internal/limits/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+const HeaderRetryAfter = "Retry-After"
+
+// Reject answers a ban with 403 and an exhausted rate limit with 429, both
+// with a Retry-After header in whole seconds.
+func Reject(c echo.Context, retry *internalErrors.RetryError) error {
+	seconds := int64(math.Ceil(retry.After.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Response().Header().Set(HeaderRetryAfter, strconv.FormatInt(seconds, 10))
+
+	if retry.Err == internalErrors.ErrBanned {
+		return echo.NewHTTPError(http.StatusForbidden, "User is banned")
+	}
+	return echo.NewHTTPError(http.StatusTooManyRequests, "Too many requests")
+}
+
+type Handler struct {
+	limitUsecase *limitUsecase.LimitUsecase
+}
+
+func NewHandler(limitUsecase *limitUsecase.LimitUsecase) *Handler {
+	return &Handler{
+		limitUsecase: limitUsecase,
+	}
+}
+
+// GetBans lists the active bans of the forum in the path, or the site-wide
+// bans on routes without a forum.
+func (h *Handler) GetBans(c echo.Context) error {
+	slug := c.Param("slug")
+
+	bans, err := h.limitUsecase.GetBans(slug)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, bans)
+}
+
+func bindBan(c echo.Context) (*models.BanRequest, time.Duration, error) {
+	request := models.BanRequest{}
+
+	if err := c.Bind(&request); err != nil {
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	duration, err := time.ParseDuration(request.Duration)
+	if err != nil || duration <= 0 {
+		return nil, 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Wrong ban duration: %s", request.Duration))
+	}
+
+	return &request, duration, nil
+}
+
+func (h *Handler) Ban(c echo.Context) error {
+	slug := c.Param("slug")
+
+	request, duration, err := bindBan(c)
+	if err != nil {
+		return err
+	}
+
+	principal, _ := authDelivery.Principal(c)
+
+	ban, err := h.limitUsecase.Ban(principal, slug, request.Nickname, request.Reason, duration)
+	if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, "Can't ban users here")
+	} else if err == internalErrors.ErrUserNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find user with nickname: %s", request.Nickname))
+	} else if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find forum with slug: %s", slug))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusCreated, ban)
+}
+
+func (h *Handler) Unban(c echo.Context) error {
+	slug := c.Param("slug")
+	nickname := c.Param("nickname")
+
+	principal, _ := authDelivery.Principal(c)
+
+	err := h.limitUsecase.Unban(principal, slug, nickname)
+	if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, "Can't lift bans here")
+	} else if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("User %s has no active ban", nickname))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, "OK")
+}
+
+// AdminBan bans a user site-wide on behalf of the admin API actor.
+func (h *Handler) AdminBan(c echo.Context) error {
+	request, duration, err := bindBan(c)
+	if err != nil {
+		return err
+	}
+
+	ban, err := h.limitUsecase.BanSiteWide(adminDelivery.Actor(c), request.Nickname, request.Reason, duration)
+	if err == internalErrors.ErrUserNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find user with nickname: %s", request.Nickname))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusCreated, ban)
+}
+
+// AdminUnban lifts a user's site-wide bans on behalf of the admin API actor.
+func (h *Handler) AdminUnban(c echo.Context) error {
+	nickname := c.Param("nickname")
+
+	err := h.limitUsecase.UnbanSiteWide(nickname)
+	if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("User %s has no active ban", nickname))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, "OK")
+}
+
+func (h *Handler) GetLimits(c echo.Context) error {
+	slug := c.Param("slug")
+
+	limits, err := h.limitUsecase.GetLimits(slug)
+	if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find forum with slug: %s", slug))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, limits)
+}
+
+func (h *Handler) SetLimit(c echo.Context) error {
+	slug := c.Param("slug")
+
+	limit := models.RateLimit{}
+
+	if err := c.Bind(&limit); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if limit.Action != models.LimitPost && limit.Action != models.LimitThread && limit.Action != models.LimitVote {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Wrong limit action: %s", limit.Action))
+	}
+	if limit.Burst < 0 || limit.PerMinute < 0 || (limit.Burst > 0 && limit.PerMinute == 0) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Limit rate and burst must be positive")
+	}
+
+	principal, _ := authDelivery.Principal(c)
+
+	err := h.limitUsecase.SetLimit(principal, slug, &limit)
+	if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't manage limits of forum: %s", slug))
+	} else if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find forum with slug: %s", slug))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, limit)
+}
+
+func (h *Handler) DeleteLimit(c echo.Context) error {
+	slug := c.Param("slug")
+	action := c.Param("action")
+
+	principal, _ := authDelivery.Principal(c)
+
+	err := h.limitUsecase.DeleteLimit(principal, slug, action)
+	if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't manage limits of forum: %s", slug))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, "OK")
+}
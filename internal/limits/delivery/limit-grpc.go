@@ -0,0 +1,82 @@
+package limitDelivery
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	internalErrors "technopark-dbms-forum/internal"
+)
+
+// RejectStatus answers a ban with PERMISSION_DENIED and an exhausted rate
+// limit with RESOURCE_EXHAUSTED, both with a RetryInfo detail.
+func RejectStatus(retry *internalErrors.RetryError) error {
+	st := status.New(codes.ResourceExhausted, "Too many requests")
+	if retry.Err == internalErrors.ErrBanned {
+		st = status.New(codes.PermissionDenied, "User is banned")
+	}
+
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retry.After)})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// ClientIP finds the address of a gRPC caller the way the HTTP API does:
+// X-Forwarded-For is only believed when the peer is a trusted proxy, and
+// then the nearest address that is not one is taken.
+func ClientIP(ctx context.Context, trusted []*net.IPNet) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	direct := p.Addr.String()
+	if host, _, err := net.SplitHostPort(direct); err == nil {
+		direct = host
+	}
+	if !isTrusted(net.ParseIP(direct), trusted) {
+		return direct
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	forwarded := md.Get("x-forwarded-for")
+	if len(forwarded) == 0 {
+		return direct
+	}
+
+	ips := strings.Split(strings.Join(forwarded, ","), ",")
+	for i := len(ips) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(ips[i]))
+		if ip == nil {
+			return direct
+		}
+		if !isTrusted(ip, trusted) {
+			return ip.String()
+		}
+	}
+
+	// Every hop is a proxy of ours; the first one is the furthest out.
+	return strings.TrimSpace(ips[0])
+}
+
+func isTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,315 @@
+package limitRepository
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	internalErrors "technopark-dbms-forum/internal"
+	"technopark-dbms-forum/pkg/models"
+)
+
+type Postgres struct {
+	sqlx *sqlx.DB
+}
+
+func NewPostgres(url string) (*Postgres, error) {
+	newSQLX, err := sqlx.Connect("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = newSQLX.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &Postgres{sqlx: newSQLX}, nil
+}
+
+func (p *Postgres) Close() error {
+	return p.sqlx.Close()
+}
+
+// GetRestrictions returns when the latest active ban of any of the users in
+// the forum or site-wide expires (zero if none), and the forum's own limit for
+// the action (nil if the forum uses the default).
+func (p *Postgres) GetRestrictions(forum, action string, nicknames []string) (*models.RateLimit, time.Time, error) {
+	var row struct {
+		BannedUntil sql.NullTime    `db:"banned_until"`
+		PerMinute   sql.NullFloat64 `db:"per_minute"`
+		Burst       sql.NullInt64   `db:"burst"`
+	}
+	err := p.sqlx.Get(
+		&row,
+		`
+			SELECT (
+			           SELECT MAX(expires)
+			           FROM bans
+			           WHERE nickname = ANY ($1::citext[]) AND (forum_slug IS NULL OR forum_slug = $2) AND expires > now()
+			       ) AS banned_until,
+			       l.per_minute,
+			       l.burst
+			FROM (SELECT 1) dummy
+			LEFT JOIN forum_rate_limits l ON l.forum_slug = $2 AND l.action = $3
+		`,
+		pq.Array(nicknames),
+		forum,
+		action,
+	)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var limit *models.RateLimit
+	if row.PerMinute.Valid {
+		limit = &models.RateLimit{
+			Action:    action,
+			PerMinute: row.PerMinute.Float64,
+			Burst:     int(row.Burst.Int64),
+		}
+	}
+
+	return limit, row.BannedUntil.Time, nil
+}
+
+// Take removes costs[key] tokens from each bucket, refilling them at the
+// limit's rate first. A bucket admits a cost once it holds that many tokens,
+// or is full when the cost is above the burst; it may then go below zero and
+// pays the debt back at the same rate. Either every bucket admits its cost or
+// none does: it returns zero when the tokens were taken, or how long until all
+// buckets would admit them.
+func (p *Postgres) Take(costs map[string]int, limit *models.RateLimit) (time.Duration, error) {
+	perSecond := limit.PerMinute / 60
+
+	// Rows are created and locked in key order, so takes that share buckets
+	// wait for each other instead of deadlocking.
+	keys := make([]string, 0, len(costs))
+	for key := range costs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	amounts := make([]float64, len(keys))
+	for i, key := range keys {
+		amounts[i] = float64(costs[key])
+	}
+
+	tx, err := p.sqlx.Beginx()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = tx.Exec(
+		`
+			INSERT INTO rate_buckets (key, tokens, updated)
+			SELECT key, $2, now() FROM unnest($1::VARCHAR[]) AS key ORDER BY key
+			ON CONFLICT (key) DO NOTHING
+		`,
+		pq.Array(keys),
+		float64(limit.Burst),
+	); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	var shortfall float64
+	err = tx.Get(
+		&shortfall,
+		`
+			SELECT COALESCE(MAX(LEAST(c.cost, $3::DOUBLE PRECISION) - LEAST($3::DOUBLE PRECISION, b.tokens + EXTRACT(EPOCH FROM now() - b.updated) * $4::DOUBLE PRECISION)), 0)
+			FROM (SELECT key, tokens, updated FROM rate_buckets WHERE key = ANY($1) ORDER BY key FOR UPDATE) AS b
+			JOIN unnest($1::VARCHAR[], $2::DOUBLE PRECISION[]) AS c (key, cost) ON c.key = b.key
+		`,
+		pq.Array(keys),
+		pq.Array(amounts),
+		float64(limit.Burst),
+		perSecond,
+	)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if shortfall > 0 {
+		tx.Rollback()
+		return time.Duration(shortfall / perSecond * float64(time.Second)), nil
+	}
+
+	if _, err = tx.Exec(
+		`
+			UPDATE rate_buckets b
+			SET tokens = LEAST($3::DOUBLE PRECISION, b.tokens + EXTRACT(EPOCH FROM now() - b.updated) * $4::DOUBLE PRECISION) - c.cost,
+			    updated = now()
+			FROM unnest($1::VARCHAR[], $2::DOUBLE PRECISION[]) AS c (key, cost)
+			WHERE b.key = c.key
+		`,
+		pq.Array(keys),
+		pq.Array(amounts),
+		float64(limit.Burst),
+		perSecond,
+	); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return 0, tx.Commit()
+}
+
+// DeleteIdleBuckets drops buckets untouched for longer than idle.
+func (p *Postgres) DeleteIdleBuckets(idle time.Duration) (int64, error) {
+	res, err := p.sqlx.Exec(
+		"DELETE FROM rate_buckets WHERE updated < now() - $1 * INTERVAL '1 second'",
+		idle.Seconds(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (p *Postgres) forumExists(slug string) (bool, error) {
+	var exists bool
+	err := p.sqlx.Get(
+		&exists,
+		"SELECT EXISTS (SELECT 1 FROM forums WHERE slug = $1)",
+		slug,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (p *Postgres) GetLimits(forum string) ([]*models.RateLimit, error) {
+	if exists, err := p.forumExists(forum); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, internalErrors.ErrNoRows
+	}
+
+	limits := make([]*models.RateLimit, 0)
+	err := p.sqlx.Select(
+		&limits,
+		`
+			SELECT action, per_minute, burst
+			FROM forum_rate_limits
+			WHERE forum_slug = $1
+			ORDER BY action
+		`,
+		forum,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return limits, nil
+}
+
+func (p *Postgres) SetLimit(forum string, limit *models.RateLimit) error {
+	_, err := p.sqlx.Exec(
+		`
+			INSERT INTO forum_rate_limits (forum_slug, action, per_minute, burst)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (forum_slug, action) DO UPDATE
+			SET per_minute = $3, burst = $4
+		`,
+		forum,
+		limit.Action,
+		limit.PerMinute,
+		limit.Burst,
+	)
+	if err != nil {
+		pgErr, ok := err.(*pq.Error)
+		if ok && pgErr.Code == "23503" {
+			return internalErrors.ErrNoRows
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (p *Postgres) DeleteLimit(forum, action string) error {
+	_, err := p.sqlx.Exec(
+		"DELETE FROM forum_rate_limits WHERE forum_slug = $1 AND action = $2",
+		forum,
+		action,
+	)
+
+	return err
+}
+
+// InsertBan bans the user in the forum, or site-wide when ban.Forum is empty.
+func (p *Postgres) InsertBan(ban *models.Ban) (*models.Ban, error) {
+	inserted := models.Ban{}
+	err := p.sqlx.Get(
+		&inserted,
+		`
+			INSERT INTO bans (nickname, forum_slug, reason, banned_by, expires)
+			VALUES ((SELECT nickname FROM users WHERE nickname = $1), NULLIF($2, ''), $3, NULLIF($4, ''), $5)
+			RETURNING id, nickname, COALESCE(forum_slug, '') AS forum_slug, reason, COALESCE(banned_by, '') AS banned_by, expires, created
+		`,
+		ban.Nickname,
+		ban.Forum,
+		ban.Reason,
+		ban.BannedBy,
+		ban.Expires,
+	)
+	if err != nil {
+		pgErr, ok := err.(*pq.Error)
+		if ok && pgErr.Code == "23502" {
+			return nil, internalErrors.ErrUserNotFound
+		} else if ok && pgErr.Code == "23503" {
+			return nil, internalErrors.ErrNoRows
+		}
+		return nil, err
+	}
+
+	return &inserted, nil
+}
+
+// GetActiveBans lists unexpired bans of the forum, or site-wide bans when forum is empty.
+func (p *Postgres) GetActiveBans(forum string) ([]*models.Ban, error) {
+	bans := make([]*models.Ban, 0)
+	err := p.sqlx.Select(
+		&bans,
+		`
+			SELECT id, nickname, COALESCE(forum_slug, '') AS forum_slug, reason, COALESCE(banned_by, '') AS banned_by, expires, created
+			FROM bans
+			WHERE forum_slug IS NOT DISTINCT FROM NULLIF($1, '')::citext AND expires > now()
+			ORDER BY expires DESC
+		`,
+		forum,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return bans, nil
+}
+
+// DeleteBans lifts the user's active bans in the forum, or site-wide when forum is empty.
+func (p *Postgres) DeleteBans(forum, nickname string) error {
+	res, err := p.sqlx.Exec(
+		`
+			DELETE FROM bans
+			WHERE nickname = $1 AND forum_slug IS NOT DISTINCT FROM NULLIF($2, '')::citext AND expires > now()
+		`,
+		nickname,
+		forum,
+	)
+	if err != nil {
+		return err
+	}
+
+	if deleted, err := res.RowsAffected(); err != nil {
+		return err
+	} else if deleted == 0 {
+		return internalErrors.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package limitUsecase
+
+import (
+	"strings"
+	"time"
+
+	internalErrors "technopark-dbms-forum/internal"
+	limitRepository "technopark-dbms-forum/internal/limits/repository"
+	roleUsecase "technopark-dbms-forum/internal/roles/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+type LimitUsecase struct {
+	r         *limitRepository.Postgres
+	roles     *roleUsecase.RoleUsecase
+	perMinute float64
+	burst     int
+}
+
+func NewLimitUsecase(repo *limitRepository.Postgres, roles *roleUsecase.RoleUsecase, perMinute float64, burst int) *LimitUsecase {
+	return &LimitUsecase{
+		r:         repo,
+		roles:     roles,
+		perMinute: perMinute,
+		burst:     burst,
+	}
+}
+
+// Check rejects the action when any of the users is banned in the forum, or
+// when one of the users or the client IP has run out of tokens; a rejected
+// action takes no tokens at all. Every nickname stands for one action, so a
+// user is charged once per post of a batch and the client IP once per post
+// in it. Rejections are *internalErrors.RetryError wrapping ErrBanned or
+// ErrRateLimited.
+func (u *LimitUsecase) Check(action, forum, clientIP string, nicknames ...string) error {
+	limit, bannedUntil, err := u.r.GetRestrictions(forum, action, nicknames)
+	if err != nil {
+		return err
+	}
+	if !bannedUntil.IsZero() {
+		return &internalErrors.RetryError{Err: internalErrors.ErrBanned, After: time.Until(bannedUntil)}
+	}
+
+	if limit == nil {
+		limit = &models.RateLimit{Action: action, PerMinute: u.perMinute, Burst: u.burst}
+	}
+	if limit.Burst <= 0 || limit.PerMinute <= 0 {
+		return nil
+	}
+
+	prefix := action + ":" + strings.ToLower(forum) + ":"
+	costs := make(map[string]int, len(nicknames)+1)
+	for _, nickname := range nicknames {
+		costs[prefix+"user:"+strings.ToLower(nickname)]++
+	}
+	if clientIP != "" {
+		costs[prefix+"ip:"+clientIP] = len(nicknames)
+	}
+
+	after, err := u.r.Take(costs, limit)
+	if err != nil {
+		return err
+	}
+	if after > 0 {
+		return &internalErrors.RetryError{Err: internalErrors.ErrRateLimited, After: after}
+	}
+
+	return nil
+}
+
+func (u *LimitUsecase) DeleteIdleBuckets(idle time.Duration) (int64, error) {
+	return u.r.DeleteIdleBuckets(idle)
+}
+
+// canManage allows forum moderators to manage their forum, and only site
+// admins to manage site-wide bans (an empty forum).
+func (u *LimitUsecase) canManage(actor, forum string) error {
+	if forum != "" {
+		return u.roles.CanModerate(actor, forum)
+	}
+	if actor == "" {
+		return internalErrors.ErrForbidden
+	}
+
+	admin, err := u.roles.IsAdmin(actor)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		return internalErrors.ErrForbidden
+	}
+
+	return nil
+}
+
+func (u *LimitUsecase) GetBans(forum string) ([]*models.Ban, error) {
+	return u.r.GetActiveBans(forum)
+}
+
+func (u *LimitUsecase) Ban(actor, forum, nickname, reason string, duration time.Duration) (*models.Ban, error) {
+	if err := u.canManage(actor, forum); err != nil {
+		return nil, err
+	}
+
+	return u.ban(actor, forum, nickname, reason, duration)
+}
+
+// BanSiteWide bans the user everywhere without checking roles: it serves the
+// admin API, whose middleware has already admitted the caller.
+func (u *LimitUsecase) BanSiteWide(bannedBy, nickname, reason string, duration time.Duration) (*models.Ban, error) {
+	return u.ban(bannedBy, "", nickname, reason, duration)
+}
+
+func (u *LimitUsecase) ban(actor, forum, nickname, reason string, duration time.Duration) (*models.Ban, error) {
+	return u.r.InsertBan(&models.Ban{
+		Nickname: nickname,
+		Forum:    forum,
+		Reason:   reason,
+		BannedBy: actor,
+		Expires:  time.Now().Add(duration),
+	})
+}
+
+func (u *LimitUsecase) Unban(actor, forum, nickname string) error {
+	if err := u.canManage(actor, forum); err != nil {
+		return err
+	}
+
+	return u.r.DeleteBans(forum, nickname)
+}
+
+// UnbanSiteWide lifts the user's site-wide bans for the admin API, like
+// BanSiteWide.
+func (u *LimitUsecase) UnbanSiteWide(nickname string) error {
+	return u.r.DeleteBans("", nickname)
+}
+
+func (u *LimitUsecase) GetLimits(forum string) ([]*models.RateLimit, error) {
+	return u.r.GetLimits(forum)
+}
+
+func (u *LimitUsecase) SetLimit(actor, forum string, limit *models.RateLimit) error {
+	if err := u.roles.CanModerate(actor, forum); err != nil {
+		return err
+	}
+
+	return u.r.SetLimit(forum, limit)
+}
+
+func (u *LimitUsecase) DeleteLimit(actor, forum, action string) error {
+	if err := u.roles.CanModerate(actor, forum); err != nil {
+		return err
+	}
+
+	return u.r.DeleteLimit(forum, action)
+}
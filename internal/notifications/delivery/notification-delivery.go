@@ -0,0 +1,90 @@
+package notificationDelivery
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	internalErrors "technopark-dbms-forum/internal"
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
+	notificationUsecase "technopark-dbms-forum/internal/notifications/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+type Handler struct {
+	notificationUsecase *notificationUsecase.NotificationUsecase
+}
+
+func NewHandler(notificationUsecase *notificationUsecase.NotificationUsecase) *Handler {
+	return &Handler{
+		notificationUsecase: notificationUsecase,
+	}
+}
+
+func (h *Handler) Get(c echo.Context) error {
+	nickname := c.Param("nickname")
+
+	var limit int64 = 100
+	var since uint64
+	unread := false
+	var err error
+
+	if c.QueryParam("limit") != "" {
+		limit, err = strconv.ParseInt(c.QueryParam("limit"), 10, 64)
+		if err != nil || limit <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Wrong limit: %s", c.QueryParam("limit")))
+		}
+	}
+
+	if c.QueryParam("since") != "" {
+		since, err = strconv.ParseUint(c.QueryParam("since"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Wrong since: %s", c.QueryParam("since")))
+		}
+	}
+
+	if c.QueryParam("unread") != "" {
+		unread, err = strconv.ParseBool(c.QueryParam("unread"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Wrong unread: %s", c.QueryParam("unread")))
+		}
+	}
+
+	principal, _ := authDelivery.Principal(c)
+
+	page, err := h.notificationUsecase.GetPage(principal, nickname, since, limit, unread)
+	if err == internalErrors.ErrUnauthorized {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	} else if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't read notifications of user: %s", nickname))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) MarkRead(c echo.Context) error {
+	nickname := c.Param("nickname")
+
+	read := models.NotificationRead{}
+
+	if err := c.Bind(&read); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	principal, _ := authDelivery.Principal(c)
+
+	page, err := h.notificationUsecase.MarkRead(principal, nickname, read.IDs)
+	if err == internalErrors.ErrUnauthorized {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	} else if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't read notifications of user: %s", nickname))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,193 @@
+package notificationRepository
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+var mentionRegexp = regexp.MustCompile(`(?:^|[^\w.])@([\w.]+)`)
+
+// ParseMentions returns the distinct nicknames mentioned as @nickname in message.
+func ParseMentions(message string) []string {
+	nicknames := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, match := range mentionRegexp.FindAllStringSubmatch(message, -1) {
+		nickname := strings.TrimRight(match[1], ".")
+		if nickname == "" || seen[strings.ToLower(nickname)] {
+			continue
+		}
+		seen[strings.ToLower(nickname)] = true
+		nicknames = append(nicknames, nickname)
+	}
+
+	return nicknames
+}
+
+type Postgres struct {
+	sqlx *sqlx.DB
+}
+
+func NewPostgres(url string) (*Postgres, error) {
+	newSQLX, err := sqlx.Connect("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = newSQLX.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &Postgres{sqlx: newSQLX}, nil
+}
+
+func (p *Postgres) Close() error {
+	return p.sqlx.Close()
+}
+
+// InsertForPosts creates reply, mention and subscription notifications for
+// posts that were just inserted in tx. Authors are never notified about their
+// own posts, a user gets at most one notification per post and unknown
+// nicknames are skipped.
+func InsertForPosts(tx sqlx.Execer, posts []*models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(posts))
+	mentionPosts := make([]int64, 0)
+	mentionNicknames := make([]string, 0)
+	for _, post := range posts {
+		ids = append(ids, int64(post.ID))
+		for _, nickname := range ParseMentions(post.Message) {
+			mentionPosts = append(mentionPosts, int64(post.ID))
+			mentionNicknames = append(mentionNicknames, nickname)
+		}
+	}
+
+	if _, err := tx.Exec(
+		`
+			INSERT INTO notifications (nickname, type, author_nickname, forum_slug, thread_id, post_id)
+			SELECT parent.author_nickname, $2, p.author_nickname, p.forum_slug, p.thread_id, p.id
+			FROM posts p
+			JOIN posts parent ON parent.id = p.parent_id
+			WHERE p.id = ANY($1) AND parent.author_nickname <> p.author_nickname
+			ON CONFLICT (nickname, post_id) DO NOTHING
+		`,
+		pq.Array(ids),
+		models.NotificationReply,
+	); err != nil {
+		return err
+	}
+
+	if len(mentionPosts) != 0 {
+		if _, err := tx.Exec(
+			`
+				INSERT INTO notifications (nickname, type, author_nickname, forum_slug, thread_id, post_id)
+				SELECT u.nickname, $3, p.author_nickname, p.forum_slug, p.thread_id, p.id
+				FROM unnest($1::BIGINT[], $2::TEXT[]) AS m(post_id, nickname)
+				JOIN users u ON u.nickname = m.nickname::citext
+				JOIN posts p ON p.id = m.post_id
+				WHERE u.nickname <> p.author_nickname
+				ON CONFLICT (nickname, post_id) DO NOTHING
+			`,
+			pq.Array(mentionPosts),
+			pq.Array(mentionNicknames),
+			models.NotificationMention,
+		); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec(
+		`
+			INSERT INTO notifications (nickname, type, author_nickname, forum_slug, thread_id, post_id)
+			SELECT s.nickname, $2, p.author_nickname, p.forum_slug, p.thread_id, p.id
+			FROM posts p
+			JOIN thread_subscriptions s ON s.thread_id = p.thread_id
+			WHERE p.id = ANY($1) AND s.nickname <> p.author_nickname
+			ON CONFLICT (nickname, post_id) DO NOTHING
+		`,
+		pq.Array(ids),
+		models.NotificationPost,
+	)
+
+	return err
+}
+
+// InsertForThread notifies the followers of the thread's forum about a thread just inserted in tx.
+func InsertForThread(tx sqlx.Execer, thread *models.ThreadResponse) error {
+	_, err := tx.Exec(
+		`
+			INSERT INTO notifications (nickname, type, author_nickname, forum_slug, thread_id)
+			SELECT f.nickname, $1, $2, f.forum_slug, $4
+			FROM forum_follows f
+			WHERE f.forum_slug = $3 AND f.nickname <> $2
+		`,
+		models.NotificationThread,
+		thread.Author,
+		thread.Forum,
+		thread.ID,
+	)
+
+	return err
+}
+
+func (p *Postgres) GetByNickname(nickname string, since uint64, limit int64, unreadOnly bool) ([]*models.Notification, error) {
+	notifications := make([]*models.Notification, 0)
+	err := p.sqlx.Select(
+		&notifications,
+		`
+			SELECT id, type, author_nickname, forum_slug, thread_id, COALESCE(post_id, 0) as post_id, is_read, created
+			FROM notifications
+			WHERE nickname = $1 AND ($2::BIGINT = 0 OR id < $2) AND (NOT $3 OR NOT is_read)
+			ORDER BY id DESC
+			LIMIT $4
+		`,
+		nickname,
+		since,
+		unreadOnly,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (p *Postgres) CountUnread(nickname string) (uint64, error) {
+	var unread uint64
+	err := p.sqlx.Get(
+		&unread,
+		"SELECT COUNT(*) FROM notifications WHERE nickname = $1 AND NOT is_read",
+		nickname,
+	)
+
+	return unread, err
+}
+
+// MarkRead marks the given notifications of the user as read, or all of them if ids is empty.
+func (p *Postgres) MarkRead(nickname string, ids []uint64) error {
+	list := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, int64(id))
+	}
+
+	_, err := p.sqlx.Exec(
+		`
+			UPDATE notifications
+			SET is_read = true
+			WHERE nickname = $1 AND NOT is_read AND (cardinality($2::BIGINT[]) = 0 OR id = ANY($2))
+		`,
+		nickname,
+		pq.Array(list),
+	)
+
+	return err
+}
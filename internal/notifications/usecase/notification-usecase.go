@@ -0,0 +1,76 @@
+package notificationUsecase
+
+import (
+	"strings"
+
+	internalErrors "technopark-dbms-forum/internal"
+	notificationRepository "technopark-dbms-forum/internal/notifications/repository"
+	"technopark-dbms-forum/pkg/models"
+)
+
+type NotificationUsecase struct {
+	r *notificationRepository.Postgres
+}
+
+func NewNotificationUsecase(repo *notificationRepository.Postgres) *NotificationUsecase {
+	return &NotificationUsecase{r: repo}
+}
+
+// canRead allows only the user themselves: notifications are private even to
+// site admins.
+func canRead(actor, nickname string) error {
+	if actor == "" {
+		return internalErrors.ErrUnauthorized
+	}
+	if !strings.EqualFold(actor, nickname) {
+		return internalErrors.ErrForbidden
+	}
+
+	return nil
+}
+
+func (n *NotificationUsecase) GetPage(actor, nickname string, since uint64, limit int64, unreadOnly bool) (*models.NotificationPage, error) {
+	if err := canRead(actor, nickname); err != nil {
+		return nil, err
+	}
+
+	notifications, err := n.r.GetByNickname(nickname, since, limit, unreadOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	unread, err := n.r.CountUnread(nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.NotificationPage{
+		Unread:        unread,
+		Notifications: notifications,
+	}
+	if int64(len(notifications)) == limit {
+		page.Next = notifications[len(notifications)-1].ID
+	}
+
+	return page, nil
+}
+
+func (n *NotificationUsecase) MarkRead(actor, nickname string, ids []uint64) (*models.NotificationPage, error) {
+	if err := canRead(actor, nickname); err != nil {
+		return nil, err
+	}
+
+	if err := n.r.MarkRead(nickname, ids); err != nil {
+		return nil, err
+	}
+
+	unread, err := n.r.CountUnread(nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.NotificationPage{
+		Unread:        unread,
+		Notifications: make([]*models.Notification, 0),
+	}, nil
+}
@@ -1,12 +1,13 @@
 package postDelivery
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 
-	"technopark-dbms-forum/internal/models"
+	"technopark-dbms-forum/pkg/models"
 
 	threadUsecase "technopark-dbms-forum/internal/threads/usecase"
 
@@ -18,7 +19,10 @@ import (
 
 	"github.com/labstack/echo/v4"
 
+	attachmentUsecase "technopark-dbms-forum/internal/attachments/usecase"
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
 	postUsecase "technopark-dbms-forum/internal/posts/usecase"
+	"technopark-dbms-forum/pkg/etag"
 )
 
 type Handler struct {
@@ -26,6 +30,8 @@ type Handler struct {
 	userUsecase   *userUsecase.UserUsecase
 	forumUsecase  *forumUsecase.ForumUsecase
 	threadUsecase *threadUsecase.ThreadUsecase
+	renderer      *postUsecase.Renderer
+	attachments   *attachmentUsecase.AttachmentUsecase
 }
 
 func NewHandler(
@@ -33,12 +39,16 @@ func NewHandler(
 	userUsecase *userUsecase.UserUsecase,
 	forumUsecase *forumUsecase.ForumUsecase,
 	threadUsecase *threadUsecase.ThreadUsecase,
+	renderer *postUsecase.Renderer,
+	attachments *attachmentUsecase.AttachmentUsecase,
 ) *Handler {
 	return &Handler{
 		postUsecase:   postUsecase,
 		userUsecase:   userUsecase,
 		forumUsecase:  forumUsecase,
 		threadUsecase: threadUsecase,
+		renderer:      renderer,
+		attachments:   attachments,
 	}
 }
 
@@ -48,15 +58,22 @@ func (h *Handler) GetInfo(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 	related := strings.Split(c.QueryParam("related"), ",")
+	html := c.QueryParam("format") == postUsecase.FormatHTML
 
-	post, err := h.postUsecase.GetByID(id)
+	post, err := h.postUsecase.GetVisible(id, authDelivery.Actor(c))
 	if err == internalErrors.ErrNoRows {
 		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find post with id: %d", id))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if err = h.attachments.Fill([]*models.Post{post}); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
 	fullInfo := &models.FullPost{
 		Post: post,
 	}
+	versions := []uint64{post.Version}
 
 	for _, elem := range related {
 		if elem == "user" {
@@ -65,6 +82,7 @@ func (h *Handler) GetInfo(c echo.Context) error {
 				return echo.NewHTTPError(http.StatusInternalServerError, err)
 			}
 			fullInfo.Author = user
+			versions = append(versions, user.Version)
 		}
 		if elem == "forum" {
 			forum, err := h.forumUsecase.GetFullBySlug(post.Forum)
@@ -72,17 +90,30 @@ func (h *Handler) GetInfo(c echo.Context) error {
 				return echo.NewHTTPError(http.StatusInternalServerError, err)
 			}
 			fullInfo.Forum = forum
+			versions = append(versions, forum.Version)
 		}
 		if elem == "thread" {
 			thread, err := h.threadUsecase.GetBySlugOrID(strconv.FormatUint(post.Thread, 10))
 			if err != nil {
 				return echo.NewHTTPError(http.StatusInternalServerError, err)
 			}
+			if html {
+				h.renderer.RenderThread(thread)
+			}
 			fullInfo.Thread = thread
+			versions = append(versions, thread.Version)
+		}
+	}
+
+	tag := etag.Make(versions...)
+	if html {
+		if err = h.renderer.RenderPosts([]*models.Post{post}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
 		}
+		tag = etag.Variant(tag, postUsecase.FormatHTML)
 	}
 
-	return c.JSON(http.StatusOK, fullInfo)
+	return etag.JSON(c, http.StatusOK, tag, fullInfo)
 }
 
 func (h *Handler) Update(c echo.Context) error {
@@ -91,6 +122,11 @@ func (h *Handler) Update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
+	versions, ok := etag.Versions(c.Request().Header.Get(etag.HeaderIfMatch))
+	if !ok {
+		return echo.NewHTTPError(http.StatusPreconditionFailed, fmt.Sprintf("Post was modified: %d", id))
+	}
+
 	post := models.Post{
 		ID: id,
 	}
@@ -100,12 +136,49 @@ func (h *Handler) Update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
-	updatedPost, err := h.postUsecase.Update(&post)
-	if err == internalErrors.ErrNoRows {
+	actor := authDelivery.Actor(c)
+
+	var rejected *internalErrors.ContentError
+
+	updatedPost, err := h.postUsecase.Update(&post, versions, actor)
+	if errors.As(err, &rejected) {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Post was rejected: %s", rejected.Reason))
+	} else if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't edit post with id: %d", id))
+	} else if err == internalErrors.ErrPreconditionFailed {
+		return echo.NewHTTPError(http.StatusPreconditionFailed, fmt.Sprintf("Post was modified: %d", id))
+	} else if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find post with id: %d", id))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return etag.JSON(c, http.StatusOK, etag.Make(updatedPost.Version), updatedPost)
+}
+
+func (h *Handler) Moderate(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	moderation := models.PostModeration{}
+
+	err = c.Bind(&moderation)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	principal, _ := authDelivery.Principal(c)
+
+	post, err := h.postUsecase.Moderate(id, &moderation, principal)
+	if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't moderate post with id: %d", id))
+	} else if err == internalErrors.ErrNoRows {
 		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find post with id: %d", id))
 	} else if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
-	return c.JSON(http.StatusOK, updatedPost)
+	return etag.JSON(c, http.StatusOK, etag.Make(post.Version), post)
 }
@@ -0,0 +1,168 @@
+package postDelivery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"technopark-dbms-forum/api/forumpb"
+	internalErrors "technopark-dbms-forum/internal"
+	attachmentUsecase "technopark-dbms-forum/internal/attachments/usecase"
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
+	forumUsecase "technopark-dbms-forum/internal/forums/usecase"
+	limitDelivery "technopark-dbms-forum/internal/limits/delivery"
+	postUsecase "technopark-dbms-forum/internal/posts/usecase"
+	threadUsecase "technopark-dbms-forum/internal/threads/usecase"
+	userUsecase "technopark-dbms-forum/internal/users/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+// GRPCServer serves forumpb.PostService with the usecases of Handler and of
+// the thread handler that creates posts.
+type GRPCServer struct {
+	forumpb.UnimplementedPostServiceServer
+
+	postUsecase   *postUsecase.PostUsecase
+	userUsecase   *userUsecase.UserUsecase
+	forumUsecase  *forumUsecase.ForumUsecase
+	threadUsecase *threadUsecase.ThreadUsecase
+	attachments   *attachmentUsecase.AttachmentUsecase
+	// trustedProxies may set the client address rate limits count on.
+	trustedProxies []*net.IPNet
+}
+
+func NewGRPCServer(
+	postUsecase *postUsecase.PostUsecase,
+	userUsecase *userUsecase.UserUsecase,
+	forumUsecase *forumUsecase.ForumUsecase,
+	threadUsecase *threadUsecase.ThreadUsecase,
+	attachments *attachmentUsecase.AttachmentUsecase,
+	trustedProxies []*net.IPNet,
+) *GRPCServer {
+	return &GRPCServer{
+		postUsecase:    postUsecase,
+		userUsecase:    userUsecase,
+		forumUsecase:   forumUsecase,
+		threadUsecase:  threadUsecase,
+		attachments:    attachments,
+		trustedProxies: trustedProxies,
+	}
+}
+
+func (s *GRPCServer) CreatePosts(ctx context.Context, req *forumpb.CreatePostsRequest) (*forumpb.Posts, error) {
+	slugOrID := req.GetSlugOrId()
+
+	posts := make([]*models.Post, 0, len(req.GetPosts()))
+	for _, post := range req.GetPosts() {
+		posts = append(posts, post.Model())
+	}
+	if principal, ok := authDelivery.PrincipalFromContext(ctx); ok {
+		for _, post := range posts {
+			post.Author = principal
+		}
+	}
+
+	var retry *internalErrors.RetryError
+	var rejected *internalErrors.ContentError
+
+	response, err := s.threadUsecase.CreatePosts(slugOrID, posts, limitDelivery.ClientIP(ctx, s.trustedProxies))
+	if errors.As(err, &retry) {
+		return nil, limitDelivery.RejectStatus(retry)
+	} else if errors.As(err, &rejected) {
+		return nil, status.Errorf(codes.InvalidArgument, "Post was rejected: %s", rejected.Reason)
+	} else if err == internalErrors.ErrNoRowsBySlug {
+		return nil, status.Errorf(codes.NotFound, "Can't find post thread by slug: %s", slugOrID)
+	} else if err == internalErrors.ErrNoRowsByID {
+		return nil, status.Errorf(codes.NotFound, "Can't find post thread by id: %s", slugOrID)
+	} else if err == internalErrors.ErrThreadClosed {
+		return nil, status.Errorf(codes.PermissionDenied, "Thread is closed: %s", slugOrID)
+	} else if err == internalErrors.ErrPostWasCreatedInAnotherThread {
+		return nil, status.Error(codes.InvalidArgument, "Post was created in another thread")
+	} else if err == internalErrors.ErrPostAuthorNotFound {
+		return nil, status.Errorf(codes.NotFound, "Can't find post author by nickname: %s", posts[0].Author)
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	for _, post := range response {
+		s.forumUsecase.PublishActivity(post.Forum, models.EventPostCreated, post)
+	}
+
+	return forumpb.FromPosts(response), nil
+}
+
+func (s *GRPCServer) GetPost(ctx context.Context, req *forumpb.GetPostRequest) (*forumpb.PostDetails, error) {
+	id := req.GetId()
+
+	post, err := s.postUsecase.GetVisible(id, authDelivery.ActorFromContext(ctx))
+	if err == internalErrors.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "Can't find post with id: %d", id)
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if err = s.attachments.Fill([]*models.Post{post}); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	details := &forumpb.PostDetails{
+		Post: forumpb.FromPost(post),
+	}
+
+	for _, elem := range req.GetRelated() {
+		if elem == "user" {
+			user, err := s.userUsecase.GetByNickname(post.Author)
+			if err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+			details.Author = forumpb.FromUser(user)
+		}
+		if elem == "forum" {
+			forum, err := s.forumUsecase.GetFullBySlug(post.Forum)
+			if err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+			details.Forum = forumpb.FromForumResponse(forum)
+		}
+		if elem == "thread" {
+			thread, err := s.threadUsecase.GetBySlugOrID(strconv.FormatUint(post.Thread, 10))
+			if err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+			details.Thread = forumpb.FromThread(thread)
+		}
+	}
+
+	return details, nil
+}
+
+func (s *GRPCServer) UpdatePost(ctx context.Context, req *forumpb.UpdatePostRequest) (*forumpb.Post, error) {
+	id := req.GetId()
+
+	post := &models.Post{
+		ID:      id,
+		Message: req.GetMessage(),
+	}
+
+	actor := authDelivery.ActorFromContext(ctx)
+
+	var rejected *internalErrors.ContentError
+
+	updatedPost, err := s.postUsecase.Update(post, forumpb.Versions(req.GetVersion()), actor)
+	if errors.As(err, &rejected) {
+		return nil, status.Errorf(codes.InvalidArgument, "Post was rejected: %s", rejected.Reason)
+	} else if err == internalErrors.ErrForbidden {
+		return nil, status.Errorf(codes.PermissionDenied, "Can't edit post with id: %d", id)
+	} else if err == internalErrors.ErrPreconditionFailed {
+		return nil, status.Errorf(codes.FailedPrecondition, "Post was modified: %d", id)
+	} else if err == internalErrors.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "Can't find post with id: %d", id)
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return forumpb.FromPost(updatedPost), nil
+}
@@ -5,7 +5,9 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	internalErrors "technopark-dbms-forum/internal"
-	"technopark-dbms-forum/internal/models"
+	eventRepository "technopark-dbms-forum/internal/events/repository"
+	filterRepository "technopark-dbms-forum/internal/filters/repository"
+	"technopark-dbms-forum/pkg/models"
 )
 
 type Postgres struct {
@@ -34,7 +36,7 @@ func (p *Postgres) GetByID(id uint64) (*models.Post, error) {
 	err := p.sqlx.Get(
 		&post,
 		`
-			SELECT id, author_nickname, forum_slug, message, thread_id, parent_id, is_edited, created
+			SELECT id, author_nickname, forum_slug, message, thread_id, parent_id, is_edited, is_hidden, created, version
 			FROM posts
 			WHERE id = $1
 		`,
@@ -53,20 +55,134 @@ func (p *Postgres) GetByID(id uint64) (*models.Post, error) {
 	return &post, nil
 }
 
-func (p *Postgres) Update(newPost *models.Post) (*models.Post, error) {
-	_, err := p.sqlx.Exec(
+// GetByIDs returns the posts found among the ids, in no particular order.
+func (p *Postgres) GetByIDs(ids []int64) ([]*models.Post, error) {
+	posts := make([]*models.Post, 0, len(ids))
+	err := p.sqlx.Select(
+		&posts,
+		`
+			SELECT id, author_nickname, forum_slug, message, thread_id, parent_id, is_edited, is_hidden, created, version
+			FROM posts
+			WHERE id = ANY($1::bigint[])
+		`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// GetIDsInThread returns those of the given post ids that belong to the thread.
+func (p *Postgres) GetIDsInThread(thread uint64, ids []int64) ([]uint64, error) {
+	found := make([]uint64, 0, len(ids))
+	err := p.sqlx.Select(
+		&found,
+		"SELECT id FROM posts WHERE thread_id = $1 AND id = ANY($2)",
+		thread,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+func (p *Postgres) Update(newPost *models.Post, versions []int64) (*models.Post, error) {
+	tx, err := p.sqlx.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	post := models.Post{}
+	err = tx.Get(
+		&post,
 		`
 			UPDATE posts 
 			SET message = COALESCE(NULLIF($1, ''), message), 
 			    is_edited = CASE WHEN message = COALESCE(NULLIF($1, ''), message) THEN is_edited ELSE true END
-			WHERE id = $2
+			WHERE id = $2 AND ($3::BIGINT[] IS NULL OR version = ANY($3))
+			RETURNING id, author_nickname, forum_slug, message, thread_id, parent_id, is_edited, is_hidden, created, version
 		`,
 		newPost.Message,
 		newPost.ID,
+		pq.Array(versions),
 	)
+	if err == sql.ErrNoRows {
+		var exists bool
+		err = tx.Get(&exists, "SELECT EXISTS (SELECT 1 FROM posts WHERE id = $1)", newPost.ID)
+		tx.Rollback()
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, internalErrors.ErrPreconditionFailed
+		}
+		return nil, internalErrors.ErrNoRows
+	} else if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	post.Flags, post.Fingerprint = newPost.Flags, newPost.Fingerprint
+	if err = filterRepository.InsertForPosts(tx, []*models.Post{&post}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = filterRepository.InsertFingerprints(tx, []*models.Post{&post}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = eventRepository.Insert(tx, models.EventPostUpdated, &post); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &post, nil
+}
+
+func (p *Postgres) Moderate(id uint64, m *models.PostModeration) (*models.Post, error) {
+	tx, err := p.sqlx.Beginx()
 	if err != nil {
 		return nil, err
 	}
 
-	return newPost, nil
+	post := models.Post{}
+	err = tx.Get(
+		&post,
+		`
+			UPDATE posts
+			SET is_hidden = COALESCE($1, is_hidden)
+			WHERE id = $2
+			RETURNING id, author_nickname, forum_slug, message, thread_id, parent_id, is_edited, is_hidden, created, version
+		`,
+		m.Hidden,
+		id,
+	)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return nil, internalErrors.ErrNoRows
+	} else if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = eventRepository.Insert(tx, models.EventPostUpdated, &post); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &post, nil
 }
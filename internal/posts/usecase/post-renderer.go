@@ -0,0 +1,149 @@
+package postUsecase
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+
+	postRepository "technopark-dbms-forum/internal/posts/repository"
+	"technopark-dbms-forum/pkg/markdown"
+	"technopark-dbms-forum/pkg/models"
+)
+
+// FormatHTML is the format query value that asks for rendered messages.
+const FormatHTML = "html"
+
+const (
+	renderPost byte = iota
+	renderThread
+)
+
+type renderKey struct {
+	kind    byte
+	id      uint64
+	version uint64
+}
+
+type renderEntry struct {
+	key  renderKey
+	html string
+}
+
+type threadPost struct {
+	thread uint64
+	id     uint64
+}
+
+// Renderer turns post and thread messages into sanitized HTML. Results are
+// cached per row version, so an edit makes the old entry unreachable and it
+// is evicted once it becomes the least recently used one.
+type Renderer struct {
+	r    *postRepository.Postgres
+	size int
+
+	mu      sync.Mutex
+	entries map[renderKey]*list.Element
+	order   *list.List
+}
+
+func NewRenderer(repo *postRepository.Postgres, size int) *Renderer {
+	return &Renderer{
+		r:       repo,
+		size:    size,
+		entries: make(map[renderKey]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (r *Renderer) get(key renderKey) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elem, ok := r.entries[key]
+	if !ok {
+		return "", false
+	}
+	r.order.MoveToFront(elem)
+
+	return elem.Value.(*renderEntry).html, true
+}
+
+func (r *Renderer) put(key renderKey, html string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if elem, ok := r.entries[key]; ok {
+		r.order.MoveToFront(elem)
+		return
+	}
+
+	r.entries[key] = r.order.PushFront(&renderEntry{key: key, html: html})
+	for r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*renderEntry).key)
+	}
+}
+
+func postHref(id uint64) string {
+	return "#post-" + strconv.FormatUint(id, 10)
+}
+
+// RenderPosts fills in MessageHTML. A >>postID back-reference becomes a link
+// only if it points to an earlier post of the same thread.
+func (r *Renderer) RenderPosts(posts []*models.Post) error {
+	missing := make([]*models.Post, 0)
+	for _, post := range posts {
+		if html, ok := r.get(renderKey{renderPost, post.ID, post.Version}); ok {
+			post.MessageHTML = html
+			continue
+		}
+		missing = append(missing, post)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	refs := make(map[uint64][]int64)
+	for _, post := range missing {
+		for _, id := range markdown.References(post.Message) {
+			if id < post.ID {
+				refs[post.Thread] = append(refs[post.Thread], int64(id))
+			}
+		}
+	}
+
+	valid := make(map[threadPost]bool)
+	for thread, ids := range refs {
+		found, err := r.r.GetIDsInThread(thread, ids)
+		if err != nil {
+			return err
+		}
+		for _, id := range found {
+			valid[threadPost{thread, id}] = true
+		}
+	}
+
+	for _, post := range missing {
+		post := post
+		post.MessageHTML = markdown.Render(post.Message, func(id uint64) (string, bool) {
+			return postHref(id), id < post.ID && valid[threadPost{post.Thread, id}]
+		})
+		r.put(renderKey{renderPost, post.ID, post.Version}, post.MessageHTML)
+	}
+
+	return nil
+}
+
+// RenderThread fills in the thread's MessageHTML. Threads have no earlier
+// posts to refer to, so back-references stay plain text.
+func (r *Renderer) RenderThread(thread *models.ThreadResponse) {
+	key := renderKey{renderThread, thread.ID, thread.Version}
+	if html, ok := r.get(key); ok {
+		thread.MessageHTML = html
+		return
+	}
+
+	thread.MessageHTML = markdown.Render(thread.Message, nil)
+	r.put(key, thread.MessageHTML)
+}
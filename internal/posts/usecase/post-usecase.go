@@ -1,26 +1,117 @@
 package postUsecase
 
 import (
-	"technopark-dbms-forum/internal/models"
+	"strings"
+
+	internalErrors "technopark-dbms-forum/internal"
+	filterUsecase "technopark-dbms-forum/internal/filters/usecase"
 	postRepository "technopark-dbms-forum/internal/posts/repository"
+	roleUsecase "technopark-dbms-forum/internal/roles/usecase"
+	"technopark-dbms-forum/pkg/models"
 )
 
 type PostUsecase struct {
-	r *postRepository.Postgres
+	r       *postRepository.Postgres
+	roles   *roleUsecase.RoleUsecase
+	filters *filterUsecase.FilterUsecase
 }
 
-func NewPostUsecase(repo *postRepository.Postgres) *PostUsecase {
-	return &PostUsecase{r: repo}
+func NewPostUsecase(repo *postRepository.Postgres, roles *roleUsecase.RoleUsecase, filters *filterUsecase.FilterUsecase) *PostUsecase {
+	return &PostUsecase{r: repo, roles: roles, filters: filters}
 }
 
 func (p *PostUsecase) GetByID(id uint64) (*models.Post, error) {
 	return p.r.GetByID(id)
 }
 
-func (p *PostUsecase) Update(post *models.Post) (*models.Post, error) {
-	_, err := p.r.Update(post)
+func (p *PostUsecase) GetByIDs(ids []int64) ([]*models.Post, error) {
+	return p.r.GetByIDs(ids)
+}
+
+// GetVisible is GetByID for readers: a hidden post is only found by those
+// who may moderate its forum, and is ErrNoRows to everyone else.
+func (p *PostUsecase) GetVisible(id uint64, actor string) (*models.Post, error) {
+	post, err := p.r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	visible, err := p.Visible([]*models.Post{post}, actor)
+	if err != nil {
+		return nil, err
+	}
+	if len(visible) == 0 {
+		return nil, internalErrors.ErrNoRows
+	}
+
+	return post, nil
+}
+
+// Visible leaves out the hidden posts the actor may not moderate, asking
+// once per forum.
+func (p *PostUsecase) Visible(posts []*models.Post, actor string) ([]*models.Post, error) {
+	moderates := make(map[string]bool)
+
+	visible := make([]*models.Post, 0, len(posts))
+	for _, post := range posts {
+		if post.IsHidden {
+			forum := strings.ToLower(post.Forum)
+			allowed, checked := moderates[forum]
+			if !checked {
+				err := p.roles.CanModerate(actor, post.Forum)
+				if err != nil && err != internalErrors.ErrForbidden {
+					return nil, err
+				}
+				allowed = err == nil
+				moderates[forum] = allowed
+			}
+			if !allowed {
+				continue
+			}
+		}
+		visible = append(visible, post)
+	}
+
+	return visible, nil
+}
+
+func (p *PostUsecase) Update(post *models.Post, versions []int64, actor string) (*models.Post, error) {
+	if actor == models.ActorAnonymous && (post.Message == "" || !p.filters.Enabled()) {
+		return p.r.Update(post, versions)
+	}
+
+	current, err := p.r.GetByID(post.ID)
 	if err != nil {
 		return nil, err
 	}
-	return p.r.GetByID(post.ID)
+	if err = p.roles.CanEdit(actor, current.Author, current.Forum); err != nil {
+		return nil, err
+	}
+
+	if post.Message != "" && post.Message != current.Message {
+		content := models.Content{
+			Author:  current.Author,
+			Forum:   current.Forum,
+			Message: post.Message,
+		}
+		flags, err := p.filters.Apply(&content)
+		if err != nil {
+			return nil, err
+		}
+		post.Message, post.Flags, post.Fingerprint = content.Message, flags, content.Fingerprint
+	}
+
+	return p.r.Update(post, versions)
+}
+
+func (p *PostUsecase) Moderate(id uint64, moderation *models.PostModeration, actor string) (*models.Post, error) {
+	post, err := p.r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.roles.CanModerate(actor, post.Forum); err != nil {
+		return nil, err
+	}
+
+	return p.r.Moderate(id, moderation)
 }
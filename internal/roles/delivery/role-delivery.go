@@ -0,0 +1,86 @@
+package roleDelivery
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	internalErrors "technopark-dbms-forum/internal"
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
+	roleUsecase "technopark-dbms-forum/internal/roles/usecase"
+)
+
+type Handler struct {
+	roleUsecase *roleUsecase.RoleUsecase
+}
+
+func NewHandler(roleUsecase *roleUsecase.RoleUsecase) *Handler {
+	return &Handler{
+		roleUsecase: roleUsecase,
+	}
+}
+
+func (h *Handler) GetModerators(c echo.Context) error {
+	slug := c.Param("slug")
+
+	moderators, err := h.roleUsecase.GetModerators(slug)
+	if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find forum with slug: %s", slug))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, moderators)
+}
+
+func (h *Handler) Grant(c echo.Context) error {
+	slug := c.Param("slug")
+	nickname := c.Param("nickname")
+	principal, _ := authDelivery.Principal(c)
+
+	created, err := h.roleUsecase.GrantModerator(principal, slug, nickname)
+	if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't manage moderators of forum: %s", slug))
+	} else if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find forum with slug: %s", slug))
+	} else if err == internalErrors.ErrUserNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find user with nickname: %s", nickname))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	moderators, err := h.roleUsecase.GetModerators(slug)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	if created {
+		return c.JSON(http.StatusCreated, moderators)
+	}
+	return c.JSON(http.StatusOK, moderators)
+}
+
+func (h *Handler) Revoke(c echo.Context) error {
+	slug := c.Param("slug")
+	nickname := c.Param("nickname")
+	principal, _ := authDelivery.Principal(c)
+
+	err := h.roleUsecase.RevokeModerator(principal, slug, nickname)
+	if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't manage moderators of forum: %s", slug))
+	} else if err == internalErrors.ErrNoRows {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find forum with slug: %s", slug))
+	} else if err == internalErrors.ErrNotModerator {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("User %s is not a moderator of forum: %s", nickname, slug))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	moderators, err := h.roleUsecase.GetModerators(slug)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, moderators)
+}
@@ -0,0 +1,162 @@
+package roleRepository
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	internalErrors "technopark-dbms-forum/internal"
+	"technopark-dbms-forum/pkg/models"
+)
+
+type Postgres struct {
+	sqlx *sqlx.DB
+}
+
+func NewPostgres(url string) (*Postgres, error) {
+	newSQLX, err := sqlx.Connect("postgres", url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = newSQLX.Ping(); err != nil {
+		return nil, err
+	}
+
+	return &Postgres{sqlx: newSQLX}, nil
+}
+
+func (p *Postgres) Close() error {
+	return p.sqlx.Close()
+}
+
+// GetRole returns the strongest role the user holds in the forum, or an
+// empty string for an ordinary user.
+func (p *Postgres) GetRole(slug, nickname string) (string, error) {
+	var role string
+	err := p.sqlx.Get(
+		&role,
+		`
+			SELECT CASE
+			    WHEN EXISTS (SELECT 1 FROM site_admins WHERE nickname = $2) THEN $3
+			    WHEN EXISTS (SELECT 1 FROM forums WHERE slug = $1 AND author_nickname = $2) THEN $4
+			    WHEN EXISTS (SELECT 1 FROM forum_moderators WHERE forum_slug = $1 AND nickname = $2) THEN $5
+			    ELSE ''
+			END
+		`,
+		slug,
+		nickname,
+		models.RoleAdmin,
+		models.RoleOwner,
+		models.RoleModerator,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
+func (p *Postgres) IsAdmin(nickname string) (bool, error) {
+	var admin bool
+	err := p.sqlx.Get(
+		&admin,
+		"SELECT EXISTS (SELECT 1 FROM site_admins WHERE nickname = $1)",
+		nickname,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return admin, nil
+}
+
+func (p *Postgres) ForumExists(slug string) (bool, error) {
+	var exists bool
+	err := p.sqlx.Get(
+		&exists,
+		"SELECT EXISTS (SELECT 1 FROM forums WHERE slug = $1)",
+		slug,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (p *Postgres) GetModerators(slug string) ([]*models.Moderator, error) {
+	if exists, err := p.ForumExists(slug); err != nil {
+		return nil, err
+	} else if !exists {
+		return nil, internalErrors.ErrNoRows
+	}
+
+	moderators := make([]*models.Moderator, 0)
+	err := p.sqlx.Select(
+		&moderators,
+		`
+			SELECT nickname, forum_slug, COALESCE(granted_by, '') AS granted_by, created
+			FROM forum_moderators
+			WHERE forum_slug = $1
+			ORDER BY nickname
+		`,
+		slug,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return moderators, nil
+}
+
+func (p *Postgres) AddModerator(slug, nickname, grantedBy string) (bool, error) {
+	if exists, err := p.ForumExists(slug); err != nil {
+		return false, err
+	} else if !exists {
+		return false, internalErrors.ErrNoRows
+	}
+
+	res, err := p.sqlx.Exec(
+		`
+			INSERT INTO forum_moderators (forum_slug, nickname, granted_by)
+			VALUES ($1, $2, NULLIF($3, ''))
+			ON CONFLICT DO NOTHING
+		`,
+		slug,
+		nickname,
+		grantedBy,
+	)
+	if err != nil {
+		pgErr, ok := err.(*pq.Error)
+		if ok && pgErr.Code == "23503" {
+			return false, internalErrors.ErrUserNotFound
+		}
+		return false, err
+	}
+
+	created, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return created != 0, nil
+}
+
+func (p *Postgres) RemoveModerator(slug, nickname string) error {
+	res, err := p.sqlx.Exec(
+		"DELETE FROM forum_moderators WHERE forum_slug = $1 AND nickname = $2",
+		slug,
+		nickname,
+	)
+	if err != nil {
+		return err
+	}
+
+	if deleted, err := res.RowsAffected(); err != nil {
+		return err
+	} else if deleted == 0 {
+		return internalErrors.ErrNotModerator
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package roleUsecase
+
+import (
+	"strings"
+
+	internalErrors "technopark-dbms-forum/internal"
+	roleRepository "technopark-dbms-forum/internal/roles/repository"
+	"technopark-dbms-forum/pkg/models"
+)
+
+// RoleUsecase answers who may do what. Every check takes the acting user's
+// nickname, or models.ActorAnonymous for an anonymous write the auth layer
+// let through in open mode, which may edit content but never moderate it.
+// Every check denies an empty actor.
+type RoleUsecase struct {
+	r *roleRepository.Postgres
+}
+
+func NewRoleUsecase(repo *roleRepository.Postgres) *RoleUsecase {
+	return &RoleUsecase{r: repo}
+}
+
+// CanModerate allows site admins and the forum's owner and moderators.
+func (u *RoleUsecase) CanModerate(actor, forum string) error {
+	if actor == "" || actor == models.ActorAnonymous {
+		return internalErrors.ErrForbidden
+	}
+
+	role, err := u.r.GetRole(forum, actor)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return internalErrors.ErrForbidden
+	}
+
+	return nil
+}
+
+// CanEdit allows the author of a post or thread and anyone who may moderate its forum.
+func (u *RoleUsecase) CanEdit(actor, author, forum string) error {
+	if actor == "" {
+		return internalErrors.ErrForbidden
+	}
+	if actor == models.ActorAnonymous || strings.EqualFold(actor, author) {
+		return nil
+	}
+
+	return u.CanModerate(actor, forum)
+}
+
+// CanEditProfile allows the user themselves and site admins.
+func (u *RoleUsecase) CanEditProfile(actor, nickname string) error {
+	if actor == "" {
+		return internalErrors.ErrForbidden
+	}
+	if actor == models.ActorAnonymous || strings.EqualFold(actor, nickname) {
+		return nil
+	}
+
+	admin, err := u.r.IsAdmin(actor)
+	if err != nil {
+		return err
+	}
+	if !admin {
+		return internalErrors.ErrForbidden
+	}
+
+	return nil
+}
+
+func (u *RoleUsecase) IsAdmin(nickname string) (bool, error) {
+	return u.r.IsAdmin(nickname)
+}
+
+func (u *RoleUsecase) GetModerators(forum string) ([]*models.Moderator, error) {
+	return u.r.GetModerators(forum)
+}
+
+// canManage is CanModerate for the moderator list, which first tells a
+// missing forum apart with ErrNoRows.
+func (u *RoleUsecase) canManage(actor, forum string) error {
+	if exists, err := u.r.ForumExists(forum); err != nil {
+		return err
+	} else if !exists {
+		return internalErrors.ErrNoRows
+	}
+
+	return u.CanModerate(actor, forum)
+}
+
+func (u *RoleUsecase) GrantModerator(actor, forum, nickname string) (bool, error) {
+	if err := u.canManage(actor, forum); err != nil {
+		return false, err
+	}
+
+	return u.r.AddModerator(forum, nickname, actor)
+}
+
+func (u *RoleUsecase) RevokeModerator(actor, forum, nickname string) error {
+	if err := u.canManage(actor, forum); err != nil {
+		return err
+	}
+
+	return u.r.RemoveModerator(forum, nickname)
+}
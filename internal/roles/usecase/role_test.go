@@ -0,0 +1,36 @@
+package roleUsecase_test
+
+import (
+	"testing"
+
+	internalErrors "technopark-dbms-forum/internal"
+	roleUsecase "technopark-dbms-forum/internal/roles/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+// The cases are decided before any role is looked up, so no repository is
+// needed.
+func TestActors(t *testing.T) {
+	u := roleUsecase.NewRoleUsecase(nil)
+
+	cases := []struct {
+		name  string
+		check func() error
+		want  error
+	}{
+		{"nobody edits", func() error { return u.CanEdit("", "alice", "pirates") }, internalErrors.ErrForbidden},
+		{"nobody edits a profile", func() error { return u.CanEditProfile("", "alice") }, internalErrors.ErrForbidden},
+		{"nobody moderates", func() error { return u.CanModerate("", "pirates") }, internalErrors.ErrForbidden},
+		{"anonymous edits", func() error { return u.CanEdit(models.ActorAnonymous, "alice", "pirates") }, nil},
+		{"anonymous edits a profile", func() error { return u.CanEditProfile(models.ActorAnonymous, "alice") }, nil},
+		{"anonymous never moderates", func() error { return u.CanModerate(models.ActorAnonymous, "pirates") }, internalErrors.ErrForbidden},
+		{"author edits", func() error { return u.CanEdit("Alice", "alice", "pirates") }, nil},
+		{"user edits own profile", func() error { return u.CanEditProfile("ALICE", "alice") }, nil},
+	}
+
+	for _, c := range cases {
+		if err := c.check(); err != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	forumRepository "technopark-dbms-forum/internal/forums/repository"
+	threadRepository "technopark-dbms-forum/internal/threads/repository"
+	userRepository "technopark-dbms-forum/internal/users/repository"
+	"technopark-dbms-forum/pkg/models"
+)
+
+// Config describes the synthetic data set. Skews are Zipf exponents: at 0
+// (or anything up to 1) threads spread evenly over forums and posts over
+// threads, larger values pile them onto a few popular ones.
+type Config struct {
+	Prefix string
+	Seed   int64
+
+	Users   int
+	Forums  int
+	Threads int
+	Posts   int
+	Votes   int
+
+	ForumSkew  float64
+	ThreadSkew float64
+	// ReplyRatio is the share of posts that reply to an earlier post instead
+	// of starting a new branch; MaxDepth caps how deep the branches grow.
+	ReplyRatio float64
+	MaxDepth   int
+	// SlugRatio is the share of threads created with a slug.
+	SlugRatio float64
+	BatchSize int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Prefix:     "seed",
+		Seed:       1,
+		Users:      1000,
+		Forums:     20,
+		Threads:    2000,
+		Posts:      100000,
+		Votes:      20000,
+		ForumSkew:  1.2,
+		ThreadSkew: 1.1,
+		ReplyRatio: 0.7,
+		MaxDepth:   8,
+		SlugRatio:  0.5,
+		BatchSize:  100,
+	}
+}
+
+type Thread struct {
+	ID    uint64 `json:"id"`
+	Slug  string `json:"slug,omitempty"`
+	Forum string `json:"forum"`
+	Posts int    `json:"posts"`
+}
+
+// Manifest lists what a run created, so that benchmarks can address it.
+type Manifest struct {
+	Users     []string  `json:"users"`
+	Forums    []string  `json:"forums"`
+	Threads   []*Thread `json:"threads"`
+	FirstPost uint64    `json:"firstPost"`
+	LastPost  uint64    `json:"lastPost"`
+}
+
+type Seeder struct {
+	cfg  Config
+	rand *rand.Rand
+
+	users   *userRepository.Postgres
+	forums  *forumRepository.Postgres
+	threads *threadRepository.Postgres
+
+	// Progress, if set, is told about each finished stage.
+	Progress func(format string, args ...interface{})
+}
+
+func NewSeeder(
+	cfg Config,
+	users *userRepository.Postgres,
+	forums *forumRepository.Postgres,
+	threads *threadRepository.Postgres,
+) *Seeder {
+	return &Seeder{
+		cfg:     cfg,
+		rand:    rand.New(rand.NewSource(cfg.Seed)),
+		users:   users,
+		forums:  forums,
+		threads: threads,
+	}
+}
+
+func (s *Seeder) progress(format string, args ...interface{}) {
+	if s.Progress != nil {
+		s.Progress(format, args...)
+	}
+}
+
+// picker returns a function choosing an index below n, skewed towards the
+// low indexes when skew is above 1.
+func (s *Seeder) picker(n int, skew float64) func() int {
+	if skew <= 1 || n < 2 {
+		return func() int { return s.rand.Intn(n) }
+	}
+
+	zipf := rand.NewZipf(s.rand, skew, 1, uint64(n-1))
+	return func() int { return int(zipf.Uint64()) }
+}
+
+var words = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod
+	tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud exercitation
+	ullamco laboris nisi aliquip ex ea commodo consequat duis aute irure in reprehenderit voluptate
+	velit esse cillum fugiat nulla pariatur excepteur sint occaecat cupidatat non proident sunt culpa`)
+
+func (s *Seeder) text(min, max int) string {
+	n := min + s.rand.Intn(max-min+1)
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = words[s.rand.Intn(len(words))]
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (s *Seeder) Run() (*Manifest, error) {
+	if s.cfg.Users < 1 || s.cfg.Forums < 1 {
+		return nil, fmt.Errorf("seed needs at least one user and one forum")
+	}
+	if s.cfg.Threads < 1 && s.cfg.Posts > 0 {
+		return nil, fmt.Errorf("seed needs threads to hold the posts")
+	}
+
+	manifest := &Manifest{}
+
+	for i := 0; i < s.cfg.Users; i++ {
+		user := &models.User{
+			Nickname: fmt.Sprintf("%s_u%d", s.cfg.Prefix, i),
+			Email:    fmt.Sprintf("%s_u%d@example.com", s.cfg.Prefix, i),
+			FullName: s.text(2, 3),
+			About:    s.text(5, 20),
+		}
+		if _, err := s.users.Create(user, ""); err != nil {
+			return nil, fmt.Errorf("create user %s: %w", user.Nickname, err)
+		}
+		manifest.Users = append(manifest.Users, user.Nickname)
+	}
+	s.progress("seed: %d users", len(manifest.Users))
+
+	for i := 0; i < s.cfg.Forums; i++ {
+		forum := &models.Forum{
+			Slug:  fmt.Sprintf("%s-f%d", s.cfg.Prefix, i),
+			Title: s.text(2, 5),
+			User:  manifest.Users[s.rand.Intn(len(manifest.Users))],
+		}
+		if _, err := s.forums.Create(forum); err != nil {
+			return nil, fmt.Errorf("create forum %s: %w", forum.Slug, err)
+		}
+		manifest.Forums = append(manifest.Forums, forum.Slug)
+	}
+	s.progress("seed: %d forums", len(manifest.Forums))
+
+	pickForum := s.picker(len(manifest.Forums), s.cfg.ForumSkew)
+	started := time.Now().Add(-365 * 24 * time.Hour)
+	for i := 0; i < s.cfg.Threads; i++ {
+		thread := &models.Thread{
+			Author:  manifest.Users[s.rand.Intn(len(manifest.Users))],
+			Forum:   manifest.Forums[pickForum()],
+			Title:   s.text(3, 8),
+			Message: s.text(10, 60),
+			Created: started.Add(time.Duration(s.rand.Int63n(int64(365 * 24 * time.Hour)))),
+		}
+		if s.rand.Float64() < s.cfg.SlugRatio {
+			thread.Slug = fmt.Sprintf("%s-t%d", s.cfg.Prefix, i)
+		}
+
+		created, err := s.threads.Create(thread)
+		if err != nil {
+			return nil, fmt.Errorf("create thread %d: %w", i, err)
+		}
+		manifest.Threads = append(manifest.Threads, &Thread{ID: created.ID, Slug: thread.Slug, Forum: created.Forum})
+	}
+	s.progress("seed: %d threads", len(manifest.Threads))
+
+	if s.cfg.Posts > 0 {
+		pickThread := s.picker(len(manifest.Threads), s.cfg.ThreadSkew)
+		for i := 0; i < s.cfg.Posts; i++ {
+			manifest.Threads[pickThread()].Posts++
+		}
+
+		for _, thread := range manifest.Threads {
+			if err := s.seedPosts(thread, manifest); err != nil {
+				return nil, err
+			}
+		}
+		s.progress("seed: %d posts", s.cfg.Posts)
+	}
+
+	if len(manifest.Threads) > 0 {
+		for i := 0; i < s.cfg.Votes; i++ {
+			thread := manifest.Threads[s.rand.Intn(len(manifest.Threads))]
+			vote := &models.Vote{
+				Nickname: manifest.Users[s.rand.Intn(len(manifest.Users))],
+				Voice:    1,
+			}
+			if s.rand.Intn(3) == 0 {
+				vote.Voice = -1
+			}
+			if _, err := s.threads.Vote(&models.ThreadResponse{ID: thread.ID}, vote); err != nil {
+				return nil, fmt.Errorf("vote in thread %d: %w", thread.ID, err)
+			}
+		}
+		s.progress("seed: %d votes", s.cfg.Votes)
+	}
+
+	return manifest, nil
+}
+
+// seedPosts grows the thread's tree in batches. A reply can only point at a
+// post from an earlier batch, so batches start small and double up to
+// BatchSize to leave room for deep branches in small threads.
+func (s *Seeder) seedPosts(thread *Thread, manifest *Manifest) error {
+	type node struct {
+		id    uint64
+		depth int
+	}
+	nodes := make([]node, 0, thread.Posts)
+	created := time.Now().Format(time.RFC3339)
+
+	for remaining, batch := thread.Posts, 1; remaining > 0; batch *= 2 {
+		if batch > s.cfg.BatchSize {
+			batch = s.cfg.BatchSize
+		}
+		if batch > remaining {
+			batch = remaining
+		}
+
+		posts := make([]*models.Post, 0, batch)
+		depths := make([]int, 0, batch)
+		for i := 0; i < batch; i++ {
+			post := &models.Post{
+				Author:  manifest.Users[s.rand.Intn(len(manifest.Users))],
+				Forum:   thread.Forum,
+				Thread:  thread.ID,
+				Message: s.text(5, 80),
+				Created: created,
+			}
+			depth := 1
+			if len(nodes) > 0 && s.rand.Float64() < s.cfg.ReplyRatio {
+				parent := nodes[s.rand.Intn(len(nodes))]
+				if parent.depth < s.cfg.MaxDepth {
+					post.Parent, depth = parent.id, parent.depth+1
+				}
+			}
+			posts = append(posts, post)
+			depths = append(depths, depth)
+		}
+
+		posts, err := s.threads.CreatePosts(posts)
+		if err != nil {
+			return fmt.Errorf("create posts in thread %d: %w", thread.ID, err)
+		}
+		for i, post := range posts {
+			nodes = append(nodes, node{id: post.ID, depth: depths[i]})
+			if manifest.FirstPost == 0 || post.ID < manifest.FirstPost {
+				manifest.FirstPost = post.ID
+			}
+			if post.ID > manifest.LastPost {
+				manifest.LastPost = post.ID
+			}
+		}
+		remaining -= batch
+	}
+
+	return nil
+}
@@ -2,7 +2,7 @@ package systemRepository
 
 import (
 	"github.com/jmoiron/sqlx"
-	"technopark-dbms-forum/internal/models"
+	"technopark-dbms-forum/pkg/models"
 )
 
 type Postgres struct {
@@ -22,6 +22,10 @@ func NewPostgres(url string) (*Postgres, error) {
 	return &Postgres{sqlx: newSQLX}, nil
 }
 
+func (p *Postgres) Close() error {
+	return p.sqlx.Close()
+}
+
 func (p *Postgres) ClearAll() error {
 	_, err := p.sqlx.Exec(
 		`
@@ -31,6 +35,11 @@ func (p *Postgres) ClearAll() error {
 			TRUNCATE threads CASCADE;
 			TRUNCATE users CASCADE;
 			TRUNCATE user_forum CASCADE;
+			TRUNCATE idempotency_keys;
+			TRUNCATE events;
+			TRUNCATE event_cursors;
+			TRUNCATE rate_buckets;
+			TRUNCATE message_fingerprints;
 		`,
 	)
 	if err != nil {
@@ -40,6 +49,12 @@ func (p *Postgres) ClearAll() error {
 	return nil
 }
 
+// ApplySchema runs a multi-statement schema script.
+func (p *Postgres) ApplySchema(schema string) error {
+	_, err := p.sqlx.Exec(schema)
+	return err
+}
+
 func (p *Postgres) GetInfo() (*models.System, error) {
 	var sys models.System
 
@@ -0,0 +1,282 @@
+// Package testenv runs integration tests against a throwaway Postgres
+// cluster and a full forum server. Packages using it call Main from their
+// TestMain, so that the cluster started by the first test is stopped when
+// the test binary exits.
+package testenv
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"technopark-dbms-forum/db"
+	systemRepository "technopark-dbms-forum/internal/system/repository"
+)
+
+const (
+	// EnvPostgresURL points the tests at a running server instead of a
+	// throwaway cluster. It is a lib/pq keyword string without dbname, e.g.
+	// "host=localhost port=5432 user=postgres password=secret sslmode=disable".
+	EnvPostgresURL = "TEST_POSTGRES_URL"
+	// EnvPostgresBin is the directory holding initdb and postgres when they
+	// are not on PATH.
+	EnvPostgresBin = "PG_BIN"
+	// EnvCI is set by CI runners. There an environment that can't run a
+	// cluster fails the tests instead of skipping them, so that a runner
+	// set up as root doesn't pass with nothing tested.
+	EnvCI = "CI"
+
+	startTimeout = 30 * time.Second
+	stopTimeout  = 10 * time.Second
+)
+
+// skipError marks environments that cannot run a cluster at all; tests are
+// skipped there rather than failed, unless they run on CI.
+type skipError struct {
+	reason string
+}
+
+func (e skipError) Error() string {
+	return e.reason
+}
+
+type cluster struct {
+	// base is the connection string without dbname.
+	base string
+	dir  string
+	cmd  *exec.Cmd
+	done chan struct{}
+}
+
+var (
+	shared     *cluster
+	sharedErr  error
+	sharedOnce sync.Once
+	databases  int64
+)
+
+// Main runs the tests and stops the cluster they started.
+func Main(m *testing.M) {
+	code := m.Run()
+	if shared != nil {
+		shared.stop()
+	}
+	os.Exit(code)
+}
+
+// binaries finds initdb and postgres on PG_BIN, PATH or in the usual Debian
+// location, newest version first.
+func binaries() (string, string, error) {
+	dirs := []string{}
+	if dir := os.Getenv(EnvPostgresBin); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if initdb, err := exec.LookPath("initdb"); err == nil {
+		dirs = append(dirs, filepath.Dir(initdb))
+	}
+	versions, _ := filepath.Glob("/usr/lib/postgresql/*/bin")
+	sort.Slice(versions, func(i, j int) bool {
+		a, _ := strconv.Atoi(filepath.Base(filepath.Dir(versions[i])))
+		b, _ := strconv.Atoi(filepath.Base(filepath.Dir(versions[j])))
+		return a > b
+	})
+	dirs = append(dirs, versions...)
+
+	for _, dir := range dirs {
+		initdb, postgres := filepath.Join(dir, "initdb"), filepath.Join(dir, "postgres")
+		if _, err := os.Stat(initdb); err != nil {
+			continue
+		}
+		if _, err := os.Stat(postgres); err != nil {
+			continue
+		}
+		return initdb, postgres, nil
+	}
+
+	return "", "", skipError{reason: "initdb and postgres not found; set " + EnvPostgresBin + " or " + EnvPostgresURL}
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func ping(dsn string) error {
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Ping()
+}
+
+// startCluster creates a cluster in a temporary directory and runs it on a
+// free local port. Durability is turned off: the data is thrown away anyway.
+func startCluster() (*cluster, error) {
+	if url := os.Getenv(EnvPostgresURL); url != "" {
+		return &cluster{base: url}, nil
+	}
+	if os.Geteuid() == 0 {
+		return nil, skipError{reason: "postgres refuses to run as root; set " + EnvPostgresURL}
+	}
+
+	initdb, postgres, err := binaries()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "forum-postgres-")
+	if err != nil {
+		return nil, err
+	}
+	data := filepath.Join(dir, "data")
+
+	out, err := exec.Command(initdb, "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-locale").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("initdb: %w\n%s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	logFile, err := os.Create(filepath.Join(dir, "postgres.log"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	defer logFile.Close()
+
+	c := &cluster{
+		base: fmt.Sprintf("host=127.0.0.1 port=%d user=postgres sslmode=disable", port),
+		dir:  dir,
+		done: make(chan struct{}),
+		cmd: exec.Command(
+			postgres,
+			"-D", data,
+			"-p", strconv.Itoa(port),
+			"-c", "listen_addresses=127.0.0.1",
+			"-c", "unix_socket_directories=",
+			"-c", "fsync=off",
+			"-c", "synchronous_commit=off",
+			"-c", "full_page_writes=off",
+		),
+	}
+	c.cmd.Stdout, c.cmd.Stderr = logFile, logFile
+	if err = c.cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	go func() {
+		c.cmd.Wait()
+		close(c.done)
+	}()
+
+	deadline := time.Now().Add(startTimeout)
+	for {
+		err = ping(c.dsn("postgres"))
+		if err == nil {
+			return c, nil
+		}
+
+		select {
+		case <-c.done:
+		case <-time.After(100 * time.Millisecond):
+			if time.Now().Before(deadline) {
+				continue
+			}
+		}
+
+		log, _ := os.ReadFile(logFile.Name())
+		c.stop()
+		return nil, fmt.Errorf("postgres did not start: %w\n%s", err, log)
+	}
+}
+
+func (c *cluster) dsn(name string) string {
+	return c.base + " dbname=" + name
+}
+
+// stop asks the server for a fast shutdown, which also ends the sessions the
+// servers under test left open.
+func (c *cluster) stop() {
+	if c.cmd == nil {
+		return
+	}
+
+	c.cmd.Process.Signal(os.Interrupt)
+	select {
+	case <-c.done:
+	case <-time.After(stopTimeout):
+		c.cmd.Process.Kill()
+		<-c.done
+	}
+	os.RemoveAll(c.dir)
+}
+
+func (c *cluster) exec(query string) error {
+	conn, err := sql.Open("postgres", c.dsn("postgres"))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Exec(query)
+	return err
+}
+
+// Database creates an empty database with the forum schema and returns its
+// connection string. Every call gets a new database, so ids start from one.
+func Database(t testing.TB) string {
+	t.Helper()
+
+	sharedOnce.Do(func() { shared, sharedErr = startCluster() })
+	var skip skipError
+	if errors.As(sharedErr, &skip) && os.Getenv(EnvCI) != "" {
+		t.Fatalf("%s; %s is set, so the test can't be skipped", skip.reason, EnvCI)
+	} else if errors.As(sharedErr, &skip) {
+		t.Skip(skip.reason)
+	} else if sharedErr != nil {
+		t.Fatal(sharedErr)
+	}
+
+	name := fmt.Sprintf("forum_test_%d_%d", os.Getpid(), atomic.AddInt64(&databases, 1))
+	if err := shared.exec("CREATE DATABASE " + name); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { shared.exec("DROP DATABASE IF EXISTS " + name + " WITH (FORCE)") })
+
+	dsn := shared.dsn(name)
+	repo, err := systemRepository.NewPostgres(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	if err = repo.ApplySchema(db.Schema); err != nil {
+		t.Fatal(err)
+	}
+
+	return dsn
+}
@@ -0,0 +1,249 @@
+package testenv
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"technopark-dbms-forum/internal/config"
+	service "technopark-dbms-forum/internal/init"
+	logger "technopark-dbms-forum/pkg"
+	"technopark-dbms-forum/pkg/models"
+)
+
+// AdminToken opens the admin API of test servers.
+const AdminToken = "test-admin-token"
+
+// Config is the configuration test servers start with: open auth, no rate
+// limits or content filters, no background counter reconciliation, and
+// contract checking on.
+func Config(t testing.TB) *config.Config {
+	return &config.Config{
+		AuthMode:              config.AuthOpen,
+		AuthSecret:            "test-auth-secret",
+		AuthTokenTTL:          time.Hour,
+		AdminToken:            AdminToken,
+		FilterBlocklistAction: models.FilterRewrite,
+		FilterLinksAction:     models.FilterFlag,
+		FilterDuplicateAction: models.FilterReject,
+		FilterMaxLengthAction: models.FilterReject,
+		AttachmentDir:         t.TempDir(),
+		AttachmentMaxSize:     1 << 20,
+		AttachmentTypes:       []string{"image/png", "image/jpeg", "text/plain"},
+		CounterReconcileBatch: 500,
+		CounterReconcileFix:   true,
+		ContractCheck:         config.ContractReport,
+		GraphQLMaxDepth:       10,
+		GraphQLMaxComplexity:  5000,
+	}
+}
+
+// Server is a forum server running on a random local port over its own
+// database.
+type Server struct {
+	// URL is the API root, without the trailing slash.
+	URL string
+	// DSN connects to the server's database.
+	DSN string
+
+	client *http.Client
+}
+
+// StartServer builds a service.Server the way the serve command does and
+// waits until it answers. Options adjust the configuration from Config.
+//
+// Unless an option turns contract checking off, a response that does not
+// match api/swagger.yml fails the test, and so do a request to a route the
+// spec leaves undocumented and a request that breaks the spec and still
+// succeeds. Negative tests send broken requests on purpose, so those the
+// server turns away with a 4xx are only logged.
+func StartServer(t testing.TB, options ...func(*config.Config)) *Server {
+	t.Helper()
+
+	dsn := Database(t)
+	cfg := Config(t)
+	for _, option := range options {
+		option(cfg)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := logger.GetInstance()
+	if !testing.Verbose() {
+		// Request logs only help when a test is being debugged.
+		l.SetOutput(io.Discard)
+	}
+
+	e := echo.New()
+	e.HideBanner, e.HidePort = true, true
+	e.Logger = l
+	// Echo serves on a listener that is already set instead of opening one.
+	e.Listener = listener
+
+	var (
+		mu         sync.Mutex
+		violations []*models.ContractViolation
+	)
+	server := service.NewServer(e, cfg)
+	server.AddContractReporter(func(violation *models.ContractViolation) {
+		mu.Lock()
+		violations = append(violations, violation)
+		mu.Unlock()
+	})
+
+	errs := make(chan error, 1)
+	go func() { errs <- server.Start(listener.Addr().String(), dsn) }()
+	t.Cleanup(func() {
+		e.Close()
+
+		mu.Lock()
+		defer mu.Unlock()
+		undocumented := make(map[string]bool)
+		for _, violation := range violations {
+			if violation.In == models.ContractResponse {
+				t.Errorf("contract: %s", violation)
+			} else if violation.In == models.ContractRequest && violation.Status < http.StatusBadRequest {
+				t.Errorf("contract: %s, answered with %d", violation, violation.Status)
+			} else if violation.In == models.ContractRequest {
+				t.Logf("contract: %s, answered with %d", violation, violation.Status)
+			} else if !undocumented[violation.String()] {
+				undocumented[violation.String()] = true
+				t.Errorf("contract: %s", violation)
+			}
+		}
+	})
+
+	s := &Server{
+		URL: "http://" + listener.Addr().String() + "/api",
+		DSN: dsn,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			// Redirects are part of the API, so the tests see them as they are.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+
+	deadline := time.Now().Add(startTimeout)
+	for {
+		select {
+		case err = <-errs:
+			t.Fatal(err)
+		default:
+		}
+
+		var resp *http.Response
+		if resp, err = s.client.Get(s.URL + "/service/status"); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return s
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not start: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// NewRequest builds a request to path under the API root. A body that is
+// not an io.Reader is sent as JSON. Headers come as name, value pairs.
+func (s *Server) NewRequest(t testing.TB, method, path string, body interface{}, headers ...string) *http.Request {
+	t.Helper()
+
+	var reader io.Reader
+	contentType := ""
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+		contentType = echo.MIMEApplicationJSON
+	}
+
+	req, err := http.NewRequest(method, s.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "" {
+		req.Header.Set(echo.HeaderContentType, contentType)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	return req
+}
+
+// Send sends a request and leaves reading the body to the caller.
+func (s *Server) Send(t testing.TB, req *http.Request) *http.Response {
+	t.Helper()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp
+}
+
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Do sends a request built by NewRequest and reads the whole answer.
+func (s *Server) Do(t testing.TB, method, path string, body interface{}, headers ...string) *Response {
+	t.Helper()
+
+	resp := s.Send(t, s.NewRequest(t, method, path, body, headers...))
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Response{Status: resp.StatusCode, Header: resp.Header, Body: data}
+}
+
+// JSON decodes the body into v.
+func (r *Response) JSON(t testing.TB, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Fatalf("decode %s: %v", strings.TrimSpace(string(r.Body)), err)
+	}
+}
+
+// Login signs the user in and returns the Authorization header value that
+// acts on the user's behalf.
+func (s *Server) Login(t testing.TB, nickname, password string) string {
+	t.Helper()
+
+	resp := s.Do(t, http.MethodPost, "/auth/login", map[string]string{"nickname": nickname, "password": password})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("login %s: got %d: %s", nickname, resp.Status, bytes.TrimSpace(resp.Body))
+	}
+
+	token := models.Token{}
+	resp.JSON(t, &token)
+
+	return "Bearer " + token.Token
+}
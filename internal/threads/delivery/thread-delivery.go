@@ -1,11 +1,16 @@
 package threadDelivery
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
+	attachmentUsecase "technopark-dbms-forum/internal/attachments/usecase"
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
 	forumUsecase "technopark-dbms-forum/internal/forums/usecase"
+	limitDelivery "technopark-dbms-forum/internal/limits/delivery"
+	postUsecase "technopark-dbms-forum/internal/posts/usecase"
 
 	internalErrors "technopark-dbms-forum/internal"
 
@@ -13,18 +18,28 @@ import (
 
 	"github.com/labstack/echo/v4"
 
-	"technopark-dbms-forum/internal/models"
+	"technopark-dbms-forum/pkg/etag"
+	"technopark-dbms-forum/pkg/models"
 )
 
 type Handler struct {
 	threadUsecase *threadUsecase.ThreadUsecase
 	forumUsecase  *forumUsecase.ForumUsecase
+	renderer      *postUsecase.Renderer
+	attachments   *attachmentUsecase.AttachmentUsecase
 }
 
-func NewHandler(threadUsecase *threadUsecase.ThreadUsecase, forumUsecase *forumUsecase.ForumUsecase) *Handler {
+func NewHandler(
+	threadUsecase *threadUsecase.ThreadUsecase,
+	forumUsecase *forumUsecase.ForumUsecase,
+	renderer *postUsecase.Renderer,
+	attachments *attachmentUsecase.AttachmentUsecase,
+) *Handler {
 	return &Handler{
 		threadUsecase: threadUsecase,
 		forumUsecase:  forumUsecase,
+		renderer:      renderer,
+		attachments:   attachments,
 	}
 }
 
@@ -43,9 +58,19 @@ func (h *Handler) Create(c echo.Context) error {
 	}
 
 	t.Forum = forum.Slug
+	if principal, ok := authDelivery.Principal(c); ok {
+		t.Author = principal
+	}
+
+	var retry *internalErrors.RetryError
+	var rejected *internalErrors.ContentError
 
-	response, err := h.threadUsecase.Create(&t)
-	if err == internalErrors.ErrAlreadyExist {
+	response, err := h.threadUsecase.Create(&t, c.RealIP())
+	if errors.As(err, &retry) {
+		return limitDelivery.Reject(c, retry)
+	} else if errors.As(err, &rejected) {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Thread was rejected: %s", rejected.Reason))
+	} else if err == internalErrors.ErrAlreadyExist {
 		return c.JSON(http.StatusConflict, response)
 	} else if err == internalErrors.ErrSlugAlreadyExist {
 		return c.JSON(http.StatusConflict, response)
@@ -55,6 +80,8 @@ func (h *Handler) Create(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
+	h.forumUsecase.PublishActivity(response.Forum, models.EventThreadCreated, response)
+
 	return c.JSON(http.StatusCreated, response)
 }
 
@@ -62,6 +89,30 @@ func (h *Handler) GetDetails(c echo.Context) error {
 	slugOrID := c.Param("slug_or_id")
 
 	thread, err := h.threadUsecase.GetBySlugOrID(slugOrID)
+	if err == internalErrors.ErrThreadSlugMoved {
+		c.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("/api/thread/%s/details", thread.Slug))
+		return c.JSON(http.StatusMovedPermanently, thread)
+	} else if err == internalErrors.ErrNoRowsBySlug {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with slug: %s", slugOrID))
+	} else if err == internalErrors.ErrNoRowsByID {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with id: %s", slugOrID))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	tag := etag.Make(thread.Version)
+	if c.QueryParam("format") == postUsecase.FormatHTML {
+		h.renderer.RenderThread(thread)
+		tag = etag.Variant(tag, postUsecase.FormatHTML)
+	}
+
+	return etag.JSON(c, http.StatusOK, tag, thread)
+}
+
+func (h *Handler) GetHistory(c echo.Context) error {
+	slugOrID := c.Param("slug_or_id")
+
+	revisions, err := h.threadUsecase.GetRevisions(slugOrID)
 	if err == internalErrors.ErrNoRowsBySlug {
 		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with slug: %s", slugOrID))
 	} else if err == internalErrors.ErrNoRowsByID {
@@ -70,20 +121,31 @@ func (h *Handler) GetDetails(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
-	return c.JSON(http.StatusOK, thread)
+	return c.JSON(http.StatusOK, revisions)
 }
 
-func (h *Handler) Update(c echo.Context) error {
+func (h *Handler) ChangeSlug(c echo.Context) error {
 	slugOrID := c.Param("slug_or_id")
 
-	thread := models.Thread{}
+	newSlug := models.ThreadSlug{}
 
-	if err := c.Bind(&thread); err != nil {
+	if err := c.Bind(&newSlug); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
+	if newSlug.Slug == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "Thread slug can't be empty")
+	}
 
-	response, err := h.threadUsecase.Update(slugOrID, thread.Message, thread.Title)
-	if err == internalErrors.ErrNoRowsBySlug {
+	actor := authDelivery.Actor(c)
+
+	response, err := h.threadUsecase.ChangeSlug(slugOrID, newSlug.Slug, actor)
+	if err == internalErrors.ErrBadSlug {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Thread slug must be letters, digits, - and _, not digits only: %s", newSlug.Slug))
+	} else if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't edit thread: %s", slugOrID))
+	} else if err == internalErrors.ErrSlugAlreadyExist {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Thread slug is already taken: %s", newSlug.Slug))
+	} else if err == internalErrors.ErrNoRowsBySlug {
 		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with slug: %s", slugOrID))
 	} else if err == internalErrors.ErrNoRowsByID {
 		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with id: %s", slugOrID))
@@ -94,6 +156,69 @@ func (h *Handler) Update(c echo.Context) error {
 	return c.JSON(http.StatusOK, response)
 }
 
+func (h *Handler) Update(c echo.Context) error {
+	slugOrID := c.Param("slug_or_id")
+
+	versions, ok := etag.Versions(c.Request().Header.Get(etag.HeaderIfMatch))
+	if !ok {
+		return echo.NewHTTPError(http.StatusPreconditionFailed, "Thread was modified")
+	}
+
+	update := models.ThreadUpdate{}
+
+	if err := c.Bind(&update); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	actor := authDelivery.Actor(c)
+
+	response, err := h.threadUsecase.Update(slugOrID, &update, versions, actor)
+	if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't edit thread: %s", slugOrID))
+	} else if err == internalErrors.ErrNullField {
+		return echo.NewHTTPError(http.StatusBadRequest, "Thread title and message can't be null")
+	} else if err == internalErrors.ErrPreconditionFailed {
+		return echo.NewHTTPError(http.StatusPreconditionFailed, "Thread was modified")
+	} else if err == internalErrors.ErrNoRowsBySlug {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with slug: %s", slugOrID))
+	} else if err == internalErrors.ErrNoRowsByID {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with id: %s", slugOrID))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	h.forumUsecase.PublishActivity(response.Forum, models.EventThreadUpdated, response)
+
+	return etag.JSON(c, http.StatusOK, etag.Make(response.Version), response)
+}
+
+func (h *Handler) Moderate(c echo.Context) error {
+	slugOrID := c.Param("slug_or_id")
+
+	moderation := models.ThreadModeration{}
+
+	if err := c.Bind(&moderation); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	principal, _ := authDelivery.Principal(c)
+
+	response, err := h.threadUsecase.Moderate(slugOrID, &moderation, principal)
+	if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't moderate thread: %s", slugOrID))
+	} else if err == internalErrors.ErrNoRowsBySlug {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with slug: %s", slugOrID))
+	} else if err == internalErrors.ErrNoRowsByID {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with id: %s", slugOrID))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	h.forumUsecase.PublishActivity(response.Forum, models.EventThreadUpdated, response)
+
+	return etag.JSON(c, http.StatusOK, etag.Make(response.Version), response)
+}
+
 func (h *Handler) Vote(c echo.Context) error {
 	slugOrID := c.Param("slug_or_id")
 
@@ -102,22 +227,34 @@ func (h *Handler) Vote(c echo.Context) error {
 	if err := c.Bind(&vote); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
+	if principal, ok := authDelivery.Principal(c); ok {
+		vote.Nickname = principal
+	}
 
-	response, err := h.threadUsecase.Vote(slugOrID, &vote)
-	if err == internalErrors.ErrNoRows {
-		if response.ID == 0 {
-			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with slug: %s", slugOrID))
-		} else if response.Slug == "" {
-			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with id: %d", response.ID))
-		}
+	var retry *internalErrors.RetryError
 
+	response, err := h.threadUsecase.Vote(slugOrID, &vote, c.RealIP())
+	if errors.As(err, &retry) {
+		return limitDelivery.Reject(c, retry)
+	} else if err == internalErrors.ErrWrongVoice {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Wrong voice: %d", vote.Voice))
+	} else if err == internalErrors.ErrNoRowsBySlug {
 		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with slug: %s", slugOrID))
+	} else if err == internalErrors.ErrNoRowsByID {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with id: %s", slugOrID))
 	} else if err == internalErrors.ErrUserNotFound {
 		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find user by nickname: %s", vote.Nickname))
 	} else if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
+	h.forumUsecase.PublishActivity(response.Forum, models.EventThreadVoted, &models.VoteEvent{
+		Thread:   response.ID,
+		Nickname: vote.Nickname,
+		Voice:    vote.Voice,
+		Votes:    response.Votes,
+	})
+
 	return c.JSON(http.StatusOK, response)
 }
 
@@ -129,12 +266,26 @@ func (h *Handler) CreatePosts(c echo.Context) error {
 	if err := c.Bind(&posts); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
+	if principal, ok := authDelivery.Principal(c); ok {
+		for _, post := range posts {
+			post.Author = principal
+		}
+	}
 
-	response, err := h.threadUsecase.CreatePosts(slugOrID, posts)
-	if err == internalErrors.ErrNoRowsBySlug {
+	var retry *internalErrors.RetryError
+	var rejected *internalErrors.ContentError
+
+	response, err := h.threadUsecase.CreatePosts(slugOrID, posts, c.RealIP())
+	if errors.As(err, &retry) {
+		return limitDelivery.Reject(c, retry)
+	} else if errors.As(err, &rejected) {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, fmt.Sprintf("Post was rejected: %s", rejected.Reason))
+	} else if err == internalErrors.ErrNoRowsBySlug {
 		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find post thread by slug: %s", slugOrID))
 	} else if err == internalErrors.ErrNoRowsByID {
 		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find post thread by id: %s", slugOrID))
+	} else if err == internalErrors.ErrThreadClosed {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Thread is closed: %s", slugOrID))
 	} else if err == internalErrors.ErrPostWasCreatedInAnotherThread {
 		return echo.NewHTTPError(http.StatusConflict, "Post was created in another thread")
 	} else if err == internalErrors.ErrPostAuthorNotFound {
@@ -143,6 +294,10 @@ func (h *Handler) CreatePosts(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
+	for _, post := range response {
+		h.forumUsecase.PublishActivity(post.Forum, models.EventPostCreated, post)
+	}
+
 	return c.JSON(http.StatusCreated, response)
 }
 
@@ -179,5 +334,70 @@ func (h *Handler) GetPosts(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
+	if err = h.attachments.Fill(posts); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if c.QueryParam("format") == postUsecase.FormatHTML {
+		if err = h.renderer.RenderPosts(posts); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+	}
+
 	return c.JSON(http.StatusOK, posts)
 }
+
+func (h *Handler) Subscribe(c echo.Context) error {
+	slugOrID := c.Param("slug_or_id")
+
+	subscription := models.ThreadSubscription{}
+
+	if err := c.Bind(&subscription); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if principal, ok := authDelivery.Principal(c); ok {
+		subscription.Nickname = principal
+	}
+
+	response, created, err := h.threadUsecase.Subscribe(slugOrID, subscription.Nickname)
+	if err == internalErrors.ErrNoRowsBySlug {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with slug: %s", slugOrID))
+	} else if err == internalErrors.ErrNoRowsByID {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with id: %s", slugOrID))
+	} else if err == internalErrors.ErrUserNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find user by nickname: %s", subscription.Nickname))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	if !created {
+		return c.JSON(http.StatusOK, response)
+	}
+
+	return c.JSON(http.StatusCreated, response)
+}
+
+func (h *Handler) Unsubscribe(c echo.Context) error {
+	slugOrID := c.Param("slug_or_id")
+
+	subscription := models.ThreadSubscription{}
+
+	if err := c.Bind(&subscription); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+	if principal, ok := authDelivery.Principal(c); ok {
+		subscription.Nickname = principal
+	}
+
+	response, err := h.threadUsecase.Unsubscribe(slugOrID, subscription.Nickname)
+	if err == internalErrors.ErrNoRowsBySlug {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with slug: %s", slugOrID))
+	} else if err == internalErrors.ErrNoRowsByID {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with id: %s", slugOrID))
+	} else if err == internalErrors.ErrNotSubscribed {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("User %s is not subscribed to thread: %s", subscription.Nickname, slugOrID))
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,283 @@
+package threadDelivery
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"technopark-dbms-forum/api/forumpb"
+	internalErrors "technopark-dbms-forum/internal"
+	attachmentUsecase "technopark-dbms-forum/internal/attachments/usecase"
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
+	forumUsecase "technopark-dbms-forum/internal/forums/usecase"
+	limitDelivery "technopark-dbms-forum/internal/limits/delivery"
+	threadUsecase "technopark-dbms-forum/internal/threads/usecase"
+	"technopark-dbms-forum/pkg/models"
+)
+
+// listPage is the number of posts ListPosts reads at a time.
+const listPage = 100
+
+// GRPCServer serves forumpb.ThreadService with the usecases of Handler.
+type GRPCServer struct {
+	forumpb.UnimplementedThreadServiceServer
+
+	threadUsecase *threadUsecase.ThreadUsecase
+	forumUsecase  *forumUsecase.ForumUsecase
+	attachments   *attachmentUsecase.AttachmentUsecase
+	// trustedProxies may set the client address rate limits count on.
+	trustedProxies []*net.IPNet
+}
+
+func NewGRPCServer(
+	threadUsecase *threadUsecase.ThreadUsecase,
+	forumUsecase *forumUsecase.ForumUsecase,
+	attachments *attachmentUsecase.AttachmentUsecase,
+	trustedProxies []*net.IPNet,
+) *GRPCServer {
+	return &GRPCServer{
+		threadUsecase:  threadUsecase,
+		forumUsecase:   forumUsecase,
+		attachments:    attachments,
+		trustedProxies: trustedProxies,
+	}
+}
+
+func (s *GRPCServer) CreateThread(ctx context.Context, req *forumpb.CreateThreadRequest) (*forumpb.Thread, error) {
+	slug := req.GetForum()
+
+	t := req.GetThread().Model()
+
+	forum, err := s.forumUsecase.GetFullBySlug(slug)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "Can't find thread forum by slug: %s", slug)
+	}
+
+	t.Forum = forum.Slug
+	if principal, ok := authDelivery.PrincipalFromContext(ctx); ok {
+		t.Author = principal
+	}
+
+	var retry *internalErrors.RetryError
+	var rejected *internalErrors.ContentError
+
+	response, err := s.threadUsecase.Create(t, limitDelivery.ClientIP(ctx, s.trustedProxies))
+	if errors.As(err, &retry) {
+		return nil, limitDelivery.RejectStatus(retry)
+	} else if errors.As(err, &rejected) {
+		return nil, status.Errorf(codes.InvalidArgument, "Thread was rejected: %s", rejected.Reason)
+	} else if err == internalErrors.ErrAlreadyExist || err == internalErrors.ErrSlugAlreadyExist {
+		st, detailErr := status.Newf(codes.AlreadyExists, "Thread slug is already taken: %s", t.Slug).
+			WithDetails(forumpb.FromThread(response))
+		if detailErr != nil {
+			return nil, status.Error(codes.Internal, detailErr.Error())
+		}
+		return nil, st.Err()
+	} else if err == internalErrors.ErrUserNotFound {
+		return nil, status.Errorf(codes.NotFound, "Can't find thread author by nickname: %s", t.Author)
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	s.forumUsecase.PublishActivity(response.Forum, models.EventThreadCreated, response)
+
+	return forumpb.FromThread(response), nil
+}
+
+// GetThread answers for an old slug of the thread too, where the REST API
+// redirects.
+func (s *GRPCServer) GetThread(ctx context.Context, req *forumpb.GetThreadRequest) (*forumpb.Thread, error) {
+	slugOrID := req.GetSlugOrId()
+
+	thread, err := s.threadUsecase.GetBySlugOrID(slugOrID)
+	if err == internalErrors.ErrNoRowsBySlug {
+		return nil, status.Errorf(codes.NotFound, "Can't find thread with slug: %s", slugOrID)
+	} else if err == internalErrors.ErrNoRowsByID {
+		return nil, status.Errorf(codes.NotFound, "Can't find thread with id: %s", slugOrID)
+	} else if err != nil && err != internalErrors.ErrThreadSlugMoved {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return forumpb.FromThread(thread), nil
+}
+
+func (s *GRPCServer) UpdateThread(ctx context.Context, req *forumpb.UpdateThreadRequest) (*forumpb.Thread, error) {
+	slugOrID := req.GetSlugOrId()
+
+	actor := authDelivery.ActorFromContext(ctx)
+
+	response, err := s.threadUsecase.Update(slugOrID, req.Update(), forumpb.Versions(req.GetVersion()), actor)
+	if err == internalErrors.ErrForbidden {
+		return nil, status.Errorf(codes.PermissionDenied, "Can't edit thread: %s", slugOrID)
+	} else if err == internalErrors.ErrNullField {
+		return nil, status.Error(codes.InvalidArgument, "Thread title and message can't be null")
+	} else if err == internalErrors.ErrPreconditionFailed {
+		return nil, status.Error(codes.FailedPrecondition, "Thread was modified")
+	} else if err == internalErrors.ErrNoRowsBySlug {
+		return nil, status.Errorf(codes.NotFound, "Can't find thread with slug: %s", slugOrID)
+	} else if err == internalErrors.ErrNoRowsByID {
+		return nil, status.Errorf(codes.NotFound, "Can't find thread with id: %s", slugOrID)
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	s.forumUsecase.PublishActivity(response.Forum, models.EventThreadUpdated, response)
+
+	return forumpb.FromThread(response), nil
+}
+
+func (s *GRPCServer) Vote(ctx context.Context, req *forumpb.VoteRequest) (*forumpb.Thread, error) {
+	slugOrID := req.GetSlugOrId()
+
+	vote := &models.Vote{
+		Nickname: req.GetNickname(),
+		Voice:    req.GetVoice(),
+	}
+	if principal, ok := authDelivery.PrincipalFromContext(ctx); ok {
+		vote.Nickname = principal
+	}
+
+	var retry *internalErrors.RetryError
+
+	response, err := s.threadUsecase.Vote(slugOrID, vote, limitDelivery.ClientIP(ctx, s.trustedProxies))
+	if errors.As(err, &retry) {
+		return nil, limitDelivery.RejectStatus(retry)
+	} else if err == internalErrors.ErrWrongVoice {
+		return nil, status.Errorf(codes.InvalidArgument, "Wrong voice: %d", vote.Voice)
+	} else if err == internalErrors.ErrNoRowsBySlug {
+		return nil, status.Errorf(codes.NotFound, "Can't find thread with slug: %s", slugOrID)
+	} else if err == internalErrors.ErrNoRowsByID {
+		return nil, status.Errorf(codes.NotFound, "Can't find thread with id: %s", slugOrID)
+	} else if err == internalErrors.ErrUserNotFound {
+		return nil, status.Errorf(codes.NotFound, "Can't find user by nickname: %s", vote.Nickname)
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	s.forumUsecase.PublishActivity(response.Forum, models.EventThreadVoted, &models.VoteEvent{
+		Thread:   response.ID,
+		Nickname: vote.Nickname,
+		Voice:    vote.Voice,
+		Votes:    response.Votes,
+	})
+
+	return forumpb.FromThread(response), nil
+}
+
+// ListPosts pages through GetPosts, going on after the last post sent. As
+// in the REST API, a page of trees may hold fewer posts than asked for
+// without being the last one, so only an empty page ends them.
+func (s *GRPCServer) ListPosts(req *forumpb.ListPostsRequest, stream forumpb.ThreadService_ListPostsServer) error {
+	slugOrID := req.GetSlugOrId()
+	sort := req.GetSort()
+	since := req.GetSince()
+	remaining := req.GetLimit()
+
+	for {
+		limit := uint64(listPage)
+		if remaining != 0 && remaining < limit {
+			limit = remaining
+		}
+
+		posts, err := s.threadUsecase.GetPosts(slugOrID, limit, since, sort.Sort(), req.GetDesc())
+		if err == internalErrors.ErrNoRowsBySlug {
+			return status.Errorf(codes.NotFound, "Can't find thread with slug: %s", slugOrID)
+		} else if err == internalErrors.ErrNoRowsByID {
+			return status.Errorf(codes.NotFound, "Can't find thread with id: %s", slugOrID)
+		} else if err == internalErrors.ErrWrongForumSlug {
+			return status.Error(codes.InvalidArgument, "Parent post was created in another thread")
+		} else if err == internalErrors.ErrNoParentPost {
+			return status.Error(codes.NotFound, "Can't find parent post")
+		} else if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+
+		if len(posts) == 0 {
+			return nil
+		}
+		if err = s.attachments.Fill(posts); err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+
+		var listed uint64
+		for _, post := range posts {
+			if err = stream.Send(forumpb.FromPost(post)); err != nil {
+				return err
+			}
+			if sort != forumpb.PostSort_POST_SORT_PARENT_TREE || post.Parent == 0 {
+				listed++
+			}
+		}
+		since = posts[len(posts)-1].ID
+
+		if remaining != 0 {
+			if listed >= remaining {
+				return nil
+			}
+			remaining -= listed
+		}
+		if sort != forumpb.PostSort_POST_SORT_PARENT_TREE && listed < limit {
+			return nil
+		}
+	}
+}
+
+// SubscribePosts is Stream for gRPC. It ends with UNAVAILABLE when the
+// subscriber falls behind, to be resumed from the position of the last post
+// received.
+func (s *GRPCServer) SubscribePosts(req *forumpb.SubscribePostsRequest, stream forumpb.ThreadService_SubscribePostsServer) error {
+	slugOrID := req.GetSlugOrId()
+
+	thread, err := s.threadUsecase.GetBySlugOrID(slugOrID)
+	if err == internalErrors.ErrNoRowsBySlug {
+		return status.Errorf(codes.NotFound, "Can't find thread with slug: %s", slugOrID)
+	} else if err == internalErrors.ErrNoRowsByID {
+		return status.Errorf(codes.NotFound, "Can't find thread with id: %s", slugOrID)
+	} else if err != nil && err != internalErrors.ErrThreadSlugMoved {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	sub := s.threadUsecase.SubscribePosts(thread.ID, streamBuffer)
+	defer s.threadUsecase.UnsubscribePosts(sub)
+
+	last := req.GetLastPosition()
+	if last != 0 {
+		for {
+			posts, err := s.threadUsecase.GetPostsAfterPosition(thread.ID, last, streamBacklog)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+
+			for _, post := range posts {
+				if err = stream.Send(forumpb.FromPostEvent(post)); err != nil {
+					return err
+				}
+				last = post.Position
+			}
+
+			if len(posts) < streamBacklog {
+				break
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-sub.Dropped:
+			return status.Error(codes.Unavailable, "Subscription fell behind")
+		case post := <-sub.Posts:
+			if post.Position <= last {
+				continue
+			}
+			if err = stream.Send(forumpb.FromPostEvent(post)); err != nil {
+				return err
+			}
+			last = post.Position
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package threadDelivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	internalErrors "technopark-dbms-forum/internal"
+	"technopark-dbms-forum/pkg/models"
+)
+
+const (
+	streamBuffer    = 256
+	streamBacklog   = 100
+	streamHeartbeat = 15 * time.Second
+
+	headerLastEventID = "Last-Event-ID"
+)
+
+// writeEvent sends a post with its id as the event id, which Last-Event-ID
+// resumes from.
+func writeEvent(c echo.Context, post *models.PostEvent) error {
+	data, err := json.Marshal(&post.Post)
+	if err != nil {
+		return err
+	}
+
+	if _, err = fmt.Fprintf(c.Response(), "id: %d\nevent: post\ndata: %s\n\n", post.ID, data); err != nil {
+		return err
+	}
+	c.Response().Flush()
+
+	return nil
+}
+
+// writeError ends a stream whose headers are already sent; the client is
+// expected to reconnect with the last id it got.
+func writeError(c echo.Context, message string) {
+	fmt.Fprintf(c.Response(), "event: error\ndata: %s\n\n", message)
+	c.Response().Flush()
+}
+
+// Stream pushes posts created in a thread as Server-Sent Events, each with
+// the post id as its event id. A client that reconnects with Last-Event-ID
+// set to the last post id it got first gets the posts created after it.
+// "After" is in commit order rather than id order: ids are taken before
+// their transactions commit, so a later post may have a smaller id. The
+// stream resumes from the post's position in the event feed instead, which
+// may repeat a post committed alongside the given one but never skips one.
+func (h *Handler) Stream(c echo.Context) error {
+	slugOrID := c.Param("slug_or_id")
+
+	thread, err := h.threadUsecase.GetBySlugOrID(slugOrID)
+	if err == internalErrors.ErrNoRowsBySlug {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with slug: %s", slugOrID))
+	} else if err == internalErrors.ErrNoRowsByID {
+		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find thread with id: %s", slugOrID))
+	} else if err != nil && err != internalErrors.ErrThreadSlugMoved {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	var last uint64
+	if header := c.Request().Header.Get(headerLastEventID); header != "" {
+		postID, err := strconv.ParseUint(header, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Wrong Last-Event-ID: %s", header))
+		}
+
+		last, err = h.threadUsecase.GetPostPosition(thread.ID, postID)
+		if err == internalErrors.ErrNoRowsByID {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Last-Event-ID is not a post of the thread: %s", header))
+		} else if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err)
+		}
+	}
+
+	sub := h.threadUsecase.SubscribePosts(thread.ID, streamBuffer)
+	defer h.threadUsecase.UnsubscribePosts(sub)
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	if last != 0 {
+		for {
+			posts, err := h.threadUsecase.GetPostsAfterPosition(thread.ID, last, streamBacklog)
+			if err != nil {
+				c.Logger().Error(err)
+				writeError(c, "Can't read missed posts")
+				return nil
+			}
+
+			for _, post := range posts {
+				if err = writeEvent(c, post); err != nil {
+					return nil
+				}
+				last = post.Position
+			}
+
+			if len(posts) < streamBacklog {
+				break
+			}
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-sub.Dropped:
+			writeError(c, "Subscription fell behind")
+			return nil
+		case <-heartbeat.C:
+			if _, err = fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case post := <-sub.Posts:
+			// The backlog may already hold the posts published meanwhile.
+			if post.Position <= last {
+				continue
+			}
+			if err = writeEvent(c, post); err != nil {
+				return nil
+			}
+			last = post.Position
+		}
+	}
+}
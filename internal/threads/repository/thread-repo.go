@@ -3,14 +3,26 @@ package threadRepository
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
+	"time"
+
 	"github.com/lib/pq"
 
 	internalErrors "technopark-dbms-forum/internal"
+	eventRepository "technopark-dbms-forum/internal/events/repository"
+	filterRepository "technopark-dbms-forum/internal/filters/repository"
+	notificationRepository "technopark-dbms-forum/internal/notifications/repository"
 
 	"github.com/jmoiron/sqlx"
-	"technopark-dbms-forum/internal/models"
+	"technopark-dbms-forum/pkg/models"
 )
 
+// PostsChannel is the notification channel that carries the thread id of
+// every committed batch of new posts. It only wakes the post stream up: ids
+// are not given out in commit order, so the stream reads the posts from the
+// event feed instead.
+const PostsChannel = "thread_posts"
+
 type Postgres struct {
 	sqlx *sqlx.DB
 }
@@ -50,7 +62,12 @@ func (p *Postgres) Create(t *models.Thread) (*models.ThreadResponse, error) {
 		Votes:   0,
 	}
 
-	if err := p.sqlx.QueryRowx(
+	tx, err := p.sqlx.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.QueryRowx(
 		`
 			INSERT INTO threads (author_nickname, created, forum, message, slug, title) 
 			VALUES ($1, $2, $3, $4, $5, $6)
@@ -63,15 +80,45 @@ func (p *Postgres) Create(t *models.Thread) (*models.ThreadResponse, error) {
 		t.Slug,
 		t.Title,
 	).Scan(&thread.ID); err != nil {
+		tx.Rollback()
+
 		pgErr, ok := err.(*pq.Error)
 		if ok {
 			if pgErr.Code == "23503" {
 				return nil, internalErrors.ErrUserNotFound
+			} else if pgErr.Code == "23505" {
+				if existing, err := p.GetBySlug(t.Slug); err == nil {
+					return existing, internalErrors.ErrSlugAlreadyExist
+				}
 			}
 		}
 		return nil, err
 	}
 
+	if err = notificationRepository.InsertForThread(tx, &thread); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = filterRepository.InsertForThread(tx, &thread, t.Flags); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = filterRepository.InsertFingerprint(tx, t.Author, t.Fingerprint); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = eventRepository.Insert(tx, models.EventThreadCreated, &thread); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	return &thread, nil
 }
 
@@ -80,7 +127,7 @@ func (p *Postgres) GetBySlug(slug string) (*models.ThreadResponse, error) {
 	err := p.sqlx.Get(
 		&thread,
 		`
-			SELECT id, author_nickname, created, forum, message, slug, title, votes
+			SELECT id, author_nickname, created, forum, message, slug, title, votes, is_closed, is_pinned, version
 			FROM threads
 			WHERE slug = $1
 		`,
@@ -100,7 +147,7 @@ func (p *Postgres) GetByID(id uint64) (*models.ThreadResponse, error) {
 	err := p.sqlx.Get(
 		&thread,
 		`
-			SELECT id, author_nickname, created, forum, message, slug, title, votes
+			SELECT id, author_nickname, created, forum, message, slug, title, votes, is_closed, is_pinned, version
 			FROM threads
 			WHERE id = $1
 		`,
@@ -115,173 +162,255 @@ func (p *Postgres) GetByID(id uint64) (*models.ThreadResponse, error) {
 	return &thread, nil
 }
 
-func (p *Postgres) UpdateByID(t *models.Thread) (*models.ThreadResponse, error) {
+// GetByIDs returns the threads found among the ids, in no particular order.
+func (p *Postgres) GetByIDs(ids []int64) ([]*models.ThreadResponse, error) {
+	threads := make([]*models.ThreadResponse, 0, len(ids))
+	err := p.sqlx.Select(
+		&threads,
+		`
+			SELECT id, author_nickname, created, forum, message, slug, title, votes, is_closed, is_pinned, version
+			FROM threads
+			WHERE id = ANY($1::bigint[])
+		`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return threads, nil
+}
+
+func (p *Postgres) UpdateByID(id uint64, u *models.ThreadUpdate, versions []int64) (*models.ThreadResponse, error) {
+	tx, err := p.sqlx.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
 	thread := models.ThreadResponse{}
-	err := p.sqlx.Get(
+	err = tx.Get(
 		&thread,
 		`
-			SELECT id, author_nickname, created, forum, message, slug, title, votes
-			FROM threads
-			WHERE id = $1
+			UPDATE threads
+			SET message = COALESCE($1, message), title = COALESCE($2, title)
+			WHERE id = $3 AND ($4::BIGINT[] IS NULL OR version = ANY($4))
+			RETURNING id, author_nickname, created, forum, message, slug, title, votes, is_closed, is_pinned, version
 		`,
-		t.ID,
+		u.Message.Ptr(),
+		u.Title.Ptr(),
+		id,
+		pq.Array(versions),
 	)
 	if err == sql.ErrNoRows {
+		var exists bool
+		err = tx.Get(&exists, "SELECT EXISTS (SELECT 1 FROM threads WHERE id = $1)", id)
+		tx.Rollback()
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, internalErrors.ErrPreconditionFailed
+		}
 		return nil, internalErrors.ErrNoRowsByID
 	} else if err != nil {
+		tx.Rollback()
 		return nil, err
 	}
 
-	if t.Message != "" {
-		thread.Message = t.Message
+	if err = eventRepository.Insert(tx, models.EventThreadUpdated, &thread); err != nil {
+		tx.Rollback()
+		return nil, err
 	}
-	if t.Title != "" {
-		thread.Title = t.Title
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
 	}
 
-	_, err = p.sqlx.Exec(
+	return &thread, nil
+}
+
+func (p *Postgres) GetByRedirectSlug(slug string) (*models.ThreadResponse, error) {
+	thread := models.ThreadResponse{}
+	err := p.sqlx.Get(
+		&thread,
 		`
-			UPDATE threads
-			SET message = $1, title = $2
-			WHERE id = $3
+			SELECT t.id, t.author_nickname, t.created, t.forum, t.message, t.slug, t.title, t.votes, t.is_closed, t.is_pinned, t.version
+			FROM thread_slug_redirects r
+			JOIN threads t ON t.id = r.thread_id
+			WHERE r.slug = $1
 		`,
-		thread.Message,
-		thread.Title,
-		thread.ID,
+		slug,
 	)
-	if err != nil {
+	if err == sql.ErrNoRows {
+		return nil, internalErrors.ErrNoRows
+	} else if err != nil {
 		return nil, err
 	}
 
 	return &thread, nil
 }
 
-func (p *Postgres) UpdateBySlug(t *models.Thread) (*models.ThreadResponse, error) {
-	thread := models.ThreadResponse{
-		ID:      t.ID,
-		Author:  t.Author,
-		Created: t.Created,
-		Forum:   t.Forum,
-		Message: t.Message,
-		Slug:    t.Slug,
-		Title:   t.Title,
-		Votes:   0,
+func (p *Postgres) ChangeSlugByID(id uint64, slug string) (*models.ThreadResponse, error) {
+	tx, err := p.sqlx.Beginx()
+	if err != nil {
+		return nil, err
 	}
-	err := p.sqlx.Get(
+
+	thread := models.ThreadResponse{}
+	err = tx.Get(
 		&thread,
 		`
-			SELECT id, author_nickname, created, forum, message, slug, title, votes
+			SELECT id, author_nickname, created, forum, message, slug, title, votes, is_closed, is_pinned
 			FROM threads
-			WHERE slug = $1
+			WHERE id = $1
+			FOR UPDATE
 		`,
-		t.Slug,
+		id,
 	)
 	if err == sql.ErrNoRows {
-		return &thread, internalErrors.ErrNoRowsBySlug
+		tx.Rollback()
+		return nil, internalErrors.ErrNoRowsByID
 	} else if err != nil {
-		return &thread, err
+		tx.Rollback()
+		return nil, err
 	}
 
-	if t.Message != "" {
-		thread.Message = t.Message
+	var taken bool
+	if err = tx.Get(
+		&taken,
+		`
+			SELECT EXISTS (SELECT 1 FROM threads WHERE slug = $1 AND id <> $2)
+			    OR EXISTS (SELECT 1 FROM thread_slug_redirects WHERE slug = $1 AND thread_id <> $2)
+		`,
+		slug,
+		id,
+	); err != nil {
+		tx.Rollback()
+		return nil, err
 	}
-	if t.Title != "" {
-		thread.Title = t.Title
+	if taken {
+		tx.Rollback()
+		return &thread, internalErrors.ErrSlugAlreadyExist
 	}
 
-	_, err = p.sqlx.Exec(
-		`
-			UPDATE threads
-			SET message = $1, title = $2
-			WHERE id = $3
-		`,
-		thread.Message,
-		thread.Title,
-		thread.ID,
-	)
-	if err != nil {
+	if _, err = tx.Exec(
+		"DELETE FROM thread_slug_redirects WHERE slug = $1",
+		slug,
+	); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if thread.Slug != "" && thread.Slug != slug {
+		if _, err = tx.Exec(
+			`
+				INSERT INTO thread_slug_redirects (slug, thread_id)
+				VALUES ($1, $2)
+				ON CONFLICT (slug) DO UPDATE
+				SET thread_id = $2
+			`,
+			thread.Slug,
+			id,
+		); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if _, err = tx.Exec(
+		"UPDATE threads SET slug = $1 WHERE id = $2",
+		slug,
+		id,
+	); err != nil {
+		tx.Rollback()
+
+		// Another thread took the slug after the check above.
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+			return &thread, internalErrors.ErrSlugAlreadyExist
+		}
+		return nil, err
+	}
+
+	thread.Slug = slug
+
+	if err = eventRepository.Insert(tx, models.EventThreadSlugChanged, &thread); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
 		return nil, err
 	}
 
 	return &thread, nil
 }
 
-func (p *Postgres) VoteBySlug(slug string, v *models.Vote) (*models.ThreadResponse, error) {
-	thread := models.ThreadResponse{
-		Slug: slug,
+func (p *Postgres) ModerateByID(id uint64, m *models.ThreadModeration) (*models.ThreadResponse, error) {
+	tx, err := p.sqlx.Beginx()
+	if err != nil {
+		return nil, err
 	}
 
-	err := p.sqlx.Get(
+	thread := models.ThreadResponse{}
+	err = tx.Get(
 		&thread,
 		`
-			SELECT id, author_nickname, created, forum, message, slug, title, votes
-			FROM threads
-			WHERE slug = $1
+			UPDATE threads
+			SET is_closed = COALESCE($1, is_closed), is_pinned = COALESCE($2, is_pinned)
+			WHERE id = $3
+			RETURNING id, author_nickname, created, forum, message, slug, title, votes, is_closed, is_pinned, version
 		`,
-		slug,
+		m.Closed,
+		m.Pinned,
+		id,
 	)
 	if err == sql.ErrNoRows {
-		return &thread, internalErrors.ErrNoRows
+		tx.Rollback()
+		return nil, internalErrors.ErrNoRowsByID
 	} else if err != nil {
-		return &thread, err
+		tx.Rollback()
+		return nil, err
 	}
 
-	_, err = p.sqlx.Exec(
-		`
-			INSERT INTO votes (nickname, thread_id, voice)
-			VALUES ($1, $2, $3)
-			ON CONFLICT (nickname, thread_id) DO UPDATE
-			SET voice = $3
-		`,
-		v.Nickname,
-		thread.ID,
-		v.Voice,
-	)
-	if err != nil {
-		pgErr, ok := err.(*pq.Error)
-		if ok && pgErr.Code == "23503" {
-			return nil, internalErrors.ErrUserNotFound
-		}
+	if err = eventRepository.Insert(tx, models.EventThreadUpdated, &thread); err != nil {
+		tx.Rollback()
 		return nil, err
 	}
 
-	err = p.sqlx.Get(
-		&thread,
-		`
-			SELECT id, author_nickname, created, forum, message, slug, title, votes
-			FROM threads
-			WHERE slug = $1
-		`,
-		slug,
-	)
-	if err != nil {
-		return &thread, err
+	if err = tx.Commit(); err != nil {
+		return nil, err
 	}
 
 	return &thread, nil
 }
 
-func (p *Postgres) VoteByID(id uint64, v *models.Vote) (*models.ThreadResponse, error) {
-	thread := models.ThreadResponse{
-		ID: id,
-	}
-
-	err := p.sqlx.Get(
-		&thread,
+func (p *Postgres) GetRevisionsByID(id uint64) ([]*models.ThreadRevision, error) {
+	revisions := make([]*models.ThreadRevision, 0)
+	err := p.sqlx.Select(
+		&revisions,
 		`
-			SELECT id, author_nickname, created, forum, message, slug, title, votes
-			FROM threads
-			WHERE id = $1
+			SELECT id, thread_id, title, message, COALESCE(slug, '') as slug, edited
+			FROM thread_revisions
+			WHERE thread_id = $1
+			ORDER BY id DESC
 		`,
 		id,
 	)
-	if err == sql.ErrNoRows {
-		return &thread, internalErrors.ErrNoRows
-	} else if err != nil {
-		return &thread, err
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// Vote stores the user's voice for the thread and refreshes its vote count.
+func (p *Postgres) Vote(thread *models.ThreadResponse, v *models.Vote) (*models.ThreadResponse, error) {
+	tx, err := p.sqlx.Beginx()
+	if err != nil {
+		return nil, err
 	}
 
-	_, err = p.sqlx.Exec(
+	_, err = tx.Exec(
 		`
 			INSERT INTO votes (nickname, thread_id, voice)
 			VALUES ($1, $2, $3)
@@ -293,6 +422,8 @@ func (p *Postgres) VoteByID(id uint64, v *models.Vote) (*models.ThreadResponse,
 		v.Voice,
 	)
 	if err != nil {
+		tx.Rollback()
+
 		pgErr, ok := err.(*pq.Error)
 		if ok && pgErr.Code == "23503" {
 			return nil, internalErrors.ErrUserNotFound
@@ -300,29 +431,44 @@ func (p *Postgres) VoteByID(id uint64, v *models.Vote) (*models.ThreadResponse,
 		return nil, err
 	}
 
-	err = p.sqlx.Get(
-		&thread,
+	err = tx.Get(
+		thread,
 		`
-			SELECT id, author_nickname, created, forum, message, slug, title, votes
+			SELECT id, author_nickname, created, forum, message, slug, title, votes, is_closed, is_pinned, version
 			FROM threads
 			WHERE id = $1
 		`,
-		id,
+		thread.ID,
 	)
 	if err != nil {
-		return &thread, err
+		tx.Rollback()
+		return thread, err
 	}
 
-	return &thread, nil
+	if err = eventRepository.Insert(tx, models.EventThreadVoted, &models.VoteEvent{
+		Thread:   thread.ID,
+		Nickname: v.Nickname,
+		Voice:    v.Voice,
+		Votes:    thread.Votes,
+	}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return thread, nil
 }
 
 func (p *Postgres) getPostsByIDFlat(id uint64, limit uint64, since uint64, desc bool) ([]*models.Post, error) {
 	posts := make([]*models.Post, 0)
 
 	query := `
-		SELECT p.id, p.author_nickname, p.created, p.forum_slug, p.is_edited, p.message, p.parent_id, p.thread_id
+		SELECT p.id, p.author_nickname, p.created, p.forum_slug, p.is_edited, p.message, p.parent_id, p.thread_id, p.version
 		FROM posts p
-		WHERE p.thread_id = $1
+		WHERE p.thread_id = $1 AND NOT p.is_hidden
 	`
 
 	if since != 0 {
@@ -361,9 +507,9 @@ func (p *Postgres) getPostsByIDTree(id uint64, limit uint64, since uint64, desc
 	posts := make([]*models.Post, 0)
 
 	query := `
-		SELECT p.id, p.author_nickname, p.created, p.forum_slug, p.is_edited, p.message, p.parent_id, p.thread_id
+		SELECT p.id, p.author_nickname, p.created, p.forum_slug, p.is_edited, p.message, p.parent_id, p.thread_id, p.version
 		FROM posts p
-		WHERE p.thread_id = $1
+		WHERE p.thread_id = $1 AND NOT p.is_hidden
 	`
 
 	if since != 0 {
@@ -411,7 +557,7 @@ func (p *Postgres) getPostsByIDParentTree(id uint64, limit uint64, since uint64,
 			err = p.sqlx.Select(
 				&posts,
 				`
-					SELECT id, parent_id, author_nickname, message, is_edited, forum_slug, created, thread_id
+					SELECT id, parent_id, author_nickname, message, is_edited, forum_slug, created, thread_id, version
 					FROM posts
 					WHERE path[1] IN (
 					      	SELECT id 
@@ -419,7 +565,7 @@ func (p *Postgres) getPostsByIDParentTree(id uint64, limit uint64, since uint64,
 					      	WHERE thread_id = $1 AND parent_id = 0 
 					      	ORDER BY id DESC 
 					      	LIMIT $2
-					      )
+					      ) AND NOT is_hidden
 					ORDER BY path[1] DESC, path;
 				`,
 				id,
@@ -429,7 +575,7 @@ func (p *Postgres) getPostsByIDParentTree(id uint64, limit uint64, since uint64,
 			err = p.sqlx.Select(
 				&posts,
 				`
-					SELECT id, parent_id, author_nickname, message, is_edited, forum_slug, created, thread_id 
+					SELECT id, parent_id, author_nickname, message, is_edited, forum_slug, created, thread_id, version
 					FROM posts
 					WHERE path[1] IN (
 					      	SELECT id 
@@ -437,7 +583,7 @@ func (p *Postgres) getPostsByIDParentTree(id uint64, limit uint64, since uint64,
 					      	WHERE thread_id = $1 AND parent_id = 0 
 					      	ORDER BY id 
 					      	LIMIT $2
-					      )
+					      ) AND NOT is_hidden
 					ORDER BY path;
 				`,
 				id,
@@ -449,7 +595,7 @@ func (p *Postgres) getPostsByIDParentTree(id uint64, limit uint64, since uint64,
 			err = p.sqlx.Select(
 				&posts,
 				`
-					SELECT id, parent_id, author_nickname, message, is_edited, forum_slug, created, thread_id 
+					SELECT id, parent_id, author_nickname, message, is_edited, forum_slug, created, thread_id, version
 					FROM posts
 					WHERE path[1] IN (
 					      	SELECT id 
@@ -457,7 +603,7 @@ func (p *Postgres) getPostsByIDParentTree(id uint64, limit uint64, since uint64,
 					      	WHERE thread_id = $1 AND parent_id = 0 AND path[1] < (SELECT path[1] FROM posts WHERE id = $2)
 					      	ORDER BY id DESC 
 					      	LIMIT $3
-					      )
+					      ) AND NOT is_hidden
 					ORDER BY path[1] DESC, path;
 				`,
 				id,
@@ -468,7 +614,7 @@ func (p *Postgres) getPostsByIDParentTree(id uint64, limit uint64, since uint64,
 			err = p.sqlx.Select(
 				&posts,
 				`
-					SELECT id, parent_id, author_nickname, message, is_edited, forum_slug, created, thread_id 
+					SELECT id, parent_id, author_nickname, message, is_edited, forum_slug, created, thread_id, version
 					FROM posts p 
 					WHERE p.path[1] IN (
 					      	SELECT id 
@@ -476,7 +622,7 @@ func (p *Postgres) getPostsByIDParentTree(id uint64, limit uint64, since uint64,
 					      	WHERE thread_id = $1 AND parent_id = 0 AND path[1] > (SELECT path[1] FROM posts WHERE id = $2)
 					      	ORDER BY id 
 					      	LIMIT $3
-					      )
+					      ) AND NOT is_hidden
 					ORDER BY path;
 				`,
 				id,
@@ -564,6 +710,41 @@ func (p *Postgres) CreatePosts(posts []*models.Post) ([]*models.Post, error) {
 		}
 	}
 
+	if err = notificationRepository.InsertForPosts(tx, posts); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = filterRepository.InsertForPosts(tx, posts); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = filterRepository.InsertFingerprints(tx, posts); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	payloads := make([]interface{}, 0, len(posts))
+	for _, post := range posts {
+		payloads = append(payloads, post)
+	}
+	if err = eventRepository.Insert(tx, models.EventPostCreated, payloads...); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if len(posts) != 0 {
+		if _, err = tx.Exec(
+			"SELECT pg_notify($1, $2)",
+			PostsChannel,
+			strconv.FormatUint(posts[0].Thread, 10),
+		); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return nil, err
@@ -571,3 +752,135 @@ func (p *Postgres) CreatePosts(posts []*models.Post) ([]*models.Post, error) {
 
 	return posts, nil
 }
+
+func NewPostsListener(url string) (*pq.Listener, error) {
+	listener := pq.NewListener(url, time.Second, time.Minute, nil)
+	if err := listener.Listen(PostsChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// postEventColumns select a created post with its position in the event feed.
+const postEventColumns = `
+	e.position, p.id, p.author_nickname, p.created, p.forum_slug, p.is_edited, p.message, p.parent_id, p.thread_id, p.version
+	FROM events e
+	JOIN posts p ON p.id = (e.payload->>'id')::BIGINT
+`
+
+// GetPostEvents returns the visible posts of every thread created after the
+// feed position, in feed order.
+func (p *Postgres) GetPostEvents(after, limit uint64) ([]*models.PostEvent, error) {
+	posts := make([]*models.PostEvent, 0)
+	err := p.sqlx.Select(
+		&posts,
+		"SELECT "+postEventColumns+`
+			WHERE e.type = $1 AND e.position > $2 AND NOT p.is_hidden
+			ORDER BY e.position
+			LIMIT $3
+		`,
+		models.EventPostCreated,
+		after,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// GetPostEventsByID is GetPostEvents for one thread.
+func (p *Postgres) GetPostEventsByID(threadID, after, limit uint64) ([]*models.PostEvent, error) {
+	posts := make([]*models.PostEvent, 0)
+	err := p.sqlx.Select(
+		&posts,
+		"SELECT "+postEventColumns+`
+			WHERE e.type = $1 AND e.position > $2 AND p.thread_id = $3 AND NOT p.is_hidden
+			ORDER BY e.position
+			LIMIT $4
+		`,
+		models.EventPostCreated,
+		after,
+		threadID,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// GetPostPosition returns the feed position of the post's creation, if the
+// post is in the thread. A post whose event is not sequenced yet gets the
+// current end of the feed, so resuming from it may repeat posts committed
+// just before it but misses none.
+func (p *Postgres) GetPostPosition(threadID, postID uint64) (uint64, error) {
+	var position uint64
+	err := p.sqlx.Get(
+		&position,
+		`
+			SELECT COALESCE(e.position, (SELECT COALESCE(MAX(position), 0) FROM events))
+			FROM events e
+			JOIN posts p ON p.id = (e.payload->>'id')::BIGINT
+			WHERE e.type = '`+models.EventPostCreated+`' AND (e.payload->>'id')::BIGINT = $1 AND p.thread_id = $2
+		`,
+		postID,
+		threadID,
+	)
+	if err == sql.ErrNoRows {
+		return 0, internalErrors.ErrNoRowsByID
+	} else if err != nil {
+		return 0, err
+	}
+
+	return position, nil
+}
+
+func (p *Postgres) Subscribe(id uint64, nickname string) (bool, error) {
+	res, err := p.sqlx.Exec(
+		`
+			INSERT INTO thread_subscriptions (nickname, thread_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`,
+		nickname,
+		id,
+	)
+	if err != nil {
+		pgErr, ok := err.(*pq.Error)
+		if ok && pgErr.Code == "23503" {
+			return false, internalErrors.ErrUserNotFound
+		}
+		return false, err
+	}
+
+	created, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return created != 0, nil
+}
+
+func (p *Postgres) Unsubscribe(id uint64, nickname string) error {
+	res, err := p.sqlx.Exec(
+		"DELETE FROM thread_subscriptions WHERE thread_id = $1 AND nickname = $2",
+		id,
+		nickname,
+	)
+	if err != nil {
+		return err
+	}
+
+	if deleted, err := res.RowsAffected(); err != nil {
+		return err
+	} else if deleted == 0 {
+		return internalErrors.ErrNotSubscribed
+	}
+
+	return nil
+}
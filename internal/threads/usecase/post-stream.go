@@ -0,0 +1,156 @@
+package threadUsecase
+
+import (
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+
+	eventRepository "technopark-dbms-forum/internal/events/repository"
+	threadRepository "technopark-dbms-forum/internal/threads/repository"
+	"technopark-dbms-forum/pkg/models"
+)
+
+const (
+	streamBatch = 100
+
+	// streamPoll catches posts whose events were sequenced after their
+	// notification arrived, because an older transaction was still running.
+	streamPoll = time.Second
+)
+
+// PostSubscription receives posts created in one thread. Dropped is closed
+// when the subscriber fell too far behind and its buffer overflowed.
+type PostSubscription struct {
+	Posts   chan *models.PostEvent
+	Dropped chan struct{}
+
+	thread uint64
+}
+
+// PostStream fans out new posts to local subscribers in event feed order.
+// LISTEN/NOTIFY only wakes it up, so posts created on any instance reach
+// every stream without waiting for the next poll.
+type PostStream struct {
+	repo     *threadRepository.Postgres
+	events   *eventRepository.Postgres
+	listener *pq.Listener
+	logger   echo.Logger
+
+	mu          sync.Mutex
+	subscribers map[uint64]map[*PostSubscription]struct{}
+}
+
+func NewPostStream(repo *threadRepository.Postgres, events *eventRepository.Postgres, listener *pq.Listener, logger echo.Logger) *PostStream {
+	return &PostStream{
+		repo:        repo,
+		events:      events,
+		listener:    listener,
+		logger:      logger,
+		subscribers: make(map[uint64]map[*PostSubscription]struct{}),
+	}
+}
+
+func (s *PostStream) Subscribe(thread uint64, buffer int) *PostSubscription {
+	sub := &PostSubscription{
+		Posts:   make(chan *models.PostEvent, buffer),
+		Dropped: make(chan struct{}),
+		thread:  thread,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.subscribers[thread] == nil {
+		s.subscribers[thread] = make(map[*PostSubscription]struct{})
+	}
+	s.subscribers[thread][sub] = struct{}{}
+
+	return sub
+}
+
+func (s *PostStream) Unsubscribe(sub *PostSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(sub)
+}
+
+func (s *PostStream) remove(sub *PostSubscription) {
+	subs, ok := s.subscribers[sub.thread]
+	if !ok {
+		return
+	}
+	if _, ok = subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(s.subscribers, sub.thread)
+	}
+	close(sub.Dropped)
+}
+
+func (s *PostStream) Run() {
+	position, err := s.events.LastPosition()
+	for err != nil {
+		s.logger.Error(err)
+		time.Sleep(streamPoll)
+		position, err = s.events.LastPosition()
+	}
+
+	poll := time.NewTicker(streamPoll)
+	defer poll.Stop()
+
+	for {
+		select {
+		case _, ok := <-s.listener.NotificationChannel():
+			if !ok {
+				return
+			}
+		case <-poll.C:
+		}
+
+		position = s.catchUp(position)
+	}
+}
+
+// catchUp sequences the events of finished transactions and publishes the
+// posts after position, returning the position of the last one published.
+func (s *PostStream) catchUp(position uint64) uint64 {
+	if _, err := s.events.Sequence(streamBatch); err != nil {
+		s.logger.Error(err)
+	}
+
+	for {
+		posts, err := s.repo.GetPostEvents(position, streamBatch)
+		if err != nil {
+			s.logger.Error(err)
+			return position
+		}
+
+		for _, post := range posts {
+			s.publish(post)
+			position = post.Position
+		}
+
+		if len(posts) < streamBatch {
+			return position
+		}
+	}
+}
+
+func (s *PostStream) publish(post *models.PostEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sub := range s.subscribers[post.Thread] {
+		select {
+		case sub.Posts <- post:
+		default:
+			s.remove(sub)
+		}
+	}
+}
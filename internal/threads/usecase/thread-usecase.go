@@ -1,30 +1,69 @@
 package threadUsecase
 
 import (
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	postRepository "technopark-dbms-forum/internal/posts/repository"
 
 	internalErrors "technopark-dbms-forum/internal"
 
-	"technopark-dbms-forum/internal/models"
+	filterUsecase "technopark-dbms-forum/internal/filters/usecase"
+	limitUsecase "technopark-dbms-forum/internal/limits/usecase"
+	roleUsecase "technopark-dbms-forum/internal/roles/usecase"
 	threadRepository "technopark-dbms-forum/internal/threads/repository"
+	"technopark-dbms-forum/pkg/models"
 )
 
 type ThreadUsecase struct {
 	threadRepo *threadRepository.Postgres
 	postsRepo  *postRepository.Postgres
+	postStream *PostStream
+	roles      *roleUsecase.RoleUsecase
+	limits     *limitUsecase.LimitUsecase
+	filters    *filterUsecase.FilterUsecase
 }
 
-func NewThreadUsecase(threadRepo *threadRepository.Postgres, postsRepo *postRepository.Postgres) *ThreadUsecase {
+func NewThreadUsecase(
+	threadRepo *threadRepository.Postgres,
+	postsRepo *postRepository.Postgres,
+	postStream *PostStream,
+	roles *roleUsecase.RoleUsecase,
+	limits *limitUsecase.LimitUsecase,
+	filters *filterUsecase.FilterUsecase,
+) *ThreadUsecase {
 	return &ThreadUsecase{
 		threadRepo: threadRepo,
 		postsRepo:  postsRepo,
+		postStream: postStream,
+		roles:      roles,
+		limits:     limits,
+		filters:    filters,
 	}
 }
 
-func (t *ThreadUsecase) Create(thread *models.Thread) (*models.ThreadResponse, error) {
+// Create checks the rate limit last, so requests rejected for what they hold
+// take no tokens; the same goes for Vote and CreatePosts.
+func (t *ThreadUsecase) Create(thread *models.Thread, clientIP string) (*models.ThreadResponse, error) {
+	content := models.Content{
+		Author:  thread.Author,
+		Forum:   thread.Forum,
+		Title:   thread.Title,
+		Message: thread.Message,
+	}
+	flags, err := t.filters.Apply(&content)
+	if err != nil {
+		return nil, err
+	}
+	thread.Title, thread.Message, thread.Flags = content.Title, content.Message, flags
+	thread.Fingerprint = content.Fingerprint
+
+	if err = t.limits.Check(models.LimitThread, thread.Forum, clientIP, thread.Author); err != nil {
+		return nil, err
+	}
+
 	return t.threadRepo.Create(thread)
 }
 
@@ -33,7 +72,14 @@ func (t *ThreadUsecase) GetBySlugOrID(slugOrID string) (*models.ThreadResponse,
 	if err != nil {
 		thread, err := t.threadRepo.GetBySlug(slugOrID)
 		if err == internalErrors.ErrNoRows {
-			return thread, internalErrors.ErrNoRowsBySlug
+			thread, err = t.threadRepo.GetByRedirectSlug(slugOrID)
+			if err == internalErrors.ErrNoRows {
+				return nil, internalErrors.ErrNoRowsBySlug
+			} else if err != nil {
+				return nil, err
+			}
+
+			return thread, internalErrors.ErrThreadSlugMoved
 		}
 		return thread, err
 	}
@@ -45,49 +91,124 @@ func (t *ThreadUsecase) GetBySlugOrID(slugOrID string) (*models.ThreadResponse,
 	return thread, err
 }
 
-func (t *ThreadUsecase) Update(slugOrID, message, title string) (*models.ThreadResponse, error) {
-	id, err := strconv.ParseUint(slugOrID, 10, 64)
+// resolve looks a thread up like GetBySlugOrID but follows old slugs
+// silently, for operations that act on the thread rather than show it.
+func (t *ThreadUsecase) resolve(slugOrID string) (*models.ThreadResponse, error) {
+	thread, err := t.GetBySlugOrID(slugOrID)
+	if err == internalErrors.ErrThreadSlugMoved {
+		return thread, nil
+	}
+	return thread, err
+}
+
+func (t *ThreadUsecase) GetByIDs(ids []int64) ([]*models.ThreadResponse, error) {
+	return t.threadRepo.GetByIDs(ids)
+}
+
+func (t *ThreadUsecase) Update(slugOrID string, update *models.ThreadUpdate, versions []int64, actor string) (*models.ThreadResponse, error) {
+	if update.Message.Null || update.Title.Null {
+		return nil, internalErrors.ErrNullField
+	}
+
+	thread, err := t.resolve(slugOrID)
 	if err != nil {
-		return t.threadRepo.UpdateBySlug(&models.Thread{
-			Slug:    slugOrID,
-			Message: message,
-			Title:   title,
-		})
+		return nil, err
+	}
+	if err = t.roles.CanEdit(actor, thread.Author, thread.Forum); err != nil {
+		return nil, err
 	}
 
-	return t.threadRepo.UpdateByID(&models.Thread{
-		ID:      id,
-		Message: message,
-		Title:   title,
-	})
+	return t.threadRepo.UpdateByID(thread.ID, update, versions)
 }
 
-func (t *ThreadUsecase) Vote(slugOrID string, vote *models.Vote) (*models.ThreadResponse, error) {
-	id, err := strconv.ParseUint(slugOrID, 10, 64)
+// slugPattern is the slug pattern of the API spec. A slug made of digits only
+// would be read back as a thread id, so those are refused as well.
+var (
+	slugPattern    = regexp.MustCompile(`^[\w-]+$`)
+	numericPattern = regexp.MustCompile(`^\d+$`)
+)
+
+func (t *ThreadUsecase) ChangeSlug(slugOrID, slug, actor string) (*models.ThreadResponse, error) {
+	if !slugPattern.MatchString(slug) || numericPattern.MatchString(slug) {
+		return nil, internalErrors.ErrBadSlug
+	}
+
+	thread, err := t.resolve(slugOrID)
 	if err != nil {
-		return t.threadRepo.VoteBySlug(slugOrID, vote)
+		return nil, err
+	}
+	if err = t.roles.CanEdit(actor, thread.Author, thread.Forum); err != nil {
+		return nil, err
 	}
 
-	return t.threadRepo.VoteByID(id, vote)
+	return t.threadRepo.ChangeSlugByID(thread.ID, slug)
 }
 
-func (t *ThreadUsecase) CreatePosts(slugOrID string, posts []*models.Post) ([]*models.Post, error) {
-	id, err := strconv.ParseUint(slugOrID, 10, 64)
-	var thread *models.ThreadResponse
+func (t *ThreadUsecase) Moderate(slugOrID string, moderation *models.ThreadModeration, actor string) (*models.ThreadResponse, error) {
+	thread, err := t.resolve(slugOrID)
 	if err != nil {
-		thread, err = t.threadRepo.GetBySlug(slugOrID)
-		if err == internalErrors.ErrNoRows {
-			return nil, internalErrors.ErrNoRowsBySlug
-		} else if err != nil {
-			return nil, err
+		return nil, err
+	}
+	if err = t.roles.CanModerate(actor, thread.Forum); err != nil {
+		return nil, err
+	}
+
+	return t.threadRepo.ModerateByID(thread.ID, moderation)
+}
+
+func (t *ThreadUsecase) GetRevisions(slugOrID string) ([]*models.ThreadRevision, error) {
+	thread, err := t.resolve(slugOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.threadRepo.GetRevisionsByID(thread.ID)
+}
+
+func (t *ThreadUsecase) Vote(slugOrID string, vote *models.Vote, clientIP string) (*models.ThreadResponse, error) {
+	if vote.Voice != 1 && vote.Voice != -1 {
+		return nil, internalErrors.ErrWrongVoice
+	}
+
+	thread, err := t.resolve(slugOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = t.limits.Check(models.LimitVote, thread.Forum, clientIP, vote.Nickname); err != nil {
+		return nil, err
+	}
+
+	return t.threadRepo.Vote(thread, vote)
+}
+
+func (t *ThreadUsecase) CreatePosts(slugOrID string, posts []*models.Post, clientIP string) ([]*models.Post, error) {
+	thread, err := t.resolve(slugOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	if thread.Closed {
+		return nil, internalErrors.ErrThreadClosed
+	}
+
+	fingerprints := make(map[string][][]byte)
+	for _, post := range posts {
+		author := strings.ToLower(post.Author)
+		content := models.Content{
+			Author:   post.Author,
+			Forum:    thread.Forum,
+			Message:  post.Message,
+			Previous: fingerprints[author],
 		}
-	} else {
-		thread, err = t.threadRepo.GetByID(id)
-		if err == internalErrors.ErrNoRows {
-			return nil, internalErrors.ErrNoRowsByID
-		} else if err != nil {
+		flags, err := t.filters.Apply(&content)
+		if err != nil {
 			return nil, err
 		}
+		post.Message, post.Flags, post.Fingerprint = content.Message, flags, content.Fingerprint
+		if content.Fingerprint != nil {
+			fingerprints[author] = append(fingerprints[author], content.Fingerprint)
+		}
 	}
 
 	timeNow := time.Now().Format(time.RFC3339)
@@ -108,27 +229,69 @@ func (t *ThreadUsecase) CreatePosts(slugOrID string, posts []*models.Post) ([]*m
 		posts[index].Created = timeNow
 	}
 
+	authors := make([]string, 0, len(posts))
+	for _, post := range posts {
+		authors = append(authors, post.Author)
+	}
+	if err = t.limits.Check(models.LimitPost, thread.Forum, clientIP, authors...); err != nil {
+		return nil, err
+	}
+
 	return t.threadRepo.CreatePosts(posts)
 }
 
 func (t *ThreadUsecase) GetPosts(slugOrID string, limit, since uint64, sort string, desc bool) ([]*models.Post, error) {
-	id, err := strconv.ParseUint(slugOrID, 10, 64)
-	var thread *models.ThreadResponse
+	thread, err := t.resolve(slugOrID)
 	if err != nil {
-		thread, err = t.threadRepo.GetBySlug(slugOrID)
-		if err == internalErrors.ErrNoRows {
-			return nil, internalErrors.ErrNoRowsBySlug
-		} else if err != nil {
-			return nil, err
-		}
-	} else {
-		thread, err = t.threadRepo.GetByID(id)
-		if err == internalErrors.ErrNoRows {
-			return nil, internalErrors.ErrNoRowsByID
-		} else if err != nil {
-			return nil, err
-		}
+		return nil, err
 	}
 
 	return t.threadRepo.GetPostsByID(thread.ID, limit, since, sort, desc)
 }
+
+func (t *ThreadUsecase) SubscribePosts(threadID uint64, buffer int) *PostSubscription {
+	return t.postStream.Subscribe(threadID, buffer)
+}
+
+func (t *ThreadUsecase) UnsubscribePosts(sub *PostSubscription) {
+	t.postStream.Unsubscribe(sub)
+}
+
+// GetPostsAfterPosition returns the posts of the thread created after the
+// event feed position, which is what a stream resumes from.
+func (t *ThreadUsecase) GetPostsAfterPosition(threadID, after, limit uint64) ([]*models.PostEvent, error) {
+	return t.threadRepo.GetPostEventsByID(threadID, after, limit)
+}
+
+// GetPostPosition returns the event feed position of a post of the thread,
+// for streams resumed from a post id.
+func (t *ThreadUsecase) GetPostPosition(threadID, postID uint64) (uint64, error) {
+	return t.threadRepo.GetPostPosition(threadID, postID)
+}
+
+func (t *ThreadUsecase) Subscribe(slugOrID, nickname string) (*models.ThreadSubscription, bool, error) {
+	thread, err := t.resolve(slugOrID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	created, err := t.threadRepo.Subscribe(thread.ID, nickname)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &models.ThreadSubscription{Nickname: nickname, Thread: thread.ID}, created, nil
+}
+
+func (t *ThreadUsecase) Unsubscribe(slugOrID, nickname string) (*models.ThreadSubscription, error) {
+	thread, err := t.resolve(slugOrID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = t.threadRepo.Unsubscribe(thread.ID, nickname); err != nil {
+		return nil, err
+	}
+
+	return &models.ThreadSubscription{Nickname: nickname, Thread: thread.ID}, nil
+}
@@ -5,11 +5,13 @@ import (
 	"net/http"
 
 	internalErrors "technopark-dbms-forum/internal"
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
 
 	"github.com/labstack/echo/v4"
 
-	"technopark-dbms-forum/internal/models"
 	userUsecase "technopark-dbms-forum/internal/users/usecase"
+	"technopark-dbms-forum/pkg/etag"
+	"technopark-dbms-forum/pkg/models"
 )
 
 type Handler struct {
@@ -23,23 +25,30 @@ func NewHandler(userUsecase *userUsecase.UserUsecase) *Handler {
 func (h *Handler) Create(c echo.Context) error {
 	nickname := c.Param("nickname")
 
-	user := models.User{
-		Nickname: nickname,
+	request := struct {
+		models.User
+		// Password is taken on creation only and never sent back.
+		Password string `json:"password"`
+	}{
+		User: models.User{
+			Nickname: nickname,
+		},
 	}
 
-	err := c.Bind(&user)
+	err := c.Bind(&request)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
+	user := request.User
 
-	response, err := h.u.Create(&user)
+	users, err := h.u.Create(&user, request.Password)
 	if err == internalErrors.ErrAlreadyExist {
-		return c.JSON(http.StatusConflict, response)
+		return c.JSON(http.StatusConflict, users)
 	} else if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
-	return c.JSON(http.StatusCreated, response)
+	return c.JSON(http.StatusCreated, users[0])
 }
 
 func (h *Handler) Get(c echo.Context) error {
@@ -52,23 +61,34 @@ func (h *Handler) Get(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
-	return c.JSON(http.StatusOK, user)
+	return etag.JSON(c, http.StatusOK, etag.Make(user.Version), user)
 }
 
 func (h *Handler) Update(c echo.Context) error {
 	nickname := c.Param("nickname")
 
-	user := models.User{
-		Nickname: nickname,
+	versions, ok := etag.Versions(c.Request().Header.Get(etag.HeaderIfMatch))
+	if !ok {
+		return echo.NewHTTPError(http.StatusPreconditionFailed, fmt.Sprintf("User was modified: %s", nickname))
 	}
 
-	err := c.Bind(&user)
+	update := models.UserUpdate{}
+
+	err := c.Bind(&update)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
-	err = h.u.Update(&user)
-	if err == internalErrors.ErrNoRows {
+	actor := authDelivery.Actor(c)
+
+	user, err := h.u.Update(nickname, &update, versions, actor)
+	if err == internalErrors.ErrForbidden {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Can't edit profile of user: %s", nickname))
+	} else if err == internalErrors.ErrNullField {
+		return echo.NewHTTPError(http.StatusBadRequest, "User email, fullname and about can't be null")
+	} else if err == internalErrors.ErrPreconditionFailed {
+		return echo.NewHTTPError(http.StatusPreconditionFailed, fmt.Sprintf("User was modified: %s", nickname))
+	} else if err == internalErrors.ErrNoRows {
 		return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Can't find user by nickname: %s", nickname))
 	} else if err == internalErrors.ErrConflictEmail {
 		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("This email is already registered by user: %s", nickname))
@@ -76,5 +96,5 @@ func (h *Handler) Update(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
-	return c.JSON(http.StatusOK, user)
+	return etag.JSON(c, http.StatusOK, etag.Make(user.Version), user)
 }
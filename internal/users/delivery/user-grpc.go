@@ -0,0 +1,79 @@
+package userDelivery
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"technopark-dbms-forum/api/forumpb"
+	internalErrors "technopark-dbms-forum/internal"
+	authDelivery "technopark-dbms-forum/internal/auth/delivery"
+	userUsecase "technopark-dbms-forum/internal/users/usecase"
+)
+
+// GRPCServer serves forumpb.UserService with the usecases of Handler.
+type GRPCServer struct {
+	forumpb.UnimplementedUserServiceServer
+
+	u *userUsecase.UserUsecase
+}
+
+func NewGRPCServer(userUsecase *userUsecase.UserUsecase) *GRPCServer {
+	return &GRPCServer{u: userUsecase}
+}
+
+func (s *GRPCServer) CreateUser(ctx context.Context, req *forumpb.CreateUserRequest) (*forumpb.User, error) {
+	user := req.GetUser().Model()
+
+	users, err := s.u.Create(user, req.GetPassword())
+	if err == internalErrors.ErrAlreadyExist {
+		st, detailErr := status.New(codes.AlreadyExists, fmt.Sprintf("Can't create user with nickname: %s", user.Nickname)).
+			WithDetails(forumpb.FromUsers(users))
+		if detailErr != nil {
+			return nil, status.Error(codes.Internal, detailErr.Error())
+		}
+		return nil, st.Err()
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return forumpb.FromUser(users[0]), nil
+}
+
+func (s *GRPCServer) GetUser(ctx context.Context, req *forumpb.GetUserRequest) (*forumpb.User, error) {
+	nickname := req.GetNickname()
+
+	user, err := s.u.GetByNickname(nickname)
+	if err == internalErrors.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "Can't find user by nickname: %s", nickname)
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return forumpb.FromUser(user), nil
+}
+
+func (s *GRPCServer) UpdateUser(ctx context.Context, req *forumpb.UpdateUserRequest) (*forumpb.User, error) {
+	nickname := req.GetNickname()
+
+	actor := authDelivery.ActorFromContext(ctx)
+
+	user, err := s.u.Update(nickname, req.Update(), forumpb.Versions(req.GetVersion()), actor)
+	if err == internalErrors.ErrForbidden {
+		return nil, status.Errorf(codes.PermissionDenied, "Can't edit profile of user: %s", nickname)
+	} else if err == internalErrors.ErrNullField {
+		return nil, status.Error(codes.InvalidArgument, "User email, fullname and about can't be null")
+	} else if err == internalErrors.ErrPreconditionFailed {
+		return nil, status.Errorf(codes.FailedPrecondition, "User was modified: %s", nickname)
+	} else if err == internalErrors.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "Can't find user by nickname: %s", nickname)
+	} else if err == internalErrors.ErrConflictEmail {
+		return nil, status.Errorf(codes.AlreadyExists, "This email is already registered by user: %s", nickname)
+	} else if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return forumpb.FromUser(user), nil
+}
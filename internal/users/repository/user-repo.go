@@ -1,10 +1,13 @@
 package userRepository
 
 import (
+	"database/sql"
+
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	internalErrors "technopark-dbms-forum/internal"
-	"technopark-dbms-forum/internal/models"
+	eventRepository "technopark-dbms-forum/internal/events/repository"
+	"technopark-dbms-forum/pkg/models"
 )
 
 type Postgres struct {
@@ -28,22 +31,68 @@ func (p *Postgres) Close() error {
 	return p.sqlx.Close()
 }
 
-func (p *Postgres) Create(u *models.User) ([]*models.User, error) {
-	var user []*models.User
+func (p *Postgres) getConflicts(u *models.User) ([]*models.User, error) {
+	var users []*models.User
 	err := p.sqlx.Select(
-		&user,
+		&users,
 		"SELECT nickname, email, fullname, about FROM users WHERE nickname = $1 OR email = $2",
 		u.Nickname,
 		u.Email,
 	)
+
+	return users, err
+}
+
+// Create inserts the user, and its password hash unless that is empty.
+func (p *Postgres) Create(u *models.User, passwordHash string) ([]*models.User, error) {
+	user, err := p.getConflicts(u)
+	if err != nil {
+		return nil, err
+	}
+
 	if len(user) == 0 {
-		if _, err = p.sqlx.Exec(
+		tx, err := p.sqlx.Beginx()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err = tx.Exec(
 			"INSERT INTO users (nickname, email, fullname, about) VALUES ($1, $2, $3, $4)",
 			u.Nickname,
 			u.Email,
 			u.FullName,
 			u.About,
 		); err != nil {
+			tx.Rollback()
+
+			// A concurrent request took the nickname or the email after the
+			// check above; answer as if it had been there already.
+			if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+				if user, err = p.getConflicts(u); err != nil {
+					return nil, err
+				}
+				return user, internalErrors.ErrAlreadyExist
+			}
+			return nil, err
+		}
+
+		if passwordHash != "" {
+			if _, err = tx.Exec(
+				"INSERT INTO user_credentials (nickname, password_hash) VALUES ($1, $2)",
+				u.Nickname,
+				passwordHash,
+			); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+
+		if err = eventRepository.Insert(tx, models.EventUserCreated, u); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		if err = tx.Commit(); err != nil {
 			return nil, err
 		}
 
@@ -59,7 +108,7 @@ func (p *Postgres) GetByNickname(nickname string) (*models.User, error) {
 	var user models.User
 	if err := p.sqlx.Get(
 		&user,
-		"SELECT nickname, email, fullname, about FROM users WHERE nickname = $1",
+		"SELECT nickname, email, fullname, about, version FROM users WHERE nickname = $1",
 		nickname,
 	); err != nil {
 		return nil, err
@@ -68,29 +117,77 @@ func (p *Postgres) GetByNickname(nickname string) (*models.User, error) {
 	return &user, nil
 }
 
-func (p *Postgres) Update(u *models.User) error {
-	_, err := p.sqlx.Exec(
+// GetByNicknames returns the users found among the nicknames, in no particular order.
+func (p *Postgres) GetByNicknames(nicknames []string) ([]*models.User, error) {
+	users := make([]*models.User, 0, len(nicknames))
+	err := p.sqlx.Select(
+		&users,
+		"SELECT nickname, email, fullname, about, version FROM users WHERE nickname = ANY($1::citext[])",
+		pq.Array(nicknames),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (p *Postgres) Update(nickname string, u *models.UserUpdate, versions []int64) (*models.User, error) {
+	tx, err := p.sqlx.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	err = tx.Get(
+		&user,
 		`
-			UPDATE users SET email = $1, fullname = $2, about = $3 WHERE nickname = $4
+			UPDATE users
+			SET email = COALESCE($1, email), fullname = COALESCE($2, fullname), about = COALESCE($3, about)
+			WHERE nickname = $4 AND ($5::BIGINT[] IS NULL OR version = ANY($5))
+			RETURNING nickname, email, fullname, about, version
 		`,
-		u.Email,
-		u.FullName,
-		u.About,
-		u.Nickname,
+		u.Email.Ptr(),
+		u.FullName.Ptr(),
+		u.About.Ptr(),
+		nickname,
+		pq.Array(versions),
 	)
-	if err != nil {
+	if err == sql.ErrNoRows {
+		var exists bool
+		err = tx.Get(&exists, "SELECT EXISTS (SELECT 1 FROM users WHERE nickname = $1)", nickname)
+		tx.Rollback()
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, internalErrors.ErrPreconditionFailed
+		}
+		return nil, sql.ErrNoRows
+	} else if err != nil {
+		tx.Rollback()
+
 		pgErr, ok := err.(*pq.Error)
 		if ok {
 			if pgErr.Code == "23505" {
 				if pgErr.Constraint == "users_email_key" {
-					return internalErrors.ErrConflictEmail
+					return nil, internalErrors.ErrConflictEmail
 				} else {
-					return internalErrors.ErrConflictNickname
+					return nil, internalErrors.ErrConflictNickname
 				}
 			}
 		}
-		return err
+		return nil, err
 	}
 
-	return nil
+	if err = eventRepository.Insert(tx, models.EventUserUpdated, &user); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
 }
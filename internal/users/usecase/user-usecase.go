@@ -3,34 +3,37 @@ package userUsecase
 import (
 	"database/sql"
 
-	"github.com/jinzhu/copier"
+	"golang.org/x/crypto/bcrypt"
 
 	internalErrors "technopark-dbms-forum/internal"
-	"technopark-dbms-forum/internal/models"
+	roleUsecase "technopark-dbms-forum/internal/roles/usecase"
 	userRepository "technopark-dbms-forum/internal/users/repository"
+	"technopark-dbms-forum/pkg/models"
 )
 
 type UserUsecase struct {
-	r *userRepository.Postgres
+	r     *userRepository.Postgres
+	roles *roleUsecase.RoleUsecase
 }
 
-func NewUserUsecase(repo *userRepository.Postgres) *UserUsecase {
-	return &UserUsecase{r: repo}
+func NewUserUsecase(repo *userRepository.Postgres, roles *roleUsecase.RoleUsecase) *UserUsecase {
+	return &UserUsecase{r: repo, roles: roles}
 }
 
-func (u *UserUsecase) Create(user *models.User) (interface{}, error) {
-	users, err := u.r.Create(user)
-	if err == internalErrors.ErrAlreadyExist {
-		return users, err
-	} else if err != nil {
-		return nil, err
+// Create returns the created user, or every user holding the nickname or the
+// email together with ErrAlreadyExist. A non-empty password becomes the
+// user's first one: later changes need a token of the user.
+func (u *UserUsecase) Create(user *models.User, password string) ([]*models.User, error) {
+	hash := ""
+	if password != "" {
+		generated, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		hash = string(generated)
 	}
 
-	if len(users) == 1 {
-		return users[0], nil
-	}
-
-	return users, nil
+	return u.r.Create(user, hash)
 }
 
 func (u *UserUsecase) GetByNickname(nickname string) (*models.User, error) {
@@ -44,21 +47,23 @@ func (u *UserUsecase) GetByNickname(nickname string) (*models.User, error) {
 	return user, nil
 }
 
-func (u *UserUsecase) Update(user *models.User) error {
-	oldUser, err := u.GetByNickname(user.Nickname)
-	if err != nil {
-		return err
-	}
+func (u *UserUsecase) GetByNicknames(nicknames []string) ([]*models.User, error) {
+	return u.r.GetByNicknames(nicknames)
+}
 
-	if err = copier.CopyWithOption(oldUser, user, copier.Option{IgnoreEmpty: true}); err != nil {
-		return err
+func (u *UserUsecase) Update(nickname string, update *models.UserUpdate, versions []int64, actor string) (*models.User, error) {
+	if err := u.roles.CanEditProfile(actor, nickname); err != nil {
+		return nil, err
 	}
 
-	*user = *oldUser
+	if update.Email.Null || update.FullName.Null || update.About.Null {
+		return nil, internalErrors.ErrNullField
+	}
 
-	if err = u.r.Update(user); err == sql.ErrNoRows {
-		return internalErrors.ErrNoRows
+	user, err := u.r.Update(nickname, update, versions)
+	if err == sql.ErrNoRows {
+		return nil, internalErrors.ErrNoRows
 	}
 
-	return err
+	return user, err
 }
@@ -0,0 +1,372 @@
+// Package client is a Go client for the forum API. It covers every route of
+// the public API under /api, decodes responses into the models package and
+// turns error responses back into the sentinel errors the server works with,
+// so callers can use errors.Is the way the server code does.
+//
+// Reads are retried on network errors, 5xx and 429 answers. Creates go
+// through the server's idempotency keys, so they are retried the same way
+// without being applied twice.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	mathRand "math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"technopark-dbms-forum/pkg/etag"
+)
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	HeaderRetryAfter     = "Retry-After"
+
+	defaultAttempts   = 3
+	defaultBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff = 5 * time.Second
+)
+
+// Client calls one forum server. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	http       *http.Client
+	token      string
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.http = httpClient
+	}
+}
+
+// WithToken authorizes every request with a token from Login.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithRetries sets how many times a request is sent at most and the delay
+// before the first retry; the delay doubles with every retry up to maxBackoff.
+// A Retry-After header from the server takes precedence. One attempt turns
+// retries off.
+func WithRetries(attempts int, backoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.attempts = attempts
+		c.backoff = backoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// New makes a client for the server at baseURL, e.g. http://localhost:5000/api.
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		http:       http.DefaultClient,
+		attempts:   defaultAttempts,
+		backoff:    defaultBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	if c.attempts < 1 {
+		c.attempts = 1
+	}
+
+	return c
+}
+
+// As returns a copy of the client that acts with another token.
+func (c *Client) As(token string) *Client {
+	copied := *c
+	copied.token = token
+	return &copied
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey makes the create call made with ctx use key instead of a
+// random one, so that a caller retrying on its own is not applied twice.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+func newIdempotencyKey(ctx context.Context) string {
+	if key, ok := ctx.Value(idempotencyKey{}).(string); ok && key != "" {
+		return key
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// Without a key the request is still sent, just not retried.
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// request describes one API call.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	// body is sent as JSON unless raw is set.
+	body interface{}
+	raw  []byte
+	// idempotent creates get an Idempotency-Key and may be retried.
+	idempotent bool
+	// repeatable calls only set state, so sending them twice does no harm.
+	repeatable bool
+	// out receives the body of a 2xx answer.
+	out interface{}
+	// conflict receives the body of a 409 answer, which is then
+	// ErrAlreadyExist.
+	conflict interface{}
+}
+
+func (r *request) retryable() bool {
+	return r.method == http.MethodGet || r.method == http.MethodDelete || r.idempotent || r.repeatable
+}
+
+// response is a 2xx or 304 answer with its body already read.
+type response struct {
+	status int
+	header http.Header
+}
+
+// version is the resource version in the ETag, for If-Match on updates.
+func (r *response) version() uint64 {
+	versions, ok := etag.Versions(r.header.Get(etag.HeaderETag))
+	if !ok || len(versions) == 0 {
+		return 0
+	}
+	return uint64(versions[0])
+}
+
+func ifMatch(version uint64) http.Header {
+	if version == 0 {
+		return nil
+	}
+	return http.Header{etag.HeaderIfMatch: {etag.Make(version)}}
+}
+
+func (c *Client) send(ctx context.Context, r *request, body []byte) (*http.Response, error) {
+	target := c.baseURL + r.path
+	if len(r.query) > 0 {
+		target += "?" + r.query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	return c.http.Do(req)
+}
+
+// delay is how long to wait before the given retry.
+func (c *Client) delay(retry int, after time.Duration) time.Duration {
+	if after > 0 {
+		return after
+	}
+
+	d := time.Duration(float64(c.backoff) * math.Pow(2, float64(retry-1)))
+	if d > c.maxBackoff || d <= 0 {
+		d = c.maxBackoff
+	}
+	// Half of the delay is random, so that clients failing together do not
+	// come back together.
+	return d/2 + time.Duration(mathRand.Int63n(int64(d/2)+1))
+}
+
+func retryAfter(header http.Header) time.Duration {
+	seconds, err := strconv.ParseInt(header.Get(HeaderRetryAfter), 10, 64)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// shouldRetry reports whether a failed attempt is worth repeating: the
+// request did not get through, or the server failed, is overloaded or still
+// runs the same idempotent request.
+func shouldRetry(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return true
+	}
+
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		return false
+	}
+	return apiErr.Status >= http.StatusInternalServerError && apiErr.Status != http.StatusNotImplemented ||
+		apiErr.Status == http.StatusTooManyRequests ||
+		apiErr.Err == ErrRequestInProgress
+}
+
+// retry calls attempt until it succeeds, fails for good or runs out of
+// attempts. attempt returns the delay the server asked for along with the error.
+func (c *Client) retry(ctx context.Context, attempts int, attempt func() (time.Duration, error)) error {
+	for i := 1; ; i++ {
+		after, err := attempt()
+		if err == nil {
+			return nil
+		}
+		if i >= attempts || ctx.Err() != nil || !shouldRetry(err) {
+			return err
+		}
+
+		timer := time.NewTimer(c.delay(i, after))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// do sends the request, retrying it when that is safe, and decodes the answer.
+func (c *Client) do(ctx context.Context, r *request) (*response, error) {
+	body := r.raw
+	if r.body != nil {
+		data, err := json.Marshal(r.body)
+		if err != nil {
+			return nil, err
+		}
+		body = data
+	}
+
+	if r.idempotent {
+		if key := newIdempotencyKey(ctx); key != "" {
+			if r.header == nil {
+				r.header = http.Header{}
+			}
+			r.header.Set(HeaderIdempotencyKey, key)
+		} else {
+			r.idempotent = false
+		}
+	}
+
+	attempts := 1
+	if r.retryable() {
+		attempts = c.attempts
+	}
+
+	var res *response
+	err := c.retry(ctx, attempts, func() (after time.Duration, err error) {
+		res, after, err = c.attempt(ctx, r, body)
+		return after, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// attempt sends the request once.
+func (c *Client) attempt(ctx context.Context, r *request, body []byte) (*response, time.Duration, error) {
+	resp, err := c.send(ctx, r, body)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	res := &response{status: resp.StatusCode, header: resp.Header}
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return res, 0, nil
+	case resp.StatusCode == http.StatusConflict && r.conflict != nil:
+		// A retry that overtook the original gets a conflict too, which
+		// carries no existing row and is retried instead.
+		if err = decodeError(resp.StatusCode, resp.Header, data); errors.Is(err, ErrRequestInProgress) {
+			return nil, retryAfter(resp.Header), err
+		}
+		if err = json.Unmarshal(data, r.conflict); err != nil {
+			return nil, 0, err
+		}
+		return nil, 0, ErrAlreadyExist
+	case resp.StatusCode >= 300:
+		return nil, retryAfter(resp.Header), decodeError(resp.StatusCode, resp.Header, data)
+	}
+
+	if r.out != nil {
+		if err = json.Unmarshal(data, r.out); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return res, 0, nil
+}
+
+// open sends a GET and hands over the body of a 2xx answer unread, for
+// downloads and streams. It is retried like any read until the server answers.
+func (c *Client) open(ctx context.Context, path string, query url.Values, header http.Header) (*http.Response, error) {
+	r := &request{method: http.MethodGet, path: path, query: query, header: header}
+
+	var resp *http.Response
+	err := c.retry(ctx, c.attempts, func() (time.Duration, error) {
+		var err error
+		if resp, err = c.send(ctx, r, nil); err != nil {
+			return 0, err
+		}
+		if resp.StatusCode < 300 {
+			return 0, nil
+		}
+
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return 0, err
+		}
+		return retryAfter(resp.Header), decodeError(resp.StatusCode, resp.Header, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// segment escapes a value for use as one path segment.
+func segment(value string) string {
+	return url.PathEscape(value)
+}
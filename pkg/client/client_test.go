@@ -0,0 +1,297 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"technopark-dbms-forum/pkg/client"
+	"technopark-dbms-forum/pkg/models"
+)
+
+func newClient(t *testing.T, handler http.HandlerFunc) *client.Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return client.New(server.URL+"/api", client.WithRetries(3, time.Millisecond, time.Millisecond))
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func TestErrors(t *testing.T) {
+	cases := []struct {
+		status  int
+		message string
+		want    error
+	}{
+		{http.StatusNotFound, "Can't find user by nickname: alice", client.ErrUserNotFound},
+		{http.StatusNotFound, "Can't find thread with slug: jolly-roger", client.ErrNoRowsBySlug},
+		{http.StatusNotFound, "Can't find post thread by id: 42", client.ErrNoRowsByID},
+		{http.StatusNotFound, "Can't find forum with slug: pirates", client.ErrNoRows},
+		{http.StatusConflict, "Parent post was created in another thread", client.ErrWrongForumSlug},
+		{http.StatusConflict, "This email is already registered by user: alice", client.ErrConflictEmail},
+		{http.StatusConflict, "Thread slug is already taken: jolly-roger", client.ErrSlugAlreadyExist},
+		{http.StatusBadRequest, "Thread title and message can't be null", client.ErrNullField},
+		{http.StatusUnauthorized, "Wrong nickname or password", client.ErrWrongCredentials},
+		{http.StatusForbidden, "Thread is closed: 1", client.ErrThreadClosed},
+		{http.StatusForbidden, "User is banned", client.ErrBanned},
+		{http.StatusPreconditionFailed, "Thread was modified", client.ErrPreconditionFailed},
+		{http.StatusUnprocessableEntity, "Post was rejected: spam", client.ErrContentRejected},
+	}
+
+	for _, c := range cases {
+		t.Run(c.message, func(t *testing.T) {
+			api := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, c.status, map[string]string{"message": c.message})
+			})
+
+			_, err := api.GetThread(context.Background(), "1")
+			if !errors.Is(err, c.want) {
+				t.Fatalf("got %v, want %v", err, c.want)
+			}
+
+			var apiErr *client.APIError
+			if !errors.As(err, &apiErr) || apiErr.Status != c.status || apiErr.Message != c.message {
+				t.Errorf("got %#v", err)
+			}
+		})
+	}
+}
+
+func TestContentError(t *testing.T) {
+	api := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "Thread was rejected: links"})
+	})
+
+	_, err := api.CreateThread(context.Background(), "pirates", &models.Thread{Title: "t", Message: "m"})
+
+	var rejected *client.ContentError
+	if !errors.As(err, &rejected) || rejected.Reason != "links" {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestCreateRetries(t *testing.T) {
+	var mu sync.Mutex
+	keys := make([]string, 0)
+
+	api := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get(client.HeaderIdempotencyKey))
+		attempt := len(keys)
+		mu.Unlock()
+
+		switch attempt {
+		case 1:
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"message": "Service Unavailable"})
+		case 2:
+			writeJSON(w, http.StatusConflict, map[string]string{"message": "Request with this idempotency key is in progress"})
+		default:
+			writeJSON(w, http.StatusCreated, []*models.Post{{ID: 1, Message: "m"}})
+		}
+	})
+
+	posts, err := api.CreatePosts(context.Background(), "1", []*models.Post{{Message: "m"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].ID != 1 {
+		t.Errorf("got %v", posts)
+	}
+
+	if len(keys) != 3 || keys[0] == "" || keys[1] != keys[0] || keys[2] != keys[0] {
+		t.Errorf("idempotency keys %q, want one key sent three times", keys)
+	}
+}
+
+// A create whose retry overtakes the original is told the key is in
+// progress; that conflict carries no existing row and is retried.
+func TestCreateInProgress(t *testing.T) {
+	inProgress := func(created interface{}) http.HandlerFunc {
+		attempts := 0
+		return func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts == 1 {
+				writeJSON(w, http.StatusConflict, map[string]string{"message": "Request with this idempotency key is in progress"})
+				return
+			}
+			writeJSON(w, http.StatusCreated, created)
+		}
+	}
+
+	t.Run("forum", func(t *testing.T) {
+		api := newClient(t, inProgress(&models.ForumResponse{Title: "Pirates", User: "alice", Slug: "pirates"}))
+
+		forum, err := api.CreateForum(context.Background(), &models.Forum{Title: "Pirates", User: "alice", Slug: "pirates"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if forum.Slug != "pirates" || forum.User != "alice" {
+			t.Errorf("got %+v", forum)
+		}
+	})
+
+	t.Run("user", func(t *testing.T) {
+		api := newClient(t, inProgress(&models.User{Nickname: "alice", Email: "alice@example.com"}))
+
+		users, err := api.CreateUser(context.Background(), &models.User{Nickname: "alice", Email: "alice@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != 1 || users[0].Nickname != "alice" {
+			t.Errorf("got %v", users)
+		}
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	attempts := 0
+	api := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set(client.HeaderRetryAfter, "0")
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"message": "Too many requests"})
+	})
+
+	_, err := api.Vote(context.Background(), "1", &models.Vote{Nickname: "alice", Voice: 1})
+
+	var retry *client.RetryError
+	if !errors.Is(err, client.ErrRateLimited) || !errors.As(err, &retry) {
+		t.Errorf("got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("got %d attempts, want 3", attempts)
+	}
+}
+
+func TestUpdatesAreNotRetried(t *testing.T) {
+	attempts := 0
+	api := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "Internal Server Error"})
+	})
+
+	if _, err := api.UpdatePost(context.Background(), 1, "m", 0); err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts != 1 {
+		t.Errorf("got %d attempts, want 1", attempts)
+	}
+}
+
+func TestUserConflict(t *testing.T) {
+	api := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusConflict, []*models.User{{Nickname: "alice"}, {Nickname: "bob"}})
+	})
+
+	users, err := api.CreateUser(context.Background(), &models.User{Nickname: "alice", Email: "bob@example.com"})
+	if err != client.ErrAlreadyExist {
+		t.Fatalf("got %v", err)
+	}
+	if len(users) != 2 {
+		t.Errorf("got %v", users)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	api := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.Header.Get("If-Match") != `"7"` {
+			writeJSON(w, http.StatusPreconditionFailed, map[string]string{"message": "User was modified: alice"})
+			return
+		}
+		w.Header().Set("ETag", `"7"`)
+		writeJSON(w, http.StatusOK, &models.User{Nickname: "alice"})
+	})
+
+	user, err := api.GetUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Version != 7 {
+		t.Fatalf("got version %d, want 7", user.Version)
+	}
+
+	if _, err = api.UpdateUser(context.Background(), "alice", &models.UserUpdate{}, user.Version); err != nil {
+		t.Error(err)
+	}
+	if _, err = api.UpdateUser(context.Background(), "alice", &models.UserUpdate{}, 6); !errors.Is(err, client.ErrPreconditionFailed) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestForumThreads(t *testing.T) {
+	base := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	threads := make([]*models.ThreadResponse, 0)
+	for i := 1; i <= 7; i++ {
+		// Threads 2 to 5 share a timestamp, more than a page holds.
+		created := base.Add(time.Duration(i) * time.Minute)
+		if i >= 2 && i <= 5 {
+			created = base.Add(2 * time.Minute)
+		}
+		threads = append(threads, &models.ThreadResponse{ID: uint64(i), Created: created})
+	}
+
+	api := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		since, _ := time.Parse(time.RFC3339Nano, r.URL.Query().Get("since"))
+
+		page := make([]*models.ThreadResponse, 0)
+		for _, thread := range threads {
+			if !thread.Created.Before(since) && len(page) < limit {
+				page = append(page, thread)
+			}
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+
+	got, err := api.ForumThreads(context.Background(), "pirates", client.ThreadsQuery{Limit: 2}).All()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]uint64, 0, len(got))
+	for _, thread := range got {
+		ids = append(ids, thread.ID)
+	}
+	if len(ids) != 7 {
+		t.Fatalf("got %v, want 1 to 7", ids)
+	}
+	for i, id := range ids {
+		if id != uint64(i+1) {
+			t.Fatalf("got %v, want 1 to 7", ids)
+		}
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	api := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		page := &models.NotificationPage{Unread: 3}
+		switch r.URL.Query().Get("since") {
+		case "":
+			page.Notifications = []*models.Notification{{ID: 3}, {ID: 2}}
+			page.Next = 2
+		case "2":
+			page.Notifications = []*models.Notification{{ID: 1}}
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+
+	got, err := api.Notifications(context.Background(), "alice", client.NotificationsQuery{Limit: 2}).All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[2].ID != 1 {
+		t.Errorf("got %v", got)
+	}
+}
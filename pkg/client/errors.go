@@ -0,0 +1,157 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	internalErrors "technopark-dbms-forum/internal"
+)
+
+// The sentinel errors of the server, for errors.Is on what the client returns.
+var (
+	ErrAlreadyExist                  = internalErrors.ErrAlreadyExist
+	ErrNoRows                        = internalErrors.ErrNoRows
+	ErrPostWasCreatedInAnotherThread = internalErrors.ErrPostWasCreatedInAnotherThread
+	ErrNoRowsBySlug                  = internalErrors.ErrNoRowsBySlug
+	ErrNoRowsByID                    = internalErrors.ErrNoRowsByID
+	ErrConflictEmail                 = internalErrors.ErrConflictEmail
+	ErrUserNotFound                  = internalErrors.ErrUserNotFound
+	ErrSlugAlreadyExist              = internalErrors.ErrSlugAlreadyExist
+	ErrWrongForumSlug                = internalErrors.ErrWrongForumSlug
+	ErrNoParentPost                  = internalErrors.ErrNoParentPost
+	ErrNullField                     = internalErrors.ErrNullField
+	ErrPreconditionFailed            = internalErrors.ErrPreconditionFailed
+	ErrNotSubscribed                 = internalErrors.ErrNotSubscribed
+	ErrWrongCredentials              = internalErrors.ErrWrongCredentials
+	ErrUnauthorized                  = internalErrors.ErrUnauthorized
+	ErrForbidden                     = internalErrors.ErrForbidden
+	ErrThreadClosed                  = internalErrors.ErrThreadClosed
+	ErrNotModerator                  = internalErrors.ErrNotModerator
+	ErrRateLimited                   = internalErrors.ErrRateLimited
+	ErrBanned                        = internalErrors.ErrBanned
+	ErrContentRejected               = internalErrors.ErrContentRejected
+	ErrAttachmentTooLarge            = internalErrors.ErrAttachmentTooLarge
+	ErrAttachmentType                = internalErrors.ErrAttachmentType
+	ErrBadArchive                    = internalErrors.ErrBadArchive
+)
+
+// Errors of the idempotency layer, which the server has no sentinels for.
+var (
+	ErrRequestInProgress    = errors.New("request with this idempotency key is in progress")
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used with another request")
+)
+
+// RetryError comes with ErrRateLimited and ErrBanned; After is the server's
+// Retry-After.
+type RetryError = internalErrors.RetryError
+
+// ContentError comes with ErrContentRejected.
+type ContentError = internalErrors.ContentError
+
+// APIError is an error answer of the server. Err is the sentinel error,
+// *RetryError or *ContentError the answer stands for, or nil if it stands for
+// none, as with malformed requests and server failures.
+type APIError struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// errorRule maps the answers with the given status whose message contains
+// text to err. An empty text matches any message.
+type errorRule struct {
+	status int
+	text   string
+	err    error
+}
+
+// errorRules follow the messages of the delivery handlers; the first match
+// wins, so the more specific texts come first.
+var errorRules = []errorRule{
+	{http.StatusBadRequest, "can't be null", ErrNullField},
+	{http.StatusBadRequest, "Malformed archive", ErrBadArchive},
+
+	{http.StatusUnauthorized, "Wrong nickname or password", ErrWrongCredentials},
+	{http.StatusUnauthorized, "Wrong old password", ErrWrongCredentials},
+	{http.StatusUnauthorized, "", ErrUnauthorized},
+
+	{http.StatusForbidden, "Thread is closed", ErrThreadClosed},
+	{http.StatusForbidden, "", ErrForbidden},
+
+	{http.StatusNotFound, "Can't find user", ErrUserNotFound},
+	{http.StatusNotFound, "Can't find post author", ErrUserNotFound},
+	{http.StatusNotFound, "Can't find thread author", ErrUserNotFound},
+	{http.StatusNotFound, "Can't find thread with slug", ErrNoRowsBySlug},
+	{http.StatusNotFound, "Can't find post thread by slug", ErrNoRowsBySlug},
+	{http.StatusNotFound, "Can't find thread with id", ErrNoRowsByID},
+	{http.StatusNotFound, "Can't find post thread by id", ErrNoRowsByID},
+	{http.StatusNotFound, "Can't find parent post", ErrNoParentPost},
+	{http.StatusNotFound, "is not subscribed", ErrNotSubscribed},
+	{http.StatusNotFound, "doesn't follow", ErrNotSubscribed},
+	{http.StatusNotFound, "is not a moderator", ErrNotModerator},
+	{http.StatusNotFound, "", ErrNoRows},
+
+	{http.StatusConflict, "Request with this idempotency key is in progress", ErrRequestInProgress},
+	{http.StatusConflict, "Parent post was created in another thread", ErrWrongForumSlug},
+	{http.StatusConflict, "Post was created in another thread", ErrPostWasCreatedInAnotherThread},
+	{http.StatusConflict, "email", ErrConflictEmail},
+	{http.StatusConflict, "Thread slug", ErrSlugAlreadyExist},
+	{http.StatusConflict, "", ErrAlreadyExist},
+
+	{http.StatusPreconditionFailed, "", ErrPreconditionFailed},
+	{http.StatusRequestEntityTooLarge, "", ErrAttachmentTooLarge},
+	{http.StatusUnsupportedMediaType, "", ErrAttachmentType},
+
+	{http.StatusUnprocessableEntity, "Idempotency key was used with another request", ErrIdempotencyKeyReused},
+}
+
+// rejectedPrefixes start the messages of content filter rejections; the
+// reason follows them.
+var rejectedPrefixes = []string{"Post was rejected: ", "Thread was rejected: "}
+
+// decodeError turns an error answer back into the error the handler started from.
+func decodeError(status int, header http.Header, body []byte) error {
+	apiErr := &APIError{Status: status, Message: http.StatusText(status)}
+
+	var message struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &message); err == nil && message.Message != "" {
+		apiErr.Message = message.Message
+	}
+
+	switch {
+	case status == http.StatusTooManyRequests:
+		apiErr.Err = &RetryError{Err: ErrRateLimited, After: retryAfter(header)}
+		return apiErr
+	case status == http.StatusForbidden && apiErr.Message == "User is banned":
+		apiErr.Err = &RetryError{Err: ErrBanned, After: retryAfter(header)}
+		return apiErr
+	case status == http.StatusUnprocessableEntity:
+		for _, prefix := range rejectedPrefixes {
+			if strings.HasPrefix(apiErr.Message, prefix) {
+				apiErr.Err = &ContentError{Reason: strings.TrimPrefix(apiErr.Message, prefix)}
+				return apiErr
+			}
+		}
+	}
+
+	for _, rule := range errorRules {
+		if rule.status == status && strings.Contains(apiErr.Message, rule.text) {
+			apiErr.Err = rule.err
+			break
+		}
+	}
+
+	return apiErr
+}
@@ -0,0 +1,219 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+// CreateForum returns the created forum, or the forum holding the slug
+// together with ErrAlreadyExist.
+func (c *Client) CreateForum(ctx context.Context, forum *models.Forum) (*models.ForumResponse, error) {
+	created := &models.ForumResponse{}
+
+	_, err := c.do(ctx, &request{
+		method:     http.MethodPost,
+		path:       "/forum/create",
+		body:       forum,
+		idempotent: true,
+		out:        created,
+		conflict:   created,
+	})
+	if err != nil && err != ErrAlreadyExist {
+		return nil, err
+	}
+
+	return created, err
+}
+
+func (c *Client) GetForum(ctx context.Context, slug string) (*models.ForumResponse, error) {
+	forum := &models.ForumResponse{}
+
+	res, err := c.do(ctx, &request{method: http.MethodGet, path: "/forum/" + segment(slug) + "/details", out: forum})
+	if err != nil {
+		return nil, err
+	}
+	forum.Version = res.version()
+
+	return forum, nil
+}
+
+// CreateThread returns the created thread, or the thread holding the slug
+// together with ErrAlreadyExist.
+func (c *Client) CreateThread(ctx context.Context, forum string, thread *models.Thread) (*models.ThreadResponse, error) {
+	created := &models.ThreadResponse{}
+
+	_, err := c.do(ctx, &request{
+		method:     http.MethodPost,
+		path:       "/forum/" + segment(forum) + "/create",
+		body:       thread,
+		idempotent: true,
+		out:        created,
+		conflict:   created,
+	})
+	if err != nil && err != ErrAlreadyExist {
+		return nil, err
+	}
+
+	return created, err
+}
+
+// ThreadsQuery orders threads by creation time. Unlike the server, which
+// lists a feed newest first by default, the zero value lists oldest first
+// everywhere.
+type ThreadsQuery struct {
+	// Limit is the page size; the server's default is used when it is zero.
+	Limit int64
+	// Since starts the list at threads created at that time, inclusive.
+	Since time.Time
+	Desc  bool
+}
+
+// threads walks a list of threads. since is inclusive on the server, so the
+// threads of the last page created at its last timestamp are asked for
+// again and skipped.
+func (c *Client) threads(ctx context.Context, path string, query ThreadsQuery) *Iterator[*models.ThreadResponse] {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	since := query.Since
+	seen := make(map[uint64]bool)
+
+	return newIterator(ctx, func(ctx context.Context) ([]*models.ThreadResponse, bool, error) {
+		for {
+			values := url.Values{}
+			values.Set("limit", strconv.FormatInt(limit, 10))
+			values.Set("desc", strconv.FormatBool(query.Desc))
+			if !since.IsZero() {
+				values.Set("since", since.Format(time.RFC3339Nano))
+			}
+
+			threads := make([]*models.ThreadResponse, 0)
+			if _, err := c.do(ctx, &request{method: http.MethodGet, path: path, query: values, out: &threads}); err != nil {
+				return nil, false, err
+			}
+			done := int64(len(threads)) < limit
+
+			page := make([]*models.ThreadResponse, 0, len(threads))
+			for _, thread := range threads {
+				if !seen[thread.ID] {
+					page = append(page, thread)
+				}
+			}
+
+			if len(threads) > 0 {
+				last := threads[len(threads)-1].Created
+				if !last.Equal(since) {
+					seen = make(map[uint64]bool)
+				}
+				for _, thread := range threads {
+					if thread.Created.Equal(last) {
+						seen[thread.ID] = true
+					}
+				}
+				since = last
+			}
+
+			if len(page) == 0 && !done {
+				// A whole page shares one timestamp: ask for more at once.
+				limit *= 2
+				continue
+			}
+
+			return page, done, nil
+		}
+	})
+}
+
+func (c *Client) ForumThreads(ctx context.Context, slug string, query ThreadsQuery) *Iterator[*models.ThreadResponse] {
+	return c.threads(ctx, "/forum/"+segment(slug)+"/threads", query)
+}
+
+// UsersQuery orders users by nickname.
+type UsersQuery struct {
+	// Limit is the page size; the server's default is used when it is zero.
+	Limit int64
+	// Since starts the list after that nickname.
+	Since string
+	Desc  bool
+}
+
+// ForumUsers walks the users who posted in the forum.
+func (c *Client) ForumUsers(ctx context.Context, slug string, query UsersQuery) *Iterator[*models.User] {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	since := query.Since
+
+	return newIterator(ctx, func(ctx context.Context) ([]*models.User, bool, error) {
+		values := url.Values{}
+		values.Set("limit", strconv.FormatInt(limit, 10))
+		values.Set("desc", strconv.FormatBool(query.Desc))
+		if since != "" {
+			values.Set("since", since)
+		}
+
+		users := make([]*models.User, 0)
+		_, err := c.do(ctx, &request{method: http.MethodGet, path: "/forum/" + segment(slug) + "/users", query: values, out: &users})
+		if err != nil {
+			return nil, false, err
+		}
+
+		if len(users) > 0 {
+			since = users[len(users)-1].Nickname
+		}
+		return users, int64(len(users)) < limit, nil
+	})
+}
+
+// Follow reports whether the user did not follow the forum before.
+func (c *Client) Follow(ctx context.Context, slug, nickname string) (*models.ForumFollow, bool, error) {
+	follow := &models.ForumFollow{}
+
+	res, err := c.do(ctx, &request{
+		method:     http.MethodPost,
+		path:       "/forum/" + segment(slug) + "/follow",
+		body:       &models.ForumFollow{Nickname: nickname},
+		repeatable: true,
+		out:        follow,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return follow, res.status == http.StatusCreated, nil
+}
+
+func (c *Client) Unfollow(ctx context.Context, slug, nickname string) (*models.ForumFollow, error) {
+	follow := &models.ForumFollow{}
+
+	_, err := c.do(ctx, &request{
+		method: http.MethodDelete,
+		path:   "/forum/" + segment(slug) + "/follow",
+		query:  url.Values{"nickname": {nickname}},
+		out:    follow,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return follow, nil
+}
+
+// ExportForum downloads the forum archive; the caller closes it. It needs
+// the token of someone who may moderate the forum.
+func (c *Client) ExportForum(ctx context.Context, slug string) (io.ReadCloser, error) {
+	resp, err := c.open(ctx, "/forum/"+segment(slug)+"/export", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
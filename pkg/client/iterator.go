@@ -0,0 +1,68 @@
+package client
+
+import "context"
+
+// Iterator walks a paginated list page by page, fetching the next page when
+// the current one runs out:
+//
+//	it := c.ForumThreads(ctx, "pirates", client.ThreadsQuery{Limit: 50})
+//	for it.Next() {
+//		thread := it.Value()
+//	}
+//	if err := it.Err(); err != nil {
+//
+// It is not safe for concurrent use.
+type Iterator[T any] struct {
+	ctx   context.Context
+	fetch func(ctx context.Context) (page []T, done bool, err error)
+
+	page []T
+	i    int
+	done bool
+	err  error
+}
+
+// newIterator walks the pages fetch returns; fetch moves its own cursor and
+// reports done with the last page.
+func newIterator[T any](ctx context.Context, fetch func(ctx context.Context) ([]T, bool, error)) *Iterator[T] {
+	return &Iterator[T]{ctx: ctx, fetch: fetch, i: -1}
+}
+
+// Next advances to the next item and reports whether there is one.
+func (it *Iterator[T]) Next() bool {
+	for it.i+1 >= len(it.page) {
+		if it.done || it.err != nil {
+			return false
+		}
+
+		it.page, it.done, it.err = it.fetch(it.ctx)
+		it.i = -1
+		if it.err != nil {
+			it.page = nil
+			return false
+		}
+	}
+
+	it.i++
+	return true
+}
+
+// Value is the current item.
+func (it *Iterator[T]) Value() T {
+	return it.page[it.i]
+}
+
+// Err is the error that stopped the iteration, if any.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// All collects the remaining items.
+func (it *Iterator[T]) All() ([]T, error) {
+	items := make([]T, 0)
+	for it.Next() {
+		items = append(items, it.Value())
+	}
+
+	return items, it.Err()
+}
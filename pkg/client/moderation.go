@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+func (c *Client) GetModerators(ctx context.Context, slug string) ([]*models.Moderator, error) {
+	moderators := make([]*models.Moderator, 0)
+
+	_, err := c.do(ctx, &request{method: http.MethodGet, path: "/forum/" + segment(slug) + "/moderators", out: &moderators})
+	if err != nil {
+		return nil, err
+	}
+
+	return moderators, nil
+}
+
+// GrantModerator returns the moderators of the forum after the grant.
+func (c *Client) GrantModerator(ctx context.Context, slug, nickname string) ([]*models.Moderator, error) {
+	moderators := make([]*models.Moderator, 0)
+
+	_, err := c.do(ctx, &request{
+		method:     http.MethodPost,
+		path:       "/forum/" + segment(slug) + "/moderators/" + segment(nickname),
+		repeatable: true,
+		out:        &moderators,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return moderators, nil
+}
+
+// RevokeModerator returns the moderators of the forum after the revocation.
+func (c *Client) RevokeModerator(ctx context.Context, slug, nickname string) ([]*models.Moderator, error) {
+	moderators := make([]*models.Moderator, 0)
+
+	_, err := c.do(ctx, &request{
+		method: http.MethodDelete,
+		path:   "/forum/" + segment(slug) + "/moderators/" + segment(nickname),
+		out:    &moderators,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return moderators, nil
+}
+
+func (c *Client) GetBans(ctx context.Context, slug string) ([]*models.Ban, error) {
+	bans := make([]*models.Ban, 0)
+
+	_, err := c.do(ctx, &request{method: http.MethodGet, path: "/forum/" + segment(slug) + "/bans", out: &bans})
+	if err != nil {
+		return nil, err
+	}
+
+	return bans, nil
+}
+
+// Ban bans a user from the forum; the duration is a Go duration string.
+func (c *Client) Ban(ctx context.Context, slug string, ban *models.BanRequest) (*models.Ban, error) {
+	created := &models.Ban{}
+
+	_, err := c.do(ctx, &request{
+		method: http.MethodPost,
+		path:   "/forum/" + segment(slug) + "/bans",
+		body:   ban,
+		out:    created,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (c *Client) Unban(ctx context.Context, slug, nickname string) error {
+	_, err := c.do(ctx, &request{method: http.MethodDelete, path: "/forum/" + segment(slug) + "/bans/" + segment(nickname)})
+	return err
+}
+
+func (c *Client) GetLimits(ctx context.Context, slug string) ([]*models.RateLimit, error) {
+	limits := make([]*models.RateLimit, 0)
+
+	_, err := c.do(ctx, &request{method: http.MethodGet, path: "/forum/" + segment(slug) + "/limits", out: &limits})
+	if err != nil {
+		return nil, err
+	}
+
+	return limits, nil
+}
+
+func (c *Client) SetLimit(ctx context.Context, slug string, limit *models.RateLimit) (*models.RateLimit, error) {
+	set := &models.RateLimit{}
+
+	_, err := c.do(ctx, &request{
+		method:     http.MethodPost,
+		path:       "/forum/" + segment(slug) + "/limits",
+		body:       limit,
+		repeatable: true,
+		out:        set,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+func (c *Client) DeleteLimit(ctx context.Context, slug, action string) error {
+	_, err := c.do(ctx, &request{method: http.MethodDelete, path: "/forum/" + segment(slug) + "/limits/" + segment(action)})
+	return err
+}
+
+// Queue walks the moderation queue of the forum, oldest first. limit is the
+// page size; the server's default is used when it is zero.
+func (c *Client) Queue(ctx context.Context, slug string, limit int64) *Iterator[*models.ModerationItem] {
+	if limit <= 0 {
+		limit = 100
+	}
+	var since uint64
+
+	return newIterator(ctx, func(ctx context.Context) ([]*models.ModerationItem, bool, error) {
+		values := url.Values{}
+		values.Set("limit", strconv.FormatInt(limit, 10))
+		values.Set("since", strconv.FormatUint(since, 10))
+
+		items := make([]*models.ModerationItem, 0)
+		_, err := c.do(ctx, &request{method: http.MethodGet, path: "/forum/" + segment(slug) + "/queue", query: values, out: &items})
+		if err != nil {
+			return nil, false, err
+		}
+
+		if len(items) > 0 {
+			since = items[len(items)-1].ID
+		}
+		return items, int64(len(items)) < limit, nil
+	})
+}
+
+func (c *Client) DismissQueueItem(ctx context.Context, slug string, id uint64) error {
+	_, err := c.do(ctx, &request{
+		method: http.MethodDelete,
+		path:   "/forum/" + segment(slug) + "/queue/" + strconv.FormatUint(id, 10),
+	})
+	return err
+}
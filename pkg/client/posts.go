@@ -0,0 +1,130 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+// Related objects of GetPost.
+const (
+	RelatedUser   = "user"
+	RelatedForum  = "forum"
+	RelatedThread = "thread"
+)
+
+// GetPost fills in the Version of the post for UpdatePost.
+func (c *Client) GetPost(ctx context.Context, id uint64, related ...string) (*models.FullPost, error) {
+	post := &models.FullPost{}
+
+	var query url.Values
+	if len(related) > 0 {
+		query = url.Values{"related": {strings.Join(related, ",")}}
+	}
+
+	res, err := c.do(ctx, &request{method: http.MethodGet, path: "/post/" + strconv.FormatUint(id, 10) + "/details", query: query, out: post})
+	if err != nil {
+		return nil, err
+	}
+	if post.Post != nil {
+		post.Post.Version = res.version()
+	}
+
+	return post, nil
+}
+
+// UpdatePost changes the message of the post. A non-zero version makes the
+// update fail with ErrPreconditionFailed if the post changed since.
+func (c *Client) UpdatePost(ctx context.Context, id uint64, message string, version uint64) (*models.Post, error) {
+	post := &models.Post{}
+
+	res, err := c.do(ctx, &request{
+		method: http.MethodPost,
+		path:   "/post/" + strconv.FormatUint(id, 10) + "/details",
+		header: ifMatch(version),
+		body: &struct {
+			Message string `json:"message"`
+		}{message},
+		out: post,
+	})
+	if err != nil {
+		return nil, err
+	}
+	post.Version = res.version()
+
+	return post, nil
+}
+
+func (c *Client) ModeratePost(ctx context.Context, id uint64, moderation *models.PostModeration) (*models.Post, error) {
+	post := &models.Post{}
+
+	res, err := c.do(ctx, &request{
+		method:     http.MethodPost,
+		path:       "/post/" + strconv.FormatUint(id, 10) + "/moderate",
+		body:       moderation,
+		repeatable: true,
+		out:        post,
+	})
+	if err != nil {
+		return nil, err
+	}
+	post.Version = res.version()
+
+	return post, nil
+}
+
+// File is an attachment to upload.
+type File struct {
+	Name    string
+	Content io.Reader
+}
+
+// UploadAttachments attaches the files to the post. The form is built in
+// memory; uploads are not retried, as a retry would attach the files twice.
+func (c *Client) UploadAttachments(ctx context.Context, postID uint64, files ...File) ([]*models.Attachment, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for _, file := range files {
+		part, err := form.CreateFormFile("file", file.Name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.Copy(part, file.Content); err != nil {
+			return nil, err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	attachments := make([]*models.Attachment, 0, len(files))
+	_, err := c.do(ctx, &request{
+		method: http.MethodPost,
+		path:   "/post/" + strconv.FormatUint(postID, 10) + "/attachments",
+		header: http.Header{"Content-Type": {form.FormDataContentType()}},
+		raw:    body.Bytes(),
+		out:    &attachments,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// GetAttachment downloads the attachment contents; the caller closes them.
+func (c *Client) GetAttachment(ctx context.Context, id uint64) (io.ReadCloser, error) {
+	resp, err := c.open(ctx, "/attachment/"+strconv.FormatUint(id, 10), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
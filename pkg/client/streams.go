@@ -0,0 +1,156 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+const headerLastEventID = "Last-Event-ID"
+
+// PostStream reads the posts pushed by ThreadStream.
+type PostStream struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	lastID uint64
+}
+
+// ThreadStream subscribes to the posts created in the thread. With a
+// non-zero lastID the stream starts with the posts created after that one,
+// so a dropped stream resumes from PostStream.LastID without gaps.
+func (c *Client) ThreadStream(ctx context.Context, slugOrID string, lastID uint64) (*PostStream, error) {
+	var header http.Header
+	if lastID != 0 {
+		header = http.Header{headerLastEventID: {strconv.FormatUint(lastID, 10)}}
+	}
+
+	resp, err := c.open(ctx, "/thread/"+segment(slugOrID)+"/stream", nil, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostStream{body: resp.Body, reader: bufio.NewReader(resp.Body), lastID: lastID}, nil
+}
+
+// Next blocks until the next post arrives. It returns io.EOF when the server
+// ends the stream.
+func (s *PostStream) Next() (*models.Post, error) {
+	var event, id string
+	var data strings.Builder
+
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if event != "post" || data.Len() == 0 {
+				event, id = "", ""
+				data.Reset()
+				continue
+			}
+
+			post := &models.Post{}
+			if err = json.Unmarshal([]byte(data.String()), post); err != nil {
+				return nil, err
+			}
+			if lastID, err := strconv.ParseUint(id, 10, 64); err == nil {
+				s.lastID = lastID
+			}
+			return post, nil
+		}
+
+		// Lines starting with a colon are heartbeats.
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "id":
+			id = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		}
+	}
+}
+
+// LastID is the id of the last post read, to resume the stream from.
+func (s *PostStream) LastID() uint64 {
+	return s.lastID
+}
+
+func (s *PostStream) Close() error {
+	return s.body.Close()
+}
+
+// ActivityStream reads the activity pushed by ForumActivity.
+type ActivityStream struct {
+	conn *websocket.Conn
+}
+
+// ForumActivity subscribes to new threads, new posts, votes and thread edits
+// of the forum; types limits it to the listed activity types.
+func (c *Client) ForumActivity(ctx context.Context, slug string, types ...string) (*ActivityStream, error) {
+	target, err := url.Parse(c.baseURL + "/forum/" + segment(slug) + "/ws")
+	if err != nil {
+		return nil, err
+	}
+	if target.Scheme == "https" {
+		target.Scheme = "wss"
+	} else {
+		target.Scheme = "ws"
+	}
+	if len(types) > 0 {
+		target.RawQuery = url.Values{"types": {strings.Join(types, ",")}}.Encode()
+	}
+
+	header := http.Header{}
+	if c.token != "" {
+		header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, target.String(), header)
+	if err == websocket.ErrBadHandshake && resp != nil {
+		defer resp.Body.Close()
+		data, readErr := io.ReadAll(resp.Body)
+		if readErr != nil {
+			return nil, readErr
+		}
+		return nil, decodeError(resp.StatusCode, resp.Header, data)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &ActivityStream{conn: conn}, nil
+}
+
+// Next blocks until the next activity arrives. Data is a *json.RawMessage
+// to decode by Type.
+func (s *ActivityStream) Next() (*models.ForumActivity, error) {
+	data := json.RawMessage{}
+	activity := &models.ForumActivity{Data: &data}
+
+	if err := s.conn.ReadJSON(activity); err != nil {
+		return nil, err
+	}
+
+	return activity, nil
+}
+
+func (s *ActivityStream) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+func (c *Client) Status(ctx context.Context) (*models.System, error) {
+	status := &models.System{}
+
+	_, err := c.do(ctx, &request{method: http.MethodGet, path: "/service/status", out: status})
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// Clear deletes all data through the admin API under /api/admin, so the
+// client needs the admin token or the token of a site admin.
+func (c *Client) Clear(ctx context.Context) error {
+	_, err := c.do(ctx, &request{method: http.MethodPost, path: "/admin/clear", repeatable: true})
+	return err
+}
+
+// Events walks the change feed from the event after the given position. limit
+// is the page size; the server's default is used when it is zero. The
+// iteration ends at the newest event; a new one picks up from the position of
+// the last event seen.
+func (c *Client) Events(ctx context.Context, after uint64, limit int64) *Iterator[*models.Event] {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	return newIterator(ctx, func(ctx context.Context) ([]*models.Event, bool, error) {
+		values := url.Values{}
+		values.Set("after", strconv.FormatUint(after, 10))
+		values.Set("limit", strconv.FormatInt(limit, 10))
+
+		events := make([]*models.Event, 0)
+		if _, err := c.do(ctx, &request{method: http.MethodGet, path: "/events", query: values, out: &events}); err != nil {
+			return nil, false, err
+		}
+
+		if len(events) > 0 {
+			after = events[len(events)-1].ID
+		}
+		return events, int64(len(events)) < limit, nil
+	})
+}
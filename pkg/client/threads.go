@@ -0,0 +1,224 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+// GetThread follows the redirect from a thread's old slug. It fills in
+// Version for UpdateThread.
+func (c *Client) GetThread(ctx context.Context, slugOrID string) (*models.ThreadResponse, error) {
+	thread := &models.ThreadResponse{}
+
+	res, err := c.do(ctx, &request{method: http.MethodGet, path: "/thread/" + segment(slugOrID) + "/details", out: thread})
+	if err != nil {
+		return nil, err
+	}
+	thread.Version = res.version()
+
+	return thread, nil
+}
+
+// UpdateThread changes the fields present in update. A non-zero version
+// makes the update fail with ErrPreconditionFailed if the thread changed since.
+func (c *Client) UpdateThread(ctx context.Context, slugOrID string, update *models.ThreadUpdate, version uint64) (*models.ThreadResponse, error) {
+	thread := &models.ThreadResponse{}
+
+	res, err := c.do(ctx, &request{
+		method: http.MethodPost,
+		path:   "/thread/" + segment(slugOrID) + "/details",
+		header: ifMatch(version),
+		body:   update,
+		out:    thread,
+	})
+	if err != nil {
+		return nil, err
+	}
+	thread.Version = res.version()
+
+	return thread, nil
+}
+
+func (c *Client) ChangeThreadSlug(ctx context.Context, slugOrID, slug string) (*models.ThreadResponse, error) {
+	thread := &models.ThreadResponse{}
+
+	_, err := c.do(ctx, &request{
+		method: http.MethodPost,
+		path:   "/thread/" + segment(slugOrID) + "/slug",
+		body:   &models.ThreadSlug{Slug: slug},
+		out:    thread,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return thread, nil
+}
+
+func (c *Client) ModerateThread(ctx context.Context, slugOrID string, moderation *models.ThreadModeration) (*models.ThreadResponse, error) {
+	thread := &models.ThreadResponse{}
+
+	res, err := c.do(ctx, &request{
+		method:     http.MethodPost,
+		path:       "/thread/" + segment(slugOrID) + "/moderate",
+		body:       moderation,
+		repeatable: true,
+		out:        thread,
+	})
+	if err != nil {
+		return nil, err
+	}
+	thread.Version = res.version()
+
+	return thread, nil
+}
+
+func (c *Client) GetThreadHistory(ctx context.Context, slugOrID string) ([]*models.ThreadRevision, error) {
+	revisions := make([]*models.ThreadRevision, 0)
+
+	_, err := c.do(ctx, &request{method: http.MethodGet, path: "/thread/" + segment(slugOrID) + "/history", out: &revisions})
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// Vote replaces the user's earlier vote in the thread, if any.
+func (c *Client) Vote(ctx context.Context, slugOrID string, vote *models.Vote) (*models.ThreadResponse, error) {
+	thread := &models.ThreadResponse{}
+
+	_, err := c.do(ctx, &request{
+		method:     http.MethodPost,
+		path:       "/thread/" + segment(slugOrID) + "/vote",
+		body:       vote,
+		idempotent: true,
+		out:        thread,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return thread, nil
+}
+
+// CreatePosts creates all of the posts or none of them.
+func (c *Client) CreatePosts(ctx context.Context, slugOrID string, posts []*models.Post) ([]*models.Post, error) {
+	created := make([]*models.Post, 0, len(posts))
+
+	_, err := c.do(ctx, &request{
+		method:     http.MethodPost,
+		path:       "/thread/" + segment(slugOrID) + "/create",
+		body:       posts,
+		idempotent: true,
+		out:        &created,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// Post sorts of ThreadPosts.
+const (
+	SortFlat       = "flat"
+	SortTree       = "tree"
+	SortParentTree = "parent_tree"
+)
+
+type PostsQuery struct {
+	// Limit is the page size; the server's default is used when it is zero.
+	// With SortParentTree it counts root posts, each with all of its replies.
+	Limit int64
+	// Since starts the list after that post.
+	Since uint64
+	// Sort is SortFlat unless set.
+	Sort string
+	Desc bool
+	// HTML adds the rendered messages.
+	HTML bool
+}
+
+// ThreadPosts walks the posts of the thread in the order of query.Sort.
+func (c *Client) ThreadPosts(ctx context.Context, slugOrID string, query PostsQuery) *Iterator[*models.Post] {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	sort := query.Sort
+	if sort == "" {
+		sort = SortFlat
+	}
+	since := query.Since
+
+	return newIterator(ctx, func(ctx context.Context) ([]*models.Post, bool, error) {
+		values := url.Values{}
+		values.Set("limit", strconv.FormatInt(limit, 10))
+		values.Set("sort", sort)
+		values.Set("desc", strconv.FormatBool(query.Desc))
+		if since != 0 {
+			values.Set("since", strconv.FormatUint(since, 10))
+		}
+		if query.HTML {
+			values.Set("format", "html")
+		}
+
+		posts := make([]*models.Post, 0)
+		_, err := c.do(ctx, &request{method: http.MethodGet, path: "/thread/" + segment(slugOrID) + "/posts", query: values, out: &posts})
+		if err != nil {
+			return nil, false, err
+		}
+
+		if len(posts) == 0 {
+			return posts, true, nil
+		}
+		since = posts[len(posts)-1].ID
+
+		// A page of trees may hold fewer posts than the limit without being
+		// the last one, and hidden roots count toward the limit too, so only an
+		// empty page ends them.
+		if sort == SortParentTree {
+			return posts, false, nil
+		}
+		return posts, int64(len(posts)) < limit, nil
+	})
+}
+
+// Subscribe reports whether the user was not subscribed to the thread before.
+func (c *Client) Subscribe(ctx context.Context, slugOrID, nickname string) (*models.ThreadSubscription, bool, error) {
+	subscription := &models.ThreadSubscription{}
+
+	res, err := c.do(ctx, &request{
+		method:     http.MethodPost,
+		path:       "/thread/" + segment(slugOrID) + "/subscribe",
+		body:       &models.ThreadSubscription{Nickname: nickname},
+		repeatable: true,
+		out:        subscription,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return subscription, res.status == http.StatusCreated, nil
+}
+
+func (c *Client) Unsubscribe(ctx context.Context, slugOrID, nickname string) (*models.ThreadSubscription, error) {
+	subscription := &models.ThreadSubscription{}
+
+	_, err := c.do(ctx, &request{
+		method: http.MethodDelete,
+		path:   "/thread/" + segment(slugOrID) + "/subscribe",
+		query:  url.Values{"nickname": {nickname}},
+		out:    subscription,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"technopark-dbms-forum/pkg/models"
+)
+
+// CreateUser returns the created user, or every user holding the nickname or
+// the email together with ErrAlreadyExist.
+func (c *Client) CreateUser(ctx context.Context, user *models.User) ([]*models.User, error) {
+	created := &models.User{}
+	conflicts := make([]*models.User, 0)
+
+	_, err := c.do(ctx, &request{
+		method:     http.MethodPost,
+		path:       "/user/" + segment(user.Nickname) + "/create",
+		body:       user,
+		idempotent: true,
+		out:        created,
+		conflict:   &conflicts,
+	})
+	if err == ErrAlreadyExist {
+		return conflicts, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return []*models.User{created}, nil
+}
+
+// GetUser fills in Version for UpdateUser.
+func (c *Client) GetUser(ctx context.Context, nickname string) (*models.User, error) {
+	user := &models.User{}
+
+	res, err := c.do(ctx, &request{method: http.MethodGet, path: "/user/" + segment(nickname) + "/profile", out: user})
+	if err != nil {
+		return nil, err
+	}
+	user.Version = res.version()
+
+	return user, nil
+}
+
+// UpdateUser changes the fields present in update. A non-zero version makes
+// the update fail with ErrPreconditionFailed if the profile changed since.
+func (c *Client) UpdateUser(ctx context.Context, nickname string, update *models.UserUpdate, version uint64) (*models.User, error) {
+	user := &models.User{}
+
+	res, err := c.do(ctx, &request{
+		method: http.MethodPost,
+		path:   "/user/" + segment(nickname) + "/profile",
+		header: ifMatch(version),
+		body:   update,
+		out:    user,
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.Version = res.version()
+
+	return user, nil
+}
+
+// Login returns a token for WithToken or As.
+func (c *Client) Login(ctx context.Context, nickname, password string) (*models.Token, error) {
+	token := &models.Token{}
+
+	_, err := c.do(ctx, &request{
+		method:     http.MethodPost,
+		path:       "/auth/login",
+		body:       &models.Credentials{Nickname: nickname, Password: password},
+		repeatable: true,
+		out:        token,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (c *Client) SetPassword(ctx context.Context, nickname string, change *models.PasswordChange) error {
+	_, err := c.do(ctx, &request{
+		method: http.MethodPost,
+		path:   "/user/" + segment(nickname) + "/password",
+		body:   change,
+	})
+	return err
+}
+
+// NotificationsQuery filters the notifications of a user, newest first.
+type NotificationsQuery struct {
+	// Limit is the page size; the server's default is used when it is zero.
+	Limit  int64
+	Unread bool
+}
+
+func (q NotificationsQuery) values(since uint64) url.Values {
+	values := url.Values{}
+	if q.Limit > 0 {
+		values.Set("limit", strconv.FormatInt(q.Limit, 10))
+	}
+	if since != 0 {
+		values.Set("since", strconv.FormatUint(since, 10))
+	}
+	if q.Unread {
+		values.Set("unread", "true")
+	}
+
+	return values
+}
+
+func (c *Client) Notifications(ctx context.Context, nickname string, query NotificationsQuery) *Iterator[*models.Notification] {
+	var since uint64
+
+	return newIterator(ctx, func(ctx context.Context) ([]*models.Notification, bool, error) {
+		page := &models.NotificationPage{}
+
+		_, err := c.do(ctx, &request{
+			method: http.MethodGet,
+			path:   "/user/" + segment(nickname) + "/notifications",
+			query:  query.values(since),
+			out:    page,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		since = page.Next
+		return page.Notifications, page.Next == 0, nil
+	})
+}
+
+// MarkNotificationsRead returns the first page of notifications with the
+// new unread count.
+func (c *Client) MarkNotificationsRead(ctx context.Context, nickname string, ids []uint64) (*models.NotificationPage, error) {
+	page := &models.NotificationPage{}
+
+	_, err := c.do(ctx, &request{
+		method:     http.MethodPost,
+		path:       "/user/" + segment(nickname) + "/notifications/read",
+		body:       &models.NotificationRead{IDs: ids},
+		repeatable: true,
+		out:        page,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// Feed walks the threads of the forums the user follows.
+func (c *Client) Feed(ctx context.Context, nickname string, query ThreadsQuery) *Iterator[*models.ThreadResponse] {
+	return c.threads(ctx, "/user/"+segment(nickname)+"/feed", query)
+}
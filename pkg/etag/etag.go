@@ -0,0 +1,104 @@
+package etag
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderETag        = "ETag"
+	HeaderIfMatch     = "If-Match"
+	HeaderIfNoneMatch = "If-None-Match"
+)
+
+// Make builds a strong ETag from row versions. The first version belongs to
+// the resource itself, the rest to related resources embedded in the response.
+func Make(versions ...uint64) string {
+	parts := make([]string, 0, len(versions))
+	for _, version := range versions {
+		parts = append(parts, strconv.FormatUint(version, 10))
+	}
+
+	return `"` + strings.Join(parts, "-") + `"`
+}
+
+// Variant tags another representation of the same versions, such as rendered
+// HTML, so that it is not confused with the default one. Versions still
+// accepts the result.
+func Variant(tag, variant string) string {
+	return strings.TrimSuffix(tag, `"`) + "-" + variant + `"`
+}
+
+func split(header string) []string {
+	tags := make([]string, 0)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// Versions parses an If-Match header into the resource versions it accepts.
+// Nil means the header is missing or "*", so any version is accepted. If-Match
+// uses the strong comparison, so weak tags never match and a header made of
+// weak tags only accepts no version at all.
+func Versions(header string) ([]int64, bool) {
+	tags := split(header)
+	if len(tags) == 0 {
+		return nil, true
+	}
+
+	versions := make([]int64, 0, len(tags))
+	for _, tag := range tags {
+		if tag == "*" {
+			return nil, true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+
+		tag = strings.Trim(tag, `"`)
+		if i := strings.IndexByte(tag, '-'); i >= 0 {
+			tag = tag[:i]
+		}
+
+		version, err := strconv.ParseInt(tag, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, true
+}
+
+// NoneMatch reports whether the If-None-Match header matches tag, using the
+// weak comparison.
+func NoneMatch(header, tag string) bool {
+	for _, t := range split(header) {
+		if t == "*" || strings.TrimPrefix(t, "W/") == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// JSON writes body with the given ETag, or 304 if the client already has it.
+func JSON(c echo.Context, code int, tag string, body interface{}) error {
+	c.Response().Header().Set(HeaderETag, tag)
+
+	method := c.Request().Method
+	if code == http.StatusOK && (method == http.MethodGet || method == http.MethodHead) &&
+		NoneMatch(c.Request().Header.Get(HeaderIfNoneMatch), tag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.JSON(code, body)
+}
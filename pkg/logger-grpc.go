@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// logCall logs a gRPC call like Middleware logs a request: failed calls at
+// error level, the rest at debug level.
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	stop := time.Now()
+
+	remoteIP := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteIP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(remoteIP); err == nil {
+			remoteIP = host
+		}
+	}
+	userAgent := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			userAgent = values[0]
+		}
+	}
+
+	fields := logrus.Fields{
+		"remote_ip":     remoteIP,
+		"method":        method,
+		"user_agent":    userAgent,
+		"status":        status.Code(err).String(),
+		"latency":       strconv.FormatInt(stop.Sub(start).Nanoseconds()/1000, 10),
+		"latency_human": stop.Sub(start).String(),
+	}
+
+	if err != nil {
+		fields["error"] = err.Error()
+		GetInstance().Logrus.WithFields(fields).Error("ERROR REQUEST")
+		return
+	}
+	GetInstance().Logrus.WithFields(fields).Debug("REQUEST")
+}
+
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, info.FullMethod, start, err)
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor logs a streaming call once it ends.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		logCall(stream.Context(), info.FullMethod, start, err)
+
+		return err
+	}
+}
@@ -0,0 +1,244 @@
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LinkAttributes are added to every link that leaves the forum.
+const LinkAttributes = ` rel="nofollow noopener noreferrer" target="_blank"`
+
+var (
+	backRefLine = regexp.MustCompile(`^>>\d+`)
+	orderedItem = regexp.MustCompile(`^\d+[.)] `)
+)
+
+// RefFunc resolves a >>postID back-reference to a link target. References it
+// rejects are rendered as plain text.
+type RefFunc func(id uint64) (string, bool)
+
+// Render converts a Markdown message to HTML. Raw HTML in the source is
+// escaped rather than passed through, so the output is safe to embed as is.
+// Paragraphs, line breaks, quote blocks, lists, fenced and inline code,
+// emphasis, links and >>postID back-references are supported.
+func Render(src string, ref RefFunc) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(src, "\n"), ref)
+
+	return b.String()
+}
+
+// References returns the post ids mentioned as >>postID in the message.
+func References(src string) []uint64 {
+	ids := make([]uint64, 0)
+	for i := 0; i+2 < len(src); i++ {
+		if src[i] != '>' || src[i+1] != '>' {
+			continue
+		}
+		if id, n := parseID(src[i+2:]); n > 0 {
+			ids = append(ids, id)
+			i += n + 1
+		}
+	}
+
+	return ids
+}
+
+func isQuote(line string) bool {
+	return strings.HasPrefix(line, ">") && !backRefLine.MatchString(line)
+}
+
+func listKind(line string) string {
+	if strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "+ ") {
+		return "ul"
+	}
+	if orderedItem.MatchString(line) {
+		return "ol"
+	}
+
+	return ""
+}
+
+func renderBlocks(b *strings.Builder, lines []string, ref RefFunc) {
+	paragraph := make([]string, 0)
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		b.WriteString("<p>")
+		for i, line := range paragraph {
+			if i > 0 {
+				b.WriteString("<br>\n")
+			}
+			renderInline(b, line, ref)
+		}
+		b.WriteString("</p>\n")
+		paragraph = paragraph[:0]
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+
+		switch {
+		case strings.HasPrefix(strings.TrimSpace(line), "```"):
+			flush()
+			b.WriteString("<pre><code>")
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				b.WriteString(html.EscapeString(lines[i]))
+				b.WriteString("\n")
+			}
+			b.WriteString("</code></pre>\n")
+		case strings.TrimSpace(line) == "":
+			flush()
+		case isQuote(line):
+			flush()
+			quoted := make([]string, 0)
+			for ; i < len(lines) && isQuote(lines[i]); i++ {
+				quoted = append(quoted, strings.TrimPrefix(lines[i][1:], " "))
+			}
+			i--
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quoted, ref)
+			b.WriteString("</blockquote>\n")
+		case listKind(line) != "":
+			flush()
+			kind := listKind(line)
+			b.WriteString("<" + kind + ">\n")
+			for ; i < len(lines) && listKind(lines[i]) == kind; i++ {
+				item := lines[i][strings.IndexByte(lines[i], ' ')+1:]
+				b.WriteString("<li>")
+				renderInline(b, strings.TrimSpace(item), ref)
+				b.WriteString("</li>\n")
+			}
+			i--
+			b.WriteString("</" + kind + ">\n")
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	flush()
+}
+
+func parseID(s string) (uint64, int) {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	if n == 0 {
+		return 0, 0
+	}
+
+	id, err := strconv.ParseUint(s[:n], 10, 64)
+	if err != nil {
+		return 0, 0
+	}
+
+	return id, n
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// safeURL reports whether a link target may be emitted. Only absolute web
+// and mail links are allowed, which rules out javascript: and data: URLs.
+func safeURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return true
+	}
+
+	return false
+}
+
+func writeLink(b *strings.Builder, href, text string) {
+	b.WriteString(`<a href="`)
+	b.WriteString(html.EscapeString(href))
+	b.WriteString(`"`)
+	b.WriteString(LinkAttributes)
+	b.WriteString(">")
+	b.WriteString(text)
+	b.WriteString("</a>")
+}
+
+func renderInline(b *strings.Builder, s string, ref RefFunc) {
+	for i := 0; i < len(s); {
+		rest := s[i:]
+
+		switch {
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				b.WriteString("<code>")
+				b.WriteString(html.EscapeString(rest[1 : end+1]))
+				b.WriteString("</code>")
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(rest, "**"):
+			if end := strings.Index(rest[2:], "**"); end > 0 {
+				b.WriteString("<strong>")
+				renderInline(b, rest[2:end+2], ref)
+				b.WriteString("</strong>")
+				i += end + 4
+				continue
+			}
+		case rest[0] == '*' || rest[0] == '_' && (i == 0 || !isWordByte(s[i-1])):
+			end := strings.IndexByte(rest[1:], rest[0])
+			if end > 0 && (rest[0] == '*' || end+2 == len(rest) || !isWordByte(rest[end+2])) {
+				b.WriteString("<em>")
+				renderInline(b, rest[1:end+1], ref)
+				b.WriteString("</em>")
+				i += end + 2
+				continue
+			}
+		case rest[0] == '[':
+			if mid := strings.Index(rest, "]("); mid > 0 {
+				if end := strings.IndexByte(rest[mid:], ')'); end > 0 && safeURL(rest[mid+2:mid+end]) {
+					var text strings.Builder
+					renderInline(&text, rest[1:mid], nil)
+					writeLink(b, rest[mid+2:mid+end], text.String())
+					i += mid + end + 1
+					continue
+				}
+			}
+		case strings.HasPrefix(rest, ">>") && ref != nil:
+			if id, n := parseID(rest[2:]); n > 0 {
+				if href, ok := ref(id); ok {
+					b.WriteString(`<a href="`)
+					b.WriteString(html.EscapeString(href))
+					b.WriteString(`" class="backref">&gt;&gt;`)
+					b.WriteString(rest[2 : n+2])
+					b.WriteString("</a>")
+					i += n + 2
+					continue
+				}
+			}
+		case (strings.HasPrefix(rest, "http://") || strings.HasPrefix(rest, "https://")) && (i == 0 || !isWordByte(s[i-1])):
+			end := strings.IndexAny(rest, " \t<>\"")
+			if end < 0 {
+				end = len(rest)
+			}
+			link := strings.TrimRight(rest[:end], ".,;:!?)'")
+			if safeURL(link) {
+				writeLink(b, link, html.EscapeString(link))
+				i += len(link)
+				continue
+			}
+		}
+
+		b.WriteString(html.EscapeString(rest[:1]))
+		i++
+	}
+}
@@ -0,0 +1,71 @@
+package markdown_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"technopark-dbms-forum/pkg/markdown"
+)
+
+// ref links every post but 9, which stands for one in another thread.
+func ref(id uint64) (string, bool) {
+	return fmt.Sprintf("/post/%d", id), id != 9
+}
+
+func link(href, text string) string {
+	return `<a href="` + href + `"` + markdown.LinkAttributes + ">" + text + "</a>"
+}
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"paragraphs", "line one\nline two\r\n\r\nnext", "<p>line one<br>\nline two</p>\n<p>next</p>\n"},
+		{"emphasis", "**a** and *b*", "<p><strong>a</strong> and <em>b</em></p>\n"},
+		{"underscores inside words", "snake_case_name", "<p>snake_case_name</p>\n"},
+		{"lists", "- one\n- two\n\n1. first", "<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n<ol>\n<li>first</li>\n</ol>\n"},
+		{"quote", "> aye", "<blockquote>\n<p>aye</p>\n</blockquote>\n"},
+		{"link", "[home](https://example.com)", "<p>" + link("https://example.com", "home") + "</p>\n"},
+		{"mail link", "[mail](mailto:a@example.com)", "<p>" + link("mailto:a@example.com", "mail") + "</p>\n"},
+		{"bare link", "visit https://example.com.", "<p>visit " + link("https://example.com", "https://example.com") + ".</p>\n"},
+		{"back-reference", ">>5 and >>9", `<p><a href="/post/5" class="backref">&gt;&gt;5</a> and &gt;&gt;9</p>` + "\n"},
+
+		{"raw html", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"html in a quote", "> <img src=x onerror=alert(1)>", "<blockquote>\n<p>&lt;img src=x onerror=alert(1)&gt;</p>\n</blockquote>\n"},
+		{"html in code", "`<i>`", "<p><code>&lt;i&gt;</code></p>\n"},
+		{"html in a code block", "```\n<script>\n```", "<pre><code>&lt;script&gt;\n</code></pre>\n"},
+		{"html in link text", "[<b>x</b>](https://example.com)", "<p>" + link("https://example.com", "&lt;b&gt;x&lt;/b&gt;") + "</p>\n"},
+		{"javascript link", "[x](javascript:alert(1))", "<p>[x](javascript:alert(1))</p>\n"},
+		{"mixed case javascript link", "[x](JavaScript:alert(1))", "<p>[x](JavaScript:alert(1))</p>\n"},
+		{"data link", "[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>[x](data:text/html;base64,PHNjcmlwdD4=)</p>\n"},
+		{"scheme-relative link", "[x](//example.com)", "<p>[x](//example.com)</p>\n"},
+		{"quote in a link target", `[x](https://example.com/?q="><script>)`, "<p>" + link("https://example.com/?q=&#34;&gt;&lt;script&gt;", "x") + "</p>\n"},
+		{"quote after a bare link", `https://example.com/"onmouseover=alert(1)`, "<p>" + link("https://example.com/", "https://example.com/") + "&#34;onmouseover=alert(1)</p>\n"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := markdown.Render(c.src, ref); got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRenderWithoutRefs(t *testing.T) {
+	if got, want := markdown.Render(">>5", nil), "<p>&gt;&gt;5</p>\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	if got, want := markdown.References(">>1 and >>22, >>x"), []uint64{1, 22}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := markdown.References("no refs > here"); len(got) != 0 {
+		t.Errorf("got %v, want none", got)
+	}
+}
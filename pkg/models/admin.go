@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type AuditEntry struct {
+	ID      uint64    `json:"id" db:"id"`
+	Actor   string    `json:"actor" db:"actor"`
+	Method  string    `json:"method" db:"method"`
+	Path    string    `json:"path" db:"path"`
+	Status  int       `json:"status" db:"status"`
+	Created time.Time `json:"created" db:"created"`
+}
+
+type AdminStats struct {
+	Users           uint64 `json:"users" db:"users"`
+	Forums          uint64 `json:"forums" db:"forums"`
+	Threads         uint64 `json:"threads" db:"threads"`
+	Posts           uint64 `json:"posts" db:"posts"`
+	Votes           uint64 `json:"votes" db:"votes"`
+	Admins          uint64 `json:"admins" db:"admins"`
+	Moderators      uint64 `json:"moderators" db:"moderators"`
+	Notifications   uint64 `json:"notifications" db:"notifications"`
+	PendingEvents   uint64 `json:"pendingEvents" db:"pending_events"`
+	IdempotencyKeys uint64 `json:"idempotencyKeys" db:"idempotency_keys"`
+	DatabaseSize    uint64 `json:"databaseSize" db:"database_size"`
+}
+
+type ForumPurge struct {
+	Forum   string `json:"forum" db:"slug"`
+	Threads uint64 `json:"threads" db:"threads"`
+	Posts   uint64 `json:"posts" db:"posts"`
+}
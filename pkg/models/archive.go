@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// ArchiveFormat names the layout of forum export archives; importers reject
+// archives with any other value.
+const ArchiveFormat = "forum-archive/1"
+
+// Archive sections, in the order they appear in an archive and are imported.
+const (
+	SectionUsers     = "users"
+	SectionForum     = "forum"
+	SectionThreads   = "threads"
+	SectionPosts     = "posts"
+	SectionVotes     = "votes"
+	SectionUserForum = "user_forum"
+)
+
+type ArchiveManifest struct {
+	Format   string    `json:"format"`
+	Forum    string    `json:"forum"`
+	Exported time.Time `json:"exported"`
+}
+
+type ArchivePost struct {
+	ID       uint64  `json:"id" db:"id"`
+	Author   string  `json:"author" db:"author_nickname"`
+	Created  string  `json:"created" db:"created"`
+	Message  string  `json:"message" db:"message"`
+	Parent   uint64  `json:"parent" db:"parent_id"`
+	Thread   uint64  `json:"thread" db:"thread_id"`
+	Path     []int64 `json:"path" db:"-"`
+	IsEdited bool    `json:"isEdited" db:"is_edited"`
+	IsHidden bool    `json:"isHidden" db:"is_hidden"`
+}
+
+type ArchiveVote struct {
+	Nickname string `json:"nickname" db:"nickname"`
+	Thread   uint64 `json:"thread" db:"thread_id"`
+	Voice    int64  `json:"voice" db:"voice"`
+}
+
+type ArchiveUserForum struct {
+	Nickname string `json:"nickname" db:"nickname"`
+}
+
+// ArchiveImport counts the rows an import created. Users that already
+// existed in the target instance are not counted.
+type ArchiveImport struct {
+	Forum   string `json:"forum"`
+	Users   int64  `json:"users"`
+	Threads int64  `json:"threads"`
+	Posts   int64  `json:"posts"`
+	Votes   int64  `json:"votes"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type Attachment struct {
+	ID       uint64    `json:"id" db:"id"`
+	Post     uint64    `json:"post" db:"post_id"`
+	Uploader string    `json:"uploader" db:"uploader"`
+	Filename string    `json:"filename" db:"filename"`
+	MimeType string    `json:"mimeType" db:"mime_type"`
+	Size     int64     `json:"size" db:"size"`
+	Hash     string    `json:"hash" db:"hash"`
+	Created  time.Time `json:"created" db:"created"`
+	URL      string    `json:"url" db:"-"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type Credentials struct {
+	Nickname string `json:"nickname"`
+	Password string `json:"password"`
+}
+
+type PasswordChange struct {
+	Password    string `json:"password"`
+	OldPassword string `json:"old_password"`
+}
+
+type Token struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
@@ -0,0 +1,29 @@
+package models
+
+// Sides of an exchange a contract violation is found on.
+const (
+	ContractRequest  = "request"
+	ContractResponse = "response"
+	// ContractRoute marks a served route the spec does not document.
+	ContractRoute = "route"
+)
+
+// ContractViolation is one way a request or a response broke api/swagger.yml.
+// Status is the status the request was answered with. Path is the spec path,
+// or the echo route when the spec does not document it.
+type ContractViolation struct {
+	Operation string `json:"operation"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	In        string `json:"in"`
+	Status    int    `json:"status,omitempty"`
+	Message   string `json:"message"`
+}
+
+func (v *ContractViolation) String() string {
+	if v.Operation == "" {
+		return v.Method + " " + v.Path + " " + v.In + ": " + v.Message
+	}
+
+	return v.Method + " " + v.Path + " (" + v.Operation + ") " + v.In + ": " + v.Message
+}
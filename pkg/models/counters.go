@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Denormalized counters checked by the reconciler.
+const (
+	CounterForumPosts   = "forums.posts"
+	CounterForumThreads = "forums.threads"
+	CounterThreadVotes  = "threads.votes"
+	CounterUserForum    = "user_forum"
+)
+
+// ForumCounts compares a forum's stored counters with its source rows.
+// Missing members have threads or posts in the forum but no user_forum row,
+// stale members have a row and nothing left to show for it.
+type ForumCounts struct {
+	Slug           string `db:"slug"`
+	Posts          int64  `db:"posts"`
+	Threads        int64  `db:"threads"`
+	ActualPosts    int64  `db:"actual_posts"`
+	ActualThreads  int64  `db:"actual_threads"`
+	Members        int64  `db:"members"`
+	MissingMembers int64  `db:"missing_members"`
+	StaleMembers   int64  `db:"stale_members"`
+}
+
+type ThreadVotes struct {
+	ID          uint64 `db:"id"`
+	Votes       int64  `db:"votes"`
+	ActualVotes int64  `db:"actual_votes"`
+}
+
+// ForumMember is one user_forum row.
+type ForumMember struct {
+	Nickname string `db:"nickname"`
+	Forum    string `db:"forum_slug"`
+}
+
+// CounterDrift is one stored value that disagrees with its source rows. For
+// user_forum the values are the forum's member count before and after the
+// rebuild.
+type CounterDrift struct {
+	Counter string `json:"counter"`
+	Key     string `json:"key"`
+	Stored  int64  `json:"stored"`
+	Actual  int64  `json:"actual"`
+	Missing int64  `json:"missing,omitempty"`
+	Stale   int64  `json:"stale,omitempty"`
+}
+
+type CounterSummary struct {
+	Counter string `json:"counter"`
+	Checked int64  `json:"checked"`
+	Drifted int64  `json:"drifted"`
+	// Drift is the sum of the absolute differences.
+	Drift int64 `json:"drift"`
+	Fixed int64 `json:"fixed"`
+}
+
+// CounterReport describes one reconciliation run. Drift lists the first
+// drifted values only; the summaries count all of them.
+type CounterReport struct {
+	Started   time.Time         `json:"started"`
+	Finished  time.Time         `json:"finished"`
+	Fix       bool              `json:"fix"`
+	Counters  []*CounterSummary `json:"counters"`
+	Drift     []CounterDrift    `json:"drift"`
+	Truncated bool              `json:"truncated,omitempty"`
+}
+
+// ForumFix counts the forums whose posts or threads were rewritten and the
+// user_forum rows inserted or deleted.
+type ForumFix struct {
+	Posts   int64 `db:"posts"`
+	Threads int64 `db:"threads"`
+	Members int64 `db:"-"`
+}
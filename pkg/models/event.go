@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventUserCreated       = "user.created"
+	EventUserUpdated       = "user.updated"
+	EventForumCreated      = "forum.created"
+	EventThreadCreated     = "thread.created"
+	EventThreadUpdated     = "thread.updated"
+	EventThreadSlugChanged = "thread.slug_changed"
+	EventThreadVoted       = "thread.voted"
+	EventPostCreated       = "post.created"
+	EventPostUpdated       = "post.updated"
+)
+
+type Event struct {
+	ID      uint64          `json:"id" db:"position"`
+	Type    string          `json:"type" db:"type"`
+	Payload json.RawMessage `json:"payload" db:"payload"`
+	Created time.Time       `json:"created" db:"created"`
+}
+
+type VoteEvent struct {
+	Thread   uint64 `json:"thread"`
+	Nickname string `json:"nickname"`
+	Voice    int64  `json:"voice"`
+	Votes    int64  `json:"votes"`
+}
+
+// PostEvent is a created post with its position in the event feed. Positions
+// follow commit order, unlike post ids, so they are safe to resume from.
+type PostEvent struct {
+	Position uint64 `db:"position"`
+	Post
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+const (
+	FilterReject  = "reject"
+	FilterFlag    = "flag"
+	FilterRewrite = "rewrite"
+)
+
+// Content is a post or thread as seen by the content filters. Filters that
+// rewrite change Title and Message in place.
+type Content struct {
+	Author  string
+	Forum   string
+	Title   string
+	Message string
+
+	// Fingerprint is set by the duplicate filter and recorded in the same
+	// transaction that writes the content.
+	Fingerprint []byte
+	// Previous holds the fingerprints of the author's earlier items in the
+	// same batch, which are not stored yet.
+	Previous [][]byte
+}
+
+type FilterVerdict struct {
+	Action string
+	Reason string
+}
+
+type ModerationItem struct {
+	ID      uint64    `json:"id" db:"id"`
+	Forum   string    `json:"forum" db:"forum_slug"`
+	Thread  uint64    `json:"thread" db:"thread_id"`
+	Post    uint64    `json:"post,omitempty" db:"post_id"`
+	Author  string    `json:"author" db:"author_nickname"`
+	Reasons []string  `json:"reasons" db:"-"`
+	Created time.Time `json:"created" db:"created"`
+}
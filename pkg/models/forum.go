@@ -12,4 +12,15 @@ type ForumResponse struct {
 	Slug    string `json:"slug" db:"slug"`
 	Threads uint64 `json:"threads" db:"threads"`
 	Posts   uint64 `json:"posts" db:"posts"`
+	Version uint64 `json:"-" db:"version"`
+}
+
+type ForumActivity struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+type ForumFollow struct {
+	Nickname string `json:"nickname" query:"nickname"`
+	Forum    string `json:"forum"`
 }
@@ -0,0 +1,9 @@
+package models
+
+// GraphQLRequest is a query to /api/graphql, sent as a JSON body or, for GET,
+// as query parameters with the variables JSON-encoded.
+type GraphQLRequest struct {
+	Query         string                 `json:"query" query:"query"`
+	OperationName string                 `json:"operationName" query:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "database/sql"
+
+type IdempotencyKey struct {
+	Principal   string        `db:"principal"`
+	Key         string        `db:"key"`
+	RequestHash []byte        `db:"request_hash"`
+	Status      sql.NullInt64 `db:"status"`
+	ContentType string        `db:"content_type"`
+	Location    string        `db:"location"`
+	ETag        string        `db:"etag"`
+	Body        []byte        `db:"body"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const (
+	LimitPost   = "post"
+	LimitThread = "thread"
+	LimitVote   = "vote"
+)
+
+type RateLimit struct {
+	Action    string  `json:"action" db:"action"`
+	PerMinute float64 `json:"perMinute" db:"per_minute"`
+	Burst     int     `json:"burst" db:"burst"`
+}
+
+type Ban struct {
+	ID       uint64    `json:"id" db:"id"`
+	Nickname string    `json:"nickname" db:"nickname"`
+	Forum    string    `json:"forum,omitempty" db:"forum_slug"`
+	Reason   string    `json:"reason" db:"reason"`
+	BannedBy string    `json:"bannedBy,omitempty" db:"banned_by"`
+	Expires  time.Time `json:"expires" db:"expires"`
+	Created  time.Time `json:"created" db:"created"`
+}
+
+type BanRequest struct {
+	Nickname string `json:"nickname"`
+	Duration string `json:"duration"`
+	Reason   string `json:"reason"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const (
+	NotificationMention = "mention"
+	NotificationReply   = "reply"
+	NotificationPost    = "post"
+	NotificationThread  = "thread"
+)
+
+type Notification struct {
+	ID      uint64    `json:"id" db:"id"`
+	Type    string    `json:"type" db:"type"`
+	Author  string    `json:"author" db:"author_nickname"`
+	Forum   string    `json:"forum" db:"forum_slug"`
+	Thread  uint64    `json:"thread" db:"thread_id"`
+	Post    uint64    `json:"post" db:"post_id"`
+	IsRead  bool      `json:"isRead" db:"is_read"`
+	Created time.Time `json:"created" db:"created"`
+}
+
+type NotificationPage struct {
+	Unread        uint64          `json:"unread"`
+	Notifications []*Notification `json:"notifications"`
+	Next          uint64          `json:"next,omitempty"`
+}
+
+type NotificationRead struct {
+	IDs []uint64 `json:"ids"`
+}
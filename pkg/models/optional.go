@@ -0,0 +1,38 @@
+package models
+
+import "encoding/json"
+
+// OptionalString tells a missing JSON field apart from an explicit null
+// and from an empty string, so partial updates can set any of them on purpose.
+type OptionalString struct {
+	Value   string
+	Present bool
+	Null    bool
+}
+
+func (o *OptionalString) UnmarshalJSON(data []byte) error {
+	o.Present = true
+	if string(data) == "null" {
+		o.Null = true
+		return nil
+	}
+
+	return json.Unmarshal(data, &o.Value)
+}
+
+func (o OptionalString) MarshalJSON() ([]byte, error) {
+	if !o.Present || o.Null {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(o.Value)
+}
+
+// Ptr returns nil when the field should be left untouched by an update.
+func (o OptionalString) Ptr() *string {
+	if !o.Present || o.Null {
+		return nil
+	}
+
+	return &o.Value
+}
@@ -6,9 +6,25 @@ type Post struct {
 	Created  string `json:"created" db:"created"`
 	Forum    string `json:"forum" db:"forum_slug"`
 	IsEdited bool   `json:"isEdited" db:"is_edited"`
+	IsHidden bool   `json:"isHidden,omitempty" db:"is_hidden"`
 	Message  string `json:"message" db:"message"`
 	Parent   uint64 `json:"parent" db:"parent_id"`
 	Thread   uint64 `json:"thread" db:"thread_id"`
+	Version  uint64 `json:"-" db:"version"`
+
+	// MessageHTML is the rendered message, filled in only for format=html reads.
+	MessageHTML string `json:"messageHtml,omitempty" db:"-"`
+
+	Attachments []*Attachment `json:"attachments,omitempty" db:"-"`
+
+	// Flags are the content filter reasons to queue the post for moderation.
+	Flags []string `json:"-" db:"-"`
+	// Fingerprint is the duplicate filter's hash of the message, if any.
+	Fingerprint []byte `json:"-" db:"-"`
+}
+
+type PostModeration struct {
+	Hidden *bool `json:"hidden"`
 }
 
 type FullPost struct {
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+const (
+	RoleAdmin     = "admin"
+	RoleOwner     = "owner"
+	RoleModerator = "moderator"
+
+	// ActorAnonymous acts for an anonymous request that the auth layer let
+	// through because authentication is open. No nickname can take it.
+	ActorAnonymous = "*"
+)
+
+type Moderator struct {
+	Nickname  string    `json:"nickname" db:"nickname"`
+	Forum     string    `json:"forum" db:"forum_slug"`
+	GrantedBy string    `json:"grantedBy,omitempty" db:"granted_by"`
+	Created   time.Time `json:"created" db:"created"`
+}
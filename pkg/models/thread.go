@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+type Thread struct {
+	ID      uint64    `json:"id"`
+	Author  string    `json:"author"`
+	Created time.Time `json:"created"`
+	Forum   string    `json:"forum"`
+	Message string    `json:"message"`
+	Slug    string    `json:"slug"`
+	Title   string    `json:"title"`
+
+	// Flags are the content filter reasons to queue the thread for moderation.
+	Flags []string `json:"-"`
+	// Fingerprint is the duplicate filter's hash of the thread, if any.
+	Fingerprint []byte `json:"-"`
+}
+
+type ThreadResponse struct {
+	ID      uint64    `json:"id" db:"id"`
+	Author  string    `json:"author" db:"author_nickname"`
+	Created time.Time `json:"created" db:"created"`
+	Forum   string    `json:"forum" db:"forum"`
+	Message string    `json:"message" db:"message"`
+	Slug    string    `json:"slug,omitempty" db:"slug"`
+	Title   string    `json:"title" db:"title"`
+	Votes   int64     `json:"votes" db:"votes"`
+	Closed  bool      `json:"closed,omitempty" db:"is_closed"`
+	Pinned  bool      `json:"pinned,omitempty" db:"is_pinned"`
+	Version uint64    `json:"-" db:"version"`
+
+	// MessageHTML is the rendered message, filled in only for format=html reads.
+	MessageHTML string `json:"messageHtml,omitempty" db:"-"`
+}
+
+type ThreadRevision struct {
+	ID      uint64    `json:"id" db:"id"`
+	Thread  uint64    `json:"thread" db:"thread_id"`
+	Title   string    `json:"title" db:"title"`
+	Message string    `json:"message" db:"message"`
+	Slug    string    `json:"slug" db:"slug"`
+	Edited  time.Time `json:"edited" db:"edited"`
+}
+
+type ThreadSlug struct {
+	Slug string `json:"slug"`
+}
+
+type ThreadUpdate struct {
+	Message OptionalString `json:"message"`
+	Title   OptionalString `json:"title"`
+}
+
+type ThreadModeration struct {
+	Closed *bool `json:"closed"`
+	Pinned *bool `json:"pinned"`
+}
+
+type ThreadSubscription struct {
+	Nickname string `json:"nickname" query:"nickname"`
+	Thread   uint64 `json:"thread"`
+}
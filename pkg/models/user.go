@@ -5,4 +5,11 @@ type User struct {
 	Email    string `json:"email" db:"email"`
 	FullName string `json:"fullname" db:"fullname"`
 	About    string `json:"about" db:"about"`
+	Version  uint64 `json:"-" db:"version"`
+}
+
+type UserUpdate struct {
+	Email    OptionalString `json:"email"`
+	FullName OptionalString `json:"fullname"`
+	About    OptionalString `json:"about"`
 }